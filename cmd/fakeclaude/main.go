@@ -0,0 +1,60 @@
+// Command fakeclaude stands in for the claude CLI. Installed on PATH as
+// "claude", it replays a cassette recorded from real Claude runs, or records
+// one by wrapping the real binary:
+//
+//	ALPINE_CASSETTE=run.json ALPINE_CASSETTE_MODE=record ALPINE_CASSETTE_CLAUDE=/path/to/claude alpine "task"
+//	ALPINE_CASSETTE=run.json alpine "task"
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Backland-Labs/alpine/internal/claude/cassette"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	path := os.Getenv(cassette.EnvPath)
+	if path == "" {
+		fmt.Fprintf(os.Stderr, "fakeclaude: %s is not set\n", cassette.EnvPath)
+		return 2
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fakeclaude: %v\n", err)
+		return 2
+	}
+	args := os.Args[1:]
+
+	var exitCode int
+	switch mode := os.Getenv(cassette.EnvMode); mode {
+	case "", cassette.ModeReplay:
+		cursor := os.Getenv(cassette.EnvCursor)
+		if cursor == "" {
+			cursor = path + ".cursor"
+		}
+		exitCode, err = cassette.Replay(path, cursor, args, dir, os.Stdout, os.Stderr)
+	case cassette.ModeRecord:
+		realClaude := os.Getenv(cassette.EnvRealClaude)
+		if realClaude == "" {
+			fmt.Fprintf(os.Stderr, "fakeclaude: %s must name the real claude binary in record mode\n", cassette.EnvRealClaude)
+			return 2
+		}
+		exitCode, err = cassette.Record(path, realClaude, args, dir, os.Stdin, os.Stdout, os.Stderr)
+	default:
+		fmt.Fprintf(os.Stderr, "fakeclaude: %s must be one of: record, replay; got: %s\n", cassette.EnvMode, mode)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fakeclaude: %v\n", err)
+		if exitCode == 0 {
+			exitCode = 2
+		}
+	}
+	return exitCode
+}
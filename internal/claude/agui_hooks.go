@@ -1,39 +1,37 @@
 package claude
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 
+	"github.com/Backland-Labs/alpine/internal/hooks"
+
 	"github.com/Backland-Labs/alpine/internal/logger"
 )
 
-// SetupAgUIHooks sets up the ag-ui event emitter hooks for HTTP mode
+// SetupAgUIHooks sets up the ag-ui event emitter hooks for HTTP mode in the
+// .claude directory under workDir (the current directory if empty).
 // Returns a cleanup function and error
-func (e *Executor) SetupAgUIHooks(eventEndpoint, runID string) (cleanup func(), err error) {
+func (e *Executor) SetupAgUIHooks(workDir, eventEndpoint, runID string) (cleanup func(), err error) {
 	logger.Debug("Setting up ag-ui event emitter hooks")
 
-	// Create .claude directory in current working directory
-	claudeDir := ".claude"
+	// Create .claude directory in the run's working directory
+	claudeDir := filepath.Join(workDir, ".claude")
 	if err := os.MkdirAll(claudeDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create .claude directory: %w", err)
 	}
 
-	// Get absolute path to ag-ui hook script
-	hookScriptPath, err := e.GetAgUIHookScriptPath()
+	// Point the hooks at the running alpine binary
+	command, err := hookCommand()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get ag-ui hook script path: %w", err)
-	}
-
-	// Verify hook script exists
-	if _, err := os.Stat(hookScriptPath); err != nil {
-		return nil, fmt.Errorf("ag-ui hook script not found at %s: %w", hookScriptPath, err)
+		return nil, fmt.Errorf("failed to resolve hook command: %w", err)
 	}
 
 	// Generate Claude settings
 	settingsPath := filepath.Join(claudeDir, "settings.json")
-	if err := e.generateAgUIClaudeSettings(settingsPath, hookScriptPath); err != nil {
+	restoreSettings, err := e.generateAgUIClaudeSettings(settingsPath, command)
+	if err != nil {
 		return nil, fmt.Errorf("failed to generate Claude settings: %w", err)
 	}
 
@@ -41,11 +39,11 @@ func (e *Executor) SetupAgUIHooks(eventEndpoint, runID string) (cleanup func(),
 	if e.envVars == nil {
 		e.envVars = make(map[string]string)
 	}
-	e.envVars["ALPINE_EVENTS_ENDPOINT"] = eventEndpoint
-	e.envVars["ALPINE_RUN_ID"] = runID
+	e.envVars[hooks.EnvEventsEndpoint] = eventEndpoint
+	e.envVars[hooks.EnvRunID] = runID
 
 	logger.WithFields(map[string]interface{}{
-		"hook_script":    hookScriptPath,
+		"hook_command":   command,
 		"settings_file":  settingsPath,
 		"event_endpoint": eventEndpoint,
 		"run_id":         runID,
@@ -54,80 +52,28 @@ func (e *Executor) SetupAgUIHooks(eventEndpoint, runID string) (cleanup func(),
 	// Return cleanup function
 	cleanup = func() {
 		logger.Debug("Cleaning up ag-ui hooks")
-		_ = os.Remove(settingsPath)
+		restoreSettings()
 		// Don't remove .claude directory - may contain user's own settings
 
 		// Clear environment variables
-		delete(e.envVars, "ALPINE_EVENTS_ENDPOINT")
-		delete(e.envVars, "ALPINE_RUN_ID")
+		delete(e.envVars, hooks.EnvEventsEndpoint)
+		delete(e.envVars, hooks.EnvRunID)
 	}
 
 	return cleanup, nil
 }
 
-// GetAgUIHookScriptPath returns the absolute path to the ag-ui hook script
-func (e *Executor) GetAgUIHookScriptPath() (string, error) {
-	// Get the current executable path to find the hooks directory
-	execPath, err := os.Executable()
-	if err != nil {
-		return "", fmt.Errorf("failed to get executable path: %w", err)
-	}
-
-	// Get the directory containing the executable
-	execDir := filepath.Dir(execPath)
-
-	// Try different paths where the hook script might be located
-	possiblePaths := []string{
-		// Development environment - hooks at project root
-		filepath.Join(execDir, "..", "..", "hooks", "alpine-ag-ui-emitter.rs"),
-		// Installed environment - hooks relative to binary
-		filepath.Join(execDir, "hooks", "alpine-ag-ui-emitter.rs"),
-		// Current working directory (for testing)
-		filepath.Join("hooks", "alpine-ag-ui-emitter.rs"),
-	}
-
-	for _, path := range possiblePaths {
-		absPath, err := filepath.Abs(path)
-		if err != nil {
-			continue
-		}
-		if _, err := os.Stat(absPath); err == nil {
-			return absPath, nil
-		}
-	}
-
-	return "", fmt.Errorf("ag-ui hook script not found in any expected location")
-}
-
-// generateAgUIClaudeSettings creates the Claude Code settings.json file with ag-ui hook configuration
-func (e *Executor) generateAgUIClaudeSettings(settingsPath, hookScriptPath string) error {
-	settings := hookSettings{
-		Hooks: map[string]interface{}{
-			"PostToolUse": []toolMatcher{
-				{
-					Matcher: ".*",
-					Hooks: []map[string]interface{}{
-						{
-							"command": hookScriptPath,
-							"type":    "command",
-						},
-					},
-				},
-			},
-		},
-	}
-
-	// Marshal to JSON
-	settingsJSON, err := json.MarshalIndent(settings, "", "  ")
+// generateAgUIClaudeSettings merges the ag-ui hooks into the Claude Code
+// settings.json file and returns a function that restores the original
+func (e *Executor) generateAgUIClaudeSettings(settingsPath, command string) (release func(), err error) {
+	release, err = mergeClaudeSettings(settingsPath, map[string][]toolMatcher{
+		hooks.EventPreToolUse:  commandHooks(".*", command),
+		hooks.EventPostToolUse: commandHooks(".*", command),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to marshal settings: %w", err)
-	}
-
-	// Write settings file
-	if err := os.WriteFile(settingsPath, settingsJSON, 0644); err != nil {
-		return fmt.Errorf("failed to write settings file: %w", err)
+		return nil, err
 	}
 
-	logger.WithField("settings_path", settingsPath).Debug("Generated ag-ui Claude settings file")
-	return nil
+	logger.WithField("settings_path", settingsPath).Debug("Merged ag-ui hooks into Claude settings file")
+	return release, nil
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,44 +13,24 @@ import (
 
 // TestSetupAgUIHooks tests setting up ag-ui event emitter hooks for HTTP mode
 func TestSetupAgUIHooks(t *testing.T) {
-	// Create a mock hook script for testing
-	createMockHookScript := func(dir string) {
-		hooksDir := filepath.Join(dir, "hooks")
-		err := os.MkdirAll(hooksDir, 0755)
-		require.NoError(t, err)
-
-		hookScript := filepath.Join(hooksDir, "alpine-ag-ui-emitter.rs")
-		err = os.WriteFile(hookScript, []byte("#!/usr/bin/env rust-script\n// Mock hook script for testing"), 0755)
-		require.NoError(t, err)
-	}
-
 	// Test cases for ag-ui hook configuration
 	t.Run("creates claude settings with ag-ui hook when HTTP mode enabled", func(t *testing.T) {
 		e := &Executor{}
 		tmpDir := t.TempDir()
 
-		// Change to temp directory to test settings creation
-		originalWd, _ := os.Getwd()
-		err := os.Chdir(tmpDir)
-		require.NoError(t, err)
-		defer func() { _ = os.Chdir(originalWd) }()
-
-		// Create mock hook script in current directory
-		createMockHookScript(tmpDir)
-
 		// Setup ag-ui hooks with event endpoint
 		eventEndpoint := "http://localhost:9090/events"
 		runID := "test-run-123"
-		cleanup, err := e.SetupAgUIHooks(eventEndpoint, runID)
+		cleanup, err := e.SetupAgUIHooks(tmpDir, eventEndpoint, runID)
 		require.NoError(t, err)
 		defer cleanup()
 
 		// Verify .claude directory was created
-		_, err = os.Stat(".claude")
+		_, err = os.Stat(filepath.Join(tmpDir, ".claude"))
 		assert.NoError(t, err, ".claude directory should be created")
 
 		// Verify settings.json was created
-		settingsPath := filepath.Join(".claude", "settings.json")
+		settingsPath := filepath.Join(tmpDir, ".claude", "settings.json")
 		_, err = os.Stat(settingsPath)
 		assert.NoError(t, err, "settings.json should be created")
 
@@ -80,32 +61,29 @@ func TestSetupAgUIHooks(t *testing.T) {
 		hook := hookList[0].(map[string]interface{})
 		assert.Equal(t, "command", hook["type"], "Hook type should be command")
 
-		// Verify hook command path is absolute
+		// Verify hook runs the alpine binary's hook subcommand
 		hookCommand := hook["command"].(string)
 		assert.True(t, filepath.IsAbs(hookCommand), "Hook command path should be absolute")
-		assert.Contains(t, hookCommand, "alpine-ag-ui-emitter", "Hook should use ag-ui emitter script")
+		assert.True(t, strings.HasSuffix(hookCommand, " hook"), "Hook should run the hook subcommand")
+
+		// Verify PreToolUse runs the same command so tool calls emit start events
+		preToolUse, ok := hooks["PreToolUse"].([]interface{})
+		require.True(t, ok, "PreToolUse hook should exist")
+		preHook := preToolUse[0].(map[string]interface{})["hooks"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, hookCommand, preHook["command"])
 	})
 
-	t.Run("resolves hook script path to absolute path", func(t *testing.T) {
+	t.Run("points hook command at the running binary", func(t *testing.T) {
 		e := &Executor{}
 		tmpDir := t.TempDir()
 
-		// Change to temp directory
-		originalWd, _ := os.Getwd()
-		err := os.Chdir(tmpDir)
-		require.NoError(t, err)
-		defer func() { _ = os.Chdir(originalWd) }()
-
-		// Create mock hook script in current directory
-		createMockHookScript(tmpDir)
-
 		// Setup hooks
-		cleanup, err := e.SetupAgUIHooks("http://localhost:9090/events", "run-123")
+		cleanup, err := e.SetupAgUIHooks(tmpDir, "http://localhost:9090/events", "run-123")
 		require.NoError(t, err)
 		defer cleanup()
 
 		// Read settings and check hook path
-		settingsData, err := os.ReadFile(filepath.Join(".claude", "settings.json"))
+		settingsData, err := os.ReadFile(filepath.Join(tmpDir, ".claude", "settings.json"))
 		require.NoError(t, err)
 
 		var settings map[string]interface{}
@@ -120,30 +98,22 @@ func TestSetupAgUIHooks(t *testing.T) {
 		hook := hookList[0].(map[string]interface{})
 		hookCommand := hook["command"].(string)
 
-		// Verify path is absolute and exists
-		assert.True(t, filepath.IsAbs(hookCommand), "Hook path should be absolute")
-		_, err = os.Stat(hookCommand)
-		assert.NoError(t, err, "Hook script should exist at specified path")
+		// Verify the binary part of the command exists
+		binary := strings.TrimSuffix(hookCommand, " hook")
+		assert.True(t, filepath.IsAbs(binary), "Hook binary path should be absolute")
+		_, err = os.Stat(binary)
+		assert.NoError(t, err, "Hook binary should exist at specified path")
 	})
 
 	t.Run("sets environment variables for hook context", func(t *testing.T) {
 		e := &Executor{}
 		tmpDir := t.TempDir()
 
-		// Change to temp directory
-		originalWd, _ := os.Getwd()
-		err := os.Chdir(tmpDir)
-		require.NoError(t, err)
-		defer func() { _ = os.Chdir(originalWd) }()
-
-		// Create mock hook script in current directory
-		createMockHookScript(tmpDir)
-
 		eventEndpoint := "http://localhost:8080/events"
 		runID := "unique-run-456"
 
 		// Setup hooks
-		cleanup, err := e.SetupAgUIHooks(eventEndpoint, runID)
+		cleanup, err := e.SetupAgUIHooks(tmpDir, eventEndpoint, runID)
 		require.NoError(t, err)
 		defer cleanup()
 
@@ -152,91 +122,34 @@ func TestSetupAgUIHooks(t *testing.T) {
 		assert.Equal(t, runID, e.envVars["ALPINE_RUN_ID"])
 	})
 
-	t.Run("handles missing hook script gracefully", func(t *testing.T) {
-		// Create a temp directory and move the hooks directory out of reach
-		tmpDir := t.TempDir()
-
-		// Change to a new empty directory where hooks won't be found
-		emptyDir := filepath.Join(tmpDir, "empty")
-		err := os.MkdirAll(emptyDir, 0755)
-		require.NoError(t, err)
-
-		originalWd, _ := os.Getwd()
-		err = os.Chdir(emptyDir)
-		require.NoError(t, err)
-		defer func() { _ = os.Chdir(originalWd) }()
-
-		// Create executor in empty directory where hook script won't be found
-		e := &Executor{}
-
-		// Attempt to setup hooks with missing script
-		_, err = e.SetupAgUIHooks("http://localhost:9090/events", "run-789")
-		assert.Error(t, err, "Should error when hook script is missing")
-		assert.Contains(t, err.Error(), "hook script", "Error should mention hook script")
-	})
-
 	t.Run("cleanup removes generated files", func(t *testing.T) {
 		e := &Executor{}
 		tmpDir := t.TempDir()
 
-		// Change to temp directory
-		originalWd, _ := os.Getwd()
-		err := os.Chdir(tmpDir)
-		require.NoError(t, err)
-		defer func() { _ = os.Chdir(originalWd) }()
-
-		// Create mock hook script in current directory
-		createMockHookScript(tmpDir)
-
 		// Setup hooks
-		cleanup, err := e.SetupAgUIHooks("http://localhost:9090/events", "run-cleanup")
+		cleanup, err := e.SetupAgUIHooks(tmpDir, "http://localhost:9090/events", "run-cleanup")
 		require.NoError(t, err)
 
 		// Verify files exist before cleanup
-		_, err = os.Stat(filepath.Join(".claude", "settings.json"))
+		_, err = os.Stat(filepath.Join(tmpDir, ".claude", "settings.json"))
 		assert.NoError(t, err, "settings.json should exist before cleanup")
 
 		// Run cleanup
 		cleanup()
 
 		// Verify settings.json is removed
-		_, err = os.Stat(filepath.Join(".claude", "settings.json"))
+		_, err = os.Stat(filepath.Join(tmpDir, ".claude", "settings.json"))
 		assert.True(t, os.IsNotExist(err), "settings.json should be removed after cleanup")
 
 		// .claude directory should remain (may contain user settings)
-		_, err = os.Stat(".claude")
+		_, err = os.Stat(filepath.Join(tmpDir, ".claude"))
 		assert.NoError(t, err, ".claude directory should remain after cleanup")
 	})
 }
 
-// TestGetAgUIHookScript tests retrieving the ag-ui hook script
-func TestGetAgUIHookScript(t *testing.T) {
-	t.Run("returns valid ag-ui hook script path", func(t *testing.T) {
-		// Create a temporary directory with hook script for testing
-		tmpDir := t.TempDir()
-		originalWd, _ := os.Getwd()
-		err := os.Chdir(tmpDir)
-		require.NoError(t, err)
-		defer func() { _ = os.Chdir(originalWd) }()
-
-		// Create hooks directory and script
-		hooksDir := filepath.Join(tmpDir, "hooks")
-		err = os.MkdirAll(hooksDir, 0755)
-		require.NoError(t, err)
-
-		hookScript := filepath.Join(hooksDir, "alpine-ag-ui-emitter.rs")
-		err = os.WriteFile(hookScript, []byte("#!/usr/bin/env rust-script\n// Mock hook script"), 0755)
-		require.NoError(t, err)
-
-		e := &Executor{}
-		scriptPath, err := e.GetAgUIHookScriptPath()
-		require.NoError(t, err)
-
-		// Verify path points to ag-ui emitter script
-		assert.Contains(t, scriptPath, "alpine-ag-ui-emitter.rs")
-
-		// Verify script exists
-		_, err = os.Stat(scriptPath)
-		assert.NoError(t, err, "ag-ui hook script should exist")
-	})
+// TestShellQuote tests quoting of hook command paths
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, "/usr/local/bin/alpine", shellQuote("/usr/local/bin/alpine"))
+	assert.Equal(t, "'/Users/me/my tools/alpine'", shellQuote("/Users/me/my tools/alpine"))
+	assert.Equal(t, `'/tmp/it'\''s/alpine'`, shellQuote("/tmp/it's/alpine"))
 }
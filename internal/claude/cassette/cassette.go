@@ -0,0 +1,186 @@
+// Package cassette records invocations of the claude CLI and replays them
+// through a fake claude binary, so whole workflows can be tested offline and
+// deterministically.
+//
+// A cassette is a JSON file holding one Interaction per invocation: the
+// arguments, a subset of the environment, the prompt, stdout, stderr, exit
+// code and the changes Claude made to the files alpine watches (the state file
+// and the plan). The fake binary in cmd/fakeclaude records a cassette by
+// wrapping the real claude, or replays one interaction per invocation in order.
+package cassette
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Backland-Labs/alpine/internal/core"
+)
+
+// Version is the cassette format version written by this package
+const Version = 1
+
+// Environment variables read by the fake claude binary
+const (
+	// EnvPath is the cassette file to record to or replay from
+	EnvPath = "ALPINE_CASSETTE"
+	// EnvMode is the mode of the fake binary: record or replay
+	EnvMode = "ALPINE_CASSETTE_MODE"
+	// EnvCursor is the file tracking how many interactions have been replayed
+	// (optional, defaults to the cassette path with a .cursor suffix)
+	EnvCursor = "ALPINE_CASSETTE_CURSOR"
+	// EnvRealClaude is the real claude binary wrapped in record mode
+	EnvRealClaude = "ALPINE_CASSETTE_CLAUDE"
+)
+
+// Modes of the fake claude binary
+const (
+	ModeRecord = "record"
+	ModeReplay = "replay"
+)
+
+// DefaultWatchedFiles are the files, relative to Claude's working directory,
+// whose changes are recorded and replayed
+var DefaultWatchedFiles = []string{
+	filepath.Join("agent_state", "agent_state.json"),
+	"plan.md",
+}
+
+// Cassette is a recorded sequence of claude invocations
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded invocation of the claude binary
+type Interaction struct {
+	// Args are the command-line arguments, without the binary name
+	Args []string `json:"args"`
+
+	// Env holds the ALPINE_ and CLAUDE_ environment variables Claude saw,
+	// excluding credentials
+	Env map[string]string `json:"env,omitempty"`
+
+	// Prompt is the value of the -p flag; replay checks it against the invocation
+	Prompt string `json:"prompt"`
+
+	Stdout   string `json:"stdout,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	ExitCode int    `json:"exit_code"`
+
+	// Files are the changes Claude made to watched files
+	Files []FileChange `json:"files,omitempty"`
+}
+
+// FileChange is the content a watched file had after an invocation
+type FileChange struct {
+	// Path is relative to Claude's working directory
+	Path string `json:"path"`
+
+	// Content is the new content of the file
+	Content string `json:"content,omitempty"`
+
+	// Deleted is set when the invocation removed the file
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// Load reads a cassette file
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	if c.Version > Version {
+		return nil, fmt.Errorf("cassette version %d is newer than supported version %d", c.Version, Version)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path, replacing any existing file
+func (c *Cassette) Save(path string) error {
+	c.Version = Version
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// Append adds an interaction to the cassette at path, creating it if needed
+func Append(path string, interaction Interaction) error {
+	c, err := Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		c, err = &Cassette{}, nil
+	}
+	if err != nil {
+		return err
+	}
+	c.Interactions = append(c.Interactions, interaction)
+	return c.Save(path)
+}
+
+// StateUpdate returns an interaction that answers prompt by printing stdout
+// and writing state to the state file, for building cassettes in tests
+func StateUpdate(prompt, stdout string, state core.State) Interaction {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		panic(fmt.Sprintf("cassette: cannot marshal state: %v", err))
+	}
+	return Interaction{
+		Prompt: prompt,
+		Stdout: stdout,
+		Files:  []FileChange{{Path: DefaultWatchedFiles[0], Content: string(data)}},
+	}
+}
+
+// PromptFromArgs returns the value of the -p flag
+func PromptFromArgs(args []string) string {
+	for i, arg := range args {
+		if (arg == "-p" || arg == "--print") && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// recordedEnv returns the ALPINE_ and CLAUDE_ variables from environ,
+// leaving out anything that looks like a credential
+func recordedEnv(environ []string) map[string]string {
+	env := map[string]string{}
+	for _, entry := range environ {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || !(strings.HasPrefix(key, "ALPINE_") || strings.HasPrefix(key, "CLAUDE_")) {
+			continue
+		}
+		if strings.HasPrefix(key, "ALPINE_CASSETTE") {
+			continue
+		}
+		upper := strings.ToUpper(key)
+		if strings.Contains(upper, "KEY") || strings.Contains(upper, "TOKEN") || strings.Contains(upper, "SECRET") {
+			continue
+		}
+		env[key] = value
+	}
+	if len(env) == 0 {
+		return nil
+	}
+	return env
+}
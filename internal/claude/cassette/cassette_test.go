@@ -0,0 +1,149 @@
+package cassette
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/core"
+)
+
+// writeScript writes an executable shell script standing in for the real claude
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "claude")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755))
+	return path
+}
+
+func TestRecord(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(t.TempDir(), "run.json")
+	realClaude := writeScript(t, `mkdir -p agent_state
+echo '{"status":"completed"}' > agent_state/agent_state.json
+echo "done"
+echo "warning" >&2
+exit 3
+`)
+	t.Setenv("ALPINE_MODEL", "claude-sonnet-4-20250514")
+	t.Setenv("ANTHROPIC_API_KEY", "sk-secret")
+	t.Setenv("CLAUDE_CODE_OAUTH_TOKEN", "secret")
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"--output-format", "text", "-p", "/start build it"}
+	exitCode, err := Record(path, realClaude, args, dir, nil, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, "done\n", stdout.String(), "output should pass through while recording")
+	assert.Equal(t, "warning\n", stderr.String())
+
+	c, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, c.Interactions, 1)
+	got := c.Interactions[0]
+	assert.Equal(t, args, got.Args)
+	assert.Equal(t, "/start build it", got.Prompt)
+	assert.Equal(t, "done\n", got.Stdout)
+	assert.Equal(t, "warning\n", got.Stderr)
+	assert.Equal(t, 3, got.ExitCode)
+	assert.Equal(t, []FileChange{{Path: DefaultWatchedFiles[0], Content: "{\"status\":\"completed\"}\n"}}, got.Files)
+	assert.Equal(t, "claude-sonnet-4-20250514", got.Env["ALPINE_MODEL"])
+	assert.NotContains(t, got.Env, "CLAUDE_CODE_OAUTH_TOKEN", "credentials must not be recorded")
+	assert.NotContains(t, got.Env, EnvPath)
+
+	// A second invocation appends to the same cassette
+	_, err = Record(path, writeScript(t, "rm -f agent_state/agent_state.json\n"), []string{"-p", "/continue"}, dir, nil, &stdout, &stderr)
+	require.NoError(t, err)
+	c, err = Load(path)
+	require.NoError(t, err)
+	require.Len(t, c.Interactions, 2)
+	assert.Equal(t, []FileChange{{Path: DefaultWatchedFiles[0], Deleted: true}}, c.Interactions[1].Files)
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(t.TempDir(), "run.json")
+	cursor := filepath.Join(t.TempDir(), "cursor")
+	c := &Cassette{Interactions: []Interaction{
+		StateUpdate("/start build it", "started\n", core.State{CurrentStepDescription: "Started", NextStepPrompt: "/continue", Status: core.StatusRunning}),
+		{Prompt: "/continue", Stderr: "boom\n", ExitCode: 1, Files: []FileChange{{Path: DefaultWatchedFiles[0], Deleted: true}}},
+	}}
+	require.NoError(t, c.Save(path))
+
+	var stdout, stderr bytes.Buffer
+	exitCode, err := Replay(path, cursor, []string{"-p", "/start build it"}, dir, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "started\n", stdout.String())
+	state, err := core.LoadState(filepath.Join(dir, DefaultWatchedFiles[0]))
+	require.NoError(t, err)
+	assert.Equal(t, "Started", state.CurrentStepDescription)
+
+	exitCode, err = Replay(path, cursor, []string{"-p", "/continue"}, dir, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, "boom\n", stderr.String())
+	assert.NoFileExists(t, filepath.Join(dir, DefaultWatchedFiles[0]))
+
+	_, err = Replay(path, cursor, []string{"-p", "/continue"}, dir, &stdout, &stderr)
+	assert.ErrorContains(t, err, "no interaction left")
+}
+
+func TestReplay_PromptMismatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	cursor := filepath.Join(t.TempDir(), "cursor")
+	require.NoError(t, (&Cassette{Interactions: []Interaction{{Prompt: "/start build it"}}}).Save(path))
+
+	_, err := Replay(path, cursor, []string{"-p", "/start something else"}, t.TempDir(), &bytes.Buffer{}, &bytes.Buffer{})
+	assert.ErrorContains(t, err, `recorded for prompt "/start build it"`)
+	played, err := readCursor(cursor)
+	require.NoError(t, err)
+	assert.Zero(t, played, "a mismatched invocation must not advance the cursor")
+}
+
+func TestReplay_RejectsPathsOutsideWorkDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	c := &Cassette{Interactions: []Interaction{{Files: []FileChange{{Path: "../escape", Content: "x"}}}}}
+	require.NoError(t, c.Save(path))
+
+	_, err := Replay(path, filepath.Join(t.TempDir(), "cursor"), nil, t.TempDir(), &bytes.Buffer{}, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "outside the working directory")
+}
+
+func TestLoad_RejectsNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"version": 99, "interactions": []}`), 0644))
+
+	_, err := Load(path)
+	assert.ErrorContains(t, err, "newer than supported")
+}
+
+func TestInstallInteractions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the fake claude binary")
+	}
+	player := InstallInteractions(t, Interaction{Prompt: "/continue", Stdout: "replayed\n"})
+	assert.Equal(t, 1, player.Remaining())
+
+	claude, err := exec.LookPath("claude")
+	require.NoError(t, err)
+	cmd := exec.Command(claude, "--output-format", "text", "-p", "/continue")
+	cmd.Dir = t.TempDir()
+	output, err := cmd.Output()
+	require.NoError(t, err)
+	assert.Equal(t, "replayed\n", string(output))
+	assert.Equal(t, 1, player.Played())
+	assert.Zero(t, player.Remaining())
+
+	// Further invocations fail instead of reaching the real Claude
+	cmd = exec.Command(claude, "-p", "/continue")
+	cmd.Dir = t.TempDir()
+	output, err = cmd.CombinedOutput()
+	require.Error(t, err)
+	assert.Contains(t, string(output), "fakeclaude:")
+}
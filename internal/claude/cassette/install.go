@@ -0,0 +1,74 @@
+package cassette
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeClaudePackage is the import path of the fake claude binary
+const fakeClaudePackage = "github.com/Backland-Labs/alpine/cmd/fakeclaude"
+
+// Player tracks a cassette being replayed by an installed fake claude
+type Player struct {
+	cursorPath string
+	total      int
+}
+
+// Install builds the fake claude binary and puts it first on PATH, replaying
+// the cassette at path for the rest of the test. Tests using it change the
+// environment and must not run in parallel.
+func Install(t testing.TB, path string) *Player {
+	t.Helper()
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %v", err)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatalf("failed to resolve cassette path: %v", err)
+	}
+
+	binDir := t.TempDir()
+	build := exec.Command("go", "build", "-o", filepath.Join(binDir, "claude"), fakeClaudePackage)
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("failed to build fake claude: %v\n%s", err, output)
+	}
+
+	player := &Player{cursorPath: filepath.Join(t.TempDir(), "cursor"), total: len(c.Interactions)}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv(EnvPath, absPath)
+	t.Setenv(EnvMode, ModeReplay)
+	t.Setenv(EnvCursor, player.cursorPath)
+	return player
+}
+
+// InstallInteractions saves interactions to a cassette in a temporary
+// directory and installs it like Install
+func InstallInteractions(t testing.TB, interactions ...Interaction) *Player {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := (&Cassette{Interactions: interactions}).Save(path); err != nil {
+		t.Fatalf("failed to save cassette: %v", err)
+	}
+	return Install(t, path)
+}
+
+// Played returns how many interactions have been replayed so far
+func (p *Player) Played() int {
+	data, err := os.ReadFile(p.cursorPath)
+	if err != nil {
+		return 0
+	}
+	played, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return played
+}
+
+// Remaining returns how many interactions have not been replayed yet
+func (p *Player) Remaining() int {
+	return p.total - p.Played()
+}
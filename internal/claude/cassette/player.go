@@ -0,0 +1,164 @@
+package cassette
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Record runs the real claude binary with args in dir, passing its output
+// through to stdout and stderr, and appends the invocation to the cassette at
+// path. It returns Claude's exit code.
+func Record(path, realClaude string, args []string, dir string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	before := snapshot(dir)
+
+	var outBuf, errBuf bytes.Buffer
+	cmd := exec.Command(realClaude, args...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	cmd.Stdin = stdin
+	cmd.Stdout = io.MultiWriter(stdout, &outBuf)
+	cmd.Stderr = io.MultiWriter(stderr, &errBuf)
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return 1, fmt.Errorf("failed to run %s: %w", realClaude, err)
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	interaction := Interaction{
+		Args:     args,
+		Env:      recordedEnv(os.Environ()),
+		Prompt:   PromptFromArgs(args),
+		Stdout:   outBuf.String(),
+		Stderr:   errBuf.String(),
+		ExitCode: exitCode,
+		Files:    changes(before, snapshot(dir)),
+	}
+	if err := Append(path, interaction); err != nil {
+		return exitCode, err
+	}
+	return exitCode, nil
+}
+
+// Replay plays the next interaction of the cassette at path for an
+// invocation with args in dir. It checks the prompt against the recording,
+// applies the recorded file changes and writes the recorded output, returning
+// the recorded exit code. cursorPath counts the interactions already played.
+func Replay(path, cursorPath string, args []string, dir string, stdout, stderr io.Writer) (int, error) {
+	c, err := Load(path)
+	if err != nil {
+		return 1, err
+	}
+
+	played, err := readCursor(cursorPath)
+	if err != nil {
+		return 1, err
+	}
+	prompt := PromptFromArgs(args)
+	if played >= len(c.Interactions) {
+		return 1, fmt.Errorf("cassette %s has no interaction left for prompt %q (played %d)", path, prompt, played)
+	}
+	interaction := c.Interactions[played]
+	if interaction.Prompt != prompt {
+		return 1, fmt.Errorf("cassette %s interaction %d was recorded for prompt %q, got %q", path, played+1, interaction.Prompt, prompt)
+	}
+	if err := os.WriteFile(cursorPath, []byte(strconv.Itoa(played+1)), 0644); err != nil {
+		return 1, fmt.Errorf("failed to update cassette cursor: %w", err)
+	}
+
+	for _, change := range interaction.Files {
+		if err := apply(dir, change); err != nil {
+			return 1, err
+		}
+	}
+	if _, err := io.WriteString(stdout, interaction.Stdout); err != nil {
+		return 1, err
+	}
+	if _, err := io.WriteString(stderr, interaction.Stderr); err != nil {
+		return 1, err
+	}
+	return interaction.ExitCode, nil
+}
+
+// readCursor returns the number of interactions already played
+func readCursor(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cassette cursor: %w", err)
+	}
+	played, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid cassette cursor %s: %w", path, err)
+	}
+	return played, nil
+}
+
+// snapshot reads the watched files in dir; missing files map to nil
+func snapshot(dir string) map[string]*string {
+	files := make(map[string]*string, len(DefaultWatchedFiles))
+	for _, rel := range DefaultWatchedFiles {
+		data, err := os.ReadFile(filepath.Join(dir, rel))
+		if err != nil {
+			files[rel] = nil
+			continue
+		}
+		content := string(data)
+		files[rel] = &content
+	}
+	return files
+}
+
+// changes lists the watched files that differ between two snapshots
+func changes(before, after map[string]*string) []FileChange {
+	var result []FileChange
+	for _, rel := range DefaultWatchedFiles {
+		old, now := before[rel], after[rel]
+		switch {
+		case now == nil && old != nil:
+			result = append(result, FileChange{Path: rel, Deleted: true})
+		case now != nil && (old == nil || *old != *now):
+			result = append(result, FileChange{Path: rel, Content: *now})
+		}
+	}
+	return result
+}
+
+// apply makes a recorded file change in dir
+func apply(dir string, change FileChange) error {
+	if filepath.IsAbs(change.Path) || strings.HasPrefix(filepath.Clean(change.Path), "..") {
+		return fmt.Errorf("cassette file change %q is outside the working directory", change.Path)
+	}
+	path := filepath.Join(dir, change.Path)
+	if change.Deleted {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", change.Path, err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", change.Path, err)
+	}
+	// Replace the file in one step, as alpine may be watching it
+	tmp := path + ".cassette-tmp"
+	if err := os.WriteFile(tmp, []byte(change.Content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", change.Path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", change.Path, err)
+	}
+	return nil
+}
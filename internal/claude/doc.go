@@ -14,7 +14,7 @@
 //	config := claude.ExecuteConfig{
 //		Prompt:      "/make_plan Implement user authentication",
 //		StateFile:   "claude_state.json",
-//		MCPServers:  []string{"context7"},
+//		MCPConfig:   "mcp.json",
 //		Timeout:     5 * time.Minute,
 //	}
 //	output, err := executor.Execute(context.Background(), config)
@@ -0,0 +1,123 @@
+package claude
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Backland-Labs/alpine/internal/core"
+	"github.com/Backland-Labs/alpine/internal/logger"
+)
+
+// DryRun replaces Claude execution with a printout of each claude command
+// line and a scripted state progression standing in for Claude's updates
+type DryRun struct {
+	out    io.Writer
+	states []core.State
+
+	mu    sync.Mutex
+	calls int
+}
+
+// NewDryRun creates a dry run that prints command lines to out (optional)
+// and writes states to the state file in order, one per invocation. Without
+// states, the first invocation continues the workflow and the second
+// completes it. Once the script is exhausted the workflow is completed.
+func NewDryRun(out io.Writer, states []core.State) *DryRun {
+	return &DryRun{out: out, states: states}
+}
+
+// LoadDryRunScript reads a JSON array of states for NewDryRun
+func LoadDryRunScript(path string) ([]core.State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dry-run script: %w", err)
+	}
+	var states []core.State
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("invalid dry-run script %s: %w", path, err)
+	}
+	return states, nil
+}
+
+// SetDryRun makes the executor print command lines instead of running Claude
+func (e *Executor) SetDryRun(dryRun *DryRun) {
+	e.dryRun = dryRun
+}
+
+// next returns the state the current invocation writes
+func (d *DryRun) next() core.State {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls++
+
+	if len(d.states) == 0 {
+		if d.calls == 1 {
+			return core.State{
+				CurrentStepDescription: "Dry run: simulated iteration 1",
+				NextStepPrompt:         "/continue",
+				Status:                 core.StatusRunning,
+			}
+		}
+		return core.State{
+			CurrentStepDescription: fmt.Sprintf("Dry run: simulated iteration %d", d.calls),
+			Status:                 core.StatusCompleted,
+		}
+	}
+	if d.calls > len(d.states) {
+		return core.State{
+			CurrentStepDescription: "Dry run: script exhausted",
+			Status:                 core.StatusCompleted,
+		}
+	}
+	return d.states[d.calls-1]
+}
+
+// executeDryRun prints the command Claude would run and writes the next
+// scripted state in place of Claude's update
+func (e *Executor) executeDryRun(config ExecuteConfig) (string, error) {
+	cmd := e.buildCommand(config)
+	line := FormatCommandLine(cmd.Args, config.EnvironmentVariables)
+
+	logger.WithFields(map[string]interface{}{
+		"args":     cmd.Args,
+		"work_dir": cmd.Dir,
+		"run_id":   e.runID,
+	}).Info("Dry run: skipping Claude execution")
+	if e.dryRun.out != nil {
+		if _, err := fmt.Fprintf(e.dryRun.out, "[dry-run] cd %s && %s\n", shellQuote(cmd.Dir), line); err != nil {
+			return "", fmt.Errorf("failed to print dry-run command: %w", err)
+		}
+	}
+
+	state := e.dryRun.next()
+	if err := state.Save(config.StateFile); err != nil {
+		return "", fmt.Errorf("failed to write dry-run state: %w", err)
+	}
+
+	e.reportSession(config, SessionInfo{ID: config.SessionID})
+	return "", nil
+}
+
+// FormatCommandLine renders args as a shell command line, preceded by the
+// extra environment variables Claude runs with
+func FormatCommandLine(args []string, env map[string]string) string {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys)+len(args))
+	for _, key := range keys {
+		parts = append(parts, key+"="+shellQuote(env[key]))
+	}
+	for _, arg := range args {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
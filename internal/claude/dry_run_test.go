@@ -0,0 +1,112 @@
+package claude
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/core"
+)
+
+func TestExecutor_DryRunPrintsCommandsAndSimulatesState(t *testing.T) {
+	// Nothing named claude can be found, so any real execution would fail
+	t.Setenv("PATH", t.TempDir())
+
+	workDir := t.TempDir()
+	stateFile := filepath.Join(workDir, "agent_state", "agent_state.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(stateFile), 0755))
+
+	var out bytes.Buffer
+	executor := NewExecutor()
+	executor.SetDryRun(NewDryRun(&out, nil))
+
+	var sessions []SessionInfo
+	config := ExecuteConfig{
+		Prompt:       "/start Add a greeting endpoint",
+		StateFile:    stateFile,
+		WorkDir:      workDir,
+		Model:        "claude-opus-4-20250514",
+		AllowedTools: []string{"Read", "Edit"},
+		SessionID:    "session-1",
+		OnSession:    func(session SessionInfo) { sessions = append(sessions, session) },
+	}
+	_, err := executor.Execute(context.Background(), config)
+	require.NoError(t, err)
+
+	line := out.String()
+	assert.True(t, strings.HasPrefix(line, "[dry-run] cd "+workDir+" && claude --output-format text"), line)
+	assert.Contains(t, line, "--allowedTools Read Edit")
+	assert.Contains(t, line, "--model claude-opus-4-20250514")
+	assert.Contains(t, line, "--session-id session-1")
+	assert.Contains(t, line, "-p '/start Add a greeting endpoint'")
+	assert.Equal(t, []SessionInfo{{ID: "session-1"}}, sessions)
+
+	state, err := core.LoadState(stateFile)
+	require.NoError(t, err)
+	assert.Equal(t, core.StatusRunning, state.Status)
+	assert.Equal(t, "/continue", state.NextStepPrompt)
+
+	// The second invocation completes the simulated workflow
+	config.Prompt = state.NextStepPrompt
+	_, err = executor.Execute(context.Background(), config)
+	require.NoError(t, err)
+	state, err = core.LoadState(stateFile)
+	require.NoError(t, err)
+	assert.Equal(t, core.StatusCompleted, state.Status)
+	assert.Equal(t, 2, strings.Count(out.String(), "[dry-run]"))
+}
+
+func TestExecutor_DryRunScript(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.json")
+	require.NoError(t, os.WriteFile(script, []byte(`[
+		{"current_step_description": "Planned", "next_step_prompt": "/run_implementation_loop", "status": "running"},
+		{"current_step_description": "Needs a decision", "status": "awaiting_input"}
+	]`), 0644))
+	states, err := LoadDryRunScript(script)
+	require.NoError(t, err)
+
+	executor := NewExecutor()
+	executor.SetDryRun(NewDryRun(nil, states))
+	stateFile := filepath.Join(dir, "agent_state.json")
+	config := ExecuteConfig{Prompt: "/start task", StateFile: stateFile, WorkDir: dir}
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		_, err := executor.Execute(context.Background(), config)
+		require.NoError(t, err)
+		state, err := core.LoadState(stateFile)
+		require.NoError(t, err)
+		got = append(got, state.CurrentStepDescription+"/"+state.Status)
+	}
+	assert.Equal(t, []string{
+		"Planned/running",
+		"Needs a decision/awaiting_input",
+		"Dry run: script exhausted/completed",
+	}, got)
+}
+
+func TestLoadDryRunScript_Invalid(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "script.json")
+	require.NoError(t, os.WriteFile(script, []byte(`{"status": "running"}`), 0644))
+
+	_, err := LoadDryRunScript(script)
+	assert.ErrorContains(t, err, "invalid dry-run script")
+
+	_, err = LoadDryRunScript(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorContains(t, err, "failed to read dry-run script")
+}
+
+func TestFormatCommandLine(t *testing.T) {
+	line := FormatCommandLine(
+		[]string{"claude", "--model", "m", "-p", "it's done", ""},
+		map[string]string{"B_VAR": "two words", "A_VAR": "x"},
+	)
+	assert.Equal(t, `A_VAR=x B_VAR='two words' claude --model m -p 'it'\''s done' ''`, line)
+}
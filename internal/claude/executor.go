@@ -6,10 +6,12 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,6 +19,8 @@ import (
 	"github.com/Backland-Labs/alpine/internal/events"
 	"github.com/Backland-Labs/alpine/internal/logger"
 	"github.com/Backland-Labs/alpine/internal/output"
+	"github.com/Backland-Labs/alpine/internal/prompts"
+	"github.com/Backland-Labs/alpine/internal/sandbox"
 )
 
 // ExecuteConfig holds configuration for executing Claude
@@ -30,15 +34,27 @@ type ExecuteConfig struct {
 	// WorkDir is the working directory for Claude execution (optional, defaults to current directory)
 	WorkDir string
 
-	// MCPServers is a list of MCP servers to enable (optional)
-	MCPServers []string
+	// MCPConfig is an MCP config file, written by WriteMCPConfig, whose
+	// servers Claude connects to (optional)
+	MCPConfig string
 
 	// AllowedTools restricts which tools Claude can use (optional)
 	AllowedTools []string
 
+	// DisallowedTools are tools Claude must not use, taking precedence over
+	// AllowedTools (optional)
+	DisallowedTools []string
+
 	// SystemPrompt overrides the default system prompt (optional)
 	SystemPrompt string
 
+	// Model is the Claude model to use (optional, defaults to DefaultModel)
+	Model string
+
+	// OutputFormat selects Claude's output format (optional, defaults to text).
+	// With stream-json, output is decoded into typed events for the streamer and printer.
+	OutputFormat string
+
 	// Timeout for the Claude execution (optional, defaults to no timeout)
 	Timeout time.Duration
 
@@ -47,6 +63,26 @@ type ExecuteConfig struct {
 
 	// EnvironmentVariables are additional environment variables to pass to Claude (optional)
 	EnvironmentVariables map[string]string
+
+	// Sandbox runs Claude confined to its working directory (optional)
+	Sandbox *sandbox.Sandbox
+
+	// Retry controls retries of transient failures such as rate limits (optional, zero disables retries)
+	Retry RetryPolicy
+
+	// OnRetry is called before each retry of a transient failure (optional)
+	OnRetry func(RetryEvent)
+
+	// SessionID starts a Claude session with this ID, or resumes it when
+	// ResumeSession is set (optional, defaults to a session chosen by Claude)
+	SessionID string
+
+	// ResumeSession resumes SessionID instead of starting a new session
+	ResumeSession bool
+
+	// OnSession is called after a successful execution with the session
+	// Claude ran in (optional, only called when SessionID is set)
+	OnSession func(SessionInfo)
 }
 
 // Executor handles execution of Claude commands
@@ -57,6 +93,7 @@ type Executor struct {
 	envVars       map[string]string // Additional environment variables to pass to Claude
 	streamer      events.Streamer   // Optional streamer for real-time output
 	runID         string            // Run ID for stream correlation
+	dryRun        *DryRun           // Prints command lines instead of running Claude, if set
 }
 
 // CommandRunner interface for testing
@@ -99,8 +136,9 @@ func (e *Executor) Execute(ctx context.Context, config ExecuteConfig) (string, e
 	logger.WithFields(map[string]interface{}{
 		"prompt_length":     len(config.Prompt),
 		"state_file":        config.StateFile,
-		"mcp_servers":       config.MCPServers,
+		"mcp_config":        config.MCPConfig,
 		"allowed_tools":     config.AllowedTools,
+		"disallowed_tools":  config.DisallowedTools,
 		"has_system_prompt": config.SystemPrompt != "",
 		"timeout":           config.Timeout,
 		"run_id":            e.runID,
@@ -133,11 +171,26 @@ func (e *Executor) Execute(ctx context.Context, config ExecuteConfig) (string, e
 
 	logger.WithFields(map[string]interface{}{
 		"state_file":     config.StateFile,
-		"mcp_servers":    len(config.MCPServers),
+		"mcp_config":     config.MCPConfig,
 		"allowed_tools":  len(config.AllowedTools),
 		"prompt_preview": truncateString(config.Prompt, 100),
 	}).Info("Claude configuration validated")
 
+	if e.dryRun != nil {
+		return e.executeDryRun(config)
+	}
+
+	return e.executeWithRetry(ctx, config)
+}
+
+// executeOnce runs a single Claude invocation using the configured output mode
+func (e *Executor) executeOnce(ctx context.Context, config ExecuteConfig) (string, error) {
+	// Structured output replaces the TODO hook and stderr capture
+	if config.OutputFormat == OutputFormatStreamJSON {
+		logger.Info("Claude execution will decode stream-json output")
+		return e.executeStreamJSON(ctx, config)
+	}
+
 	// Check if we should use todo monitoring
 	if e.config != nil && e.config.ShowTodoUpdates && e.printer != nil {
 		logger.Info("Claude execution will use TODO monitoring")
@@ -174,7 +227,7 @@ func (e *Executor) executeWithTodoMonitoring(ctx context.Context, config Execute
 	}).Debug("Starting Claude execution with TODO monitoring")
 
 	// Setup hook (fallback to normal execution on failure)
-	todoFile, cleanup, err := e.setupTodoHook()
+	todoFile, cleanup, err := e.setupTodoHook(config.WorkDir)
 	if err != nil {
 		logger.WithFields(map[string]interface{}{
 			"error":  err.Error(),
@@ -289,7 +342,7 @@ func (e *Executor) executeClaudeCommand(ctx context.Context, config ExecuteConfi
 		"args_count":  len(cmd.Args),
 		"working_dir": cmd.Dir,
 	}).Info("Executing Claude command")
-	output, err := cmd.CombinedOutput()
+	output, err := combinedOutput(cmd)
 	duration := time.Since(startTime)
 
 	if err != nil {
@@ -300,7 +353,7 @@ func (e *Executor) executeClaudeCommand(ctx context.Context, config ExecuteConfi
 			"output_length":  len(output),
 			"output_preview": truncateString(string(output), 200),
 		}).Error("Claude execution failed")
-		return "", fmt.Errorf("claude execution failed: %w", err)
+		return "", newExecutionError(err, string(output))
 	}
 
 	logger.WithFields(map[string]interface{}{
@@ -311,6 +364,51 @@ func (e *Executor) executeClaudeCommand(ctx context.Context, config ExecuteConfi
 	return string(output), nil
 }
 
+// processWaitDelay bounds how long Claude's output is read after the process
+// exits or is killed. Processes Claude started, such as MCP servers or
+// commands run by tools, may keep its stdout and stderr open.
+var processWaitDelay = 5 * time.Second
+
+// waitAndClose waits for cmd in the background and then closes writers, the
+// process's output pipes, so their readers reach EOF. The returned channel
+// receives the result of cmd.Wait; output still held open by other processes
+// after processWaitDelay is abandoned without failing the command.
+func waitAndClose(cmd *exec.Cmd, writers ...*io.PipeWriter) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		err := waitProcess(cmd)
+		for _, w := range writers {
+			_ = w.Close()
+		}
+		done <- err
+	}()
+	return done
+}
+
+// waitProcess waits for cmd, which must have WaitDelay set, ignoring output
+// pipes left open by processes it started
+func waitProcess(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+	if errors.Is(err, exec.ErrWaitDelay) {
+		logger.WithField("command", cmd.Path).Debug("Claude exited with its output still held open by another process")
+		return nil
+	}
+	return err
+}
+
+// combinedOutput runs cmd and returns its combined stdout and stderr, like
+// cmd.CombinedOutput but without waiting on output held open by children
+func combinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	var output bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &output, &output
+	cmd.WaitDelay = processWaitDelay
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	err := waitProcess(cmd)
+	return output.Bytes(), err
+}
+
 // executeWithStderrCapture runs a command with separate stdout/stderr handling
 // stderr lines are sent to printer.AddToolLog() in real-time
 // stdout is streamed in real-time if a streamer is configured
@@ -331,17 +429,12 @@ func (e *Executor) executeWithStderrCapture(ctx context.Context, cmd *exec.Cmd)
 		}
 	}
 
-	// Get stdout pipe
-	stdoutPipe, err := cmd.StdoutPipe()
-	if err != nil {
-		return "", fmt.Errorf("failed to create stdout pipe: %w", err)
-	}
-
-	// Get stderr pipe
-	stderrPipe, err := cmd.StderrPipe()
-	if err != nil {
-		return "", fmt.Errorf("failed to create stderr pipe: %w", err)
-	}
+	// Read stdout and stderr through pipes closed once the process has been
+	// waited for, so children holding them open cannot block the readers
+	stdoutPipe, stdoutWriter := io.Pipe()
+	stderrPipe, stderrWriter := io.Pipe()
+	cmd.Stdout, cmd.Stderr = stdoutWriter, stderrWriter
+	cmd.WaitDelay = processWaitDelay
 
 	// Start the command
 	logger.WithFields(map[string]interface{}{
@@ -354,6 +447,7 @@ func (e *Executor) executeWithStderrCapture(ctx context.Context, cmd *exec.Cmd)
 		logger.WithField("error", err.Error()).Error("Failed to start Claude command")
 		return "", fmt.Errorf("failed to start command: %w", err)
 	}
+	waitDone := waitAndClose(cmd, stdoutWriter, stderrWriter)
 
 	// Capture stdout
 	var stdoutBuf bytes.Buffer
@@ -363,6 +457,7 @@ func (e *Executor) executeWithStderrCapture(ctx context.Context, cmd *exec.Cmd)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		defer func() { _ = stdoutPipe.Close() }()
 
 		// Create writers based on streaming configuration
 		var writer io.Writer = &stdoutBuf
@@ -397,13 +492,17 @@ func (e *Executor) executeWithStderrCapture(ctx context.Context, cmd *exec.Cmd)
 		}
 	}()
 
-	// Read stderr line-by-line and send to AddToolLog
+	// Read stderr line-by-line and send to AddToolLog, keeping it for
+	// classifying failures
+	var stderrBuf bytes.Buffer
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
+		defer func() { _ = stderrPipe.Close() }()
 		scanner := bufio.NewScanner(stderrPipe)
 		for scanner.Scan() {
 			line := scanner.Text()
+			stderrBuf.WriteString(line + "\n")
 			if e.printer != nil {
 				e.printer.AddToolLog(line)
 				e.printer.RenderToolLogs()
@@ -415,12 +514,10 @@ func (e *Executor) executeWithStderrCapture(ctx context.Context, cmd *exec.Cmd)
 		}
 	}()
 
-	// Wait for command to complete
-	err = cmd.Wait()
-	duration := time.Since(startTime)
-
-	// Wait for all goroutines to finish reading
+	// Wait for command to complete, then finish reading both pipes
+	err := <-waitDone
 	wg.Wait()
+	duration := time.Since(startTime)
 
 	output := stdoutBuf.String()
 
@@ -442,9 +539,10 @@ func (e *Executor) executeWithStderrCapture(ctx context.Context, cmd *exec.Cmd)
 			"duration_ms":    duration.Milliseconds(),
 			"output_length":  len(output),
 			"output_preview": truncateString(output, 200),
+			"stderr_preview": truncateString(stderrBuf.String(), 200),
 			"run_id":         e.runID,
 		}).Error("Claude execution failed with stderr capture")
-		return "", fmt.Errorf("claude execution failed: %w", err)
+		return "", newExecutionError(err, output+"\n"+stderrBuf.String())
 	}
 
 	logger.WithFields(map[string]interface{}{
@@ -478,31 +576,41 @@ func truncateString(s string, maxLen int) string {
 }
 
 // DefaultSystemPrompt is the default system prompt used when none is provided
-const DefaultSystemPrompt = "You are an expert software engineer with deep knowledge of TDD, Python, Typescript. Execute the following tasks with surgical precision while taking care not to overengineer solutions."
-
-// DefaultAllowedTools are the default tools allowed when none are specified
-var DefaultAllowedTools = []string{
-	"Bash",
-	"Read",
-	"Write",
-	"Edit",
-	"Remove",
-	"TodoWrite",
-	"Task",
+var DefaultSystemPrompt = strings.TrimSpace(prompts.PromptSystem)
+
+// DefaultModel is the Claude model used when none is specified
+const DefaultModel = config.DefaultModel
+
+// Output formats accepted in ExecuteConfig.OutputFormat
+const (
+	OutputFormatText       = config.OutputFormatText
+	OutputFormatStreamJSON = config.OutputFormatStreamJSON
+)
+
+// DefaultAllowedTools are the default tools allowed when none are specified,
+// those of the default permission profile
+var DefaultAllowedTools = defaultProfileTools()
+
+// defaultProfileTools returns the allowed tools of the default permission profile
+func defaultProfileTools() []string {
+	p, _ := config.BuiltinProfile(config.ProfileDefault)
+	return p.AllowedTools
 }
 
 // buildCommand constructs the exec.Cmd for Claude
 func (e *Executor) buildCommand(config ExecuteConfig) *exec.Cmd {
 	args := []string{}
 
-	// Add output format
-	args = append(args, "--output-format", "text")
+	// Add output format; stream-json requires --verbose in print mode
+	if config.OutputFormat == OutputFormatStreamJSON {
+		args = append(args, "--output-format", OutputFormatStreamJSON, "--verbose")
+	} else {
+		args = append(args, "--output-format", OutputFormatText)
+	}
 
 	// Add MCP servers
-	if len(config.MCPServers) > 0 {
-		for _, server := range config.MCPServers {
-			args = append(args, "--mcp-server", server)
-		}
+	if config.MCPConfig != "" {
+		args = append(args, "--mcp-config", config.MCPConfig)
 	}
 
 	// Add allowed tools restriction
@@ -514,6 +622,10 @@ func (e *Executor) buildCommand(config ExecuteConfig) *exec.Cmd {
 		args = append(args, "--allowedTools")
 		args = append(args, allowedTools...)
 	}
+	if len(config.DisallowedTools) > 0 {
+		args = append(args, "--disallowedTools")
+		args = append(args, config.DisallowedTools...)
+	}
 
 	// Add system prompt
 	systemPrompt := config.SystemPrompt
@@ -522,8 +634,21 @@ func (e *Executor) buildCommand(config ExecuteConfig) *exec.Cmd {
 	}
 	args = append(args, "--append-system-prompt", systemPrompt)
 
-	// set model
-	args = append(args, "--model", "claude-sonnet-4-20250514")
+	// Set model
+	model := config.Model
+	if model == "" {
+		model = DefaultModel
+	}
+	args = append(args, "--model", model)
+
+	// Start or resume a specific session
+	if config.SessionID != "" {
+		if config.ResumeSession {
+			args = append(args, "--resume", config.SessionID)
+		} else {
+			args = append(args, "--session-id", config.SessionID)
+		}
+	}
 
 	// Note: Claude CLI doesn't have a --project flag
 	// It uses the current working directory by default
@@ -585,6 +710,11 @@ func (e *Executor) buildCommand(config ExecuteConfig) *exec.Cmd {
 		}
 	}
 
+	if config.Sandbox != nil {
+		config.Sandbox.Wrap(cmd)
+		logger.WithField("command", cmd.Args[0]).Debug("Running Claude in sandbox")
+	}
+
 	return cmd
 }
 
@@ -655,7 +785,7 @@ func (r *defaultCommandRunner) Run(ctx context.Context, config ExecuteConfig) (s
 		"command":     cmd.Path,
 		"working_dir": cmd.Dir,
 	}).Info("Executing Claude command")
-	output, err := cmd.CombinedOutput()
+	output, err := combinedOutput(cmd)
 	duration := time.Since(startTime)
 
 	if err != nil {
@@ -666,7 +796,7 @@ func (r *defaultCommandRunner) Run(ctx context.Context, config ExecuteConfig) (s
 			"output_length":  len(output),
 			"output_preview": truncateString(string(output), 200),
 		}).Error("Claude execution failed")
-		return "", fmt.Errorf("claude execution failed: %w", err)
+		return "", newExecutionError(err, string(output))
 	}
 
 	logger.WithFields(map[string]interface{}{
@@ -0,0 +1,218 @@
+package claude
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/Backland-Labs/alpine/internal/logger"
+)
+
+// executeStreamJSON runs Claude with stream-json output and routes the decoded
+// events to the streamer and the printer's tool log. Tool activity and usage
+// come straight from Claude's own events, so no hook script or stderr parsing
+// is involved.
+func (e *Executor) executeStreamJSON(ctx context.Context, config ExecuteConfig) (string, error) {
+	baseCmd := e.buildCommandWithValidation(config)
+
+	// Handle timeout
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+		logger.WithFields(map[string]interface{}{
+			"timeout": config.Timeout,
+			"run_id":  e.runID,
+		}).Info("Setting Claude execution timeout")
+	}
+
+	// Create command with context
+	cmd := exec.CommandContext(ctx, baseCmd.Path, baseCmd.Args[1:]...)
+	cmd.Env = baseCmd.Env
+	cmd.Dir = baseCmd.Dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdoutPipe, stdoutWriter := io.Pipe()
+	cmd.Stdout = stdoutWriter
+	cmd.WaitDelay = processWaitDelay
+
+	// Generate message ID for streaming if streamer is available
+	var messageID string
+	if e.streamer != nil && e.runID != "" {
+		messageID = generateMessageID()
+		if err := e.streamer.StreamStart(e.runID, messageID); err != nil {
+			logger.WithFields(map[string]interface{}{
+				"error":     err,
+				"runID":     e.runID,
+				"messageID": messageID,
+			}).Debug("Failed to start streaming")
+		}
+		defer func() {
+			if err := e.streamer.StreamEnd(e.runID, messageID); err != nil {
+				logger.WithFields(map[string]interface{}{
+					"error":     err,
+					"runID":     e.runID,
+					"messageID": messageID,
+				}).Debug("Failed to end streaming")
+			}
+		}()
+	}
+
+	showTodos := e.config != nil && e.config.ShowTodoUpdates && e.printer != nil
+	if showTodos {
+		e.printer.StartTodoMonitoring()
+		defer e.printer.StopTodoMonitoring()
+	}
+
+	startTime := time.Now()
+	logger.WithFields(map[string]interface{}{
+		"command":     cmd.Path,
+		"working_dir": cmd.Dir,
+		"streaming":   messageID != "",
+		"run_id":      e.runID,
+	}).Info("Starting Claude command with stream-json output")
+	if err := cmd.Start(); err != nil {
+		logger.WithField("error", err.Error()).Error("Failed to start Claude command")
+		return "", fmt.Errorf("failed to start command: %w", err)
+	}
+	waitDone := waitAndClose(cmd, stdoutWriter)
+
+	var text strings.Builder
+	session := SessionInfo{ID: config.SessionID}
+	result, parseErr := ParseStream(stdoutPipe, func(event StreamEvent) {
+		session.observe(event)
+		e.handleStreamEvent(event, messageID, showTodos, &text)
+	}, func(err error) {
+		logger.WithField("error", err.Error()).Debug("Skipping undecodable stream-json line")
+	})
+
+	// Unblock the process's output if parsing stopped early
+	_ = stdoutPipe.Close()
+	waitErr := <-waitDone
+	duration := time.Since(startTime)
+
+	if waitErr != nil {
+		logger.WithFields(map[string]interface{}{
+			"error":          waitErr.Error(),
+			"duration":       duration.String(),
+			"duration_ms":    duration.Milliseconds(),
+			"stderr_preview": truncateString(stderr.String(), 200),
+			"run_id":         e.runID,
+		}).Error("Claude execution failed with stream-json output")
+		// API errors are reported in the result event rather than on stderr
+		output := stderr.String()
+		if result != nil {
+			output = resultErrorMessage(result) + "\n" + output
+		}
+		return "", newExecutionError(waitErr, output)
+	}
+	if parseErr != nil {
+		return "", fmt.Errorf("failed to read stream-json output: %w", parseErr)
+	}
+
+	if result == nil {
+		logger.WithField("run_id", e.runID).Debug("stream-json output ended without a result event")
+		e.reportSession(config, session)
+		return text.String(), nil
+	}
+	if result.IsError {
+		message := resultErrorMessage(result)
+		return "", newExecutionError(errors.New(message), message+"\n"+stderr.String())
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"duration":      duration.String(),
+		"duration_ms":   duration.Milliseconds(),
+		"output_length": len(result.Result),
+		"run_id":        e.runID,
+	}).Info("Claude execution completed successfully with stream-json output")
+	e.reportSession(config, session)
+	return result.Result, nil
+}
+
+// handleStreamEvent routes a single decoded event to the streamer, printer and logs
+func (e *Executor) handleStreamEvent(event StreamEvent, messageID string, showTodos bool, text *strings.Builder) {
+	switch event.Type {
+	case StreamEventInit:
+		logger.WithFields(map[string]interface{}{
+			"session_id": event.SessionID,
+			"model":      event.Model,
+			"run_id":     e.runID,
+		}).Debug("Claude session started")
+
+	case StreamEventText:
+		text.WriteString(event.Text)
+		if messageID != "" {
+			if err := e.streamer.StreamContent(e.runID, messageID, event.Text); err != nil {
+				logger.WithField("error", err).Debug("Failed to stream content")
+			}
+		}
+
+	case StreamEventToolUse:
+		logger.WithFields(map[string]interface{}{
+			"tool":        event.ToolUse.Name,
+			"tool_use_id": event.ToolUse.ID,
+			"run_id":      e.runID,
+		}).Debug("Claude tool use")
+		if e.config != nil && e.config.ShowToolUpdates && e.printer != nil {
+			e.printer.AddToolLog(event.ToolUse.summary())
+			e.printer.RenderToolLogs()
+		}
+		if showTodos {
+			if task := event.ToolUse.activeTodo(); task != "" {
+				e.printer.UpdateCurrentTask(task)
+			}
+		}
+
+	case StreamEventToolResult:
+		logger.WithFields(map[string]interface{}{
+			"tool_use_id": event.ToolResult.ToolUseID,
+			"is_error":    event.ToolResult.IsError,
+			"run_id":      e.runID,
+		}).Debug("Claude tool result")
+		if event.ToolResult.IsError && e.config != nil && e.config.ShowToolUpdates && e.printer != nil {
+			e.printer.AddToolLog("Tool error: " + truncateString(event.ToolResult.Content, 80))
+			e.printer.RenderToolLogs()
+		}
+
+	case StreamEventUsage:
+		logger.WithFields(map[string]interface{}{
+			"input_tokens":          event.Usage.InputTokens,
+			"output_tokens":         event.Usage.OutputTokens,
+			"cache_creation_tokens": event.Usage.CacheCreationInputTokens,
+			"cache_read_tokens":     event.Usage.CacheReadInputTokens,
+			"run_id":                e.runID,
+		}).Debug("Claude token usage")
+
+	case StreamEventResult:
+		logger.WithFields(map[string]interface{}{
+			"subtype":        event.Result.Subtype,
+			"is_error":       event.Result.IsError,
+			"num_turns":      event.Result.NumTurns,
+			"duration_ms":    event.Result.DurationMS,
+			"total_cost_usd": event.Result.TotalCostUSD,
+			"input_tokens":   event.Result.Usage.InputTokens,
+			"output_tokens":  event.Result.Usage.OutputTokens,
+			"session_id":     event.Result.SessionID,
+			"run_id":         e.runID,
+		}).Info("Claude run result")
+	}
+}
+
+// resultErrorMessage describes a failed result event
+func resultErrorMessage(result *Result) string {
+	if result.Result != "" {
+		return result.Result
+	}
+	if result.Subtype != "" {
+		return result.Subtype
+	}
+	return "unknown error"
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/Backland-Labs/alpine/internal/config"
 	"github.com/Backland-Labs/alpine/internal/output"
+	"github.com/Backland-Labs/alpine/internal/sandbox"
 )
 
 func TestNewExecutor(t *testing.T) {
@@ -45,9 +46,9 @@ func TestExecutor_Execute(t *testing.T) {
 		{
 			name: "successful execution with MCP servers",
 			config: ExecuteConfig{
-				Prompt:     "test prompt",
-				StateFile:  "/tmp/state.json",
-				MCPServers: []string{"playwright", "context7"},
+				Prompt:    "test prompt",
+				StateFile: "/tmp/state.json",
+				MCPConfig: "/tmp/mcp.json",
 			},
 			mockCommand: &mockCommand{
 				output: "Claude execution with MCP servers successful",
@@ -183,16 +184,15 @@ func TestExecutor_buildCommand(t *testing.T) {
 			expectedWorkDir: "", // Will be set to current directory by buildCommand
 		},
 		{
-			name: "command with multiple MCP servers",
+			name: "command with an MCP config",
 			config: ExecuteConfig{
-				Prompt:     "test prompt",
-				StateFile:  "/tmp/state.json",
-				MCPServers: []string{"playwright", "web-mrkdwn"},
+				Prompt:    "test prompt",
+				StateFile: "/tmp/state.json",
+				MCPConfig: "/tmp/mcp.json",
 			},
 			expectedArgs: []string{
 				"--output-format", "text",
-				"--mcp-server", "playwright",
-				"--mcp-server", "web-mrkdwn",
+				"--mcp-config", "/tmp/mcp.json",
 				"--allowedTools",
 				"--append-system-prompt",
 				"-p", "test prompt",
@@ -232,6 +232,24 @@ func TestExecutor_buildCommand(t *testing.T) {
 			expectedEnvSet:  map[string]bool{},
 			expectedWorkDir: "", // Will be set to current directory
 		},
+		{
+			name: "command with disallowed tools",
+			config: ExecuteConfig{
+				Prompt:          "test prompt",
+				StateFile:       "/tmp/state.json",
+				AllowedTools:    []string{"Read", "Bash(go test:*)"},
+				DisallowedTools: []string{"Write", "WebFetch"},
+			},
+			expectedArgs: []string{
+				"--output-format", "text",
+				"--allowedTools", "Read", "Bash(go test:*)",
+				"--disallowedTools", "Write", "WebFetch",
+				"--append-system-prompt",
+				"-p", "test prompt",
+			},
+			expectedEnvSet:  map[string]bool{},
+			expectedWorkDir: "", // Will be set to current directory
+		},
 		{
 			name: "command with additional args",
 			config: ExecuteConfig{
@@ -418,6 +436,77 @@ func (e *mockError) Error() string {
 	return e.msg
 }
 
+func TestExecutor_BuildCommand_Model(t *testing.T) {
+	tests := []struct {
+		name          string
+		model         string
+		expectedModel string
+	}{
+		{name: "defaults to DefaultModel", model: "", expectedModel: DefaultModel},
+		{name: "uses configured model", model: "claude-opus-4-20250514", expectedModel: "claude-opus-4-20250514"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exec := &Executor{}
+			cmd := exec.buildCommand(ExecuteConfig{
+				Prompt:    "test prompt",
+				StateFile: "/tmp/state.json",
+				Model:     tt.model,
+			})
+
+			args := cmd.Args[1:]
+			found := false
+			for i, arg := range args {
+				if arg == "--model" {
+					found = true
+					if i+1 >= len(args) || args[i+1] != tt.expectedModel {
+						t.Errorf("expected --model %q, got args %v", tt.expectedModel, args)
+					}
+				}
+			}
+			if !found {
+				t.Errorf("expected --model flag in args %v", args)
+			}
+		})
+	}
+}
+
+func TestExecutor_BuildCommand_Sandbox(t *testing.T) {
+	workDir := t.TempDir()
+	sb, err := sandbox.New(sandbox.Options{
+		Backend:  config.SandboxBubblewrap,
+		Writable: []string{workDir},
+		Helper:   []string{"/usr/bin/alpine", sandbox.HelperCommand},
+	})
+	if err != nil {
+		t.Fatalf("sandbox.New() returned unexpected error: %v", err)
+	}
+	defer func() { _ = sb.Close() }()
+
+	exec := &Executor{}
+	cmd := exec.buildCommand(ExecuteConfig{
+		Prompt:    "test prompt",
+		StateFile: "/tmp/state.json",
+		WorkDir:   workDir,
+		Sandbox:   sb,
+	})
+
+	if cmd.Args[0] != "bwrap" {
+		t.Fatalf("expected the command to run under bwrap, got args %v", cmd.Args)
+	}
+	if cmd.Dir != workDir {
+		t.Errorf("expected cmd.Dir to stay %q, got %q", workDir, cmd.Dir)
+	}
+	joined := strings.Join(cmd.Args, " ")
+	if !strings.Contains(joined, "--bind "+workDir+" "+workDir) {
+		t.Errorf("expected the working directory to be writable, got args %v", cmd.Args)
+	}
+	if !strings.Contains(joined, "/usr/bin/alpine sandbox-exec -- ") || !strings.Contains(joined, "--model "+DefaultModel) {
+		t.Errorf("expected claude to run through the sandbox helper, got args %v", cmd.Args)
+	}
+}
+
 func TestExecutor_BuildCommand_SetsWorkingDirectory(t *testing.T) {
 	// Test that buildCommand sets the working directory to the current directory
 	// This ensures Claude commands execute in the correct directory for worktree isolation
@@ -1018,7 +1107,7 @@ func TestExecutor_ValidatesWorkingDirectory(t *testing.T) {
 			Prompt:       "test validation prompt",
 			StateFile:    "test-state.json",
 			WorkDir:      "/invalid/nonexistent/directory",
-			MCPServers:   []string{"test-server"},
+			MCPConfig:    "test-mcp.json",
 			AllowedTools: []string{"Read", "Write"},
 			SystemPrompt: "Custom validation prompt",
 		}
@@ -1044,8 +1133,8 @@ func TestExecutor_ValidatesWorkingDirectory(t *testing.T) {
 		if !strings.Contains(argsStr, "test validation prompt") {
 			t.Error("Expected prompt to be preserved in arguments")
 		}
-		if !strings.Contains(argsStr, "test-server") {
-			t.Error("Expected MCP server to be preserved in arguments")
+		if !strings.Contains(argsStr, "--mcp-config test-mcp.json") {
+			t.Error("Expected MCP config to be preserved in arguments")
 		}
 		if !strings.Contains(argsStr, "Read") || !strings.Contains(argsStr, "Write") {
 			t.Error("Expected allowed tools to be preserved in arguments")
@@ -0,0 +1,68 @@
+package claude
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// installClaudeWithChild installs a fake claude that leaves a background
+// child holding its stdout and stderr open, like an MCP server that outlives
+// Claude, then prints done and sleeps for sleep seconds
+func installClaudeWithChild(t *testing.T, sleep string) {
+	t.Helper()
+	dir := t.TempDir()
+	script := "#!/bin/sh\nsleep 5 &\necho done\nsleep " + sleep + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "claude"), []byte(script), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	delay := processWaitDelay
+	processWaitDelay = 100 * time.Millisecond
+	t.Cleanup(func() { processWaitDelay = delay })
+}
+
+func TestExecutor_ChildHoldingOutputDoesNotBlock(t *testing.T) {
+	modes := map[string]func(*Executor, *ExecuteConfig){
+		"combined output": func(e *Executor, cfg *ExecuteConfig) {},
+		"stderr capture": func(e *Executor, cfg *ExecuteConfig) {
+			e.SetStreamer(&mockStreamer{})
+			e.SetRunID("run-1")
+		},
+		"stream-json": func(e *Executor, cfg *ExecuteConfig) { cfg.OutputFormat = "stream-json" },
+	}
+	for name, setup := range modes {
+		t.Run(name, func(t *testing.T) {
+			t.Run("claude exits", func(t *testing.T) {
+				installClaudeWithChild(t, "0")
+				executor := NewExecutor()
+				cfg := ExecuteConfig{Prompt: "/start", StateFile: filepath.Join(t.TempDir(), "agent_state.json"), WorkDir: t.TempDir()}
+				setup(executor, &cfg)
+
+				start := time.Now()
+				output, err := executor.Execute(context.Background(), cfg)
+				require.NoError(t, err)
+				assert.Less(t, time.Since(start), 3*time.Second)
+				if cfg.OutputFormat == "" {
+					assert.Contains(t, output, "done")
+				}
+			})
+
+			t.Run("claude times out", func(t *testing.T) {
+				installClaudeWithChild(t, "5")
+				executor := NewExecutor()
+				cfg := ExecuteConfig{Prompt: "/start", StateFile: filepath.Join(t.TempDir(), "agent_state.json"), WorkDir: t.TempDir(), Timeout: 200 * time.Millisecond}
+				setup(executor, &cfg)
+
+				start := time.Now()
+				_, err := executor.Execute(context.Background(), cfg)
+				require.Error(t, err)
+				assert.Less(t, time.Since(start), 3*time.Second, "the iteration must end at its timeout")
+			})
+		})
+	}
+}
@@ -1,29 +1,25 @@
 package claude
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/Backland-Labs/alpine/internal/hooks"
 	"github.com/Backland-Labs/alpine/internal/logger"
 )
 
-// hookSettings represents Claude Code settings for hooks
-type hookSettings struct {
-	Hooks map[string]interface{} `json:"hooks"`
-}
-
 // toolMatcher represents a hook configuration for a specific tool
 type toolMatcher struct {
 	Matcher string                   `json:"matcher"`
 	Hooks   []map[string]interface{} `json:"hooks"`
 }
 
-// setupTodoHook sets up the TodoWrite PostToolUse hook for Claude Code
+// setupTodoHook sets up the TodoWrite PostToolUse hook for Claude Code in the
+// .claude directory under workDir (the current directory if empty).
 // Returns the todo file path and cleanup function
-func (e *Executor) setupTodoHook() (todoFilePath string, cleanup func(), err error) {
+func (e *Executor) setupTodoHook(workDir string) (todoFilePath string, cleanup func(), err error) {
 	logger.WithField("run_id", e.runID).Info("Setting up TodoWrite hook for TODO monitoring")
 
 	// Create temporary file for todo updates
@@ -43,8 +39,8 @@ func (e *Executor) setupTodoHook() (todoFilePath string, cleanup func(), err err
 		return "", nil, fmt.Errorf("failed to close todo file: %w", err)
 	}
 
-	// Create .claude directory in current working directory
-	claudeDir := ".claude"
+	// Create .claude directory in the run's working directory
+	claudeDir := filepath.Join(workDir, ".claude")
 	logger.WithField("claude_dir", claudeDir).Debug("Creating .claude directory")
 	if err := os.MkdirAll(claudeDir, 0755); err != nil {
 		_ = os.Remove(todoFilePath)
@@ -55,42 +51,22 @@ func (e *Executor) setupTodoHook() (todoFilePath string, cleanup func(), err err
 		return "", nil, fmt.Errorf("failed to create .claude directory: %w", err)
 	}
 
-	// Copy hook script to .claude directory
-	hookScriptPath := filepath.Join(claudeDir, "todo-monitor.rs")
-	logger.WithField("hook_script_path", hookScriptPath).Debug("Copying hook script")
-	if err := e.copyHookScript(hookScriptPath); err != nil {
-		_ = os.Remove(todoFilePath)
-		logger.WithFields(map[string]interface{}{
-			"error":            err.Error(),
-			"hook_script_path": hookScriptPath,
-		}).Error("Failed to copy hook script")
-		return "", nil, fmt.Errorf("failed to copy hook script: %w", err)
-	}
-
-	// Make hook script executable
-	if err := os.Chmod(hookScriptPath, 0755); err != nil {
-		_ = os.Remove(todoFilePath)
-		_ = os.Remove(hookScriptPath)
-		return "", nil, fmt.Errorf("failed to make hook script executable: %w", err)
-	}
-
-	// Convert hook script path to absolute path
-	absHookScriptPath, err := filepath.Abs(hookScriptPath)
+	// Point the hooks at the running alpine binary
+	command, err := hookCommand()
 	if err != nil {
 		_ = os.Remove(todoFilePath)
-		_ = os.Remove(hookScriptPath)
-		return "", nil, fmt.Errorf("failed to get absolute path of hook script: %w", err)
+		return "", nil, fmt.Errorf("failed to resolve hook command: %w", err)
 	}
 
 	// Generate Claude settings
 	settingsPath := filepath.Join(claudeDir, "settings.local.json")
 	logger.WithFields(map[string]interface{}{
 		"settings_path": settingsPath,
-		"hook_script":   absHookScriptPath,
+		"hook_command":  command,
 	}).Debug("Generating Claude settings")
-	if err := e.generateClaudeSettings(settingsPath, absHookScriptPath); err != nil {
+	restoreSettings, err := e.generateClaudeSettings(settingsPath, command)
+	if err != nil {
 		_ = os.Remove(todoFilePath)
-		_ = os.Remove(hookScriptPath)
 		logger.WithFields(map[string]interface{}{
 			"error":         err.Error(),
 			"settings_path": settingsPath,
@@ -100,7 +76,7 @@ func (e *Executor) setupTodoHook() (todoFilePath string, cleanup func(), err err
 
 	logger.WithFields(map[string]interface{}{
 		"todo_file":     todoFilePath,
-		"hook_script":   absHookScriptPath,
+		"hook_command":  command,
 		"settings_file": settingsPath,
 	}).Debug("TodoWrite hook setup completed")
 
@@ -108,83 +84,73 @@ func (e *Executor) setupTodoHook() (todoFilePath string, cleanup func(), err err
 	cleanup = func() {
 		logger.WithFields(map[string]interface{}{
 			"todo_file":     todoFilePath,
-			"hook_script":   hookScriptPath,
 			"settings_file": settingsPath,
 		}).Debug("Cleaning up TodoWrite hook")
 		_ = os.Remove(todoFilePath)
-		_ = os.Remove(hookScriptPath)
-		_ = os.Remove(settingsPath)
+		restoreSettings()
 		// Don't remove .claude directory - may contain user's own settings
 	}
 
 	return todoFilePath, cleanup, nil
 }
 
-// copyHookScript copies the embedded hook script to the specified path
-func (e *Executor) copyHookScript(destPath string) error {
-	// Get the hook script content
-	scriptContent, err := hooks.GetTodoMonitorScript()
+// hookCommand returns the shell command Claude Code runs for Alpine's hooks,
+// which is the hook subcommand of the running alpine binary
+func hookCommand() (string, error) {
+	execPath, err := os.Executable()
 	if err != nil {
-		return fmt.Errorf("failed to get hook script: %w", err)
+		return "", fmt.Errorf("failed to get executable path: %w", err)
 	}
-
-	// Write script to destination
-	if err := os.WriteFile(destPath, []byte(scriptContent), 0644); err != nil {
-		return fmt.Errorf("failed to write hook script: %w", err)
+	if resolved, err := filepath.EvalSymlinks(execPath); err == nil {
+		execPath = resolved
 	}
+	return shellQuote(execPath) + " hook", nil
+}
 
-	// Explicitly set executable permissions
-	if err := os.Chmod(destPath, 0755); err != nil {
-		return fmt.Errorf("failed to set executable permissions: %w", err)
+// shellQuote quotes a path or argument for the shell when it is empty or
+// contains characters the shell would interpret
+func shellQuote(s string) string {
+	if s == "" {
+		return "''"
 	}
-
-	return nil
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("/._-+", r)) {
+			return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+		}
+	}
+	return s
 }
 
-// generateClaudeSettings creates the Claude Code settings.local.json file with hook configuration
-func (e *Executor) generateClaudeSettings(settingsPath, hookScriptPath string) error {
-	settings := hookSettings{
-		Hooks: map[string]interface{}{
-			"PostToolUse": []toolMatcher{
-				{
-					Matcher: "",
-					Hooks: []map[string]interface{}{
-						{
-							"command": hookScriptPath,
-							"type":    "command",
-						},
-					},
-				},
-			},
-			"SubagentStop": []toolMatcher{
+// commandHooks returns a hook entry that runs command for tools matching matcher
+func commandHooks(matcher, command string) []toolMatcher {
+	return []toolMatcher{
+		{
+			Matcher: matcher,
+			Hooks: []map[string]interface{}{
 				{
-					Matcher: "",
-					Hooks: []map[string]interface{}{
-						{
-							"command": hookScriptPath,
-							"type":    "command",
-						},
-					},
+					"command": command,
+					"type":    "command",
 				},
 			},
 		},
 	}
+}
 
-	// Marshal to JSON
-	settingsJSON, err := json.MarshalIndent(settings, "", "  ")
+// generateClaudeSettings merges the TodoWrite hooks into the Claude Code
+// settings.local.json file and returns a function that restores the original
+func (e *Executor) generateClaudeSettings(settingsPath, command string) (release func(), err error) {
+	release, err = mergeClaudeSettings(settingsPath, map[string][]toolMatcher{
+		hooks.EventPostToolUse:  commandHooks("", command),
+		hooks.EventSubagentStop: commandHooks("", command),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to marshal settings: %w", err)
-	}
-
-	// Write settings file
-	if err := os.WriteFile(settingsPath, settingsJSON, 0644); err != nil {
-		return fmt.Errorf("failed to write settings file: %w", err)
+		return nil, err
 	}
 
 	logger.WithFields(map[string]interface{}{
 		"settings_path": settingsPath,
-		"hook_script":   hookScriptPath,
+		"hook_command":  command,
 		"hooks_count":   2,
-	}).Info("Generated Claude settings file with TodoWrite hooks")
-	return nil
+	}).Info("Merged TodoWrite hooks into Claude settings file")
+	return release, nil
 }
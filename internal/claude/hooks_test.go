@@ -19,7 +19,8 @@ func TestExecutor_setupTodoHook(t *testing.T) {
 			printer: output.NewPrinterWithWriters(os.Stdout, os.Stderr, false),
 		}
 
-		todoFile, cleanup, err := executor.setupTodoHook()
+		workDir := t.TempDir()
+		todoFile, cleanup, err := executor.setupTodoHook(workDir)
 		if err != nil {
 			t.Fatalf("setupTodoHook failed: %v", err)
 		}
@@ -29,8 +30,8 @@ func TestExecutor_setupTodoHook(t *testing.T) {
 			t.Error("Todo file was not created")
 		}
 
-		// Verify .claude directory was created
-		claudeDir := ".claude"
+		// Verify .claude directory was created in the working directory
+		claudeDir := filepath.Join(workDir, ".claude")
 		if _, err := os.Stat(claudeDir); os.IsNotExist(err) {
 			t.Error(".claude directory was not created")
 		}
@@ -41,12 +42,9 @@ func TestExecutor_setupTodoHook(t *testing.T) {
 			t.Error("settings.local.json was not created")
 		}
 
-		// Verify hook script was created
-		hookScriptPath := filepath.Join(claudeDir, "todo-monitor.rs")
-		if info, err := os.Stat(hookScriptPath); os.IsNotExist(err) {
-			t.Error("Hook script was not created")
-		} else if info.Mode()&0111 == 0 {
-			t.Error("Hook script is not executable")
+		// Verify no hook script is copied; the hooks run the alpine binary
+		if _, err := os.Stat(filepath.Join(claudeDir, "todo-monitor.rs")); !os.IsNotExist(err) {
+			t.Error("Hook script should not be created")
 		}
 
 		// Verify settings file contains absolute path
@@ -70,6 +68,9 @@ func TestExecutor_setupTodoHook(t *testing.T) {
 								if !filepath.IsAbs(command) {
 									t.Errorf("Hook command path is not absolute: %s", command)
 								}
+								if !strings.HasSuffix(command, " hook") {
+									t.Errorf("Hook command does not run the hook subcommand: %s", command)
+								}
 							} else {
 								t.Error("Command field not found or not a string")
 							}
@@ -89,9 +90,6 @@ func TestExecutor_setupTodoHook(t *testing.T) {
 		if _, err := os.Stat(settingsFile); !os.IsNotExist(err) {
 			t.Error("settings.local.json was not cleaned up")
 		}
-		if _, err := os.Stat(hookScriptPath); !os.IsNotExist(err) {
-			t.Error("Hook script was not cleaned up")
-		}
 	})
 
 	t.Run("cleanup handles missing files gracefully", func(t *testing.T) {
@@ -100,14 +98,15 @@ func TestExecutor_setupTodoHook(t *testing.T) {
 			printer: output.NewPrinterWithWriters(os.Stdout, os.Stderr, false),
 		}
 
-		todoFile, cleanup, err := executor.setupTodoHook()
+		workDir := t.TempDir()
+		todoFile, cleanup, err := executor.setupTodoHook(workDir)
 		if err != nil {
 			t.Fatalf("setupTodoHook failed: %v", err)
 		}
 
 		// Remove files manually before cleanup
 		_ = os.Remove(todoFile)
-		_ = os.RemoveAll(".claude")
+		_ = os.RemoveAll(filepath.Join(workDir, ".claude"))
 
 		// Cleanup should not panic
 		cleanup()
@@ -124,12 +123,13 @@ func TestExecutor_generateClaudeSettings(t *testing.T) {
 
 		tmpDir := t.TempDir()
 		settingsPath := filepath.Join(tmpDir, "settings.local.json")
-		hookPath := "/tmp/test-hook.sh"
+		hookPath := "/usr/local/bin/alpine hook"
 
-		err := executor.generateClaudeSettings(settingsPath, hookPath)
+		release, err := executor.generateClaudeSettings(settingsPath, hookPath)
 		if err != nil {
 			t.Fatalf("generateClaudeSettings failed: %v", err)
 		}
+		defer release()
 
 		// Read and parse the generated settings
 		content, err := os.ReadFile(settingsPath)
@@ -217,45 +217,27 @@ func TestExecutor_generateClaudeSettings(t *testing.T) {
 	})
 }
 
-func TestExecutor_copyHookScript(t *testing.T) {
-	// Test hook script copying
-	t.Run("copies script with correct permissions", func(t *testing.T) {
-		executor := &Executor{
-			config:  &config.Config{},
-			printer: output.NewPrinterWithWriters(os.Stdout, os.Stderr, false),
-		}
-
-		tmpDir := t.TempDir()
-		scriptPath := filepath.Join(tmpDir, "todo-monitor.rs")
-
-		err := executor.copyHookScript(scriptPath)
-		if err != nil {
-			t.Fatalf("copyHookScript failed: %v", err)
-		}
-
-		// Verify file exists
-		info, err := os.Stat(scriptPath)
+func TestHookCommand(t *testing.T) {
+	t.Run("runs the hook subcommand of the current executable", func(t *testing.T) {
+		command, err := hookCommand()
 		if err != nil {
-			t.Fatalf("Script file not found: %v", err)
+			t.Fatalf("hookCommand failed: %v", err)
 		}
 
-		// Verify it's executable
-		if info.Mode()&0111 == 0 {
-			t.Error("Script is not executable")
+		if !strings.HasSuffix(command, " hook") {
+			t.Errorf("Expected command to end with ' hook', got %q", command)
 		}
 
-		// Verify content
-		content, err := os.ReadFile(scriptPath)
+		execPath, err := os.Executable()
 		if err != nil {
-			t.Fatalf("Failed to read script: %v", err)
+			t.Fatalf("Failed to get executable: %v", err)
 		}
-
-		if !strings.Contains(string(content), "#!/usr/bin/env rust-script") {
-			t.Error("Script missing shebang")
+		resolved, err := filepath.EvalSymlinks(execPath)
+		if err != nil {
+			t.Fatalf("Failed to resolve executable: %v", err)
 		}
-
-		if !strings.Contains(string(content), "TodoWrite") {
-			t.Error("Script missing TodoWrite matcher logic")
+		if want := shellQuote(resolved) + " hook"; command != want {
+			t.Errorf("Expected command %q, got %q", want, command)
 		}
 	})
 }
@@ -0,0 +1,67 @@
+package claude
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/Backland-Labs/alpine/internal/config"
+)
+
+// mcpConfigFile is the document Claude reads with --mcp-config
+type mcpConfigFile struct {
+	MCPServers map[string]mcpServerEntry `json:"mcpServers"`
+}
+
+// mcpServerEntry is one server in an mcpConfigFile
+type mcpServerEntry struct {
+	Type    string            `json:"type"`
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// WriteMCPConfig writes servers to path in the format of Claude's
+// --mcp-config option
+func WriteMCPConfig(path string, servers map[string]config.MCPServer) error {
+	doc := mcpConfigFile{MCPServers: make(map[string]mcpServerEntry, len(servers))}
+	for name, s := range servers {
+		entry := mcpServerEntry{Env: s.Env, Headers: s.Headers}
+		if s.Command != "" {
+			entry.Type = "stdio"
+			entry.Command = s.Command
+			entry.Args = s.Args
+		} else {
+			entry.Type = s.Transport
+			if entry.Type == "" {
+				entry.Type = config.MCPTransportHTTP
+			}
+			entry.URL = s.URL
+		}
+		doc.MCPServers[name] = entry
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode MCP config: %w", err)
+	}
+	// The file may hold tokens from env and headers
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write MCP config: %w", err)
+	}
+	return nil
+}
+
+// MCPToolPatterns returns the permission entries that allow every tool of
+// the named servers, e.g. mcp__github for a server named github
+func MCPToolPatterns(servers map[string]config.MCPServer) []string {
+	patterns := make([]string, 0, len(servers))
+	for name := range servers {
+		patterns = append(patterns, "mcp__"+name)
+	}
+	sort.Strings(patterns)
+	return patterns
+}
@@ -0,0 +1,53 @@
+package claude
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/config"
+)
+
+func TestWriteMCPConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp.json")
+	servers := map[string]config.MCPServer{
+		"context7": {Command: "npx", Args: []string{"-y", "@upstash/context7-mcp"}, Env: map[string]string{"DEBUG": "1"}},
+		"github":   {URL: "https://api.githubcopilot.com/mcp/", Headers: map[string]string{"Authorization": "Bearer t"}},
+		"events":   {URL: "http://localhost:9000/sse", Transport: config.MCPTransportSSE},
+	}
+	require.NoError(t, WriteMCPConfig(path, servers))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var doc map[string]map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &doc))
+
+	entries := doc["mcpServers"]
+	require.Len(t, entries, 3)
+	assert.Equal(t, map[string]interface{}{
+		"type":    "stdio",
+		"command": "npx",
+		"args":    []interface{}{"-y", "@upstash/context7-mcp"},
+		"env":     map[string]interface{}{"DEBUG": "1"},
+	}, entries["context7"])
+	assert.Equal(t, map[string]interface{}{
+		"type":    "http",
+		"url":     "https://api.githubcopilot.com/mcp/",
+		"headers": map[string]interface{}{"Authorization": "Bearer t"},
+	}, entries["github"])
+	assert.Equal(t, "sse", entries["events"]["type"])
+}
+
+func TestMCPToolPatterns(t *testing.T) {
+	servers := map[string]config.MCPServer{"github": {}, "context7": {}}
+	assert.Equal(t, []string{"mcp__context7", "mcp__github"}, MCPToolPatterns(servers))
+	assert.Empty(t, MCPToolPatterns(nil))
+}
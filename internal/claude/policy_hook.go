@@ -0,0 +1,36 @@
+package claude
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/Backland-Labs/alpine/internal/hooks"
+	"github.com/Backland-Labs/alpine/internal/logger"
+)
+
+// SetupPolicyHook merges the PreToolUse hook that enforces a run's command and
+// path policy into the Claude settings in the .claude directory under workDir.
+// The hook checks calls against the policy file named by hooks.EnvPolicyFile
+// in Claude's environment and does nothing without it.
+// Returns a function that restores the settings.
+func SetupPolicyHook(workDir string) (cleanup func(), err error) {
+	// Point the hook at the running alpine binary
+	command, err := hookCommand()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve hook command: %w", err)
+	}
+
+	settingsPath := filepath.Join(workDir, ".claude", "settings.local.json")
+	release, err := mergeClaudeSettings(settingsPath, map[string][]toolMatcher{
+		hooks.EventPreToolUse: commandHooks("", command),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate Claude settings: %w", err)
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"settings_path": settingsPath,
+		"hook_command":  command,
+	}).Debug("Merged policy hook into Claude settings file")
+	return release, nil
+}
@@ -0,0 +1,248 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Backland-Labs/alpine/internal/config"
+	"github.com/Backland-Labs/alpine/internal/logger"
+)
+
+// FailureKind classifies why a Claude invocation failed
+type FailureKind string
+
+// Failure kinds recognized from Claude's exit code and output
+const (
+	// FailureRateLimit means the Claude API rejected the request with a rate or usage limit
+	FailureRateLimit FailureKind = "rate_limit"
+	// FailureOverloaded means the Claude API was overloaded or returned a server error
+	FailureOverloaded FailureKind = "overloaded"
+	// FailureNetwork means Claude could not reach the API
+	FailureNetwork FailureKind = "network"
+	// FailureAuth means Claude is not logged in or its credentials were rejected
+	FailureAuth FailureKind = "auth"
+	// FailureInvalidArgs means the claude CLI rejected the arguments alpine passed
+	FailureInvalidArgs FailureKind = "invalid_args"
+	// FailureSessionNotFound means the session to resume does not exist
+	FailureSessionNotFound FailureKind = "session_not_found"
+	// FailureUnknown is any other failure
+	FailureUnknown FailureKind = "unknown"
+)
+
+// Transient reports whether a failure of this kind may succeed when retried
+func (k FailureKind) Transient() bool {
+	switch k {
+	case FailureRateLimit, FailureOverloaded, FailureNetwork:
+		return true
+	}
+	return false
+}
+
+// failurePatterns are matched against Claude's output in order, so fail-fast
+// kinds win when a message mentions more than one cause
+var failurePatterns = []struct {
+	kind    FailureKind
+	pattern *regexp.Regexp
+}{
+	{FailureSessionNotFound, regexp.MustCompile(`(?i)no conversation found`)},
+	{FailureAuth, regexp.MustCompile(`(?i)invalid api key|invalid x-api-key|authentication_error|authentication failed|unauthorized|\b401\b|not logged in|please run /login|oauth token (has )?expired`)},
+	{FailureInvalidArgs, regexp.MustCompile(`(?i)unknown option|unknown command|invalid argument|invalid value for|missing required argument|too many arguments|not_found_error|invalid model`)},
+	{FailureRateLimit, regexp.MustCompile(`(?i)rate[ _-]?limit|\b429\b|too many requests|usage limit`)},
+	{FailureOverloaded, regexp.MustCompile(`(?i)overloaded|\b529\b|api error: 5\d\d|service unavailable|bad gateway|internal server error`)},
+	{FailureNetwork, regexp.MustCompile(`(?i)econnreset|econnrefused|etimedout|enotfound|eai_again|socket hang up|network error|connection (reset|refused|error)|fetch failed|request timed out`)},
+}
+
+// classifyFailure determines the failure kind from Claude's output and
+// returns the output line that identified it
+func classifyFailure(output string) (FailureKind, string) {
+	lines := strings.Split(output, "\n")
+	for _, fp := range failurePatterns {
+		for _, line := range lines {
+			if fp.pattern.MatchString(line) {
+				return fp.kind, strings.TrimSpace(line)
+			}
+		}
+	}
+	return FailureUnknown, ""
+}
+
+// maxFailureOutput bounds how much of Claude's output is kept for classification
+const maxFailureOutput = 4096
+
+// ExecutionError is returned when a Claude invocation fails. It wraps the
+// underlying error, so errors.As still finds an *exec.ExitError.
+type ExecutionError struct {
+	// Kind is the classified cause of the failure
+	Kind FailureKind
+
+	// ExitCode is Claude's exit code, or -1 if it did not exit normally
+	ExitCode int
+
+	// Detail is the line of Claude's output that identified the failure
+	Detail string
+
+	// Output is the tail of Claude's output
+	Output string
+
+	// Err is the underlying error
+	Err error
+}
+
+// newExecutionError classifies a failed Claude invocation from its error and output
+func newExecutionError(err error, output string) *ExecutionError {
+	if len(output) > maxFailureOutput {
+		output = output[len(output)-maxFailureOutput:]
+	}
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+	kind, detail := classifyFailure(output)
+	return &ExecutionError{Kind: kind, ExitCode: exitCode, Detail: detail, Output: output, Err: err}
+}
+
+// Error describes the failure along with what to do about it
+func (e *ExecutionError) Error() string {
+	msg := fmt.Sprintf("claude execution failed: %v", e.Err)
+	switch e.Kind {
+	case FailureAuth:
+		msg += "; Claude is not authenticated: run `claude login` or set ANTHROPIC_API_KEY"
+	case FailureInvalidArgs:
+		msg += "; the claude CLI rejected its arguments: check ALPINE_MODEL and ALPINE_PLAN_MODEL, or update the claude CLI"
+	case FailureRateLimit:
+		msg += "; rate limited by the Claude API"
+	case FailureOverloaded:
+		msg += "; the Claude API is overloaded"
+	case FailureNetwork:
+		msg += "; could not reach the Claude API"
+	case FailureSessionNotFound:
+		msg += "; the Claude session to resume no longer exists"
+	}
+	if e.Detail != "" && e.Detail != e.Err.Error() {
+		msg += fmt.Sprintf(" (%s)", e.Detail)
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error
+func (e *ExecutionError) Unwrap() error {
+	return e.Err
+}
+
+// RetryPolicy controls retries of transient Claude failures
+type RetryPolicy = config.RetryConfig
+
+// RetryEvent describes a transient failure that is about to be retried
+type RetryEvent struct {
+	// Attempt is the number of the retry about to be made, starting at 1
+	Attempt int
+
+	// MaxRetries is the number of retries allowed by the policy
+	MaxRetries int
+
+	// Delay is how long the executor waits before retrying
+	Delay time.Duration
+
+	// Err is the failure being retried
+	Err *ExecutionError
+}
+
+// retryDelay returns the randomized exponential backoff before the given retry.
+// The delay is drawn from the upper half of the backoff window so concurrent
+// runs spread out without retrying immediately.
+func retryDelay(policy RetryPolicy, attempt int) time.Duration {
+	if policy.InitialBackoff <= 0 {
+		return 0
+	}
+	backoff := policy.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff >= policy.MaxBackoff {
+			break
+		}
+	}
+	if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+	half := backoff / 2
+	return half + rand.N(half+1)
+}
+
+// executeWithRetry runs Claude, retrying transient failures according to the
+// policy in config. Authentication and argument errors are never retried.
+func (e *Executor) executeWithRetry(ctx context.Context, config ExecuteConfig) (string, error) {
+	policy := config.Retry
+	for attempt := 1; ; attempt++ {
+		result, err := e.executeOnce(ctx, config)
+		if err == nil {
+			if config.OutputFormat != OutputFormatStreamJSON {
+				// Only stream-json reports the session, so assume Claude
+				// kept the ID it was given
+				e.reportSession(config, SessionInfo{ID: config.SessionID})
+			}
+			return result, nil
+		}
+
+		var execErr *ExecutionError
+		if !errors.As(err, &execErr) || ctx.Err() != nil {
+			return "", err
+		}
+		if execErr.Kind == FailureSessionNotFound && config.ResumeSession {
+			// Fall back to a fresh session without using up a retry
+			logger.WithFields(map[string]interface{}{
+				"session_id": config.SessionID,
+				"run_id":     e.runID,
+			}).Warn("Claude session to resume not found, starting a new session")
+			config.SessionID = NewSessionID()
+			config.ResumeSession = false
+			attempt--
+			continue
+		}
+		fields := map[string]interface{}{
+			"kind":      string(execErr.Kind),
+			"exit_code": execErr.ExitCode,
+			"detail":    execErr.Detail,
+			"attempt":   attempt,
+			"run_id":    e.runID,
+		}
+		if !execErr.Kind.Transient() {
+			if execErr.Kind != FailureUnknown {
+				logger.WithFields(fields).Error("Claude failed with a non-retryable error")
+			}
+			return "", err
+		}
+		if attempt > policy.MaxRetries {
+			logger.WithFields(fields).Error("Claude failed after exhausting retries")
+			return "", err
+		}
+
+		delay := retryDelay(policy, attempt)
+		fields["delay"] = delay.String()
+		fields["max_retries"] = policy.MaxRetries
+		logger.WithFields(fields).Warn("Retrying Claude after transient failure")
+		if config.OnRetry != nil {
+			config.OnRetry(RetryEvent{Attempt: attempt, MaxRetries: policy.MaxRetries, Delay: delay, Err: execErr})
+		}
+		if config.SessionID != "" && !config.ResumeSession {
+			// The failed attempt may have created the session, so its ID
+			// cannot be used to start another one
+			config.SessionID = NewSessionID()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logger.WithField("run_id", e.runID).Warn("Claude retry cancelled")
+			return "", ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package claude
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		output string
+		want   FailureKind
+	}{
+		{"API Error: 429 {\"type\":\"error\",\"error\":{\"type\":\"rate_limit_error\"}}", FailureRateLimit},
+		{"Claude AI usage limit reached|1760000000", FailureRateLimit},
+		{"API Error: 529 {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}", FailureOverloaded},
+		{"API Error: 503 Service Unavailable", FailureOverloaded},
+		{"API Error: Connection error.\nTypeError: fetch failed", FailureNetwork},
+		{"Error: getaddrinfo ENOTFOUND api.anthropic.com", FailureNetwork},
+		{"Invalid API key · Please run /login", FailureAuth},
+		{"API Error: 401 {\"type\":\"error\",\"error\":{\"type\":\"authentication_error\"}}", FailureAuth},
+		{"error: unknown option '--mcp-server'", FailureInvalidArgs},
+		{"API Error: 404 {\"type\":\"error\",\"error\":{\"type\":\"not_found_error\",\"message\":\"model: claude-nope\"}}", FailureInvalidArgs},
+		{"panic: something unexpected", FailureUnknown},
+		{"", FailureUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.want)+"/"+truncateString(tt.output, 30), func(t *testing.T) {
+			kind, detail := classifyFailure(tt.output)
+			assert.Equal(t, tt.want, kind)
+			if tt.want != FailureUnknown {
+				assert.NotEmpty(t, detail)
+			}
+		})
+	}
+}
+
+func TestExecutionError(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 1").Run()
+	require.Error(t, exitErr)
+
+	err := newExecutionError(exitErr, "some progress\nInvalid API key · Please run /login\n")
+	assert.Equal(t, FailureAuth, err.Kind)
+	assert.Equal(t, 1, err.ExitCode)
+	assert.False(t, err.Kind.Transient())
+	assert.Contains(t, err.Error(), "claude execution failed")
+	assert.Contains(t, err.Error(), "claude login")
+	assert.Contains(t, err.Error(), "Invalid API key")
+
+	// The exit error stays reachable for callers recording exit codes
+	var target *exec.ExitError
+	assert.True(t, errors.As(err, &target))
+
+	long := newExecutionError(errors.New("failed"), strings.Repeat("x", 2*maxFailureOutput)+"overloaded")
+	assert.Equal(t, FailureOverloaded, long.Kind)
+	assert.Equal(t, -1, long.ExitCode)
+	assert.Len(t, long.Output, maxFailureOutput)
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 5, InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+
+	for attempt, window := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		for i := 0; i < 20; i++ {
+			delay := retryDelay(policy, attempt)
+			assert.GreaterOrEqual(t, delay, window/2, "attempt %d", attempt)
+			assert.LessOrEqual(t, delay, window, "attempt %d", attempt)
+		}
+	}
+	assert.Zero(t, retryDelay(RetryPolicy{MaxRetries: 1}, 1))
+}
+
+// installFlakyClaude installs a fake claude that writes failure to stderr and
+// exits 1 for its first failures calls, then succeeds. It returns a function
+// reporting how many times it was invoked.
+func installFlakyClaude(t *testing.T, failure string, failures int) func() int {
+	t.Helper()
+	dir := t.TempDir()
+	countFile := filepath.Join(dir, "calls")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "failure.txt"), []byte(failure), 0644))
+	script := `#!/bin/sh
+dir="$(dirname "$0")"
+echo x >> "$dir/calls"
+if [ "$(wc -l < "$dir/calls")" -le ` + strconv.Itoa(failures) + ` ]; then
+  cat "$dir/failure.txt" >&2
+  exit 1
+fi
+echo "done"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "claude"), []byte(script), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return func() int {
+		data, err := os.ReadFile(countFile)
+		if err != nil {
+			return 0
+		}
+		return strings.Count(string(data), "\n")
+	}
+}
+
+func retryConfig(t *testing.T, policy RetryPolicy, events *[]RetryEvent) ExecuteConfig {
+	return ExecuteConfig{
+		Prompt:    "/start",
+		StateFile: filepath.Join(t.TempDir(), "agent_state.json"),
+		WorkDir:   t.TempDir(),
+		Retry:     policy,
+		OnRetry: func(event RetryEvent) {
+			*events = append(*events, event)
+		},
+	}
+}
+
+func TestExecutor_RetriesTransientFailures(t *testing.T) {
+	calls := installFlakyClaude(t, "API Error: 529 Overloaded", 2)
+
+	var retries []RetryEvent
+	policy := RetryPolicy{MaxRetries: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+	result, err := NewExecutor().Execute(context.Background(), retryConfig(t, policy, &retries))
+	require.NoError(t, err)
+	assert.Contains(t, result, "done")
+	assert.Equal(t, 3, calls())
+
+	require.Len(t, retries, 2)
+	for i, event := range retries {
+		assert.Equal(t, i+1, event.Attempt)
+		assert.Equal(t, 3, event.MaxRetries)
+		assert.Equal(t, FailureOverloaded, event.Err.Kind)
+	}
+}
+
+func TestExecutor_GivesUpAfterMaxRetries(t *testing.T) {
+	calls := installFlakyClaude(t, "API Error: 429 rate_limit_error", 5)
+
+	var retries []RetryEvent
+	policy := RetryPolicy{MaxRetries: 2, InitialBackoff: time.Millisecond}
+	_, err := NewExecutor().Execute(context.Background(), retryConfig(t, policy, &retries))
+	require.Error(t, err)
+	assert.Equal(t, 3, calls())
+	assert.Len(t, retries, 2)
+
+	var execErr *ExecutionError
+	require.True(t, errors.As(err, &execErr))
+	assert.Equal(t, FailureRateLimit, execErr.Kind)
+}
+
+func TestExecutor_FailsFastOnAuthAndArgumentErrors(t *testing.T) {
+	for failure, kind := range map[string]FailureKind{
+		"Invalid API key · Please run /login": FailureAuth,
+		"error: unknown option '--bogus'":     FailureInvalidArgs,
+	} {
+		t.Run(string(kind), func(t *testing.T) {
+			calls := installFlakyClaude(t, failure, 5)
+
+			var retries []RetryEvent
+			policy := RetryPolicy{MaxRetries: 3, InitialBackoff: time.Millisecond}
+			_, err := NewExecutor().Execute(context.Background(), retryConfig(t, policy, &retries))
+			require.Error(t, err)
+			assert.Equal(t, 1, calls())
+			assert.Empty(t, retries)
+
+			var execErr *ExecutionError
+			require.True(t, errors.As(err, &execErr))
+			assert.Equal(t, kind, execErr.Kind)
+		})
+	}
+}
+
+func TestExecutor_RetryBackoffRespectsCancellation(t *testing.T) {
+	calls := installFlakyClaude(t, "TypeError: fetch failed", 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var retries []RetryEvent
+	config := retryConfig(t, RetryPolicy{MaxRetries: 3, InitialBackoff: time.Hour}, &retries)
+	config.OnRetry = func(event RetryEvent) {
+		retries = append(retries, event)
+		cancel()
+	}
+
+	start := time.Now()
+	_, err := NewExecutor().Execute(ctx, config)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Minute)
+	assert.Equal(t, 1, calls())
+	require.Len(t, retries, 1)
+	assert.Equal(t, FailureNetwork, retries[0].Err.Kind)
+}
+
+func TestExecutor_StderrCaptureClassifiesFailures(t *testing.T) {
+	calls := installFlakyClaude(t, "API Error: 529 Overloaded", 1)
+
+	executor := NewExecutor()
+	executor.SetStreamer(&mockStreamer{})
+	executor.SetRunID("run-1")
+
+	var retries []RetryEvent
+	policy := RetryPolicy{MaxRetries: 1, InitialBackoff: time.Millisecond}
+	result, err := executor.Execute(context.Background(), retryConfig(t, policy, &retries))
+	require.NoError(t, err)
+	assert.Contains(t, result, "done")
+	assert.Equal(t, 2, calls())
+	require.Len(t, retries, 1)
+	assert.Equal(t, FailureOverloaded, retries[0].Err.Kind)
+}
@@ -0,0 +1,46 @@
+package claude
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/Backland-Labs/alpine/internal/logger"
+)
+
+// SessionInfo describes the Claude session an execution ran in
+type SessionInfo struct {
+	// ID is the Claude session ID, which can be resumed by a later execution
+	ID string
+
+	// ContextTokens is the size of the context Claude last sent to the model,
+	// including cached tokens. It is only known with stream-json output.
+	ContextTokens int
+}
+
+// NewSessionID returns a new ID for starting a Claude session
+func NewSessionID() string {
+	return uuid.New().String()
+}
+
+// observe records the session ID and context size reported by a stream event
+func (s *SessionInfo) observe(event StreamEvent) {
+	if event.SessionID != "" {
+		s.ID = event.SessionID
+	}
+	if event.Type == StreamEventUsage && event.Usage != nil {
+		s.ContextTokens = event.Usage.InputTokens + event.Usage.CacheCreationInputTokens + event.Usage.CacheReadInputTokens
+	}
+}
+
+// reportSession passes the session an execution ran in to config.OnSession
+func (e *Executor) reportSession(config ExecuteConfig, session SessionInfo) {
+	if config.OnSession == nil || config.SessionID == "" {
+		return
+	}
+	logger.WithFields(map[string]interface{}{
+		"session_id":     session.ID,
+		"resumed":        config.ResumeSession,
+		"context_tokens": session.ContextTokens,
+		"run_id":         e.runID,
+	}).Debug("Claude session recorded")
+	config.OnSession(session)
+}
@@ -0,0 +1,114 @@
+package claude
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutor_BuildCommand_Session(t *testing.T) {
+	exec := &Executor{}
+
+	cmd := exec.buildCommand(ExecuteConfig{Prompt: "p", StateFile: "/tmp/s.json", SessionID: "sess-1"})
+	args := strings.Join(cmd.Args[1:], " ")
+	assert.Contains(t, args, "--session-id sess-1")
+	assert.NotContains(t, args, "--resume")
+
+	cmd = exec.buildCommand(ExecuteConfig{Prompt: "p", StateFile: "/tmp/s.json", SessionID: "sess-1", ResumeSession: true})
+	args = strings.Join(cmd.Args[1:], " ")
+	assert.Contains(t, args, "--resume sess-1")
+	assert.NotContains(t, args, "--session-id")
+
+	cmd = exec.buildCommand(ExecuteConfig{Prompt: "p", StateFile: "/tmp/s.json"})
+	args = strings.Join(cmd.Args[1:], " ")
+	assert.NotContains(t, args, "--session-id")
+	assert.NotContains(t, args, "--resume")
+}
+
+func TestExecutor_StreamJSON_ReportsSession(t *testing.T) {
+	installFakeClaude(t, sampleStream, 0)
+
+	var sessions []SessionInfo
+	_, err := NewExecutor().Execute(context.Background(), ExecuteConfig{
+		Prompt:        "/continue",
+		StateFile:     filepath.Join(t.TempDir(), "agent_state.json"),
+		WorkDir:       t.TempDir(),
+		OutputFormat:  OutputFormatStreamJSON,
+		SessionID:     "requested",
+		ResumeSession: true,
+		OnSession:     func(session SessionInfo) { sessions = append(sessions, session) },
+	})
+	require.NoError(t, err)
+
+	// The session Claude reports wins over the requested one, and the
+	// context size comes from the last assistant message
+	require.Len(t, sessions, 1)
+	assert.Equal(t, SessionInfo{ID: "sess-1", ContextTokens: 3}, sessions[0])
+}
+
+func TestExecutor_TextOutput_ReportsRequestedSession(t *testing.T) {
+	installFakeClaude(t, "done\n", 0)
+
+	var sessions []SessionInfo
+	config := ExecuteConfig{
+		Prompt:    "/continue",
+		StateFile: filepath.Join(t.TempDir(), "agent_state.json"),
+		WorkDir:   t.TempDir(),
+		SessionID: "sess-2",
+		OnSession: func(session SessionInfo) { sessions = append(sessions, session) },
+	}
+	_, err := NewExecutor().Execute(context.Background(), config)
+	require.NoError(t, err)
+	assert.Equal(t, []SessionInfo{{ID: "sess-2"}}, sessions)
+
+	// Without a session ID there is nothing to report
+	sessions = nil
+	config.SessionID = ""
+	_, err = NewExecutor().Execute(context.Background(), config)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestExecutor_StartsNewSessionWhenResumedSessionIsMissing(t *testing.T) {
+	dir := t.TempDir()
+	script := `#!/bin/sh
+echo "$@" >> "$(dirname "$0")/args"
+for arg in "$@"; do
+  if [ "$arg" = "--resume" ]; then
+    echo "No conversation found with session ID: gone" >&2
+    exit 1
+  fi
+done
+echo done
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "claude"), []byte(script), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var sessions []SessionInfo
+	var retries []RetryEvent
+	config := retryConfig(t, RetryPolicy{InitialBackoff: time.Millisecond}, &retries)
+	config.SessionID = "gone"
+	config.ResumeSession = true
+	config.OnSession = func(session SessionInfo) { sessions = append(sessions, session) }
+
+	_, err := NewExecutor().Execute(context.Background(), config)
+	require.NoError(t, err)
+	assert.Empty(t, retries, "falling back to a new session is not a retry")
+
+	data, err := os.ReadFile(filepath.Join(dir, "args"))
+	require.NoError(t, err)
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, calls, 2)
+	assert.Contains(t, calls[0], "--resume gone")
+	assert.Contains(t, calls[1], "--session-id")
+
+	require.Len(t, sessions, 1)
+	assert.NotEqual(t, "gone", sessions[0].ID)
+	assert.Contains(t, calls[1], "--session-id "+sessions[0].ID)
+}
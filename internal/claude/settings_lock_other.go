@@ -0,0 +1,13 @@
+//go:build !unix
+
+package claude
+
+// lockDir is a no-op where advisory file locks are unavailable
+func lockDir(dir string) (unlock func(), err error) {
+	return func() {}, nil
+}
+
+// processAlive assumes the process is running, since liveness cannot be checked
+func processAlive(pid int) bool {
+	return true
+}
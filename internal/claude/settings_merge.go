@@ -0,0 +1,335 @@
+package claude
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/Backland-Labs/alpine/internal/logger"
+)
+
+// settingsLedgerSuffix names the bookkeeping file kept next to a Claude
+// settings file while alpine has hooks merged into it
+const settingsLedgerSuffix = ".alpine"
+
+// LocalSettingsFiles matches, relative to a working directory, the Claude
+// settings file alpine merges its hooks into and the files it keeps next to
+// it, such as the ledger. It is a git pathspec and git clean pattern.
+var LocalSettingsFiles = filepath.Join(".claude", "settings.local.json") + "*"
+
+// settingsLedger records the original contents of a Claude settings file and
+// the runs whose hooks are currently merged into it. It lets the last run to
+// finish, or the next run after a crash, restore the original exactly.
+type settingsLedger struct {
+	OriginalExists bool          `json:"original_exists"`
+	Original       []byte        `json:"original,omitempty"`
+	Mode           os.FileMode   `json:"mode,omitempty"`
+	Runs           []settingsRun `json:"runs"`
+}
+
+// settingsRun is one alpine run's contribution to a merged settings file
+type settingsRun struct {
+	ID    string                   `json:"id"`
+	PID   int                      `json:"pid"`
+	Hooks map[string][]toolMatcher `json:"hooks"`
+}
+
+// mergeClaudeSettings merges hooks into the Claude settings file at
+// settingsPath, keeping every setting and hook already present. The returned
+// release function removes this run's hooks; once no run is using the file it
+// is restored byte-for-byte, or removed if it did not exist. Concurrent runs in
+// the same directory are serialized by a lock on the settings directory.
+func mergeClaudeSettings(settingsPath string, hooks map[string][]toolMatcher) (release func(), err error) {
+	dir := filepath.Dir(settingsPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	unlock, err := lockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	ledgerPath := settingsPath + settingsLedgerSuffix
+	ledger, err := loadSettingsLedger(ledgerPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if ledger != nil {
+		ledger.pruneDeadRuns()
+		if len(ledger.Runs) == 0 {
+			// Every run that merged hooks has died, so put the original back first
+			if err := ledger.restore(settingsPath); err != nil {
+				return nil, err
+			}
+			_ = os.Remove(ledgerPath)
+			logger.WithField("settings_path", settingsPath).Info("Restored Claude settings left behind by an interrupted run")
+			ledger = nil
+		}
+	}
+
+	if ledger == nil {
+		ledger, err = newSettingsLedger(settingsPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	run := settingsRun{ID: newSettingsRunID(), PID: os.Getpid(), Hooks: hooks}
+	ledger.Runs = append(ledger.Runs, run)
+
+	// The ledger is written before the settings so a crash in between can
+	// always be undone
+	if err := ledger.save(ledgerPath); err != nil {
+		return nil, err
+	}
+	if err := ledger.apply(settingsPath); err != nil {
+		ledger.Runs = ledger.Runs[:len(ledger.Runs)-1]
+		if len(ledger.Runs) == 0 {
+			_ = os.Remove(ledgerPath)
+		} else {
+			_ = ledger.save(ledgerPath)
+		}
+		return nil, err
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"settings_path": settingsPath,
+		"active_runs":   len(ledger.Runs),
+	}).Debug("Merged alpine hooks into Claude settings")
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			if err := releaseClaudeSettings(settingsPath, run.ID); err != nil {
+				logger.WithFields(map[string]interface{}{
+					"error":         err.Error(),
+					"settings_path": settingsPath,
+				}).Warn("Failed to restore Claude settings")
+			}
+		})
+	}
+	return release, nil
+}
+
+// releaseClaudeSettings removes a run's hooks from the settings file, restoring
+// the original when no other run still uses it
+func releaseClaudeSettings(settingsPath, runID string) error {
+	unlock, err := lockDir(filepath.Dir(settingsPath))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ledgerPath := settingsPath + settingsLedgerSuffix
+	ledger, err := loadSettingsLedger(ledgerPath)
+	if err != nil || ledger == nil {
+		return err
+	}
+
+	remaining := ledger.Runs[:0]
+	for _, run := range ledger.Runs {
+		if run.ID != runID {
+			remaining = append(remaining, run)
+		}
+	}
+	ledger.Runs = remaining
+	ledger.pruneDeadRuns()
+
+	if len(ledger.Runs) == 0 {
+		if err := ledger.restore(settingsPath); err != nil {
+			return err
+		}
+		return removeIfExists(ledgerPath)
+	}
+
+	if err := ledger.save(ledgerPath); err != nil {
+		return err
+	}
+	return ledger.apply(settingsPath)
+}
+
+// newSettingsLedger captures the current contents of the settings file
+func newSettingsLedger(settingsPath string) (*settingsLedger, error) {
+	ledger := &settingsLedger{Mode: 0644}
+	info, err := os.Stat(settingsPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", settingsPath, err)
+	}
+
+	original, err := os.ReadFile(settingsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", settingsPath, err)
+	}
+	// Refuse to touch settings we cannot merge into
+	if _, err := mergeHooksJSON(original, nil); err != nil {
+		return nil, fmt.Errorf("cannot merge hooks into %s: %w", settingsPath, err)
+	}
+
+	ledger.OriginalExists = true
+	ledger.Original = original
+	ledger.Mode = info.Mode().Perm()
+	return ledger, nil
+}
+
+// loadSettingsLedger reads a ledger, returning nil if none exists
+func loadSettingsLedger(path string) (*settingsLedger, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings ledger: %w", err)
+	}
+	var ledger settingsLedger
+	if err := json.Unmarshal(data, &ledger); err != nil {
+		return nil, fmt.Errorf("invalid settings ledger %s: %w", path, err)
+	}
+	return &ledger, nil
+}
+
+// save writes the ledger atomically
+func (l *settingsLedger) save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings ledger: %w", err)
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// pruneDeadRuns drops runs whose process has exited without releasing
+func (l *settingsLedger) pruneDeadRuns() {
+	alive := l.Runs[:0]
+	for _, run := range l.Runs {
+		if processAlive(run.PID) {
+			alive = append(alive, run)
+		}
+	}
+	l.Runs = alive
+}
+
+// apply writes the original settings merged with every active run's hooks
+func (l *settingsLedger) apply(settingsPath string) error {
+	merged, err := mergeHooksJSON(l.Original, l.Runs)
+	if err != nil {
+		return fmt.Errorf("failed to merge hooks into %s: %w", settingsPath, err)
+	}
+	return writeFileAtomic(settingsPath, merged, l.Mode)
+}
+
+// restore puts the original settings file back exactly as it was
+func (l *settingsLedger) restore(settingsPath string) error {
+	if !l.OriginalExists {
+		return removeIfExists(settingsPath)
+	}
+	return writeFileAtomic(settingsPath, l.Original, l.Mode)
+}
+
+// mergeHooksJSON appends each run's hook matchers to the hooks in original,
+// preserving all other settings. Identical matchers are only added once.
+func mergeHooksJSON(original []byte, runs []settingsRun) ([]byte, error) {
+	settings := map[string]json.RawMessage{}
+	if len(bytes.TrimSpace(original)) > 0 {
+		if err := json.Unmarshal(original, &settings); err != nil {
+			return nil, fmt.Errorf("settings are not a JSON object: %w", err)
+		}
+	}
+
+	events := map[string][]json.RawMessage{}
+	if raw, ok := settings["hooks"]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &events); err != nil {
+			return nil, fmt.Errorf("settings hooks are not a map of hook lists: %w", err)
+		}
+	}
+
+	for _, run := range runs {
+		for event, matchers := range run.Hooks {
+			for _, matcher := range matchers {
+				entry, err := json.Marshal(matcher)
+				if err != nil {
+					return nil, fmt.Errorf("failed to marshal %s hook: %w", event, err)
+				}
+				if !containsJSON(events[event], entry) {
+					events[event] = append(events[event], entry)
+				}
+			}
+		}
+	}
+
+	if len(events) > 0 {
+		raw, err := json.Marshal(events)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal hooks: %w", err)
+		}
+		settings["hooks"] = raw
+	}
+
+	return json.MarshalIndent(settings, "", "  ")
+}
+
+// containsJSON reports whether list holds an entry equivalent to entry
+func containsJSON(list []json.RawMessage, entry []byte) bool {
+	for _, existing := range list {
+		var compacted bytes.Buffer
+		if json.Compact(&compacted, existing) == nil && bytes.Equal(compacted.Bytes(), entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it into place, so readers never observe a partial file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for %s: %w", path, err)
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to set permissions on %s: %w", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// removeIfExists removes path, ignoring a missing file
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
+
+// newSettingsRunID returns a unique identifier for a settings merge
+func newSettingsRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", os.Getpid())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,196 @@
+package claude
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// projectSettings is a hand-formatted settings file with its own hooks and permissions
+const projectSettings = `{
+    "permissions": {"allow": ["Bash(go test:*)"]},
+    "hooks": {
+        "PostToolUse": [{"matcher": "Write", "hooks": [{"type": "command", "command": "make fmt"}]}]
+    }
+}
+`
+
+// readHooks returns the hook commands registered for an event in a settings file
+func readHooks(t *testing.T, settingsPath, event string) []string {
+	t.Helper()
+	data, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+
+	var settings struct {
+		Hooks map[string][]toolMatcher `json:"hooks"`
+	}
+	require.NoError(t, json.Unmarshal(data, &settings))
+
+	var commands []string
+	for _, matcher := range settings.Hooks[event] {
+		for _, hook := range matcher.Hooks {
+			commands = append(commands, hook["command"].(string))
+		}
+	}
+	return commands
+}
+
+func TestMergeClaudeSettings_PreservesAndRestoresProjectSettings(t *testing.T) {
+	settingsPath := filepath.Join(t.TempDir(), ".claude", "settings.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(settingsPath), 0755))
+	require.NoError(t, os.WriteFile(settingsPath, []byte(projectSettings), 0600))
+
+	release, err := mergeClaudeSettings(settingsPath, map[string][]toolMatcher{
+		"PostToolUse":  commandHooks("", "/bin/alpine hook"),
+		"SubagentStop": commandHooks("", "/bin/alpine hook"),
+	})
+	require.NoError(t, err)
+
+	// Project hooks and settings are kept alongside alpine's hooks
+	assert.Equal(t, []string{"make fmt", "/bin/alpine hook"}, readHooks(t, settingsPath, "PostToolUse"))
+	assert.Equal(t, []string{"/bin/alpine hook"}, readHooks(t, settingsPath, "SubagentStop"))
+	data, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Bash(go test:*)")
+
+	release()
+
+	// The original is restored byte-for-byte with its permissions
+	restored, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, projectSettings, string(restored))
+	info, err := os.Stat(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	_, err = os.Stat(settingsPath + settingsLedgerSuffix)
+	assert.True(t, os.IsNotExist(err), "ledger should be removed once no run uses the settings")
+
+	// Releasing twice is harmless
+	release()
+	restored, err = os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, projectSettings, string(restored))
+}
+
+func TestMergeClaudeSettings_RemovesFileThatDidNotExist(t *testing.T) {
+	settingsPath := filepath.Join(t.TempDir(), ".claude", "settings.local.json")
+
+	release, err := mergeClaudeSettings(settingsPath, map[string][]toolMatcher{
+		"PostToolUse": commandHooks("", "/bin/alpine hook"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/bin/alpine hook"}, readHooks(t, settingsPath, "PostToolUse"))
+
+	release()
+	_, err = os.Stat(settingsPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMergeClaudeSettings_RejectsInvalidSettings(t *testing.T) {
+	settingsPath := filepath.Join(t.TempDir(), "settings.json")
+	require.NoError(t, os.WriteFile(settingsPath, []byte("{not json"), 0644))
+
+	_, err := mergeClaudeSettings(settingsPath, map[string][]toolMatcher{
+		"PostToolUse": commandHooks("", "/bin/alpine hook"),
+	})
+	require.Error(t, err)
+
+	data, readErr := os.ReadFile(settingsPath)
+	require.NoError(t, readErr)
+	assert.Equal(t, "{not json", string(data), "unmergeable settings must be left untouched")
+}
+
+func TestMergeClaudeSettings_OverlappingRuns(t *testing.T) {
+	settingsPath := filepath.Join(t.TempDir(), "settings.json")
+	require.NoError(t, os.WriteFile(settingsPath, []byte(projectSettings), 0644))
+
+	releaseA, err := mergeClaudeSettings(settingsPath, map[string][]toolMatcher{
+		"PostToolUse": commandHooks("", "alpine-a hook"),
+	})
+	require.NoError(t, err)
+	releaseB, err := mergeClaudeSettings(settingsPath, map[string][]toolMatcher{
+		"PostToolUse": commandHooks("", "alpine-b hook"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"make fmt", "alpine-a hook", "alpine-b hook"}, readHooks(t, settingsPath, "PostToolUse"))
+
+	// The first run finishing must not strip the second run's hooks
+	releaseA()
+	assert.Equal(t, []string{"make fmt", "alpine-b hook"}, readHooks(t, settingsPath, "PostToolUse"))
+
+	releaseB()
+	restored, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, projectSettings, string(restored))
+}
+
+func TestMergeClaudeSettings_ConcurrentRuns(t *testing.T) {
+	settingsPath := filepath.Join(t.TempDir(), "settings.json")
+	require.NoError(t, os.WriteFile(settingsPath, []byte(projectSettings), 0644))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			release, err := mergeClaudeSettings(settingsPath, map[string][]toolMatcher{
+				"PostToolUse": commandHooks("", fmt.Sprintf("alpine-%d hook", i)),
+			})
+			if err != nil {
+				errs <- err
+				return
+			}
+			release()
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("merge failed: %v", err)
+	}
+
+	restored, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, projectSettings, string(restored))
+}
+
+func TestMergeClaudeSettings_RecoversAfterCrash(t *testing.T) {
+	settingsPath := filepath.Join(t.TempDir(), "settings.json")
+	require.NoError(t, os.WriteFile(settingsPath, []byte(projectSettings), 0644))
+
+	// Simulate a run that merged its hooks and then died without releasing
+	dead := exec.Command("true")
+	require.NoError(t, dead.Run())
+	crashed := &settingsLedger{
+		OriginalExists: true,
+		Original:       []byte(projectSettings),
+		Mode:           0644,
+		Runs: []settingsRun{{
+			ID:    "crashed",
+			PID:   dead.Process.Pid,
+			Hooks: map[string][]toolMatcher{"PostToolUse": commandHooks("", "crashed hook")},
+		}},
+	}
+	require.NoError(t, crashed.save(settingsPath+settingsLedgerSuffix))
+	require.NoError(t, crashed.apply(settingsPath))
+
+	release, err := mergeClaudeSettings(settingsPath, map[string][]toolMatcher{
+		"PostToolUse": commandHooks("", "alpine hook"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"make fmt", "alpine hook"}, readHooks(t, settingsPath, "PostToolUse"),
+		"hooks of the crashed run should be dropped")
+
+	release()
+	restored, err := os.ReadFile(settingsPath)
+	require.NoError(t, err)
+	assert.Equal(t, projectSettings, string(restored))
+}
@@ -0,0 +1,279 @@
+package claude
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamEventType identifies the kind of a decoded stream-json event
+type StreamEventType string
+
+const (
+	// StreamEventInit is emitted once when Claude starts a session
+	StreamEventInit StreamEventType = "init"
+	// StreamEventText carries assistant text output
+	StreamEventText StreamEventType = "text"
+	// StreamEventToolUse is emitted when Claude invokes a tool
+	StreamEventToolUse StreamEventType = "tool_use"
+	// StreamEventToolResult carries the result of a tool invocation
+	StreamEventToolResult StreamEventType = "tool_result"
+	// StreamEventUsage reports token usage for an assistant message
+	StreamEventUsage StreamEventType = "usage"
+	// StreamEventResult is the final event of a run
+	StreamEventResult StreamEventType = "result"
+)
+
+// StreamEvent is a single typed event decoded from Claude's stream-json output.
+// Exactly one of the payload fields is set, matching Type.
+type StreamEvent struct {
+	Type      StreamEventType
+	SessionID string
+
+	// Model is set for StreamEventInit
+	Model string
+	// Text is set for StreamEventText
+	Text string
+	// ToolUse is set for StreamEventToolUse
+	ToolUse *ToolUse
+	// ToolResult is set for StreamEventToolResult
+	ToolResult *ToolResult
+	// Usage is set for StreamEventUsage
+	Usage *Usage
+	// Result is set for StreamEventResult
+	Result *Result
+}
+
+// ToolUse describes a tool invocation requested by Claude
+type ToolUse struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+// ToolResult describes the outcome of a tool invocation
+type ToolResult struct {
+	ToolUseID string
+	Content   string
+	IsError   bool
+}
+
+// Usage holds token counts reported by Claude
+type Usage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// Result is the final summary Claude emits at the end of a run
+type Result struct {
+	Subtype      string  `json:"subtype"`
+	IsError      bool    `json:"is_error"`
+	Result       string  `json:"result"`
+	SessionID    string  `json:"session_id"`
+	NumTurns     int     `json:"num_turns"`
+	DurationMS   int64   `json:"duration_ms"`
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	Usage        Usage   `json:"usage"`
+}
+
+// streamMessage is the raw envelope of a stream-json line
+type streamMessage struct {
+	Type      string `json:"type"`
+	Subtype   string `json:"subtype"`
+	SessionID string `json:"session_id"`
+	Model     string `json:"model"`
+	Message   struct {
+		Content json.RawMessage `json:"content"`
+		Usage   *Usage          `json:"usage"`
+	} `json:"message"`
+}
+
+// contentBlock is a single entry in a message's content array
+type contentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	IsError   bool            `json:"is_error"`
+}
+
+// DecodeStreamLine decodes one line of stream-json output into typed events.
+// Blank lines and message types that carry no useful events yield no events.
+func DecodeStreamLine(line []byte) ([]StreamEvent, error) {
+	if len(strings.TrimSpace(string(line))) == 0 {
+		return nil, nil
+	}
+
+	var msg streamMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return nil, fmt.Errorf("invalid stream-json line: %w", err)
+	}
+
+	switch msg.Type {
+	case "system":
+		if msg.Subtype != "init" {
+			return nil, nil
+		}
+		return []StreamEvent{{Type: StreamEventInit, SessionID: msg.SessionID, Model: msg.Model}}, nil
+
+	case "assistant", "user":
+		blocks, err := decodeContent(msg.Message.Content)
+		if err != nil {
+			return nil, err
+		}
+		var events []StreamEvent
+		for _, block := range blocks {
+			switch block.Type {
+			case "text":
+				if block.Text != "" {
+					events = append(events, StreamEvent{Type: StreamEventText, SessionID: msg.SessionID, Text: block.Text})
+				}
+			case "tool_use":
+				events = append(events, StreamEvent{
+					Type:      StreamEventToolUse,
+					SessionID: msg.SessionID,
+					ToolUse:   &ToolUse{ID: block.ID, Name: block.Name, Input: block.Input},
+				})
+			case "tool_result":
+				events = append(events, StreamEvent{
+					Type:      StreamEventToolResult,
+					SessionID: msg.SessionID,
+					ToolResult: &ToolResult{
+						ToolUseID: block.ToolUseID,
+						Content:   toolResultText(block.Content),
+						IsError:   block.IsError,
+					},
+				})
+			}
+		}
+		if msg.Type == "assistant" && msg.Message.Usage != nil {
+			events = append(events, StreamEvent{Type: StreamEventUsage, SessionID: msg.SessionID, Usage: msg.Message.Usage})
+		}
+		return events, nil
+
+	case "result":
+		var result Result
+		if err := json.Unmarshal(line, &result); err != nil {
+			return nil, fmt.Errorf("invalid stream-json result: %w", err)
+		}
+		return []StreamEvent{{Type: StreamEventResult, SessionID: result.SessionID, Result: &result}}, nil
+	}
+
+	return nil, nil
+}
+
+// decodeContent decodes message content, which is either a string or an array of blocks
+func decodeContent(raw json.RawMessage) ([]contentBlock, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return []contentBlock{{Type: "text", Text: text}}, nil
+	}
+	var blocks []contentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil, fmt.Errorf("invalid stream-json message content: %w", err)
+	}
+	return blocks, nil
+}
+
+// toolResultText flattens tool result content, which is either a string or text blocks
+func toolResultText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var blocks []contentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return string(raw)
+	}
+	parts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ParseStream reads stream-json output line by line and calls handle for each event.
+// Lines that fail to decode are reported to onError and skipped, so a single
+// malformed line does not abort the run. It returns the final result, or nil if
+// the stream ended without one.
+func ParseStream(r io.Reader, handle func(StreamEvent), onError func(error)) (*Result, error) {
+	reader := bufio.NewReader(r)
+	var final *Result
+
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			events, err := DecodeStreamLine(line)
+			if err != nil {
+				if onError != nil {
+					onError(err)
+				}
+			}
+			for _, event := range events {
+				if event.Type == StreamEventResult {
+					final = event.Result
+				}
+				if handle != nil {
+					handle(event)
+				}
+			}
+		}
+		if readErr != nil {
+			if errors.Is(readErr, io.EOF) {
+				return final, nil
+			}
+			return final, readErr
+		}
+	}
+}
+
+// summary returns a short human-readable description of a tool invocation
+func (t *ToolUse) summary() string {
+	var input map[string]interface{}
+	_ = json.Unmarshal(t.Input, &input)
+
+	for _, key := range []string{"command", "file_path", "path", "pattern", "url", "description"} {
+		if value, ok := input[key].(string); ok && value != "" {
+			return fmt.Sprintf("%s: %s", t.Name, truncateString(value, 80))
+		}
+	}
+	return t.Name
+}
+
+// activeTodo returns the in-progress task from a TodoWrite invocation, if any
+func (t *ToolUse) activeTodo() string {
+	if t.Name != "TodoWrite" {
+		return ""
+	}
+	var input struct {
+		Todos []struct {
+			Content string `json:"content"`
+			Status  string `json:"status"`
+		} `json:"todos"`
+	}
+	if err := json.Unmarshal(t.Input, &input); err != nil {
+		return ""
+	}
+	for _, todo := range input.Todos {
+		if todo.Status == "in_progress" {
+			return todo.Content
+		}
+	}
+	return ""
+}
@@ -0,0 +1,247 @@
+package claude
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/config"
+	"github.com/Backland-Labs/alpine/internal/output"
+)
+
+// sampleStream is a representative stream-json transcript from a Claude run
+const sampleStream = `{"type":"system","subtype":"init","session_id":"sess-1","model":"claude-sonnet-4-20250514","tools":["Bash","Read"]}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Running the tests."},{"type":"tool_use","id":"toolu_1","name":"Bash","input":{"command":"go test ./..."}}],"usage":{"input_tokens":12,"output_tokens":34,"cache_creation_input_tokens":5,"cache_read_input_tokens":6}},"session_id":"sess-1"}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"ok  \tpkg\t0.1s","is_error":false}]},"session_id":"sess-1"}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"tool_use","id":"toolu_2","name":"TodoWrite","input":{"todos":[{"content":"Write tests","status":"completed"},{"content":"Fix parser","status":"in_progress"}]}}],"usage":{"input_tokens":1,"output_tokens":2}},"session_id":"sess-1"}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_2","content":[{"type":"text","text":"permission denied"}],"is_error":true}]},"session_id":"sess-1"}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"All done."}],"usage":{"input_tokens":3,"output_tokens":4}},"session_id":"sess-1"}
+{"type":"result","subtype":"success","is_error":false,"duration_ms":1234,"num_turns":3,"result":"All done.","session_id":"sess-1","total_cost_usd":0.0125,"usage":{"input_tokens":16,"output_tokens":40}}
+`
+
+func TestDecodeStreamLine(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(sampleStream), "\n")
+
+	t.Run("system init", func(t *testing.T) {
+		events, err := DecodeStreamLine([]byte(lines[0]))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, StreamEventInit, events[0].Type)
+		assert.Equal(t, "sess-1", events[0].SessionID)
+		assert.Equal(t, "claude-sonnet-4-20250514", events[0].Model)
+	})
+
+	t.Run("assistant text, tool use and usage", func(t *testing.T) {
+		events, err := DecodeStreamLine([]byte(lines[1]))
+		require.NoError(t, err)
+		require.Len(t, events, 3)
+
+		assert.Equal(t, StreamEventText, events[0].Type)
+		assert.Equal(t, "Running the tests.", events[0].Text)
+
+		assert.Equal(t, StreamEventToolUse, events[1].Type)
+		assert.Equal(t, "toolu_1", events[1].ToolUse.ID)
+		assert.Equal(t, "Bash", events[1].ToolUse.Name)
+		assert.JSONEq(t, `{"command":"go test ./..."}`, string(events[1].ToolUse.Input))
+		assert.Equal(t, "Bash: go test ./...", events[1].ToolUse.summary())
+
+		assert.Equal(t, StreamEventUsage, events[2].Type)
+		assert.Equal(t, Usage{InputTokens: 12, OutputTokens: 34, CacheCreationInputTokens: 5, CacheReadInputTokens: 6}, *events[2].Usage)
+	})
+
+	t.Run("tool result with string content", func(t *testing.T) {
+		events, err := DecodeStreamLine([]byte(lines[2]))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, StreamEventToolResult, events[0].Type)
+		assert.Equal(t, "toolu_1", events[0].ToolResult.ToolUseID)
+		assert.Equal(t, "ok  \tpkg\t0.1s", events[0].ToolResult.Content)
+		assert.False(t, events[0].ToolResult.IsError)
+	})
+
+	t.Run("tool result with block content", func(t *testing.T) {
+		events, err := DecodeStreamLine([]byte(lines[4]))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "permission denied", events[0].ToolResult.Content)
+		assert.True(t, events[0].ToolResult.IsError)
+	})
+
+	t.Run("final result", func(t *testing.T) {
+		events, err := DecodeStreamLine([]byte(lines[6]))
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		result := events[0].Result
+		require.NotNil(t, result)
+		assert.Equal(t, "success", result.Subtype)
+		assert.Equal(t, "All done.", result.Result)
+		assert.Equal(t, "sess-1", result.SessionID)
+		assert.Equal(t, 3, result.NumTurns)
+		assert.InDelta(t, 0.0125, result.TotalCostUSD, 1e-9)
+		assert.Equal(t, 40, result.Usage.OutputTokens)
+	})
+
+	t.Run("blank and unknown lines yield no events", func(t *testing.T) {
+		events, err := DecodeStreamLine([]byte("  \n"))
+		require.NoError(t, err)
+		assert.Empty(t, events)
+
+		events, err = DecodeStreamLine([]byte(`{"type":"system","subtype":"compact_boundary"}`))
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("malformed line", func(t *testing.T) {
+		_, err := DecodeStreamLine([]byte(`{"type":"assistant"`))
+		assert.Error(t, err)
+	})
+}
+
+func TestParseStream(t *testing.T) {
+	input := "not json\n" + sampleStream
+
+	var types []StreamEventType
+	var decodeErrors int
+	result, err := ParseStream(strings.NewReader(input), func(event StreamEvent) {
+		types = append(types, event.Type)
+	}, func(error) {
+		decodeErrors++
+	})
+
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, "All done.", result.Result)
+	assert.Equal(t, 1, decodeErrors, "malformed lines are reported and skipped")
+	assert.Equal(t, []StreamEventType{
+		StreamEventInit,
+		StreamEventText, StreamEventToolUse, StreamEventUsage,
+		StreamEventToolResult,
+		StreamEventToolUse, StreamEventUsage,
+		StreamEventToolResult,
+		StreamEventText, StreamEventUsage,
+		StreamEventResult,
+	}, types)
+}
+
+func TestToolUse_ActiveTodo(t *testing.T) {
+	todo := &ToolUse{Name: "TodoWrite", Input: []byte(`{"todos":[{"content":"a","status":"completed"},{"content":"b","status":"in_progress"}]}`)}
+	assert.Equal(t, "b", todo.activeTodo())
+
+	other := &ToolUse{Name: "Bash", Input: []byte(`{"command":"ls"}`)}
+	assert.Equal(t, "", other.activeTodo())
+}
+
+// installFakeClaude puts a claude script that prints stdout on PATH
+func installFakeClaude(t *testing.T, stdout string, exitCode int) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "output.jsonl"), []byte(stdout), 0644))
+	script := "#!/bin/sh\ncat \"$(dirname \"$0\")/output.jsonl\"\nexit " + strconv.Itoa(exitCode) + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "claude"), []byte(script), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestExecutor_StreamJSON(t *testing.T) {
+	installFakeClaude(t, sampleStream, 0)
+
+	var out bytes.Buffer
+	printer := output.NewPrinterWithWriters(&out, &out, false)
+	cfg := &config.Config{ShowToolUpdates: true, ShowTodoUpdates: true}
+	executor := NewExecutorWithConfig(cfg, printer)
+	streamer := &mockStreamer{}
+	executor.SetStreamer(streamer)
+	executor.SetRunID("run-1")
+
+	result, err := executor.Execute(context.Background(), ExecuteConfig{
+		Prompt:       "/start",
+		StateFile:    filepath.Join(t.TempDir(), "agent_state.json"),
+		WorkDir:      t.TempDir(),
+		OutputFormat: OutputFormatStreamJSON,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "All done.", result)
+
+	// Assistant text goes to the streamer within one message
+	require.Len(t, streamer.startCalls, 1)
+	require.Len(t, streamer.endCalls, 1)
+	var streamed []string
+	for _, call := range streamer.contentCalls {
+		assert.Equal(t, streamer.startCalls[0].messageID, call.messageID)
+		streamed = append(streamed, call.content)
+	}
+	assert.Equal(t, []string{"Running the tests.", "All done."}, streamed)
+
+	// Tool activity goes to the printer's tool log
+	toolLog := printer.RenderToolLogs()
+	assert.Contains(t, toolLog, "Bash: go test ./...")
+	assert.Contains(t, toolLog, "TodoWrite")
+	assert.Contains(t, toolLog, "Tool error: permission denied")
+
+	// TodoWrite updates the current task
+	assert.Contains(t, out.String(), "Working on: Fix parser")
+}
+
+func TestExecutor_StreamJSON_ErrorResult(t *testing.T) {
+	installFakeClaude(t, `{"type":"result","subtype":"error_max_turns","is_error":true,"session_id":"s"}`+"\n", 0)
+
+	executor := NewExecutor()
+	_, err := executor.Execute(context.Background(), ExecuteConfig{
+		Prompt:       "/start",
+		StateFile:    filepath.Join(t.TempDir(), "agent_state.json"),
+		WorkDir:      t.TempDir(),
+		OutputFormat: OutputFormatStreamJSON,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error_max_turns")
+}
+
+func TestExecutor_StreamJSON_ProcessFailure(t *testing.T) {
+	installFakeClaude(t, "", 3)
+
+	executor := NewExecutor()
+	_, err := executor.Execute(context.Background(), ExecuteConfig{
+		Prompt:       "/start",
+		StateFile:    filepath.Join(t.TempDir(), "agent_state.json"),
+		WorkDir:      t.TempDir(),
+		OutputFormat: OutputFormatStreamJSON,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "claude execution failed")
+}
+
+func TestExecutor_StreamJSON_RetriesResultErrors(t *testing.T) {
+	installFakeClaude(t, `{"type":"result","subtype":"success","is_error":true,"result":"API Error: 429 {\"type\":\"error\",\"error\":{\"type\":\"rate_limit_error\"}}","session_id":"s"}`+"\n", 1)
+
+	var retries []RetryEvent
+	cfg := retryConfig(t, RetryPolicy{MaxRetries: 1, InitialBackoff: time.Millisecond}, &retries)
+	cfg.OutputFormat = OutputFormatStreamJSON
+	_, err := NewExecutor().Execute(context.Background(), cfg)
+	require.Error(t, err)
+
+	var execErr *ExecutionError
+	require.True(t, errors.As(err, &execErr))
+	assert.Equal(t, FailureRateLimit, execErr.Kind)
+	assert.Equal(t, 1, execErr.ExitCode)
+	require.Len(t, retries, 1, "rate limits reported in the result event are retried")
+}
+
+func TestExecutor_BuildCommand_StreamJSON(t *testing.T) {
+	exec := &Executor{}
+	cmd := exec.buildCommand(ExecuteConfig{Prompt: "p", StateFile: "/tmp/s.json", OutputFormat: OutputFormatStreamJSON})
+	args := strings.Join(cmd.Args[1:], " ")
+	assert.Contains(t, args, "--output-format stream-json --verbose")
+
+	cmd = exec.buildCommand(ExecuteConfig{Prompt: "p", StateFile: "/tmp/s.json"})
+	args = strings.Join(cmd.Args[1:], " ")
+	assert.Contains(t, args, "--output-format text")
+	assert.NotContains(t, args, "--verbose")
+}
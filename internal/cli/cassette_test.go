@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude/cassette"
+	"github.com/Backland-Labs/alpine/internal/core"
+)
+
+// TestRootCommand_ReplaysCassette runs the alpine command end to end against
+// the fake claude binary
+func TestRootCommand_ReplaysCassette(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the fake claude binary")
+	}
+	player := cassette.InstallInteractions(t,
+		cassette.StateUpdate("/start Add a greeting endpoint", "Wrote a failing test\n", core.State{
+			CurrentStepDescription: "Added failing test for GET /greeting",
+			NextStepPrompt:         "/continue",
+			Status:                 core.StatusRunning,
+		}),
+		cassette.StateUpdate("/continue", "Implemented the handler\n", core.State{
+			CurrentStepDescription: "Implemented GET /greeting handler",
+			Status:                 core.StatusCompleted,
+		}),
+	)
+	t.Setenv("ALPINE_WORKDIR", t.TempDir())
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"--no-plan", "--no-worktree", "Add a greeting endpoint"})
+	require.NoError(t, cmd.Execute())
+	assert.Zero(t, player.Remaining(), "every recorded invocation should be replayed")
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Backland-Labs/alpine/internal/config"
+)
+
+// configCmd represents the config command for inspecting the effective configuration
+type configCmd struct {
+	cmd *cobra.Command
+}
+
+// NewConfigCommand creates a new config command (exported for tests)
+func NewConfigCommand() *cobra.Command {
+	return newConfigCmd().Command()
+}
+
+// newConfigCmd creates a new config command
+func newConfigCmd() *configCmd {
+	cc := &configCmd{}
+
+	cc.cmd = &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the effective configuration",
+		Long: `Inspect the configuration alpine runs with.
+
+Each setting is taken from the first of these that sets it:
+
+  1. Command-line flags (e.g. --model, --max-iterations)
+  2. ALPINE_* environment variables
+  3. The repository's .alpine.yaml
+  4. The user's config file (e.g. ~/.config/alpine/config.yaml)
+  5. Built-in defaults
+
+Config files use the keys printed by 'alpine config show', nested by dot:
+
+  model: claude-opus-4-20250514
+  allowed_tools: [Bash, Read, Write, Edit]
+  git:
+    base_branch: develop
+  budget:
+    max_iterations: 20
+    max_duration: 2h
+
+Examples:
+  alpine config show
+  alpine config show --origin`,
+	}
+
+	var showOrigin bool
+	showCmd := &cobra.Command{
+		Use:          "show",
+		Short:        "Print the effective value of every setting",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cc.show(cmd, showOrigin)
+		},
+	}
+	showCmd.Flags().BoolVar(&showOrigin, "origin", false, "Also print where each value came from")
+	cc.cmd.AddCommand(showCmd)
+
+	return cc
+}
+
+// Command returns the cobra command
+func (cc *configCmd) Command() *cobra.Command {
+	return cc.cmd
+}
+
+// show prints each setting's key and effective value, and optionally its origin
+func (cc *configCmd) show(cmd *cobra.Command, showOrigin bool) error {
+	cfg, origins, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	if showOrigin {
+		_, _ = fmt.Fprintln(w, "KEY\tVALUE\tORIGIN")
+	} else {
+		_, _ = fmt.Fprintln(w, "KEY\tVALUE")
+	}
+	for _, s := range config.Describe(cfg, origins) {
+		value := s.Value
+		if value == "" {
+			value = "-"
+		}
+		if showOrigin {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, value, s.Origin)
+		} else {
+			_, _ = fmt.Fprintf(w, "%s\t%s\n", s.Key, value)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/config"
+)
+
+// runConfigCommand runs alpine config with args and returns its output
+func runConfigCommand(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	cmd := NewConfigCommand()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestConfigCommand_ShowOrigin(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(repo, ".git"), 0755))
+	repoFile := filepath.Join(repo, config.RepoFileName)
+	require.NoError(t, os.WriteFile(repoFile, []byte("model: repo-model\nbudget:\n  max_iterations: 12\n"), 0644))
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("ALPINE_WORKDIR", repo)
+	t.Setenv("ALPINE_MODEL", "")
+	t.Setenv("ALPINE_MAX_ITERATIONS", "")
+	t.Setenv("ALPINE_GIT_BASE_BRANCH", "env-branch")
+
+	out, err := runConfigCommand(t, "show", "--origin")
+	require.NoError(t, err)
+	assert.Regexp(t, `KEY\s+VALUE\s+ORIGIN`, out)
+	assert.Regexp(t, `model\s+repo-model\s+repo file `+regexp.QuoteMeta(repoFile), out)
+	assert.Regexp(t, `budget\.max_iterations\s+12\s+repo file`, out)
+	assert.Regexp(t, `git\.base_branch\s+env-branch\s+env`, out)
+	assert.Regexp(t, `stall\.action\s+nudge\s+default`, out)
+
+	out, err = runConfigCommand(t, "show")
+	require.NoError(t, err)
+	assert.Regexp(t, `model\s+repo-model\n`, out)
+	assert.NotContains(t, out, "ORIGIN")
+}
+
+func TestConfigCommand_ShowInvalidFile(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(repo, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, config.RepoFileName), []byte("modle: typo\n"), 0644))
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("ALPINE_WORKDIR", repo)
+
+	_, err := runConfigCommand(t, "show")
+	assert.ErrorContains(t, err, `unknown setting "modle"`)
+}
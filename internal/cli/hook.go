@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Backland-Labs/alpine/internal/hooks"
+)
+
+// hookCmd represents the hook command Claude Code invokes for Alpine's hooks
+type hookCmd struct {
+	cmd *cobra.Command
+}
+
+// NewHookCommand creates a new hook command (exported for tests)
+func NewHookCommand() *cobra.Command {
+	return newHookCmd().Command()
+}
+
+// newHookCmd creates a new hook command
+func newHookCmd() *hookCmd {
+	hc := &hookCmd{}
+
+	hc.cmd = &cobra.Command{
+		Use:   "hook",
+		Short: "Handle a Claude Code hook event",
+		Long: `Handle a Claude Code hook event read as JSON from stdin.
+
+Alpine registers this command for the PreToolUse, PostToolUse and SubagentStop
+hooks in the Claude settings it generates. It logs tool activity to stderr,
+records the in-progress TodoWrite task in $ALPINE_TODO_FILE, denies PreToolUse
+calls that break the policy in $ALPINE_POLICY_FILE and posts AG-UI tool call
+events to $ALPINE_EVENTS_ENDPOINT when set.`,
+		Args:          cobra.NoArgs,
+		Hidden:        true,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE:          hc.execute,
+	}
+
+	return hc
+}
+
+// Command returns the cobra command
+func (hc *hookCmd) Command() *cobra.Command {
+	return hc.cmd
+}
+
+// execute handles the hook event on stdin
+func (hc *hookCmd) execute(cmd *cobra.Command, args []string) error {
+	return hooks.NewHandler(cmd.OutOrStdout(), cmd.ErrOrStderr(), os.Getenv).Handle(cmd.InOrStdin())
+}
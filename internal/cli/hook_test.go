@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHookCommandExists tests that the hidden hook command is registered in the root command
+func TestHookCommandExists(t *testing.T) {
+	rootCmd := NewRootCommand()
+	hookCmd, _, err := rootCmd.Find([]string{"hook"})
+	require.NoError(t, err)
+	assert.Equal(t, "hook", hookCmd.Use)
+	assert.True(t, hookCmd.Hidden, "hook command is invoked by Claude and should not be listed in help")
+}
+
+// TestHookCommand_HandlesStdin tests that the hook command processes a hook event from stdin
+func TestHookCommand_HandlesStdin(t *testing.T) {
+	todoFile := filepath.Join(t.TempDir(), "todo.txt")
+	t.Setenv("ALPINE_TODO_FILE", todoFile)
+
+	rootCmd := NewRootCommand()
+	stderr := &bytes.Buffer{}
+	rootCmd.SetErr(stderr)
+	rootCmd.SetIn(strings.NewReader(`{"hook_event_name":"PostToolUse","tool_name":"TodoWrite","tool_input":{"todos":[{"content":"Write tests","status":"in_progress"}]}}`))
+	rootCmd.SetArgs([]string{"hook"})
+
+	require.NoError(t, rootCmd.Execute())
+	assert.Contains(t, stderr.String(), "[TODO] Current task: Write tests")
+
+	content, err := os.ReadFile(todoFile)
+	require.NoError(t, err)
+	assert.Equal(t, "Write tests", string(content))
+}
+
+// TestHookCommand_IgnoresInvalidInput tests that malformed hook input does not fail the command
+func TestHookCommand_IgnoresInvalidInput(t *testing.T) {
+	rootCmd := NewRootCommand()
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetIn(strings.NewReader("not json"))
+	rootCmd.SetArgs([]string{"hook"})
+
+	assert.NoError(t, rootCmd.Execute())
+}
@@ -0,0 +1,285 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/config"
+)
+
+// preRunWithArgs parses flags on a fresh root command and runs its PreRunE
+func preRunWithArgs(t *testing.T, args ...string) (context.Context, error) {
+	t.Helper()
+	cmd := NewRootCommand()
+	cmd.SetContext(context.Background())
+	require.NoError(t, cmd.ParseFlags(args))
+	err := cmd.PreRunE(cmd, cmd.Flags().Args())
+	return cmd.Context(), err
+}
+
+// TestBudgetFlagOverrides tests that budget flags override configuration only when set
+func TestBudgetFlagOverrides(t *testing.T) {
+	t.Run("flags override configured limits", func(t *testing.T) {
+		ctx, err := preRunWithArgs(t, "--max-iterations", "7", "--max-duration", "90m", "--iteration-timeout", "10m", "task")
+		require.NoError(t, err)
+
+		cfg := &config.Config{Budget: config.BudgetConfig{MaxIterations: 50}}
+		applyBudgetOverrides(ctx, cfg)
+
+		assert.Equal(t, 7, cfg.Budget.MaxIterations)
+		assert.Equal(t, 90*time.Minute, cfg.Budget.MaxDuration)
+		assert.Equal(t, 10*time.Minute, cfg.Budget.IterationTimeout)
+	})
+
+	t.Run("unset flags keep configured limits", func(t *testing.T) {
+		ctx, err := preRunWithArgs(t, "task")
+		require.NoError(t, err)
+
+		cfg := &config.Config{Budget: config.BudgetConfig{MaxIterations: 50, MaxDuration: time.Hour}}
+		applyBudgetOverrides(ctx, cfg)
+
+		assert.Equal(t, 50, cfg.Budget.MaxIterations)
+		assert.Equal(t, time.Hour, cfg.Budget.MaxDuration)
+	})
+
+	t.Run("negative values are rejected", func(t *testing.T) {
+		_, err := preRunWithArgs(t, "--max-iterations", "-1", "task")
+		assert.ErrorContains(t, err, "--max-iterations must not be negative")
+	})
+}
+
+// TestModelFlagOverrides tests that --model and --plan-model override configured models
+func TestModelFlagOverrides(t *testing.T) {
+	t.Run("flags override configured models", func(t *testing.T) {
+		ctx, err := preRunWithArgs(t, "--model", "exec-model", "--plan-model", "plan-model", "task")
+		require.NoError(t, err)
+
+		cfg := &config.Config{Model: config.DefaultModel}
+		applyModelOverrides(ctx, cfg)
+
+		assert.Equal(t, "exec-model", cfg.Model)
+		assert.Equal(t, "plan-model", cfg.PlanningModel())
+	})
+
+	t.Run("unset flags keep configured models", func(t *testing.T) {
+		ctx, err := preRunWithArgs(t, "task")
+		require.NoError(t, err)
+
+		cfg := &config.Config{Model: "env-model", PlanModel: "env-plan-model"}
+		applyModelOverrides(ctx, cfg)
+
+		assert.Equal(t, "env-model", cfg.Model)
+		assert.Equal(t, "env-plan-model", cfg.PlanModel)
+	})
+}
+
+// TestPlanCommandModelFlag tests that the plan command resolves its model from flag or config
+func TestPlanCommandModelFlag(t *testing.T) {
+	cmd := NewPlanCommand()
+	modelFlag := cmd.Flags().Lookup("model")
+	require.NotNil(t, modelFlag, "plan command should have --model flag")
+
+	model, err := resolvePlanModel("flag-model")
+	require.NoError(t, err)
+	assert.Equal(t, "flag-model", model)
+
+	t.Setenv("ALPINE_MODEL", "env-model")
+	t.Setenv("ALPINE_PLAN_MODEL", "env-plan-model")
+	model, err = resolvePlanModel("")
+	require.NoError(t, err)
+	assert.Equal(t, "env-plan-model", model)
+}
+
+// TestProfileFlagOverrides tests that --profile and --plan-profile override configured profiles
+func TestProfileFlagOverrides(t *testing.T) {
+	t.Run("flags override configured profiles", func(t *testing.T) {
+		ctx, err := preRunWithArgs(t, "--profile", "full", "--plan-profile", "ci", "task")
+		require.NoError(t, err)
+
+		cfg := &config.Config{
+			Profile:  config.ProfileDefault,
+			Profiles: map[string]config.PermissionProfile{"ci": {AllowedTools: []string{"Read"}}},
+		}
+		require.NoError(t, applyProfileOverrides(ctx, cfg))
+
+		assert.Equal(t, config.ProfileFull, cfg.Profile)
+		assert.Equal(t, "ci", cfg.PlanProfile)
+	})
+
+	t.Run("unknown profiles are rejected", func(t *testing.T) {
+		ctx, err := preRunWithArgs(t, "--plan-profile", "nope", "task")
+		require.NoError(t, err)
+
+		cfg := &config.Config{Profile: config.ProfileDefault}
+		err = applyProfileOverrides(ctx, cfg)
+		assert.ErrorContains(t, err, `--plan-profile: unknown permission profile "nope"`)
+		assert.Empty(t, cfg.PlanProfile)
+	})
+}
+
+// TestPlanCommandProfileFlag tests that the plan command resolves its permissions from flag or config
+func TestPlanCommandProfileFlag(t *testing.T) {
+	require.NotNil(t, NewPlanCommand().Flags().Lookup("profile"), "plan command should have --profile flag")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("ALPINE_PLAN_PROFILE", "")
+
+	readonly, _ := config.BuiltinProfile(config.ProfileReadOnly)
+	access, err := resolvePlanAccess("")
+	require.NoError(t, err)
+	assert.Equal(t, readonly, access.permissions)
+
+	full, _ := config.BuiltinProfile(config.ProfileFull)
+	access, err = resolvePlanAccess("full")
+	require.NoError(t, err)
+	assert.Equal(t, full, access.permissions)
+
+	t.Setenv("ALPINE_PLAN_PROFILE", "default")
+	defaultProfile, _ := config.BuiltinProfile(config.ProfileDefault)
+	access, err = resolvePlanAccess("")
+	require.NoError(t, err)
+	assert.Equal(t, defaultProfile, access.permissions)
+
+	_, err = resolvePlanAccess("nope")
+	assert.ErrorContains(t, err, `unknown permission profile "nope"`)
+}
+
+// TestPlanCommandMCPServers tests that alpine plan gets only plan-phase MCP
+// servers and fails on a missing stdio command
+func TestPlanCommandMCPServers(t *testing.T) {
+	repo := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(repo, ".git"), 0755))
+	repoFile := filepath.Join(repo, config.RepoFileName)
+	require.NoError(t, os.WriteFile(repoFile, []byte(`
+mcp_servers:
+  docs:
+    command: sh
+    phases: [plan]
+  github:
+    url: https://example.com/mcp
+    phases: [execute]
+`), 0644))
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("ALPINE_WORKDIR", repo)
+	t.Setenv("ALPINE_PLAN_PROFILE", "")
+
+	access, err := resolvePlanAccess("")
+	require.NoError(t, err)
+	assert.Len(t, access.mcpServers, 1)
+	assert.Contains(t, access.mcpServers, "docs")
+
+	require.NoError(t, os.WriteFile(repoFile, []byte("mcp_servers:\n  docs:\n    command: alpine-test-no-such-mcp-server\n"), 0644))
+	_, err = resolvePlanAccess("")
+	assert.ErrorContains(t, err, `MCP server "docs": command "alpine-test-no-such-mcp-server" not found`)
+}
+
+// TestDryRunFlags tests that --dry-run and --dry-run-script switch the executor and config to a dry run
+func TestDryRunFlags(t *testing.T) {
+	t.Run("dry-run script implies a dry run", func(t *testing.T) {
+		script := filepath.Join(t.TempDir(), "script.json")
+		require.NoError(t, os.WriteFile(script, []byte(`[{"status": "completed"}]`), 0644))
+
+		ctx, err := preRunWithArgs(t, "--dry-run-script", script, "task")
+		require.NoError(t, err)
+		assert.Equal(t, true, ctx.Value(dryRunKey))
+		assert.NoError(t, applyDryRun(ctx, claude.NewExecutor()))
+	})
+
+	t.Run("dry run is marked on the config", func(t *testing.T) {
+		ctx, err := preRunWithArgs(t, "--dry-run", "task")
+		require.NoError(t, err)
+		cfg := &config.Config{}
+		applyDryRunOverride(ctx, cfg)
+		assert.True(t, cfg.DryRun)
+	})
+
+	t.Run("without the flag the executor is left alone", func(t *testing.T) {
+		ctx, err := preRunWithArgs(t, "task")
+		require.NoError(t, err)
+		assert.NoError(t, applyDryRun(ctx, nil))
+		cfg := &config.Config{}
+		applyDryRunOverride(ctx, cfg)
+		assert.False(t, cfg.DryRun)
+	})
+
+	t.Run("missing script is reported", func(t *testing.T) {
+		ctx, err := preRunWithArgs(t, "--dry-run-script", filepath.Join(t.TempDir(), "missing.json"), "task")
+		require.NoError(t, err)
+		assert.ErrorContains(t, applyDryRun(ctx, claude.NewExecutor()), "failed to read dry-run script")
+	})
+}
+
+// TestRootCommand_DryRun runs a whole workflow without a claude binary
+func TestRootCommand_DryRun(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	workDir := t.TempDir()
+	t.Setenv("ALPINE_WORKDIR", workDir)
+
+	cmd := NewRootCommand()
+	cmd.SetArgs([]string{"--dry-run", "--no-plan", "--no-worktree", "Add a greeting endpoint"})
+	require.NoError(t, cmd.Execute())
+
+	// The simulated run completed and cleaned up its state file
+	assert.NoFileExists(t, filepath.Join(workDir, "agent_state", "agent_state.json"))
+}
+
+// TestSandboxFlagOverrides tests that --sandbox overrides the configured backend only when set
+func TestSandboxFlagOverrides(t *testing.T) {
+	t.Run("flag overrides configured backend", func(t *testing.T) {
+		ctx, err := preRunWithArgs(t, "--sandbox", "unshare", "task")
+		require.NoError(t, err)
+
+		cfg := &config.Config{Sandbox: config.SandboxConfig{Backend: config.SandboxNone}}
+		require.NoError(t, applySandboxOverrides(ctx, cfg))
+		assert.Equal(t, config.SandboxUnshare, cfg.Sandbox.Backend)
+	})
+
+	t.Run("configured backend is kept without the flag", func(t *testing.T) {
+		ctx, err := preRunWithArgs(t, "task")
+		require.NoError(t, err)
+
+		cfg := &config.Config{Sandbox: config.SandboxConfig{Backend: config.SandboxBubblewrap}}
+		require.NoError(t, applySandboxOverrides(ctx, cfg))
+		assert.Equal(t, config.SandboxBubblewrap, cfg.Sandbox.Backend)
+	})
+
+	t.Run("unknown backends are rejected", func(t *testing.T) {
+		ctx, err := preRunWithArgs(t, "--sandbox", "docker", "task")
+		require.NoError(t, err)
+
+		cfg := &config.Config{Sandbox: config.SandboxConfig{Backend: config.SandboxNone}}
+		err = applySandboxOverrides(ctx, cfg)
+		assert.EqualError(t, err, "--sandbox: sandbox must be one of: none, bwrap, unshare; got: docker")
+		assert.Equal(t, config.SandboxNone, cfg.Sandbox.Backend)
+	})
+}
+
+// TestPullRequestFlagOverrides tests that --pr overrides the configured option only when set
+func TestPullRequestFlagOverrides(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		configured bool
+		want       bool
+	}{
+		{"flag enables pull requests", []string{"--pr", "task"}, false, true},
+		{"flag disables configured pull requests", []string{"--pr=false", "task"}, true, false},
+		{"configured option is kept without the flag", []string{"task"}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := preRunWithArgs(t, tt.args...)
+			require.NoError(t, err)
+
+			cfg := &config.Config{Git: config.GitConfig{PullRequest: tt.configured}}
+			applyPullRequestOverride(ctx, cfg)
+			assert.Equal(t, tt.want, cfg.Git.PullRequest)
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/config"
 	"github.com/Backland-Labs/alpine/internal/github"
 	"github.com/Backland-Labs/alpine/internal/gitx"
 	"github.com/Backland-Labs/alpine/internal/output"
@@ -31,6 +32,8 @@ func newPlanCmd() *planCmd {
 	pc := &planCmd{}
 	var worktreeFlag bool
 	var cleanupFlag bool
+	var modelFlag string
+	var profileFlag string
 
 	pc.cmd = &cobra.Command{
 		Use:   "plan <task-description>",
@@ -42,24 +45,41 @@ that can be used with Alpine's implementation workflow.`,
   alpine plan "Implement user authentication"
   
   # Generate a plan from a GitHub issue
-  alpine plan gh-issue https://github.com/owner/repo/issues/123`,
+  alpine plan gh-issue https://github.com/owner/repo/issues/123
+
+  # Generate a plan with a specific model
+  alpine plan "Implement user authentication" --model claude-opus-4-20250514
+
+  # Generate a plan with a permission profile that allows running commands
+  alpine plan "Implement user authentication" --profile default`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			task := args[0]
 
+			model, err := resolvePlanModel(modelFlag)
+			if err != nil {
+				return err
+			}
+			access, err := resolvePlanAccess(profileFlag)
+			if err != nil {
+				return err
+			}
+
 			// Check if worktree flag is set
 			if worktreeFlag {
-				return runPlanInWorktree(task, cleanupFlag)
+				return runPlanInWorktree(task, model, access, cleanupFlag)
 			}
 
 			// Always use Claude Code for plan generation
-			return generatePlan(task)
+			return generatePlan(task, model, access)
 		},
 	}
 
 	// Add the --worktree and --cleanup flags
 	pc.cmd.Flags().BoolVar(&worktreeFlag, "worktree", false, "Generate the plan in an isolated git worktree")
 	pc.cmd.Flags().BoolVar(&cleanupFlag, "cleanup", true, "Automatically clean up (remove) the worktree after plan generation")
+	pc.cmd.Flags().StringVar(&modelFlag, "model", "", "Claude model for plan generation (overrides ALPINE_PLAN_MODEL and ALPINE_MODEL)")
+	pc.cmd.Flags().StringVar(&profileFlag, "profile", "", "Permission profile for plan generation (overrides ALPINE_PLAN_PROFILE, default: readonly)")
 
 	// Add gh-issue subcommand
 	pc.cmd.AddCommand(newGhIssueCmd())
@@ -72,13 +92,73 @@ func (pc *planCmd) Command() *cobra.Command {
 	return pc.cmd
 }
 
-// generatePlan generates an implementation plan using Claude Code
-func generatePlan(task string) error {
+// resolvePlanModel returns the model to use for plan generation.
+// An explicit override wins over the configured planning model.
+func resolvePlanModel(override string) (string, error) {
+	if override != "" {
+		return override, nil
+	}
+	cfg, err := config.New()
+	if err != nil {
+		return "", fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return cfg.PlanningModel(), nil
+}
+
+// planAccess holds the tools and MCP servers available to plan generation
+type planAccess struct {
+	permissions config.PermissionProfile
+	mcpServers  map[string]config.MCPServer
+}
+
+// resolvePlanAccess returns the permission profile and MCP servers for plan
+// generation. An explicit profile override wins over the configured planning
+// profile, which defaults to readonly.
+func resolvePlanAccess(profileOverride string) (planAccess, error) {
+	cfg, err := config.New()
+	if err != nil {
+		return planAccess{}, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := cfg.ValidateMCPCommands(); err != nil {
+		return planAccess{}, err
+	}
+	name := profileOverride
+	if name == "" {
+		name = cfg.PlanProfile
+	}
+	if name == "" {
+		name = config.ProfileReadOnly
+	}
+	permissions, err := cfg.PermissionProfile(name)
+	if err != nil {
+		return planAccess{}, err
+	}
+	return planAccess{permissions: permissions, mcpServers: cfg.MCPServersFor(config.PhasePlan)}, nil
+}
+
+// generatePlan generates an implementation plan using Claude Code.
+// An empty model uses Claude's default model.
+func generatePlan(task, model string, access planAccess) error {
+	// Get current working directory for Claude execution
+	workDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current working directory: %w", err)
+	}
+	return generatePlanInDir(task, model, access, workDir)
+}
+
+// generatePlanInDir runs plan generation with workDir as Claude's working
+// directory. Empty permissions use the readonly profile.
+func generatePlanInDir(task, model string, access planAccess, workDir string) error {
 	// Create printer for progress indicator
 	printer := output.NewPrinter()
 
-	// Replace placeholders in the prompt template
-	prompt := strings.ReplaceAll(prompts.PromptPlan, "{{TASK}}", task)
+	// Render the plan prompt template for the repository being planned
+	loader, data := promptLoaderFor(context.Background(), workDir, task)
+	prompt, err := loader.Render(prompts.NamePlan, data)
+	if err != nil {
+		return err
+	}
 
 	// Create a temporary state file (required by executor)
 	stateFile, err := os.CreateTemp("", "claude_state_*.json")
@@ -95,31 +175,48 @@ func generatePlan(task string) error {
 		return fmt.Errorf("failed to write initial state: %w", err)
 	}
 
-	// Create Claude executor
-	executor := claude.NewExecutor()
+	permissions := access.permissions
+	if len(permissions.AllowedTools) == 0 && len(permissions.DisallowedTools) == 0 {
+		permissions, _ = config.BuiltinProfile(config.ProfileReadOnly)
+	}
 
-	// Get current working directory for Claude execution
-	workDir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get current working directory: %w", err)
+	// Write the planning MCP servers to a temporary MCP config
+	var mcpConfig string
+	if len(access.mcpServers) > 0 {
+		mcpFile, err := os.CreateTemp("", "alpine_mcp_*.json")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary MCP config: %w", err)
+		}
+		_ = mcpFile.Close()
+		defer func() {
+			_ = os.Remove(mcpFile.Name())
+		}()
+		if err := claude.WriteMCPConfig(mcpFile.Name(), access.mcpServers); err != nil {
+			return err
+		}
+		mcpConfig = mcpFile.Name()
+		permissions.AllowedTools = append(append([]string(nil), permissions.AllowedTools...), claude.MCPToolPatterns(access.mcpServers)...)
 	}
 
+	// Create Claude executor
+	executor := claude.NewExecutor()
+
 	// Configure ExecuteConfig for planning
 	config := claude.ExecuteConfig{
 		Prompt:    prompt,
 		StateFile: stateFile.Name(),
 		WorkDir:   workDir,
-		// No MCP servers for planning
-		MCPServers: []string{},
-		// Planning-specific allowed tools (read-only)
-		AllowedTools: []string{
-			"Read", "Grep", "Glob", "LS",
-			"WebSearch", "WebFetch", "mcp__context7__*",
-		},
+		// MCP servers enabled for planning, if any
+		MCPConfig: mcpConfig,
+		// Planning permission profile (readonly unless configured)
+		AllowedTools:    permissions.AllowedTools,
+		DisallowedTools: permissions.DisallowedTools,
 		// Planning-specific system prompt
 		SystemPrompt: "You are a senior Technical Product Manager creating implementation plans. " +
 			"Focus on understanding the codebase and creating detailed plan.md files. " +
 			"Follow TDD principles and Alpine's planning conventions.",
+		// Model selected for the planning phase
+		Model: model,
 		// 5-minute timeout for plan generation
 		Timeout: 5 * time.Minute,
 		// Add current directory for codebase context
@@ -161,16 +258,10 @@ func generatePlan(task string) error {
 
 
 // runPlanInWorktree executes plan generation in an isolated git worktree
-func runPlanInWorktree(task string, cleanup bool) error {
+func runPlanInWorktree(task, model string, access planAccess, cleanup bool) error {
 	// Create printer for consistent output
 	printer := output.NewPrinter()
 
-	// Get current working directory
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get working directory: %w", err)
-	}
-
 	// Create worktree manager
 	ctx := context.Background()
 	wtMgr := gitx.NewCLIWorktreeManager(".", "main")
@@ -182,13 +273,8 @@ func runPlanInWorktree(task string, cleanup bool) error {
 		return fmt.Errorf("failed to create worktree: %w", err)
 	}
 
-	// Ensure we return to original directory and handle cleanup
+	// Handle cleanup when done
 	defer func() {
-		// Always return to original directory
-		if err := os.Chdir(originalDir); err != nil {
-			printer.Warning("Failed to return to original directory: %v", err)
-		}
-
 		// Handle cleanup if requested
 		if cleanup {
 			printer.Info("Cleaning up worktree...")
@@ -202,15 +288,10 @@ func runPlanInWorktree(task string, cleanup bool) error {
 		}
 	}()
 
-	// Change to the worktree directory
-	if err := os.Chdir(wt.Path); err != nil {
-		return fmt.Errorf("failed to change to worktree directory: %w", err)
-	}
-
 	printer.Info("Generating plan in worktree: %s", wt.Path)
 
-	// Call the plan generation function
-	return generatePlan(task)
+	// Generate the plan with the worktree as Claude's working directory
+	return generatePlanInDir(task, model, access, wt.Path)
 }
 
 // validatePlanFile checks if plan.md exists and has content
@@ -267,14 +348,25 @@ Requirements:
 			// Access parent command's flags
 			worktreeFlag, _ := cmd.Parent().Flags().GetBool("worktree")
 			cleanupFlag, _ := cmd.Parent().Flags().GetBool("cleanup")
+			modelFlag, _ := cmd.Parent().Flags().GetString("model")
+			profileFlag, _ := cmd.Parent().Flags().GetString("profile")
+
+			model, err := resolvePlanModel(modelFlag)
+			if err != nil {
+				return err
+			}
+			access, err := resolvePlanAccess(profileFlag)
+			if err != nil {
+				return err
+			}
 
 			// Check if worktree flag is set
 			if worktreeFlag {
-				return runPlanInWorktree(task, cleanupFlag)
+				return runPlanInWorktree(task, model, access, cleanupFlag)
 			}
 
 			// Always use Claude Code for plan generation
-			return generatePlan(task)
+			return generatePlan(task, model, access)
 		},
 	}
 }
@@ -173,7 +173,7 @@ func TestGeneratePlan(t *testing.T) {
 
 		// Test that generatePlan creates necessary state files and calls Claude
 		// This will fail gracefully if there are configuration issues
-		err := generatePlan("test task")
+		err := generatePlan("test task", "", planAccess{})
 		// We expect this to fail in test environment, but not with missing API key errors
 		if err != nil && strings.Contains(err.Error(), "GEMINI_API_KEY") {
 			t.Error("generatePlan should not reference GEMINI_API_KEY - should use Claude Code only")
@@ -491,7 +491,7 @@ func TestGeneratePlanWithClaude_ProgressIndicator(t *testing.T) {
 		}()
 
 		// Call generatePlan (will fail due to missing Claude CLI)
-		_ = generatePlan("test task")
+		_ = generatePlan("test task", "", planAccess{})
 
 		// Restore stdout and get output
 		_ = w.Close()
@@ -546,7 +546,7 @@ func TestGeneratePlanWithClaude_ProgressIndicator(t *testing.T) {
 		}()
 
 		// Call generatePlan (will fail due to missing prompt template)
-		_ = generatePlan("test task")
+		_ = generatePlan("test task", "", planAccess{})
 
 		// Restore stdout and get output
 		_ = w.Close()
@@ -0,0 +1,191 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Backland-Labs/alpine/internal/gitx"
+	"github.com/Backland-Labs/alpine/internal/prompts"
+)
+
+// promptsCmd represents the prompts command for inspecting prompt templates
+type promptsCmd struct {
+	cmd *cobra.Command
+}
+
+// NewPromptsCommand creates a new prompts command (exported for tests)
+func NewPromptsCommand() *cobra.Command {
+	return newPromptsCmd().Command()
+}
+
+// newPromptsCmd creates a new prompts command
+func newPromptsCmd() *promptsCmd {
+	pc := &promptsCmd{}
+
+	pc.cmd = &cobra.Command{
+		Use:   "prompts",
+		Short: "List, render and diff the effective prompt templates",
+		Long: `List, render and diff the prompt templates alpine sends to Claude.
+
+Templates use Go text/template syntax. Each template is looked up in the
+repository's .alpine/prompts/<name>.md, then in the user's configuration
+directory (e.g. ~/.config/alpine/prompts/<name>.md), then in alpine's built-in
+defaults. Templates can use these variables:
+
+  {{.Task}}       Task description (issue title and body for GitHub issues)
+  {{.Issue}}      GitHub issue with .URL, .Number, .Title and .Body, nil for plain tasks
+  {{.Repo}}       Repository name
+  {{.Branch}}     Checked-out branch
+  {{.Iteration}}  Workflow iteration, 0 for the plan prompt
+
+Examples:
+  alpine prompts list
+  alpine prompts render plan --task "Add caching"
+  alpine prompts diff system`,
+	}
+
+	pc.cmd.AddCommand(&cobra.Command{
+		Use:          "list",
+		Short:        "List templates and where each is loaded from",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pc.list(cmd)
+		},
+	})
+
+	var task string
+	var iteration int
+	renderCmd := &cobra.Command{
+		Use:          "render <name>",
+		Short:        "Render the effective template for the current repository",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pc.render(cmd, args[0], task, iteration)
+		},
+	}
+	renderCmd.Flags().StringVar(&task, "task", "", "Task description to render with")
+	renderCmd.Flags().IntVar(&iteration, "iteration", 1, "Workflow iteration to render with")
+	pc.cmd.AddCommand(renderCmd)
+
+	pc.cmd.AddCommand(&cobra.Command{
+		Use:          "diff [name...]",
+		Short:        "Show how the effective templates differ from the built-in defaults",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pc.diff(cmd, args)
+		},
+	})
+
+	return pc
+}
+
+// Command returns the cobra command
+func (pc *promptsCmd) Command() *cobra.Command {
+	return pc.cmd
+}
+
+// list prints each template with its source and path
+func (pc *promptsCmd) list(cmd *cobra.Command) error {
+	loader, _, err := currentPromptLoader(cmd.Context(), "")
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tSOURCE\tPATH")
+	for _, name := range prompts.Names() {
+		t, err := loader.Lookup(name)
+		if err != nil {
+			return err
+		}
+		path := t.Path
+		if path == "" {
+			path = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", t.Name, t.Source, path)
+	}
+	return w.Flush()
+}
+
+// render prints the effective template rendered for the current repository
+func (pc *promptsCmd) render(cmd *cobra.Command, name, task string, iteration int) error {
+	loader, data, err := currentPromptLoader(cmd.Context(), task)
+	if err != nil {
+		return err
+	}
+	data.Iteration = iteration
+	rendered, err := loader.Render(name, data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(cmd.OutOrStdout(), rendered)
+	return err
+}
+
+// diff prints a unified diff from the built-in default to the effective
+// template for each named template, or for all of them
+func (pc *promptsCmd) diff(cmd *cobra.Command, names []string) error {
+	loader, _, err := currentPromptLoader(cmd.Context(), "")
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		names = prompts.Names()
+	}
+
+	out := cmd.OutOrStdout()
+	for _, name := range names {
+		t, err := loader.Lookup(name)
+		if err != nil {
+			return err
+		}
+		if t.Source == prompts.SourceBuiltin {
+			_, _ = fmt.Fprintf(out, "%s: using built-in default\n", name)
+			continue
+		}
+		diff, err := prompts.Diff(t)
+		if err != nil {
+			return err
+		}
+		if diff == "" {
+			_, _ = fmt.Fprintf(out, "%s: %s matches the built-in default\n", name, t.Path)
+			continue
+		}
+		_, _ = fmt.Fprint(out, diff)
+	}
+	return nil
+}
+
+// currentPromptLoader returns the prompt loader and template variables for
+// the repository containing the current directory
+func currentPromptLoader(ctx context.Context, task string) (*prompts.Loader, prompts.Data, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, prompts.Data{}, fmt.Errorf("failed to get current directory: %w", err)
+	}
+	loader, data := promptLoaderFor(ctx, cwd, task)
+	return loader, data, nil
+}
+
+// promptLoaderFor returns the prompt loader and template variables for the
+// repository containing dir, falling back to dir itself outside git
+func promptLoaderFor(ctx context.Context, dir, task string) (*prompts.Loader, prompts.Data) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	loader := prompts.NewLoader(dir)
+	data := prompts.Data{Task: task, Repo: filepath.Base(dir)}
+	if info, err := gitx.InspectRepo(ctx, dir); err == nil {
+		loader.RepoRoot = info.Root
+		data.Repo = info.Name
+		data.Branch = info.Branch
+	}
+	return loader, data
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/prompts"
+)
+
+// runPromptsCommand runs alpine prompts with args in dir and returns its output
+func runPromptsCommand(t *testing.T, dir string, args ...string) (string, error) {
+	t.Helper()
+	t.Chdir(dir)
+	cmd := NewPromptsCommand()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+// promptsRepo returns a directory with a repository system prompt template
+func promptsRepo(t *testing.T) string {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repo := t.TempDir()
+	dir := filepath.Join(repo, prompts.RepoDir)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "system.md"), []byte("Work on {{.Task}} (iteration {{.Iteration}})\n"), 0644))
+	return repo
+}
+
+func TestPromptsCommand_List(t *testing.T) {
+	repo := promptsRepo(t)
+
+	out, err := runPromptsCommand(t, repo, "list")
+	require.NoError(t, err)
+	assert.Regexp(t, `plan\s+builtin\s+-`, out)
+	assert.Regexp(t, `system\s+repo\s+\S*\.alpine/prompts/system\.md`, out)
+}
+
+func TestPromptsCommand_Render(t *testing.T) {
+	repo := promptsRepo(t)
+
+	out, err := runPromptsCommand(t, repo, "render", "system", "--task", "Add caching", "--iteration", "4")
+	require.NoError(t, err)
+	assert.Equal(t, "Work on Add caching (iteration 4)\n", out)
+
+	_, err = runPromptsCommand(t, repo, "render", "nope")
+	assert.ErrorContains(t, err, `unknown prompt template "nope"`)
+}
+
+func TestPromptsCommand_Diff(t *testing.T) {
+	repo := promptsRepo(t)
+
+	out, err := runPromptsCommand(t, repo, "diff")
+	require.NoError(t, err)
+	assert.Contains(t, out, "plan: using built-in default")
+	assert.Contains(t, out, "--- builtin/system.md")
+	assert.Contains(t, out, "+Work on {{.Task}} (iteration {{.Iteration}})")
+}
@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Backland-Labs/alpine/internal/core"
+	"github.com/Backland-Labs/alpine/internal/gitx"
+	"github.com/Backland-Labs/alpine/internal/workflow"
+)
+
+// rollbackCmd represents the rollback command
+type rollbackCmd struct {
+	cmd       *cobra.Command
+	to        int
+	dir       string
+	stateFile string
+	force     bool
+}
+
+// NewRollbackCommand creates a new rollback command (exported for tests)
+func NewRollbackCommand() *cobra.Command {
+	return newRollbackCmd().Command()
+}
+
+// newRollbackCmd creates a new rollback command
+func newRollbackCmd() *rollbackCmd {
+	rc := &rollbackCmd{}
+
+	rc.cmd = &cobra.Command{
+		Use:   "rollback <run-id> --to <iteration>",
+		Short: "Rewind a run's branch to an earlier iteration",
+		Long: `Rewind the branch checked out in the run's worktree to the commit alpine
+made after an iteration of the run, discarding later commits and changes.
+
+The run is found from the Alpine-Run-ID trailers of the branch's commits; a
+unique prefix of the run ID is enough. When the iteration made no commit, the
+branch is rewound to the last commit before it; --to 0 rewinds to before the
+run's first commit. The state file and Claude's local settings are kept, and
+the state is restored from the journal so that running alpine without a task
+resumes from that iteration.
+
+Example:
+  alpine rollback 3f2a9c1e --to 2`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE:         rc.execute,
+	}
+
+	rc.cmd.Flags().IntVar(&rc.to, "to", -1, "Iteration to rewind to")
+	rc.cmd.Flags().StringVar(&rc.dir, "dir", ".", "Worktree of the run")
+	rc.cmd.Flags().StringVar(&rc.stateFile, "file", filepath.Join("agent_state", "agent_state.json"), "Path to the state file, relative to --dir")
+	rc.cmd.Flags().BoolVar(&rc.force, "force", false, "Discard uncommitted changes")
+	_ = rc.cmd.MarkFlagRequired("to")
+
+	return rc
+}
+
+// Command returns the cobra command
+func (rc *rollbackCmd) Command() *cobra.Command {
+	return rc.cmd
+}
+
+// execute rewinds the run to the requested iteration
+func (rc *rollbackCmd) execute(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+	if rc.to < 0 {
+		return fmt.Errorf("--to must not be negative, got: %d", rc.to)
+	}
+	stateFile := rc.stateFile
+	if !filepath.IsAbs(stateFile) {
+		stateFile = filepath.Join(rc.dir, stateFile)
+	}
+
+	all, err := gitx.Checkpoints(ctx, rc.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read the branch's commits: %w", err)
+	}
+	runID, checkpoints, err := runCheckpoints(all, args[0])
+	if err != nil {
+		return err
+	}
+	if latest := checkpoints[0]; latest.Iteration <= rc.to {
+		return fmt.Errorf("run %s is already at iteration %d", runID, latest.Iteration)
+	}
+
+	// The newest commit at or before the iteration, or the commit the run started from
+	target := checkpoints[len(checkpoints)-1].Commit + "^"
+	iteration := 0
+	for _, c := range checkpoints {
+		if c.Iteration <= rc.to {
+			target, iteration = c.Commit, c.Iteration
+			break
+		}
+	}
+
+	// Files alpine keeps for a run, which may still be live, are left alone
+	excludes := workflow.WorktreeExcludes(rc.dir, stateFile)
+	if !rc.force {
+		dirty, err := gitx.Dirty(ctx, rc.dir, excludes)
+		if err != nil {
+			return fmt.Errorf("failed to check for uncommitted changes: %w", err)
+		}
+		if dirty {
+			return fmt.Errorf("the worktree has uncommitted changes; commit them or pass --force to discard them")
+		}
+	}
+	if err := gitx.Reset(ctx, rc.dir, target, excludes); err != nil {
+		return fmt.Errorf("failed to roll back: %w", err)
+	}
+	out := cmd.OutOrStdout()
+	_, _ = fmt.Fprintf(out, "Rolled back run %s to iteration %d\n", runID, iteration)
+
+	restored, err := restoreJournalState(stateFile, runID, target)
+	if err != nil {
+		return err
+	}
+	if restored {
+		_, _ = fmt.Fprintf(out, "Restored %s; run alpine without a task to resume\n", stateFile)
+	} else {
+		_, _ = fmt.Fprintf(out, "No journal entry for the commit; %s was left unchanged\n", stateFile)
+	}
+	return nil
+}
+
+// runCheckpoints returns the run matching runID, exactly or by a unique
+// prefix, and its checkpoints, newest first
+func runCheckpoints(checkpoints []gitx.Checkpoint, runID string) (string, []gitx.Checkpoint, error) {
+	byRun := map[string][]gitx.Checkpoint{}
+	for _, c := range checkpoints {
+		if strings.HasPrefix(c.RunID, runID) {
+			byRun[c.RunID] = append(byRun[c.RunID], c)
+		}
+	}
+	if run, ok := byRun[runID]; ok {
+		return runID, run, nil
+	}
+	switch len(byRun) {
+	case 0:
+		return "", nil, fmt.Errorf("no commits of run %s on the current branch", runID)
+	case 1:
+		for id, run := range byRun {
+			return id, run, nil
+		}
+	}
+	return "", nil, fmt.Errorf("run ID %s is ambiguous", runID)
+}
+
+// restoreJournalState writes the state journaled with commit back to
+// stateFile, reporting whether there was one
+func restoreJournalState(stateFile, runID, commit string) (bool, error) {
+	entries, err := core.RunJournal(core.JournalPath(stateFile), runID)
+	if err != nil {
+		return false, err
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Commit != commit {
+			continue
+		}
+		state := entries[i].State
+		state.Status = core.StatusRunning
+		if err := state.Save(stateFile); err != nil {
+			return false, fmt.Errorf("failed to restore state: %w", err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
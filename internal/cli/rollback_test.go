@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/core"
+	"github.com/Backland-Labs/alpine/internal/gitx"
+)
+
+// rollbackRepo creates a repository with three iteration commits of run
+// run-1234, journaled in its state directory, and returns the repository
+// and the commits
+func rollbackRepo(t *testing.T) (string, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "alpine/test"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		require.NoError(t, err, "git %v: %s", args, output)
+	}
+
+	stateFile := filepath.Join(dir, "agent_state", "agent_state.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(stateFile), 0755))
+	var commits []string
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "step"+strconv.Itoa(i)+".txt"), []byte("x"), 0644))
+		hash, err := gitx.CommitAll(context.Background(), dir, gitx.Commit{
+			Message:  "Step " + strconv.Itoa(i),
+			Trailers: []gitx.Trailer{{Key: gitx.TrailerRunID, Value: "run-1234"}, {Key: gitx.TrailerIteration, Value: strconv.Itoa(i)}},
+			Exclude:  []string{"agent_state"},
+		})
+		require.NoError(t, err)
+		commits = append(commits, hash)
+		require.NoError(t, core.AppendJournal(core.JournalPath(stateFile), core.JournalEntry{
+			RunID:     "run-1234",
+			Iteration: i,
+			Commit:    hash,
+			State:     core.State{CurrentStepDescription: "Step " + strconv.Itoa(i), NextStepPrompt: "/continue", Status: core.StatusRunning},
+		}))
+	}
+	require.NoError(t, (&core.State{CurrentStepDescription: "Failed", NextStepPrompt: "/continue", Status: core.StatusFailed}).Save(stateFile))
+	return dir, commits
+}
+
+// runRollback executes alpine rollback with args
+func runRollback(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	rootCmd := NewRootCommand()
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs(append([]string{"rollback"}, args...))
+	err := rootCmd.Execute()
+	return out.String(), err
+}
+
+func TestRollbackCommand(t *testing.T) {
+	dir, commits := rollbackRepo(t)
+
+	// A live run's merged Claude settings and their ledger
+	settings := filepath.Join(dir, ".claude", "settings.local.json")
+	require.NoError(t, os.MkdirAll(filepath.Dir(settings), 0755))
+	require.NoError(t, os.WriteFile(settings, []byte(`{"hooks":{}}`), 0644))
+	require.NoError(t, os.WriteFile(settings+".alpine", []byte(`{"runs":[]}`), 0644))
+
+	out, err := runRollback(t, "run-12", "--to", "1", "--dir", dir)
+	require.NoError(t, err)
+	assert.Contains(t, out, "Rolled back run run-1234 to iteration 1")
+
+	head, err := gitx.Head(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, commits[0], head)
+	assert.NoFileExists(t, filepath.Join(dir, "step2.txt"))
+	assert.FileExists(t, settings)
+	assert.FileExists(t, settings+".alpine", "the ledger of a live run is kept")
+
+	// The state after iteration 1 is restored for resuming
+	state, err := core.LoadState(filepath.Join(dir, "agent_state", "agent_state.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "Step 1", state.CurrentStepDescription)
+	assert.Equal(t, core.StatusRunning, state.Status)
+}
+
+func TestRollbackCommand_BeforeRun(t *testing.T) {
+	dir, _ := rollbackRepo(t)
+
+	out, err := runRollback(t, "run-1234", "--to", "0", "--dir", dir)
+	require.NoError(t, err)
+	assert.Contains(t, out, "to iteration 0")
+	assert.Contains(t, out, "left unchanged")
+	assert.NoFileExists(t, filepath.Join(dir, "step1.txt"))
+}
+
+func TestRollbackCommand_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		dirty  bool
+		errMsg string
+	}{
+		{"unknown run", []string{"other", "--to", "1"}, false, "no commits of run other"},
+		{"already there", []string{"run-1234", "--to", "3"}, false, "already at iteration 3"},
+		{"negative iteration", []string{"run-1234", "--to", "-2"}, false, "must not be negative"},
+		{"missing iteration", []string{"run-1234"}, false, `required flag(s) "to" not set`},
+		{"uncommitted changes", []string{"run-1234", "--to", "1"}, true, "pass --force"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, commits := rollbackRepo(t)
+			if tt.dirty {
+				require.NoError(t, os.WriteFile(filepath.Join(dir, "step3.txt"), []byte("edited"), 0644))
+			}
+
+			_, err := runRollback(t, append(tt.args, "--dir", dir)...)
+			require.Error(t, err)
+			assert.True(t, strings.Contains(err.Error(), tt.errMsg), "error %q should contain %q", err, tt.errMsg)
+
+			head, headErr := gitx.Head(context.Background(), dir)
+			require.NoError(t, headErr)
+			assert.Equal(t, commits[2], head, "a failed rollback leaves the branch alone")
+		})
+	}
+}
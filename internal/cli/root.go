@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/Backland-Labs/alpine/internal/output"
 	"github.com/spf13/cobra"
@@ -19,6 +20,23 @@ const (
 	noWorktreeKey contextKey = "noWorktree"
 	serveKey      contextKey = "serve"
 	portKey       contextKey = "port"
+
+	maxIterationsKey    contextKey = "maxIterations"
+	maxDurationKey      contextKey = "maxDuration"
+	iterationTimeoutKey contextKey = "iterationTimeout"
+
+	modelKey     contextKey = "model"
+	planModelKey contextKey = "planModel"
+
+	profileKey     contextKey = "profile"
+	planProfileKey contextKey = "planProfile"
+
+	sandboxKey contextKey = "sandbox"
+
+	pullRequestKey contextKey = "pullRequest"
+
+	dryRunKey       contextKey = "dryRun"
+	dryRunScriptKey contextKey = "dryRunScript"
 )
 
 const version = "0.2.0" // Bumped version for new implementation
@@ -35,6 +53,17 @@ func NewRootCommand() *cobra.Command {
 	var noWorktree bool
 	var serve bool
 	var port int
+	var maxIterations int
+	var maxDuration time.Duration
+	var iterationTimeout time.Duration
+	var model string
+	var planModel string
+	var profile string
+	var planProfile string
+	var sandboxBackend string
+	var pullRequest bool
+	var dryRun bool
+	var dryRunScript string
 
 	cmd := &cobra.Command{
 		Use:   "alpine <task-description>",
@@ -49,7 +78,13 @@ Examples:
   alpine "Fix bug in payment processing" --no-plan
   alpine --no-plan --no-worktree              # Bare execution mode (continue from existing state)
   alpine --serve                               # Run HTTP server with SSE support
-  alpine --serve "Add new feature"             # Run HTTP server + execute task with SSE events`,
+  alpine --serve "Add new feature"             # Run HTTP server + execute task with SSE events
+  alpine "Refactor parser" --max-iterations 20 --max-duration 2h
+  alpine "Add caching" --model claude-opus-4-20250514 --plan-model claude-sonnet-4-20250514
+  alpine "Add caching" --profile full --plan-profile readonly
+  alpine "Add caching" --sandbox bwrap         # Confine Claude to the worktree
+  alpine "Add caching" --pr                    # Open a pull request when the run completes
+  alpine "Add caching" --dry-run               # Print each claude command instead of running it`,
 		Args: func(cmd *cobra.Command, args []string) error {
 			if showVersion {
 				return nil
@@ -83,6 +118,17 @@ Examples:
 	cmd.Flags().BoolVar(&noWorktree, "no-worktree", false, "Disable git worktree creation")
 	cmd.Flags().BoolVar(&serve, "serve", false, "Start HTTP server with Server-Sent Events support")
 	cmd.Flags().IntVar(&port, "port", 3001, "HTTP server port (default: 3001)")
+	cmd.Flags().IntVar(&maxIterations, "max-iterations", 0, "Stop the run after this many Claude iterations (0 = unlimited)")
+	cmd.Flags().DurationVar(&maxDuration, "max-duration", 0, "Stop the run after this much wall-clock time, e.g. 90m (0 = unlimited)")
+	cmd.Flags().DurationVar(&iterationTimeout, "iteration-timeout", 0, "Stop the run if a single iteration takes longer than this (0 = unlimited)")
+	cmd.Flags().StringVar(&model, "model", "", "Claude model for implementation iterations (overrides ALPINE_MODEL)")
+	cmd.Flags().StringVar(&planModel, "plan-model", "", "Claude model for plan generation (overrides ALPINE_PLAN_MODEL)")
+	cmd.Flags().StringVar(&profile, "profile", "", "Permission profile for implementation iterations: readonly, default, full or one from config (overrides ALPINE_PROFILE)")
+	cmd.Flags().StringVar(&planProfile, "plan-profile", "", "Permission profile for plan generation (overrides ALPINE_PLAN_PROFILE)")
+	cmd.Flags().StringVar(&sandboxBackend, "sandbox", "", "Run Claude in a sandbox confined to the worktree: none, bwrap or unshare (overrides ALPINE_SANDBOX)")
+	cmd.Flags().BoolVar(&pullRequest, "pr", false, "Push the run's branch and open a pull request against the base branch when the run completes (overrides ALPINE_GIT_PULL_REQUEST)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print each claude command instead of running it, simulating Claude's state updates")
+	cmd.Flags().StringVar(&dryRunScript, "dry-run-script", "", "JSON file with the states to simulate in a dry run, one per iteration (implies --dry-run)")
 
 	// Store flags in command context for runWorkflow
 	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
@@ -91,6 +137,60 @@ Examples:
 		ctx = context.WithValue(ctx, noWorktreeKey, noWorktree)
 		ctx = context.WithValue(ctx, serveKey, serve)
 		ctx = context.WithValue(ctx, portKey, port)
+
+		// Budget flags only override configuration when explicitly set
+		if cmd.Flags().Changed("max-iterations") {
+			if maxIterations < 0 {
+				return fmt.Errorf("--max-iterations must not be negative")
+			}
+			ctx = context.WithValue(ctx, maxIterationsKey, maxIterations)
+		}
+		if cmd.Flags().Changed("max-duration") {
+			if maxDuration < 0 {
+				return fmt.Errorf("--max-duration must not be negative")
+			}
+			ctx = context.WithValue(ctx, maxDurationKey, maxDuration)
+		}
+		if cmd.Flags().Changed("iteration-timeout") {
+			if iterationTimeout < 0 {
+				return fmt.Errorf("--iteration-timeout must not be negative")
+			}
+			ctx = context.WithValue(ctx, iterationTimeoutKey, iterationTimeout)
+		}
+
+		// Model flags only override configuration when non-empty
+		if model != "" {
+			ctx = context.WithValue(ctx, modelKey, model)
+		}
+		if planModel != "" {
+			ctx = context.WithValue(ctx, planModelKey, planModel)
+		}
+
+		// Permission profile flags only override configuration when non-empty
+		if profile != "" {
+			ctx = context.WithValue(ctx, profileKey, profile)
+		}
+		if planProfile != "" {
+			ctx = context.WithValue(ctx, planProfileKey, planProfile)
+		}
+
+		// The sandbox flag only overrides configuration when non-empty
+		if sandboxBackend != "" {
+			ctx = context.WithValue(ctx, sandboxKey, sandboxBackend)
+		}
+
+		// The pull request flag only overrides configuration when explicitly set
+		if cmd.Flags().Changed("pr") {
+			ctx = context.WithValue(ctx, pullRequestKey, pullRequest)
+		}
+
+		// A dry-run script implies a dry run
+		if dryRun || dryRunScript != "" {
+			ctx = context.WithValue(ctx, dryRunKey, true)
+		}
+		if dryRunScript != "" {
+			ctx = context.WithValue(ctx, dryRunScriptKey, dryRunScript)
+		}
 		cmd.SetContext(ctx)
 		return nil
 	}
@@ -99,6 +199,12 @@ Examples:
 	cmd.AddCommand(newMultiCmd().Command())
 	cmd.AddCommand(newPlanCmd().Command())
 	cmd.AddCommand(newReviewCmd().Command())
+	cmd.AddCommand(newRollbackCmd().Command())
+	cmd.AddCommand(newHookCmd().Command())
+	cmd.AddCommand(newSandboxExecCmd().Command())
+	cmd.AddCommand(newStateCmd().Command())
+	cmd.AddCommand(newPromptsCmd().Command())
+	cmd.AddCommand(newConfigCmd().Command())
 
 	return cmd
 }
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Backland-Labs/alpine/internal/sandbox"
+)
+
+// sandboxExecCmd represents the command that runs Claude inside a sandbox
+type sandboxExecCmd struct {
+	cmd *cobra.Command
+}
+
+// NewSandboxExecCommand creates a new sandbox-exec command (exported for tests)
+func NewSandboxExecCommand() *cobra.Command {
+	return newSandboxExecCmd().Command()
+}
+
+// newSandboxExecCmd creates a new sandbox-exec command
+func newSandboxExecCmd() *sandboxExecCmd {
+	sc := &sandboxExecCmd{}
+
+	sc.cmd = &cobra.Command{
+		Use:   sandbox.HelperCommand + " [options] -- <command> [args...]",
+		Short: "Run a command inside an alpine sandbox",
+		Long: `Run a command inside the namespaces of an alpine sandbox.
+
+Alpine runs Claude through this command when a sandbox is configured. It sets
+up the sandbox's read-only mounts under unshare, forwards a loopback proxy
+address to alpine's network proxy and then runs the command, exiting with its
+exit code.`,
+		Hidden:             true,
+		DisableFlagParsing: true,
+		SilenceUsage:       true,
+		SilenceErrors:      true,
+		Run:                sc.execute,
+	}
+
+	return sc
+}
+
+// Command returns the cobra command
+func (sc *sandboxExecCmd) Command() *cobra.Command {
+	return sc.cmd
+}
+
+// execute runs the command and exits with its exit code
+func (sc *sandboxExecCmd) execute(cmd *cobra.Command, args []string) {
+	os.Exit(sandbox.RunHelper(args, cmd.ErrOrStderr()))
+}
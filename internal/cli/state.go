@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Backland-Labs/alpine/internal/core"
+)
+
+// stateCmd represents the state command for working with the agent state file
+type stateCmd struct {
+	cmd *cobra.Command
+}
+
+// NewStateCommand creates a new state command (exported for tests)
+func NewStateCommand() *cobra.Command {
+	return newStateCmd().Command()
+}
+
+// newStateCmd creates a new state command
+func newStateCmd() *stateCmd {
+	sc := &stateCmd{}
+
+	sc.cmd = &cobra.Command{
+		Use:   "state",
+		Short: "Work with the agent state file",
+	}
+
+	var file string
+	writeCmd := &cobra.Command{
+		Use:   "write",
+		Short: "Atomically replace the agent state file with JSON from stdin",
+		Long: `Atomically replace the agent state file with a JSON state read from stdin.
+
+The file is written to a temporary file and renamed into place while holding
+the same lock alpine uses, so alpine never reads a partially written state.
+Scripts and hooks that update agent_state.json should use this command instead
+of writing the file directly.
+
+Example:
+  echo '{"current_step_description":"Done","next_step_prompt":"","status":"completed"}' | alpine state write`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return sc.write(cmd, file)
+		},
+	}
+	writeCmd.Flags().StringVar(&file, "file", filepath.Join("agent_state", "agent_state.json"), "Path to the state file")
+	sc.cmd.AddCommand(writeCmd)
+
+	return sc
+}
+
+// Command returns the cobra command
+func (sc *stateCmd) Command() *cobra.Command {
+	return sc.cmd
+}
+
+// write reads a state from stdin and writes it to file
+func (sc *stateCmd) write(cmd *cobra.Command, file string) error {
+	data, err := io.ReadAll(cmd.InOrStdin())
+	if err != nil {
+		return fmt.Errorf("failed to read state from stdin: %w", err)
+	}
+	return core.WriteStateFile(file, data)
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/core"
+)
+
+// TestStateWriteCommand tests that state write replaces the state file with stdin
+func TestStateWriteCommand(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "agent_state.json")
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetIn(strings.NewReader(`{"current_step_description":"Done","next_step_prompt":"","status":"completed"}`))
+	rootCmd.SetArgs([]string{"state", "write", "--file", stateFile})
+	require.NoError(t, rootCmd.Execute())
+
+	state, err := core.LoadState(stateFile)
+	require.NoError(t, err)
+	assert.Equal(t, "Done", state.CurrentStepDescription)
+	assert.True(t, state.IsCompleted())
+}
+
+// TestStateWriteCommand_RejectsInvalidJSON tests that invalid input does not touch the state file
+func TestStateWriteCommand_RejectsInvalidJSON(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "agent_state.json")
+	require.NoError(t, os.WriteFile(stateFile, []byte(`{"status":"running"}`), 0644))
+
+	rootCmd := NewRootCommand()
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetIn(strings.NewReader("not json"))
+	rootCmd.SetArgs([]string{"state", "write", "--file", stateFile})
+	assert.Error(t, rootCmd.Execute())
+
+	data, err := os.ReadFile(stateFile)
+	require.NoError(t, err)
+	assert.Equal(t, `{"status":"running"}`, string(data))
+}
@@ -9,10 +9,14 @@ import (
 	"time"
 
 	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/config"
+	"github.com/Backland-Labs/alpine/internal/core"
 	"github.com/Backland-Labs/alpine/internal/events"
 	"github.com/Backland-Labs/alpine/internal/gitx"
 	"github.com/Backland-Labs/alpine/internal/logger"
+	"github.com/Backland-Labs/alpine/internal/output"
 	"github.com/Backland-Labs/alpine/internal/server"
+	"github.com/Backland-Labs/alpine/internal/workflow"
 )
 
 // runWorkflowWithDependencies is the testable version of runWorkflow with dependency injection
@@ -26,6 +30,23 @@ func runWorkflowWithDependencies(ctx context.Context, args []string, noPlan bool
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
+		// Apply budget, model and permission overrides from flags so REST API runs honour them too
+		applyBudgetOverrides(ctx, cfg)
+		applyModelOverrides(ctx, cfg)
+		if err := applyProfileOverrides(ctx, cfg); err != nil {
+			return err
+		}
+		if err := applySandboxOverrides(ctx, cfg); err != nil {
+			return err
+		}
+		applyPullRequestOverride(ctx, cfg)
+		if err := cfg.ValidateMCPCommands(); err != nil {
+			return err
+		}
+		if err := cfg.ValidateSandbox(); err != nil {
+			return err
+		}
+
 		// Initialize logger based on configuration (for production use)
 		logger.InitializeFromConfig(cfg)
 		logger.Infof("Starting Alpine in server-only mode")
@@ -96,6 +117,36 @@ func runWorkflowWithDependencies(ctx context.Context, args []string, noPlan bool
 		cfg.Git.WorktreeEnabled = false
 	}
 
+	// Override budget limits if budget flags are used
+	applyBudgetOverrides(ctx, cfg)
+
+	// Override models if model flags are used
+	applyModelOverrides(ctx, cfg)
+
+	// Override permission profiles if profile flags are used
+	if err := applyProfileOverrides(ctx, cfg); err != nil {
+		return err
+	}
+
+	// Override the sandbox backend if the sandbox flag is used
+	if err := applySandboxOverrides(ctx, cfg); err != nil {
+		return err
+	}
+
+	// Open a pull request on completion if the pr flag is used
+	applyPullRequestOverride(ctx, cfg)
+
+	// Print git, GitHub and gate actions instead of performing them in a dry run
+	applyDryRunOverride(ctx, cfg)
+
+	// Fail before any work starts if an MCP server command or the sandbox is missing
+	if err := cfg.ValidateMCPCommands(); err != nil {
+		return err
+	}
+	if err := cfg.ValidateSandbox(); err != nil {
+		return err
+	}
+
 	// Initialize logger based on configuration (for production use)
 	logger.InitializeFromConfig(cfg)
 	logger.Debugf("Starting Alpine workflow for task: %s", taskDescription)
@@ -116,6 +167,11 @@ func runWorkflowWithDependencies(ctx context.Context, args []string, noPlan bool
 
 		engine, wtMgr, claudeExecutor := CreateWorkflowEngine(cfg, streamer)
 
+		// Replace Claude execution with printed command lines in a dry run
+		if err := applyDryRun(ctx, claudeExecutor); err != nil {
+			return err
+		}
+
 		// Connect ServerEventEmitter when --serve mode is active
 		if httpServer != nil {
 			// Create a broadcast function that converts to server's WorkflowEvent format
@@ -167,6 +223,100 @@ func runWorkflowWithDependencies(ctx context.Context, args []string, noPlan bool
 	return workflowErr
 }
 
+// applyBudgetOverrides replaces configured budget limits with any values set
+// through the --max-iterations, --max-duration and --iteration-timeout flags
+func applyBudgetOverrides(ctx context.Context, cfg *config.Config) {
+	if v, ok := ctx.Value(maxIterationsKey).(int); ok {
+		cfg.Budget.MaxIterations = v
+	}
+	if v, ok := ctx.Value(maxDurationKey).(time.Duration); ok {
+		cfg.Budget.MaxDuration = v
+	}
+	if v, ok := ctx.Value(iterationTimeoutKey).(time.Duration); ok {
+		cfg.Budget.IterationTimeout = v
+	}
+}
+
+// applyModelOverrides replaces configured models with any values set
+// through the --model and --plan-model flags
+func applyModelOverrides(ctx context.Context, cfg *config.Config) {
+	if v, ok := ctx.Value(modelKey).(string); ok {
+		cfg.Model = v
+	}
+	if v, ok := ctx.Value(planModelKey).(string); ok {
+		cfg.PlanModel = v
+	}
+}
+
+// applyProfileOverrides replaces configured permission profiles with any
+// set through the --profile and --plan-profile flags, rejecting unknown names
+func applyProfileOverrides(ctx context.Context, cfg *config.Config) error {
+	if v, ok := ctx.Value(profileKey).(string); ok {
+		if _, err := cfg.PermissionProfile(v); err != nil {
+			return fmt.Errorf("--profile: %w", err)
+		}
+		cfg.Profile = v
+	}
+	if v, ok := ctx.Value(planProfileKey).(string); ok {
+		if _, err := cfg.PermissionProfile(v); err != nil {
+			return fmt.Errorf("--plan-profile: %w", err)
+		}
+		cfg.PlanProfile = v
+	}
+	return nil
+}
+
+// applySandboxOverrides replaces the configured sandbox backend with the one
+// set through the --sandbox flag, rejecting unknown backends
+func applySandboxOverrides(ctx context.Context, cfg *config.Config) error {
+	if v, ok := ctx.Value(sandboxKey).(string); ok {
+		if err := config.ValidateSandboxBackend(v); err != nil {
+			return fmt.Errorf("--sandbox: %w", err)
+		}
+		cfg.Sandbox.Backend = v
+	}
+	return nil
+}
+
+// applyPullRequestOverride replaces the configured pull request option with
+// the value of the --pr flag when it is set
+func applyPullRequestOverride(ctx context.Context, cfg *config.Config) {
+	if v, ok := ctx.Value(pullRequestKey).(bool); ok {
+		cfg.Git.PullRequest = v
+	}
+}
+
+// applyDryRunOverride marks cfg as a dry run when --dry-run or
+// --dry-run-script is set
+func applyDryRunOverride(ctx context.Context, cfg *config.Config) {
+	if dryRun, _ := ctx.Value(dryRunKey).(bool); dryRun {
+		cfg.DryRun = true
+	}
+}
+
+// applyDryRun makes executor print each claude command line instead of
+// running it when --dry-run or --dry-run-script is set
+func applyDryRun(ctx context.Context, executor workflow.ClaudeExecutor) error {
+	if dryRun, _ := ctx.Value(dryRunKey).(bool); !dryRun {
+		return nil
+	}
+	claudeExecutor, ok := executor.(*claude.Executor)
+	if !ok {
+		return fmt.Errorf("dry run is not supported by this Claude executor")
+	}
+
+	var states []core.State
+	if path, ok := ctx.Value(dryRunScriptKey).(string); ok {
+		var err error
+		if states, err = claude.LoadDryRunScript(path); err != nil {
+			return err
+		}
+	}
+	claudeExecutor.SetDryRun(claude.NewDryRun(os.Stdout, states))
+	output.NewPrinter().Info("Dry run: printing claude commands instead of running them")
+	return nil
+}
+
 // startServerIfRequested starts the HTTP server if the --serve flag is set in the context.
 // The server runs in a separate goroutine and will be shut down when the context is cancelled.
 // Returns the server instance if started, nil otherwise.
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBudgetConfigDefaults tests that all budgets are disabled by default
+func TestBudgetConfigDefaults(t *testing.T) {
+	for _, env := range []string{"ALPINE_MAX_ITERATIONS", "ALPINE_MAX_DURATION", "ALPINE_ITERATION_TIMEOUT"} {
+		_ = os.Unsetenv(env)
+	}
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+
+	if cfg.Budget != (BudgetConfig{}) {
+		t.Errorf("Budget = %+v, want zero value (all limits disabled)", cfg.Budget)
+	}
+}
+
+// TestBudgetConfigEnvironmentVariables tests loading budget limits from environment
+func TestBudgetConfigEnvironmentVariables(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    BudgetConfig
+		errMsg  string
+	}{
+		{
+			name: "all values set",
+			envVars: map[string]string{
+				"ALPINE_MAX_ITERATIONS":    "25",
+				"ALPINE_MAX_DURATION":      "7200",
+				"ALPINE_ITERATION_TIMEOUT": "900",
+			},
+			want: BudgetConfig{
+				MaxIterations:    25,
+				MaxDuration:      2 * time.Hour,
+				IterationTimeout: 15 * time.Minute,
+			},
+		},
+		{
+			name:    "zero disables a limit",
+			envVars: map[string]string{"ALPINE_MAX_ITERATIONS": "0"},
+			want:    BudgetConfig{},
+		},
+		{
+			name:    "invalid max iterations",
+			envVars: map[string]string{"ALPINE_MAX_ITERATIONS": "many"},
+			errMsg:  "invalid ALPINE_MAX_ITERATIONS",
+		},
+		{
+			name:    "negative max duration",
+			envVars: map[string]string{"ALPINE_MAX_DURATION": "-5"},
+			errMsg:  "ALPINE_MAX_DURATION must not be negative",
+		},
+		{
+			name:    "negative iteration timeout",
+			envVars: map[string]string{"ALPINE_ITERATION_TIMEOUT": "-1"},
+			errMsg:  "ALPINE_ITERATION_TIMEOUT must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range []string{"ALPINE_MAX_ITERATIONS", "ALPINE_MAX_DURATION", "ALPINE_ITERATION_TIMEOUT"} {
+				_ = os.Unsetenv(env)
+			}
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := New()
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("New() error = %v, want error containing %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() returned unexpected error: %v", err)
+			}
+			if cfg.Budget != tt.want {
+				t.Errorf("Budget = %+v, want %+v", cfg.Budget, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,6 @@
 // Package config provides configuration management for the Alpine CLI.
-// It loads configuration from environment variables with sensible defaults.
+// It loads configuration from environment variables, the repository's
+// .alpine.yaml and the user's config file, with sensible defaults.
 package config
 
 import (
@@ -7,7 +8,10 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/Backland-Labs/alpine/internal/policy"
 )
 
 // Verbosity represents the output verbosity level
@@ -22,6 +26,17 @@ const (
 	VerbosityDebug Verbosity = "debug"
 )
 
+// Claude output formats
+const (
+	// OutputFormatText runs Claude with plain text output
+	OutputFormatText = "text"
+	// OutputFormatStreamJSON runs Claude with stream-json output decoded into typed events
+	OutputFormatStreamJSON = "stream-json"
+)
+
+// DefaultModel is the Claude model used when no model is configured
+const DefaultModel = "claude-sonnet-4-20250514"
+
 // GitCloneConfig holds git clone-related configuration for server operations
 type GitCloneConfig struct {
 	// Enabled controls whether git clone operations are enabled
@@ -48,6 +63,16 @@ type GitConfig struct {
 	// AutoCleanupWT controls whether to clean up worktrees after completion
 	AutoCleanupWT bool
 
+	// AutoCommit commits the worktree's changes after each completed iteration
+	AutoCommit bool
+
+	// Squash replaces a completed run's commits with a single commit
+	Squash bool
+
+	// PullRequest pushes a completed run's branch and opens a pull request
+	// against BaseBranch
+	PullRequest bool
+
 	// Clone holds git clone-related configuration
 	Clone GitCloneConfig
 }
@@ -67,6 +92,115 @@ type ServerConfig struct {
 	MaxClientsPerRun int
 }
 
+// BudgetConfig holds limits that bound the cost of a single workflow run.
+// A zero value for any field means the limit is disabled.
+type BudgetConfig struct {
+	// MaxIterations is the maximum number of Claude iterations per run
+	MaxIterations int
+
+	// MaxDuration is the maximum total wall-clock time for a run
+	MaxDuration time.Duration
+
+	// IterationTimeout is the maximum wall-clock time for a single iteration
+	IterationTimeout time.Duration
+}
+
+// Stall actions
+const (
+	// StallActionNudge re-runs Claude with the nudge prompt after a stall
+	StallActionNudge = "nudge"
+	// StallActionRetry re-runs Claude with the prompt that stalled
+	StallActionRetry = "retry"
+)
+
+// DefaultStallNudgePrompt is sent to Claude when it exits without updating the state file
+const DefaultStallNudgePrompt = "You exited without updating agent_state/agent_state.json. " +
+	"Review what you have done so far, then update agent_state/agent_state.json with the current step description, " +
+	"the next step prompt and the status before you finish."
+
+// StallConfig controls how a run reacts when Claude exits without advancing
+// the state file. A zero MaxStalls disables stall detection.
+type StallConfig struct {
+	// MaxStalls is the number of consecutive stalls after which the run fails
+	MaxStalls int
+
+	// GracePeriod is how long to wait for a state update after Claude exits
+	GracePeriod time.Duration
+
+	// Backoff is the delay before re-running Claude after the first stall;
+	// it doubles with each consecutive stall
+	Backoff time.Duration
+
+	// Action is what to run after a stall: nudge or retry
+	Action string
+
+	// NudgePrompt is the prompt sent to Claude when Action is nudge
+	NudgePrompt string
+}
+
+// RollbackConfig controls how a run recovers from an iteration that leaves
+// the working directory broken. Rollback is disabled without a Check.
+type RollbackConfig struct {
+	// Check is a shell command run in the working directory after each
+	// iteration; when it fails the iteration is rolled back to the last
+	// iteration commit and retried with the check's output
+	Check string
+
+	// Timeout bounds each run of Check
+	Timeout time.Duration
+
+	// MaxRollbacks is the number of consecutive rollbacks after which the run fails
+	MaxRollbacks int
+}
+
+// RetryConfig controls how Claude invocations that fail for transient
+// reasons (rate limits, overloaded API, network errors) are retried.
+// A zero MaxRetries disables retries.
+type RetryConfig struct {
+	// MaxRetries is the number of times a transient failure is retried
+	MaxRetries int
+
+	// InitialBackoff is the delay before the first retry; it doubles with
+	// each further retry and is randomized to spread out concurrent runs
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between retries
+	MaxBackoff time.Duration
+}
+
+// SessionConfig controls whether workflow iterations continue the previous
+// Claude session instead of starting a fresh one. Continuation is off unless
+// Continue is set. A zero limit disables that limit.
+type SessionConfig struct {
+	// Continue resumes the previous iteration's Claude session
+	Continue bool
+
+	// MaxContextTokens starts a new session once the last iteration's
+	// context reached this many tokens (known only with stream-json output)
+	MaxContextTokens int
+
+	// MaxIterations is the number of iterations after which a new session starts
+	MaxIterations int
+}
+
+// PolicyConfig holds the command and path policy alpine's PreToolUse hook
+// enforces in workflow runs
+type PolicyConfig struct {
+	// Enabled controls whether the policy hook is installed; it is off
+	// unless enabled in the user config file or environment
+	Enabled bool
+
+	// WorktreeOnly blocks file writes outside the run's working directory
+	WorktreeOnly bool
+
+	// Bash rules match the commands of Bash tool calls; they add to the
+	// built-in rules denying force pushes and rm -rf /
+	Bash policy.Rules
+
+	// Paths rules match the paths of file tool calls
+	Paths policy.Rules
+}
+
 // Config holds all configuration for the Alpine CLI
 type Config struct {
 	// WorkDir is the working directory for Claude execution
@@ -90,19 +224,122 @@ type Config struct {
 	// AutoCleanup controls whether state file is deleted on success
 	AutoCleanup bool
 
+	// DryRun prints the commits, pushes, pull requests, gates and checks of
+	// workflow runs instead of performing them. It is set by --dry-run and
+	// dry-run server runs, not by config files.
+	DryRun bool
+
+	// Model is the Claude model used for implementation iterations
+	Model string
+
+	// PlanModel is the Claude model used for plan generation (/make_plan and alpine plan).
+	// Empty means Model is used.
+	PlanModel string
+
+	// OutputFormat is the Claude output format: text or stream-json
+	OutputFormat string
+
+	// AllowedTools replaces the allowed tools of the execution permission
+	// profile when set
+	AllowedTools []string
+
+	// Profile is the permission profile for implementation iterations
+	Profile string
+
+	// PlanProfile is the permission profile for plan generation. Empty means
+	// readonly for alpine plan and Profile for the workflow's planning
+	// iteration, which must be able to update the state file.
+	PlanProfile string
+
+	// Profiles holds the permission profiles defined in config files by name.
+	// They replace built-in profiles of the same name.
+	Profiles map[string]PermissionProfile
+
+	// MCPServers holds the MCP servers declared in config files by name
+	MCPServers map[string]MCPServer
+
 	// Git holds git-related configuration
 	Git GitConfig
 
 	// Server holds server-related configuration
 	Server ServerConfig
+
+	// Budget holds iteration and wall-clock limits for workflow runs
+	Budget BudgetConfig
+
+	// Stall holds stall detection settings for workflow runs
+	Stall StallConfig
+
+	// Rollback holds the check that rolls back broken iterations
+	Rollback RollbackConfig
+
+	// Gates holds the quality gates run after each iteration
+	Gates GatesConfig
+
+	// GitHub holds the GitHub API used to open pull requests
+	GitHub GitHubConfig
+
+	// Retry holds the retry policy for transient Claude failures
+	Retry RetryConfig
+
+	// Session holds Claude session continuation settings for workflow runs
+	Session SessionConfig
+
+	// Policy holds the command and path policy for workflow runs
+	Policy PolicyConfig
+
+	// Sandbox holds the sandbox Claude runs in
+	Sandbox SandboxConfig
 }
 
-// New creates a new Config instance from environment variables
+// New creates a new Config instance from environment variables layered over
+// the repository and user config files
 func New() (*Config, error) {
+	cfg, _, err := Load()
+	return cfg, err
+}
+
+// Load creates a new Config like New and reports where each setting came
+// from. Environment variables take precedence over the repository's
+// .alpine.yaml, which takes precedence over the user's config file.
+func Load() (*Config, Origins, error) {
+	// An empty ALPINE_WORKDIR is an error rather than unset
+	if workDir, ok := os.LookupEnv("ALPINE_WORKDIR"); ok && workDir == "" {
+		return nil, nil, fmt.Errorf("ALPINE_WORKDIR cannot be empty")
+	}
+
+	// The repository file is found from ALPINE_WORKDIR or the current directory
+	searchDir := os.Getenv("ALPINE_WORKDIR")
+	if searchDir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get current directory: %w", err)
+		}
+		searchDir = cwd
+	}
+	repoFile, err := loadFileLayer(FindRepoFile(searchDir), OriginRepoFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	userFile, err := loadFileLayer(UserFilePath(), OriginUserFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	src := newSource(repoFile, userFile)
+	cfg, err := load(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, src.origins, nil
+}
+
+// load creates a new Config from the settings in src
+func load(src *source) (*Config, error) {
 	cfg := &Config{}
 
 	// Load WorkDir - defaults to current directory
-	workDir, exists := os.LookupEnv("ALPINE_WORKDIR")
+	workDir, exists := src.lookup("ALPINE_WORKDIR")
 	if !exists {
 		// Environment variable not set, use current directory
 		cwd, err := os.Getwd()
@@ -111,10 +348,6 @@ func New() (*Config, error) {
 		}
 		cfg.WorkDir = cwd
 	} else {
-		// Environment variable exists, validate it
-		if workDir == "" {
-			return nil, fmt.Errorf("ALPINE_WORKDIR cannot be empty")
-		}
 		// Validate that WorkDir is absolute
 		if !filepath.IsAbs(workDir) {
 			return nil, fmt.Errorf("ALPINE_WORKDIR must be an absolute path, got: %s", workDir)
@@ -123,7 +356,7 @@ func New() (*Config, error) {
 	}
 
 	// Load Verbosity - defaults to normal
-	verbosity := os.Getenv("ALPINE_VERBOSITY")
+	verbosity := src.get("ALPINE_VERBOSITY")
 	if verbosity == "" {
 		cfg.Verbosity = VerbosityNormal
 	} else {
@@ -136,21 +369,21 @@ func New() (*Config, error) {
 	}
 
 	// Load ShowOutput - defaults to true
-	showOutput, err := parseBoolEnv("ALPINE_SHOW_OUTPUT", true)
+	showOutput, err := src.parseBool("ALPINE_SHOW_OUTPUT", true)
 	if err != nil {
 		return nil, err
 	}
 	cfg.ShowOutput = showOutput
 
 	// Load ShowTodoUpdates - defaults to true
-	showTodoUpdates, err := parseBoolEnv("ALPINE_SHOW_TODO_UPDATES", true)
+	showTodoUpdates, err := src.parseBool("ALPINE_SHOW_TODO_UPDATES", true)
 	if err != nil {
 		return nil, err
 	}
 	cfg.ShowTodoUpdates = showTodoUpdates
 
 	// Load ShowToolUpdates - defaults to true
-	showToolUpdates, err := parseBoolEnv("ALPINE_SHOW_TOOL_UPDATES", true)
+	showToolUpdates, err := src.parseBool("ALPINE_SHOW_TOOL_UPDATES", true)
 	if err != nil {
 		return nil, err
 	}
@@ -160,24 +393,68 @@ func New() (*Config, error) {
 	cfg.StateFile = filepath.Join("agent_state", "agent_state.json")
 
 	// Load AutoCleanup - defaults to true
-	autoCleanup, err := parseBoolEnv("ALPINE_AUTO_CLEANUP", true)
+	autoCleanup, err := src.parseBool("ALPINE_AUTO_CLEANUP", true)
 	if err != nil {
 		return nil, err
 	}
 	cfg.AutoCleanup = autoCleanup
 
+	// Load Model - defaults to DefaultModel
+	cfg.Model = src.get("ALPINE_MODEL")
+	if cfg.Model == "" {
+		cfg.Model = DefaultModel
+	}
+
+	// Load PlanModel - defaults to empty (use Model)
+	cfg.PlanModel = src.get("ALPINE_PLAN_MODEL")
+
+	// Load AllowedTools - defaults to empty (Claude executor defaults)
+	cfg.AllowedTools = parseList(src.get("ALPINE_ALLOWED_TOOLS"))
+
+	// Load permission profiles and MCP servers - these come from config files only
+	cfg.Profiles = src.profiles()
+	cfg.MCPServers = src.mcpServers()
+
+	// Load Profile - defaults to the default profile
+	cfg.Profile = src.get("ALPINE_PROFILE")
+	if cfg.Profile == "" {
+		cfg.Profile = ProfileDefault
+	}
+	if _, err := cfg.PermissionProfile(cfg.Profile); err != nil {
+		return nil, fmt.Errorf("ALPINE_PROFILE: %w", err)
+	}
+
+	// Load PlanProfile - defaults to empty (phase default)
+	cfg.PlanProfile = src.get("ALPINE_PLAN_PROFILE")
+	if cfg.PlanProfile != "" {
+		if _, err := cfg.PermissionProfile(cfg.PlanProfile); err != nil {
+			return nil, fmt.Errorf("ALPINE_PLAN_PROFILE: %w", err)
+		}
+	}
+
+	// Load OutputFormat - defaults to text
+	outputFormat := src.get("ALPINE_OUTPUT_FORMAT")
+	switch outputFormat {
+	case "":
+		cfg.OutputFormat = OutputFormatText
+	case OutputFormatText, OutputFormatStreamJSON:
+		cfg.OutputFormat = outputFormat
+	default:
+		return nil, fmt.Errorf("ALPINE_OUTPUT_FORMAT must be one of: text, stream-json; got: %s", outputFormat)
+	}
+
 	// Load Git configuration
 	cfg.Git = GitConfig{}
 
 	// Load WorktreeEnabled - defaults to true
-	worktreeEnabled, err := parseBoolEnv("ALPINE_GIT_ENABLED", true)
+	worktreeEnabled, err := src.parseBool("ALPINE_GIT_ENABLED", true)
 	if err != nil {
 		return nil, err
 	}
 	cfg.Git.WorktreeEnabled = worktreeEnabled
 
 	// Load BaseBranch - defaults to "main"
-	baseBranch := os.Getenv("ALPINE_GIT_BASE_BRANCH")
+	baseBranch := src.get("ALPINE_GIT_BASE_BRANCH")
 	if baseBranch == "" {
 		cfg.Git.BaseBranch = "main"
 	} else {
@@ -185,27 +462,48 @@ func New() (*Config, error) {
 	}
 
 	// Load AutoCleanupWT - defaults to true
-	autoCleanupWT, err := parseBoolEnv("ALPINE_GIT_AUTO_CLEANUP", true)
+	autoCleanupWT, err := src.parseBool("ALPINE_GIT_AUTO_CLEANUP", true)
 	if err != nil {
 		return nil, err
 	}
 	cfg.Git.AutoCleanupWT = autoCleanupWT
 
+	// Load AutoCommit - defaults to false; runs opening a pull request commit regardless
+	autoCommit, err := src.parseBool("ALPINE_GIT_AUTO_COMMIT", false)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Git.AutoCommit = autoCommit
+
+	// Load Squash - defaults to false
+	squash, err := src.parseBool("ALPINE_GIT_SQUASH", false)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Git.Squash = squash
+
+	// Load PullRequest - defaults to false
+	pullRequest, err := src.parseBool("ALPINE_GIT_PULL_REQUEST", false)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Git.PullRequest = pullRequest
+
 	// Load Git Clone configuration
 	cfg.Git.Clone = GitCloneConfig{}
 
 	// Load Clone.Enabled - defaults to true
-	cloneEnabled, err := parseBoolEnv("ALPINE_GIT_CLONE_ENABLED", true)
+	cloneEnabled, err := src.parseBool("ALPINE_GIT_CLONE_ENABLED", true)
 	if err != nil {
 		return nil, err
 	}
 	cfg.Git.Clone.Enabled = cloneEnabled
 
 	// Load Clone.AuthToken - defaults to empty string
-	cfg.Git.Clone.AuthToken = os.Getenv("ALPINE_GIT_CLONE_AUTH_TOKEN")
+	cfg.Git.Clone.AuthToken = src.get("ALPINE_GIT_CLONE_AUTH_TOKEN")
 
 	// Load Clone.Timeout - defaults to 300 seconds
-	cloneTimeoutStr := os.Getenv("ALPINE_GIT_CLONE_TIMEOUT")
+	cloneTimeoutStr := src.get("ALPINE_GIT_CLONE_TIMEOUT")
 	if cloneTimeoutStr == "" {
 		cfg.Git.Clone.Timeout = 300 * time.Second
 	} else {
@@ -220,7 +518,7 @@ func New() (*Config, error) {
 	}
 
 	// Load Clone.Depth - defaults to 1
-	cloneDepthStr := os.Getenv("ALPINE_GIT_CLONE_DEPTH")
+	cloneDepthStr := src.get("ALPINE_GIT_CLONE_DEPTH")
 	if cloneDepthStr == "" {
 		cfg.Git.Clone.Depth = 1
 	} else {
@@ -238,14 +536,14 @@ func New() (*Config, error) {
 	cfg.Server = ServerConfig{}
 
 	// Load Server.Enabled - defaults to false
-	serverEnabled, err := parseBoolEnv("ALPINE_HTTP_ENABLED", false)
+	serverEnabled, err := src.parseBool("ALPINE_HTTP_ENABLED", false)
 	if err != nil {
 		return nil, err
 	}
 	cfg.Server.Enabled = serverEnabled
 
 	// Load Server.Port - defaults to 3001
-	httpPortStr := os.Getenv("ALPINE_HTTP_PORT")
+	httpPortStr := src.get("ALPINE_HTTP_PORT")
 	if httpPortStr == "" {
 		cfg.Server.Port = 3001
 	} else {
@@ -257,7 +555,7 @@ func New() (*Config, error) {
 	}
 
 	// Load Server.StreamBufferSize - defaults to 100
-	streamBufferSizeStr := os.Getenv("ALPINE_STREAM_BUFFER_SIZE")
+	streamBufferSizeStr := src.get("ALPINE_STREAM_BUFFER_SIZE")
 	if streamBufferSizeStr == "" {
 		cfg.Server.StreamBufferSize = 100
 	} else {
@@ -271,7 +569,7 @@ func New() (*Config, error) {
 	}
 
 	// Load Server.MaxClientsPerRun - defaults to 100
-	maxClientsStr := os.Getenv("ALPINE_MAX_CLIENTS_PER_RUN")
+	maxClientsStr := src.get("ALPINE_MAX_CLIENTS_PER_RUN")
 	if maxClientsStr == "" {
 		cfg.Server.MaxClientsPerRun = 100
 	} else {
@@ -284,6 +582,253 @@ func New() (*Config, error) {
 		}
 	}
 
+	// Load Budget configuration - all limits default to 0 (disabled)
+	cfg.Budget = BudgetConfig{}
+
+	maxIterations, err := src.parseNonNegativeInt("ALPINE_MAX_ITERATIONS")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Budget.MaxIterations = maxIterations
+
+	maxDurationSecs, err := src.parseNonNegativeInt("ALPINE_MAX_DURATION")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Budget.MaxDuration = time.Duration(maxDurationSecs) * time.Second
+
+	iterationTimeoutSecs, err := src.parseNonNegativeInt("ALPINE_ITERATION_TIMEOUT")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Budget.IterationTimeout = time.Duration(iterationTimeoutSecs) * time.Second
+
+	// Load Stall configuration
+	cfg.Stall = StallConfig{}
+
+	// Load MaxStalls - defaults to 3; 0 disables stall detection
+	cfg.Stall.MaxStalls = 3
+	if src.get("ALPINE_MAX_STALLS") != "" {
+		maxStalls, err := src.parseNonNegativeInt("ALPINE_MAX_STALLS")
+		if err != nil {
+			return nil, err
+		}
+		cfg.Stall.MaxStalls = maxStalls
+	}
+
+	// Load GracePeriod - defaults to 5 seconds
+	cfg.Stall.GracePeriod = 5 * time.Second
+	if src.get("ALPINE_STALL_GRACE") != "" {
+		graceSecs, err := src.parseNonNegativeInt("ALPINE_STALL_GRACE")
+		if err != nil {
+			return nil, err
+		}
+		cfg.Stall.GracePeriod = time.Duration(graceSecs) * time.Second
+	}
+
+	// Load Backoff - defaults to 2 seconds
+	cfg.Stall.Backoff = 2 * time.Second
+	if src.get("ALPINE_STALL_BACKOFF") != "" {
+		backoffSecs, err := src.parseNonNegativeInt("ALPINE_STALL_BACKOFF")
+		if err != nil {
+			return nil, err
+		}
+		cfg.Stall.Backoff = time.Duration(backoffSecs) * time.Second
+	}
+
+	// Load Action - defaults to nudge
+	stallAction := src.get("ALPINE_STALL_ACTION")
+	switch stallAction {
+	case "":
+		cfg.Stall.Action = StallActionNudge
+	case StallActionNudge, StallActionRetry:
+		cfg.Stall.Action = stallAction
+	default:
+		return nil, fmt.Errorf("ALPINE_STALL_ACTION must be one of: nudge, retry; got: %s", stallAction)
+	}
+
+	// Load NudgePrompt - defaults to DefaultStallNudgePrompt
+	cfg.Stall.NudgePrompt = src.get("ALPINE_STALL_NUDGE_PROMPT")
+	if cfg.Stall.NudgePrompt == "" {
+		cfg.Stall.NudgePrompt = DefaultStallNudgePrompt
+	}
+
+	// Load Rollback configuration
+	cfg.Rollback = RollbackConfig{}
+
+	// Load Check - defaults to none, which disables rollback
+	cfg.Rollback.Check = src.get("ALPINE_ROLLBACK_CHECK")
+
+	// Load Timeout - defaults to 10 minutes
+	cfg.Rollback.Timeout = 10 * time.Minute
+	if src.get("ALPINE_ROLLBACK_TIMEOUT") != "" {
+		timeoutSecs, err := src.parseNonNegativeInt("ALPINE_ROLLBACK_TIMEOUT")
+		if err != nil {
+			return nil, err
+		}
+		if timeoutSecs == 0 {
+			return nil, fmt.Errorf("ALPINE_ROLLBACK_TIMEOUT must be positive")
+		}
+		cfg.Rollback.Timeout = time.Duration(timeoutSecs) * time.Second
+	}
+
+	// Load MaxRollbacks - defaults to 3
+	cfg.Rollback.MaxRollbacks = 3
+	if src.get("ALPINE_MAX_ROLLBACKS") != "" {
+		maxRollbacks, err := src.parseNonNegativeInt("ALPINE_MAX_ROLLBACKS")
+		if err != nil {
+			return nil, err
+		}
+		cfg.Rollback.MaxRollbacks = maxRollbacks
+	}
+
+	// Load Gates configuration; every gate is disabled by default
+	cfg.Gates = GatesConfig{
+		Build: src.get("ALPINE_GATE_BUILD"),
+		Lint:  src.get("ALPINE_GATE_LINT"),
+		Test:  src.get("ALPINE_GATE_TEST"),
+	}
+
+	// Load Timeout - defaults to 10 minutes
+	cfg.Gates.Timeout = 10 * time.Minute
+	if src.get("ALPINE_GATE_TIMEOUT") != "" {
+		timeoutSecs, err := src.parseNonNegativeInt("ALPINE_GATE_TIMEOUT")
+		if err != nil {
+			return nil, err
+		}
+		if timeoutSecs == 0 {
+			return nil, fmt.Errorf("ALPINE_GATE_TIMEOUT must be positive")
+		}
+		cfg.Gates.Timeout = time.Duration(timeoutSecs) * time.Second
+	}
+
+	// Load MaxFailures - defaults to 3
+	cfg.Gates.MaxFailures = 3
+	if src.get("ALPINE_MAX_GATE_FAILURES") != "" {
+		maxFailures, err := src.parseNonNegativeInt("ALPINE_MAX_GATE_FAILURES")
+		if err != nil {
+			return nil, err
+		}
+		cfg.Gates.MaxFailures = maxFailures
+	}
+
+	// Load GitHub configuration; the API URL defaults to github.com's
+	cfg.GitHub = GitHubConfig{
+		APIURL: strings.TrimSuffix(src.get("ALPINE_GITHUB_API_URL"), "/"),
+		Token:  src.get("ALPINE_GITHUB_TOKEN"),
+	}
+	if cfg.GitHub.APIURL == "" {
+		cfg.GitHub.APIURL = DefaultGitHubAPIURL
+	}
+
+	// Load Retry configuration
+	cfg.Retry = RetryConfig{}
+
+	// Load MaxRetries - defaults to 3; 0 disables retries
+	cfg.Retry.MaxRetries = 3
+	if src.get("ALPINE_CLAUDE_MAX_RETRIES") != "" {
+		maxRetries, err := src.parseNonNegativeInt("ALPINE_CLAUDE_MAX_RETRIES")
+		if err != nil {
+			return nil, err
+		}
+		cfg.Retry.MaxRetries = maxRetries
+	}
+
+	// Load InitialBackoff - defaults to 5 seconds
+	cfg.Retry.InitialBackoff = 5 * time.Second
+	if src.get("ALPINE_CLAUDE_RETRY_BACKOFF") != "" {
+		backoffSecs, err := src.parseNonNegativeInt("ALPINE_CLAUDE_RETRY_BACKOFF")
+		if err != nil {
+			return nil, err
+		}
+		cfg.Retry.InitialBackoff = time.Duration(backoffSecs) * time.Second
+	}
+
+	// Load MaxBackoff - defaults to 2 minutes
+	cfg.Retry.MaxBackoff = 2 * time.Minute
+	if src.get("ALPINE_CLAUDE_RETRY_MAX_BACKOFF") != "" {
+		maxBackoffSecs, err := src.parseNonNegativeInt("ALPINE_CLAUDE_RETRY_MAX_BACKOFF")
+		if err != nil {
+			return nil, err
+		}
+		cfg.Retry.MaxBackoff = time.Duration(maxBackoffSecs) * time.Second
+	}
+
+	// Load Session configuration
+	cfg.Session = SessionConfig{}
+
+	// Load Continue - defaults to false
+	continueSession, err := src.parseBool("ALPINE_CONTINUE_SESSION", false)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Session.Continue = continueSession
+
+	// Load MaxContextTokens - defaults to 150000; 0 disables the limit
+	cfg.Session.MaxContextTokens = 150000
+	if src.get("ALPINE_SESSION_MAX_TOKENS") != "" {
+		maxTokens, err := src.parseNonNegativeInt("ALPINE_SESSION_MAX_TOKENS")
+		if err != nil {
+			return nil, err
+		}
+		cfg.Session.MaxContextTokens = maxTokens
+	}
+
+	// Load MaxIterations - defaults to 0 (no limit)
+	sessionIterations, err := src.parseNonNegativeInt("ALPINE_SESSION_MAX_ITERATIONS")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Session.MaxIterations = sessionIterations
+
+	// Load Policy configuration; rules come from config files only
+	cfg.Policy = src.policy()
+
+	// Load Policy.Enabled - defaults to false; the hook is opt-in
+	policyEnabled, err := src.parseBool("ALPINE_POLICY_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Policy.Enabled = policyEnabled
+
+	// Load Policy.WorktreeOnly - defaults to true
+	worktreeOnly, err := src.parseBool("ALPINE_POLICY_WORKTREE_ONLY", true)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Policy.WorktreeOnly = worktreeOnly
+
+	// Load Sandbox configuration
+	cfg.Sandbox = SandboxConfig{}
+
+	// Load Backend - defaults to none
+	cfg.Sandbox.Backend = src.get("ALPINE_SANDBOX")
+	if cfg.Sandbox.Backend == "" {
+		cfg.Sandbox.Backend = SandboxNone
+	}
+	if err := ValidateSandboxBackend(cfg.Sandbox.Backend); err != nil {
+		return nil, fmt.Errorf("ALPINE_SANDBOX: %w", err)
+	}
+
+	// Load AllowedHosts - defaults to the Claude API; an empty list means no network
+	if hosts, ok := src.lookup("ALPINE_SANDBOX_ALLOWED_HOSTS"); ok {
+		cfg.Sandbox.AllowedHosts = parseList(hosts)
+	} else {
+		cfg.Sandbox.AllowedHosts = DefaultSandboxAllowedHosts
+	}
+
+	// Load Writable - defaults to DefaultSandboxWritable
+	cfg.Sandbox.Writable = DefaultSandboxWritable
+	if writable, ok := src.lookup("ALPINE_SANDBOX_WRITABLE"); ok {
+		cfg.Sandbox.Writable = parseList(writable)
+	}
+
+	// Load Hide - defaults to DefaultSandboxHide
+	cfg.Sandbox.Hide = DefaultSandboxHide
+	if hide, ok := src.lookup("ALPINE_SANDBOX_HIDE"); ok {
+		cfg.Sandbox.Hide = parseList(hide)
+	}
+
 	return cfg, nil
 }
 
@@ -292,14 +837,22 @@ func (c *Config) IsVerbose() bool {
 	return c.Verbosity == VerbosityVerbose || c.Verbosity == VerbosityDebug
 }
 
+// PlanningModel returns the model used for plan generation, falling back to Model
+func (c *Config) PlanningModel() string {
+	if c.PlanModel != "" {
+		return c.PlanModel
+	}
+	return c.Model
+}
+
 // IsDebug returns true if verbosity is debug
 func (c *Config) IsDebug() bool {
 	return c.Verbosity == VerbosityDebug
 }
 
-// parseBoolEnv parses a boolean environment variable with a default value
-func parseBoolEnv(key string, defaultValue bool) (bool, error) {
-	value := os.Getenv(key)
+// parseBool parses a boolean setting with a default value
+func (s *source) parseBool(key string, defaultValue bool) (bool, error) {
+	value := s.get(key)
 	if value == "" {
 		return defaultValue, nil
 	}
@@ -313,6 +866,34 @@ func parseBoolEnv(key string, defaultValue bool) (bool, error) {
 	}
 }
 
+// parseNonNegativeInt parses an integer setting that must not be negative.
+// An unset or empty setting yields 0.
+func (s *source) parseNonNegativeInt(key string) (int, error) {
+	value := s.get(key)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%s must not be negative, got: %d", key, n)
+	}
+	return n, nil
+}
+
+// parseList splits a comma-separated list, dropping empty items
+func parseList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // parsePort parses and validates a port number string
 func parsePort(portStr string) (int, error) {
 	port, err := strconv.Atoi(portStr)
@@ -69,6 +69,18 @@ func TestNewConfig(t *testing.T) {
 		t.Error("Git.AutoCleanupWT = false, want true")
 	}
 
+	if cfg.Git.AutoCommit {
+		t.Error("Git.AutoCommit = true, want false")
+	}
+
+	if cfg.Git.Squash {
+		t.Error("Git.Squash = true, want false")
+	}
+
+	if cfg.Policy.Enabled || !cfg.Policy.WorktreeOnly {
+		t.Errorf("Policy = %+v, want disabled and worktree only once enabled", cfg.Policy)
+	}
+
 	// Test ShowTodoUpdates default
 	if !cfg.ShowTodoUpdates {
 		t.Error("ShowTodoUpdates = false, want true")
@@ -92,6 +104,8 @@ func TestConfigFromEnvironment(t *testing.T) {
 	_ = os.Setenv("ALPINE_GIT_ENABLED", "false")
 	_ = os.Setenv("ALPINE_GIT_BASE_BRANCH", "develop")
 	_ = os.Setenv("ALPINE_GIT_AUTO_CLEANUP", "false")
+	_ = os.Setenv("ALPINE_GIT_AUTO_COMMIT", "true")
+	_ = os.Setenv("ALPINE_GIT_SQUASH", "true")
 	_ = os.Setenv("ALPINE_SHOW_TODO_UPDATES", "false")
 	_ = os.Setenv("ALPINE_SHOW_TOOL_UPDATES", "false")
 	_ = os.Setenv("ALPINE_HTTP_ENABLED", "true")
@@ -107,6 +121,8 @@ func TestConfigFromEnvironment(t *testing.T) {
 		_ = os.Unsetenv("ALPINE_GIT_ENABLED")
 		_ = os.Unsetenv("ALPINE_GIT_BASE_BRANCH")
 		_ = os.Unsetenv("ALPINE_GIT_AUTO_CLEANUP")
+		_ = os.Unsetenv("ALPINE_GIT_AUTO_COMMIT")
+		_ = os.Unsetenv("ALPINE_GIT_SQUASH")
 		_ = os.Unsetenv("ALPINE_SHOW_TODO_UPDATES")
 		_ = os.Unsetenv("ALPINE_SHOW_TOOL_UPDATES")
 		_ = os.Unsetenv("ALPINE_HTTP_ENABLED")
@@ -152,6 +168,14 @@ func TestConfigFromEnvironment(t *testing.T) {
 		t.Error("Git.AutoCleanupWT = true, want false")
 	}
 
+	if !cfg.Git.AutoCommit {
+		t.Error("Git.AutoCommit = false, want true")
+	}
+
+	if !cfg.Git.Squash {
+		t.Error("Git.Squash = false, want true")
+	}
+
 	// Test ShowTodoUpdates
 	if cfg.ShowTodoUpdates {
 		t.Error("ShowTodoUpdates = true, want false")
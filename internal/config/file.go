@@ -0,0 +1,543 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Backland-Labs/alpine/internal/policy"
+)
+
+// RepoFileName is the name of the config file looked up in the repository root
+const RepoFileName = ".alpine.yaml"
+
+// Origin sources, in increasing order of precedence
+const (
+	OriginDefault  = "default"
+	OriginUserFile = "user file"
+	OriginRepoFile = "repo file"
+	OriginEnv      = "env"
+)
+
+// Origin describes where a setting's effective value came from
+type Origin struct {
+	// Source is one of the Origin* constants
+	Source string
+
+	// Path is the config file the value was read from, empty for other sources
+	Path string
+}
+
+// String returns the source, followed by the path for config files
+func (o Origin) String() string {
+	if o.Path != "" {
+		return o.Source + " " + o.Path
+	}
+	return o.Source
+}
+
+// Origins maps setting keys (e.g. "git.base_branch") to where their values came from
+type Origins map[string]Origin
+
+// Setting is one configuration setting with its effective value
+type Setting struct {
+	// Key is the setting's key in config files, with dots for nesting
+	Key string
+
+	// Env is the environment variable that overrides the setting
+	Env string
+
+	// Value is the effective value; secrets are masked
+	Value string
+
+	// Origin is where Value came from
+	Origin Origin
+}
+
+// setting describes a configuration setting that can be set in a config
+// file or through its environment variable
+type setting struct {
+	key   string
+	env   string
+	value func(c *Config) string
+
+	// duration settings accept Go durations (e.g. "90s") in config files
+	duration bool
+
+	// list settings accept YAML sequences in config files
+	list bool
+
+	// secret settings are masked by Describe
+	secret bool
+
+	// userOnly settings guard the user rather than configure the project, so
+	// the repository's config file cannot set them
+	userOnly bool
+}
+
+// settings lists every configurable setting in display order
+var settings = []setting{
+	{key: "workdir", env: "ALPINE_WORKDIR", value: func(c *Config) string { return c.WorkDir }},
+	{key: "verbosity", env: "ALPINE_VERBOSITY", value: func(c *Config) string { return string(c.Verbosity) }},
+	{key: "show_output", env: "ALPINE_SHOW_OUTPUT", value: func(c *Config) string { return strconv.FormatBool(c.ShowOutput) }},
+	{key: "show_todo_updates", env: "ALPINE_SHOW_TODO_UPDATES", value: func(c *Config) string { return strconv.FormatBool(c.ShowTodoUpdates) }},
+	{key: "show_tool_updates", env: "ALPINE_SHOW_TOOL_UPDATES", value: func(c *Config) string { return strconv.FormatBool(c.ShowToolUpdates) }},
+	{key: "auto_cleanup", env: "ALPINE_AUTO_CLEANUP", value: func(c *Config) string { return strconv.FormatBool(c.AutoCleanup) }},
+	{key: "model", env: "ALPINE_MODEL", value: func(c *Config) string { return c.Model }},
+	{key: "plan_model", env: "ALPINE_PLAN_MODEL", value: func(c *Config) string { return c.PlanModel }},
+	{key: "profile", env: "ALPINE_PROFILE", value: func(c *Config) string { return c.Profile }},
+	{key: "plan_profile", env: "ALPINE_PLAN_PROFILE", value: func(c *Config) string { return c.PlanProfile }},
+	{key: "output_format", env: "ALPINE_OUTPUT_FORMAT", value: func(c *Config) string { return c.OutputFormat }},
+	{key: "allowed_tools", env: "ALPINE_ALLOWED_TOOLS", list: true, value: func(c *Config) string { return strings.Join(c.AllowedTools, ",") }},
+
+	{key: "git.enabled", env: "ALPINE_GIT_ENABLED", value: func(c *Config) string { return strconv.FormatBool(c.Git.WorktreeEnabled) }},
+	{key: "git.base_branch", env: "ALPINE_GIT_BASE_BRANCH", value: func(c *Config) string { return c.Git.BaseBranch }},
+	{key: "git.auto_cleanup", env: "ALPINE_GIT_AUTO_CLEANUP", value: func(c *Config) string { return strconv.FormatBool(c.Git.AutoCleanupWT) }},
+	{key: "git.auto_commit", env: "ALPINE_GIT_AUTO_COMMIT", value: func(c *Config) string { return strconv.FormatBool(c.Git.AutoCommit) }},
+	{key: "git.squash", env: "ALPINE_GIT_SQUASH", value: func(c *Config) string { return strconv.FormatBool(c.Git.Squash) }},
+	{key: "git.pull_request", env: "ALPINE_GIT_PULL_REQUEST", value: func(c *Config) string { return strconv.FormatBool(c.Git.PullRequest) }},
+	{key: "git.clone.enabled", env: "ALPINE_GIT_CLONE_ENABLED", value: func(c *Config) string { return strconv.FormatBool(c.Git.Clone.Enabled) }},
+	{key: "git.clone.auth_token", env: "ALPINE_GIT_CLONE_AUTH_TOKEN", secret: true, value: func(c *Config) string { return c.Git.Clone.AuthToken }},
+	{key: "git.clone.timeout", env: "ALPINE_GIT_CLONE_TIMEOUT", duration: true, value: func(c *Config) string { return c.Git.Clone.Timeout.String() }},
+	{key: "git.clone.depth", env: "ALPINE_GIT_CLONE_DEPTH", value: func(c *Config) string { return strconv.Itoa(c.Git.Clone.Depth) }},
+
+	{key: "server.enabled", env: "ALPINE_HTTP_ENABLED", value: func(c *Config) string { return strconv.FormatBool(c.Server.Enabled) }},
+	{key: "server.port", env: "ALPINE_HTTP_PORT", value: func(c *Config) string { return strconv.Itoa(c.Server.Port) }},
+	{key: "server.stream_buffer_size", env: "ALPINE_STREAM_BUFFER_SIZE", value: func(c *Config) string { return strconv.Itoa(c.Server.StreamBufferSize) }},
+	{key: "server.max_clients_per_run", env: "ALPINE_MAX_CLIENTS_PER_RUN", value: func(c *Config) string { return strconv.Itoa(c.Server.MaxClientsPerRun) }},
+
+	{key: "budget.max_iterations", env: "ALPINE_MAX_ITERATIONS", value: func(c *Config) string { return strconv.Itoa(c.Budget.MaxIterations) }},
+	{key: "budget.max_duration", env: "ALPINE_MAX_DURATION", duration: true, value: func(c *Config) string { return c.Budget.MaxDuration.String() }},
+	{key: "budget.iteration_timeout", env: "ALPINE_ITERATION_TIMEOUT", duration: true, value: func(c *Config) string { return c.Budget.IterationTimeout.String() }},
+
+	{key: "stall.max_stalls", env: "ALPINE_MAX_STALLS", value: func(c *Config) string { return strconv.Itoa(c.Stall.MaxStalls) }},
+	{key: "stall.grace_period", env: "ALPINE_STALL_GRACE", duration: true, value: func(c *Config) string { return c.Stall.GracePeriod.String() }},
+	{key: "stall.backoff", env: "ALPINE_STALL_BACKOFF", duration: true, value: func(c *Config) string { return c.Stall.Backoff.String() }},
+	{key: "stall.action", env: "ALPINE_STALL_ACTION", value: func(c *Config) string { return c.Stall.Action }},
+	{key: "stall.nudge_prompt", env: "ALPINE_STALL_NUDGE_PROMPT", value: func(c *Config) string { return c.Stall.NudgePrompt }},
+
+	{key: "rollback.check", env: "ALPINE_ROLLBACK_CHECK", value: func(c *Config) string { return c.Rollback.Check }},
+	{key: "rollback.timeout", env: "ALPINE_ROLLBACK_TIMEOUT", duration: true, value: func(c *Config) string { return c.Rollback.Timeout.String() }},
+	{key: "rollback.max_rollbacks", env: "ALPINE_MAX_ROLLBACKS", value: func(c *Config) string { return strconv.Itoa(c.Rollback.MaxRollbacks) }},
+
+	{key: "gates.build", env: "ALPINE_GATE_BUILD", value: func(c *Config) string { return c.Gates.Build }},
+	{key: "gates.lint", env: "ALPINE_GATE_LINT", value: func(c *Config) string { return c.Gates.Lint }},
+	{key: "gates.test", env: "ALPINE_GATE_TEST", value: func(c *Config) string { return c.Gates.Test }},
+	{key: "gates.timeout", env: "ALPINE_GATE_TIMEOUT", duration: true, value: func(c *Config) string { return c.Gates.Timeout.String() }},
+	{key: "gates.max_failures", env: "ALPINE_MAX_GATE_FAILURES", value: func(c *Config) string { return strconv.Itoa(c.Gates.MaxFailures) }},
+
+	{key: "github.api_url", env: "ALPINE_GITHUB_API_URL", userOnly: true, value: func(c *Config) string { return c.GitHub.APIURL }},
+	{key: "github.token", env: "ALPINE_GITHUB_TOKEN", userOnly: true, secret: true, value: func(c *Config) string { return c.GitHub.Token }},
+
+	{key: "retry.max_retries", env: "ALPINE_CLAUDE_MAX_RETRIES", value: func(c *Config) string { return strconv.Itoa(c.Retry.MaxRetries) }},
+	{key: "retry.initial_backoff", env: "ALPINE_CLAUDE_RETRY_BACKOFF", duration: true, value: func(c *Config) string { return c.Retry.InitialBackoff.String() }},
+	{key: "retry.max_backoff", env: "ALPINE_CLAUDE_RETRY_MAX_BACKOFF", duration: true, value: func(c *Config) string { return c.Retry.MaxBackoff.String() }},
+
+	{key: "session.continue", env: "ALPINE_CONTINUE_SESSION", value: func(c *Config) string { return strconv.FormatBool(c.Session.Continue) }},
+	{key: "session.max_context_tokens", env: "ALPINE_SESSION_MAX_TOKENS", value: func(c *Config) string { return strconv.Itoa(c.Session.MaxContextTokens) }},
+	{key: "session.max_iterations", env: "ALPINE_SESSION_MAX_ITERATIONS", value: func(c *Config) string { return strconv.Itoa(c.Session.MaxIterations) }},
+
+	{key: "policy.enabled", env: "ALPINE_POLICY_ENABLED", userOnly: true, value: func(c *Config) string { return strconv.FormatBool(c.Policy.Enabled) }},
+	{key: "policy.worktree_only", env: "ALPINE_POLICY_WORKTREE_ONLY", userOnly: true, value: func(c *Config) string { return strconv.FormatBool(c.Policy.WorktreeOnly) }},
+
+	{key: "sandbox.backend", env: "ALPINE_SANDBOX", userOnly: true, value: func(c *Config) string { return c.Sandbox.Backend }},
+	{key: "sandbox.allowed_hosts", env: "ALPINE_SANDBOX_ALLOWED_HOSTS", userOnly: true, list: true, value: func(c *Config) string { return strings.Join(c.Sandbox.AllowedHosts, ",") }},
+	{key: "sandbox.writable", env: "ALPINE_SANDBOX_WRITABLE", userOnly: true, list: true, value: func(c *Config) string { return strings.Join(c.Sandbox.Writable, ",") }},
+	{key: "sandbox.hide", env: "ALPINE_SANDBOX_HIDE", userOnly: true, list: true, value: func(c *Config) string { return strings.Join(c.Sandbox.Hide, ",") }},
+}
+
+// warnings receives the warnings about ignored settings
+var warnings io.Writer = os.Stderr
+
+// policyRuleKeys are the policy sections holding rules rather than settings
+var policyRuleKeys = []string{"bash", "paths"}
+
+// settingByKey returns the setting with the given config file key
+func settingByKey(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// settingKeyForEnv returns the config file key of the setting read from env
+func settingKeyForEnv(env string) string {
+	for _, s := range settings {
+		if s.env == env {
+			return s.key
+		}
+	}
+	return env
+}
+
+// layer holds the values of one configuration source, keyed by environment
+// variable name
+type layer struct {
+	origin Origin
+	values map[string]string
+
+	// profiles are the permission profiles defined in the file
+	profiles map[string]PermissionProfile
+
+	// mcpServers are the MCP servers declared in the file
+	mcpServers map[string]MCPServer
+
+	// policyRules are the policy rules in the file, keyed by policyRuleKeys
+	policyRules map[string]policy.Rules
+}
+
+// source looks up settings by environment variable name in the environment,
+// then the repository config file, then the user config file, and records
+// which of them each value came from
+type source struct {
+	layers  []layer
+	origins Origins
+}
+
+// newSource creates a source reading the environment and the given files,
+// the first file taking precedence over the second. Empty environment
+// variables count as unset.
+func newSource(files ...layer) *source {
+	env := layer{origin: Origin{Source: OriginEnv}, values: map[string]string{}}
+	for _, s := range settings {
+		if value := os.Getenv(s.env); value != "" {
+			env.values[s.env] = value
+		}
+	}
+	return &source{layers: append([]layer{env}, files...), origins: Origins{}}
+}
+
+// lookup returns the value of the setting read from env and whether any
+// source sets it
+func (s *source) lookup(env string) (string, bool) {
+	for _, l := range s.layers {
+		if value, ok := l.values[env]; ok {
+			s.origins[settingKeyForEnv(env)] = l.origin
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// profiles returns the permission profiles defined in config files, those in
+// earlier layers replacing same-named ones in later layers
+func (s *source) profiles() map[string]PermissionProfile {
+	var profiles map[string]PermissionProfile
+	for i := len(s.layers) - 1; i >= 0; i-- {
+		l := s.layers[i]
+		for name, p := range l.profiles {
+			if profiles == nil {
+				profiles = map[string]PermissionProfile{}
+			}
+			profiles[name] = p
+			s.origins["profiles."+name] = l.origin
+		}
+	}
+	return profiles
+}
+
+// mcpServers returns the MCP servers declared in config files, those in
+// earlier layers replacing same-named ones in later layers
+func (s *source) mcpServers() map[string]MCPServer {
+	var servers map[string]MCPServer
+	for i := len(s.layers) - 1; i >= 0; i-- {
+		l := s.layers[i]
+		for name, server := range l.mcpServers {
+			if servers == nil {
+				servers = map[string]MCPServer{}
+			}
+			servers[name] = server
+			s.origins["mcp_servers."+name] = l.origin
+		}
+	}
+	return servers
+}
+
+// policy returns the policy rules in config files. Unlike other settings,
+// rules from every file apply, the user file's first.
+func (s *source) policy() PolicyConfig {
+	var p PolicyConfig
+	for i := len(s.layers) - 1; i >= 0; i-- {
+		l := s.layers[i]
+		for _, key := range policyRuleKeys {
+			rules, ok := l.policyRules[key]
+			if !ok {
+				continue
+			}
+			target := &p.Bash
+			if key == "paths" {
+				target = &p.Paths
+			}
+			target.Allow = append(target.Allow, rules.Allow...)
+			target.Deny = append(target.Deny, rules.Deny...)
+			s.origins["policy."+key] = l.origin
+		}
+	}
+	return p
+}
+
+// get returns the value of the setting read from env, empty when no source sets it
+func (s *source) get(env string) string {
+	value, _ := s.lookup(env)
+	return value
+}
+
+// loadFileLayer reads a config file into a layer. A missing file yields an
+// empty layer. User-only settings in the repository file are ignored with a
+// warning.
+func loadFileLayer(path, originSource string) (layer, error) {
+	l := layer{origin: Origin{Source: originSource, Path: path}, values: map[string]string{}}
+	if path == "" {
+		return l, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return l, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return l, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if raw, ok := doc["profiles"]; ok {
+		delete(doc, "profiles")
+		if err := decodeSection(raw, &l.profiles); err != nil {
+			return l, fmt.Errorf("invalid config file %s: profiles: %w", path, err)
+		}
+	}
+	if raw, ok := doc["mcp_servers"]; ok {
+		delete(doc, "mcp_servers")
+		if err := decodeSection(raw, &l.mcpServers); err != nil {
+			return l, fmt.Errorf("invalid config file %s: mcp_servers: %w", path, err)
+		}
+		for name, server := range l.mcpServers {
+			if err := server.validate(); err != nil {
+				return l, fmt.Errorf("invalid config file %s: mcp_servers.%s: %w", path, name, err)
+			}
+		}
+	}
+	if section, ok := doc["policy"].(map[string]interface{}); ok {
+		for _, key := range policyRuleKeys {
+			raw, ok := section[key]
+			if !ok {
+				continue
+			}
+			delete(section, key)
+			var rules policy.Rules
+			if err := decodeSection(raw, &rules); err != nil {
+				return l, fmt.Errorf("invalid config file %s: policy.%s: %w", path, key, err)
+			}
+			if err := rules.Validate(); err != nil {
+				return l, fmt.Errorf("invalid config file %s: policy.%s: %w", path, key, err)
+			}
+			if l.policyRules == nil {
+				l.policyRules = map[string]policy.Rules{}
+			}
+			l.policyRules[key] = rules
+		}
+	}
+	if err := flattenInto(l.values, "", doc); err != nil {
+		return l, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if originSource == OriginRepoFile {
+		for _, s := range settings {
+			if _, ok := l.values[s.env]; ok && s.userOnly {
+				delete(l.values, s.env)
+				_, _ = fmt.Fprintf(warnings, "Warning: ignoring %s in %s; set it in the user config file or with %s\n", s.key, path, s.env)
+			}
+		}
+	}
+	return l, nil
+}
+
+// decodeSection decodes a structured section of a config file into out,
+// rejecting unknown keys
+func decodeSection(raw interface{}, out interface{}) error {
+	data, err := yaml.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// flattenInto stores the values of a decoded YAML mapping in values, keyed
+// by the environment variable of the setting at each dotted key path
+func flattenInto(values map[string]string, prefix string, doc map[string]interface{}) error {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		raw := doc[key]
+		if nested, ok := raw.(map[string]interface{}); ok {
+			if err := flattenInto(values, path, nested); err != nil {
+				return err
+			}
+			continue
+		}
+
+		s, ok := settingByKey(path)
+		if !ok {
+			return fmt.Errorf("unknown setting %q", path)
+		}
+		if raw == nil {
+			continue
+		}
+		value, err := fileValue(s, raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		values[s.env] = value
+	}
+	return nil
+}
+
+// fileValue converts a YAML value to the string form of the setting's
+// environment variable
+func fileValue(s setting, raw interface{}) (string, error) {
+	switch v := raw.(type) {
+	case []interface{}:
+		if !s.list {
+			return "", fmt.Errorf("must be a single value, got a list")
+		}
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ","), nil
+	case string:
+		if s.duration {
+			if _, err := strconv.Atoi(v); err == nil {
+				return v, nil
+			}
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return "", fmt.Errorf("must be a number of seconds or a duration like 90s, got: %s", v)
+			}
+			if d%time.Second != 0 {
+				return "", fmt.Errorf("must be a whole number of seconds, got: %s", v)
+			}
+			return strconv.Itoa(int(d / time.Second)), nil
+		}
+		return v, nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// UserFilePath returns the user's config file, e.g.
+// ~/.config/alpine/config.yaml, or "" when it cannot be determined
+func UserFilePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "alpine", "config.yaml")
+}
+
+// FindRepoFile returns the RepoFileName in dir or its closest parent, stopping
+// at the repository root (the first directory containing .git). It returns ""
+// when there is none.
+func FindRepoFile(dir string) string {
+	for {
+		path := filepath.Join(dir, RepoFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Describe returns every setting of cfg with its effective value and origin,
+// followed by the permission profiles, MCP servers and policy rules defined in
+// config files. Settings without a recorded origin are reported as defaults.
+func Describe(cfg *Config, origins Origins) []Setting {
+	described := make([]Setting, 0, len(settings))
+	for _, s := range settings {
+		origin, ok := origins[s.key]
+		if !ok {
+			origin = Origin{Source: OriginDefault}
+		}
+		value := s.value(cfg)
+		if s.secret && value != "" {
+			value = "********"
+		}
+		described = append(described, Setting{Key: s.key, Env: s.env, Value: value, Origin: origin})
+	}
+
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := cfg.Profiles[name]
+		value := "allow=" + strings.Join(p.AllowedTools, ",")
+		if len(p.DisallowedTools) > 0 {
+			value += " deny=" + strings.Join(p.DisallowedTools, ",")
+		}
+		key := "profiles." + name
+		described = append(described, Setting{Key: key, Value: value, Origin: origins[key]})
+	}
+
+	names = names[:0]
+	for name := range cfg.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key := "mcp_servers." + name
+		described = append(described, Setting{Key: key, Value: cfg.MCPServers[name].describe(), Origin: origins[key]})
+	}
+
+	for _, key := range policyRuleKeys {
+		rules := cfg.Policy.Bash
+		if key == "paths" {
+			rules = cfg.Policy.Paths
+		}
+		var parts []string
+		if len(rules.Allow) > 0 {
+			parts = append(parts, "allow="+strings.Join(rules.Allow, ","))
+		}
+		if len(rules.Deny) > 0 {
+			parts = append(parts, "deny="+strings.Join(rules.Deny, ","))
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		described = append(described, Setting{Key: "policy." + key, Value: strings.Join(parts, " "), Origin: origins["policy."+key]})
+	}
+	return described
+}
@@ -0,0 +1,377 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// configDirs creates a repository and a user config directory holding the
+// given config files, points ALPINE_WORKDIR and XDG_CONFIG_HOME at them and
+// returns the paths of the two files
+func configDirs(t *testing.T, repoYAML, userYAML string) (repoFile, userFile string) {
+	t.Helper()
+	for _, s := range settings {
+		t.Setenv(s.env, "")
+	}
+
+	repo := t.TempDir()
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create .git: %v", err)
+	}
+	repoFile = filepath.Join(repo, RepoFileName)
+	if repoYAML != "" {
+		if err := os.WriteFile(repoFile, []byte(repoYAML), 0644); err != nil {
+			t.Fatalf("Failed to write repo config: %v", err)
+		}
+	}
+
+	userDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", userDir)
+	userFile = filepath.Join(userDir, "alpine", "config.yaml")
+	if userYAML != "" {
+		if err := os.MkdirAll(filepath.Dir(userFile), 0755); err != nil {
+			t.Fatalf("Failed to create user config dir: %v", err)
+		}
+		if err := os.WriteFile(userFile, []byte(userYAML), 0644); err != nil {
+			t.Fatalf("Failed to write user config: %v", err)
+		}
+	}
+
+	t.Setenv("ALPINE_WORKDIR", repo)
+	return repoFile, userFile
+}
+
+// TestLoad_Precedence tests that env overrides the repo file, which overrides
+// the user file, which overrides defaults
+func TestLoad_Precedence(t *testing.T) {
+	repoFile, userFile := configDirs(t, `
+model: repo-model
+git:
+  base_branch: develop
+budget:
+  max_duration: 2h
+`, `
+model: user-model
+plan_model: user-plan-model
+git:
+  base_branch: user-branch
+  clone:
+    depth: 5
+budget:
+  max_duration: 30m
+  max_iterations: 7
+`)
+	t.Setenv("ALPINE_GIT_BASE_BRANCH", "env-branch")
+
+	cfg, origins, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+
+	if cfg.Model != "repo-model" {
+		t.Errorf("Model = %q, want repo-model", cfg.Model)
+	}
+	if cfg.PlanModel != "user-plan-model" {
+		t.Errorf("PlanModel = %q, want user-plan-model", cfg.PlanModel)
+	}
+	if cfg.Git.BaseBranch != "env-branch" {
+		t.Errorf("Git.BaseBranch = %q, want env-branch", cfg.Git.BaseBranch)
+	}
+	if cfg.Git.Clone.Depth != 5 {
+		t.Errorf("Git.Clone.Depth = %d, want 5", cfg.Git.Clone.Depth)
+	}
+	if cfg.Budget.MaxDuration != 2*time.Hour {
+		t.Errorf("Budget.MaxDuration = %v, want 2h", cfg.Budget.MaxDuration)
+	}
+	if cfg.Budget.MaxIterations != 7 {
+		t.Errorf("Budget.MaxIterations = %d, want 7", cfg.Budget.MaxIterations)
+	}
+
+	want := map[string]Origin{
+		"model":                 {Source: OriginRepoFile, Path: repoFile},
+		"plan_model":            {Source: OriginUserFile, Path: userFile},
+		"git.base_branch":       {Source: OriginEnv},
+		"git.clone.depth":       {Source: OriginUserFile, Path: userFile},
+		"budget.max_duration":   {Source: OriginRepoFile, Path: repoFile},
+		"budget.max_iterations": {Source: OriginUserFile, Path: userFile},
+	}
+	for key, origin := range want {
+		if origins[key] != origin {
+			t.Errorf("origin of %s = %v, want %v", key, origins[key], origin)
+		}
+	}
+	if _, ok := origins["verbosity"]; ok {
+		t.Errorf("verbosity should have no origin, got %v", origins["verbosity"])
+	}
+}
+
+// TestLoad_FileValues tests lists, booleans and durations in config files
+func TestLoad_FileValues(t *testing.T) {
+	configDirs(t, `
+allowed_tools: [Bash, Read, "mcp__context7__*"]
+show_output: false
+stall:
+  grace_period: 10
+  backoff: 1m
+retry:
+  max_retries: 0
+`, "")
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+	if want := []string{"Bash", "Read", "mcp__context7__*"}; !reflect.DeepEqual(cfg.AllowedTools, want) {
+		t.Errorf("AllowedTools = %v, want %v", cfg.AllowedTools, want)
+	}
+	if cfg.ShowOutput {
+		t.Error("ShowOutput = true, want false")
+	}
+	if cfg.Stall.GracePeriod != 10*time.Second {
+		t.Errorf("Stall.GracePeriod = %v, want 10s", cfg.Stall.GracePeriod)
+	}
+	if cfg.Stall.Backoff != time.Minute {
+		t.Errorf("Stall.Backoff = %v, want 1m", cfg.Stall.Backoff)
+	}
+	if cfg.Retry.MaxRetries != 0 {
+		t.Errorf("Retry.MaxRetries = %d, want 0", cfg.Retry.MaxRetries)
+	}
+}
+
+// TestLoad_AllowedToolsEnv tests the comma-separated ALPINE_ALLOWED_TOOLS
+func TestLoad_AllowedToolsEnv(t *testing.T) {
+	configDirs(t, "allowed_tools: [Read]\n", "")
+	t.Setenv("ALPINE_ALLOWED_TOOLS", "Bash(go test:*), Read,,Edit")
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+	if want := []string{"Bash(go test:*)", "Read", "Edit"}; !reflect.DeepEqual(cfg.AllowedTools, want) {
+		t.Errorf("AllowedTools = %v, want %v", cfg.AllowedTools, want)
+	}
+}
+
+// TestLoad_InvalidFiles tests that bad config files are reported with their path
+func TestLoad_InvalidFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"unknown key", "git:\n  branch: main\n", `unknown setting "git.branch"`},
+		{"list for scalar", "model: [a, b]\n", "model: must be a single value"},
+		{"bad duration", "budget:\n  max_duration: soon\n", "budget.max_duration: must be a number of seconds"},
+		{"fractional duration", "stall:\n  backoff: 1500ms\n", "whole number of seconds"},
+		{"malformed", "model: [\n", "invalid config file"},
+		{"invalid value", "output_format: xml\n", "ALPINE_OUTPUT_FORMAT must be one of"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoFile, _ := configDirs(t, tt.yaml, "")
+			_, err := New()
+			if err == nil {
+				t.Fatal("New() succeeded, want error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.wantErr)
+			}
+			if tt.name != "invalid value" && !strings.Contains(err.Error(), repoFile) {
+				t.Errorf("error = %q, want it to name %s", err, repoFile)
+			}
+		})
+	}
+}
+
+// TestLoad_Policy tests that policy rules from both files apply and the
+// policy switches follow the usual precedence
+func TestLoad_Policy(t *testing.T) {
+	repoFile, userFile := configDirs(t, `
+policy:
+  bash:
+    deny: ["npm publish*"]
+`, `
+policy:
+  enabled: true
+  worktree_only: false
+  bash:
+    allow: ["go *", "git *"]
+    deny: ["re:^git reset --hard"]
+  paths:
+    deny: ["**/.env"]
+`)
+
+	cfg, origins, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if !cfg.Policy.Enabled || cfg.Policy.WorktreeOnly {
+		t.Errorf("Policy = %+v, want enabled and not worktree only", cfg.Policy)
+	}
+	if want := []string{"re:^git reset --hard", "npm publish*"}; !reflect.DeepEqual(cfg.Policy.Bash.Deny, want) {
+		t.Errorf("Policy.Bash.Deny = %v, want %v", cfg.Policy.Bash.Deny, want)
+	}
+	if want := []string{"go *", "git *"}; !reflect.DeepEqual(cfg.Policy.Bash.Allow, want) {
+		t.Errorf("Policy.Bash.Allow = %v, want %v", cfg.Policy.Bash.Allow, want)
+	}
+	if want := []string{"**/.env"}; !reflect.DeepEqual(cfg.Policy.Paths.Deny, want) {
+		t.Errorf("Policy.Paths.Deny = %v, want %v", cfg.Policy.Paths.Deny, want)
+	}
+	if origins["policy.bash"].Path != repoFile || origins["policy.paths"].Path != userFile {
+		t.Errorf("policy origins = %v, %v", origins["policy.bash"], origins["policy.paths"])
+	}
+	if origins["policy.worktree_only"].Path != userFile {
+		t.Errorf("origin of policy.worktree_only = %v, want the user file", origins["policy.worktree_only"])
+	}
+
+	byKey := map[string]Setting{}
+	for _, s := range Describe(cfg, origins) {
+		byKey[s.Key] = s
+	}
+	if got := byKey["policy.paths"].Value; got != "deny=**/.env" {
+		t.Errorf("policy.paths described as %q", got)
+	}
+
+	t.Setenv("ALPINE_POLICY_ENABLED", "false")
+	cfg, err = New()
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+	if cfg.Policy.Enabled {
+		t.Error("Policy.Enabled = true, want false from ALPINE_POLICY_ENABLED")
+	}
+}
+
+// TestLoad_UserOnlySettings tests that the repository file cannot set
+// settings guarding the user, which the user file and environment still can
+func TestLoad_UserOnlySettings(t *testing.T) {
+	repoFile, _ := configDirs(t, `
+model: repo-model
+github:
+  api_url: https://attacker.example
+policy:
+  enabled: false
+sandbox:
+  backend: none
+  allowed_hosts: [attacker.example]
+`, `
+policy:
+  enabled: true
+sandbox:
+  backend: bwrap
+`)
+	var warned strings.Builder
+	warnings = &warned
+	t.Cleanup(func() { warnings = os.Stderr })
+
+	cfg, origins, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.Model != "repo-model" {
+		t.Errorf("Model = %q, want repo-model", cfg.Model)
+	}
+	if cfg.GitHub.APIURL != DefaultGitHubAPIURL || !cfg.Policy.Enabled || cfg.Sandbox.Backend != SandboxBubblewrap || !reflect.DeepEqual(cfg.Sandbox.AllowedHosts, DefaultSandboxAllowedHosts) {
+		t.Errorf("user-only settings taken from the repo file: api_url=%q policy=%+v sandbox=%+v", cfg.GitHub.APIURL, cfg.Policy, cfg.Sandbox)
+	}
+	if origins["sandbox.backend"].Source != OriginUserFile {
+		t.Errorf("origin of sandbox.backend = %v, want the user file", origins["sandbox.backend"])
+	}
+	for _, key := range []string{"github.api_url", "policy.enabled", "sandbox.backend", "sandbox.allowed_hosts"} {
+		if want := "Warning: ignoring " + key + " in " + repoFile; !strings.Contains(warned.String(), want) {
+			t.Errorf("warnings %q should contain %q", warned.String(), want)
+		}
+	}
+
+	t.Setenv("ALPINE_GITHUB_API_URL", "https://github.example/api/v3")
+	cfg, err = New()
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+	if cfg.GitHub.APIURL != "https://github.example/api/v3" {
+		t.Errorf("GitHub.APIURL = %q, want the environment's", cfg.GitHub.APIURL)
+	}
+}
+
+// TestLoad_InvalidPolicy tests that malformed policy rules are rejected
+func TestLoad_InvalidPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"bad regex", "policy:\n  bash:\n    deny: [\"re:(\"]\n", `policy.bash: invalid rule "re:("`},
+		{"unknown rule key", "policy:\n  paths:\n    block: [a]\n", "policy.paths:"},
+		{"unknown setting", "policy:\n  strict: true\n", `unknown setting "policy.strict"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configDirs(t, tt.yaml, "")
+			_, err := New()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestFindRepoFile tests that the repo file is found up to the repository root
+func TestFindRepoFile(t *testing.T) {
+	outer := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outer, RepoFileName), []byte("model: outer\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repo := filepath.Join(outer, "repo")
+	sub := filepath.Join(repo, "a", "b")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := FindRepoFile(sub); got != "" {
+		t.Errorf("FindRepoFile() = %q, want none beyond the repository root", got)
+	}
+
+	repoFile := filepath.Join(repo, RepoFileName)
+	if err := os.WriteFile(repoFile, []byte("model: repo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindRepoFile(sub); got != repoFile {
+		t.Errorf("FindRepoFile() = %q, want %q", got, repoFile)
+	}
+}
+
+// TestDescribe tests that every setting is described and secrets are masked
+func TestDescribe(t *testing.T) {
+	configDirs(t, "", "git:\n  clone:\n    auth_token: ghp_secret\n")
+
+	cfg, origins, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	described := Describe(cfg, origins)
+	if len(described) != len(settings) {
+		t.Fatalf("Describe() returned %d settings, want %d", len(described), len(settings))
+	}
+
+	byKey := map[string]Setting{}
+	for _, s := range described {
+		byKey[s.Key] = s
+	}
+	token := byKey["git.clone.auth_token"]
+	if token.Value != "********" || token.Origin.Source != OriginUserFile {
+		t.Errorf("auth token described as %+v, want masked from the user file", token)
+	}
+	if retry := byKey["retry.max_backoff"]; retry.Value != "2m0s" || retry.Origin.Source != OriginDefault {
+		t.Errorf("retry.max_backoff described as %+v, want default 2m0s", retry)
+	}
+	if workdir := byKey["workdir"]; workdir.Origin.Source != OriginEnv {
+		t.Errorf("workdir origin = %v, want env", workdir.Origin)
+	}
+}
@@ -0,0 +1,44 @@
+package config
+
+import "time"
+
+// Quality gates, in the order they run
+const (
+	GateBuild = "build"
+	GateLint  = "lint"
+	GateTest  = "test"
+)
+
+// GatesConfig holds the quality gate commands alpine runs itself after each
+// iteration. A run is only accepted as completed once every gate passes.
+type GatesConfig struct {
+	// Build, Lint and Test are shell commands run in the working directory;
+	// an empty command disables the gate
+	Build string
+	Lint  string
+	Test  string
+
+	// Timeout bounds each gate command
+	Timeout time.Duration
+
+	// MaxFailures is the number of consecutive iterations failing a gate
+	// after which the run fails
+	MaxFailures int
+}
+
+// Gate is a configured quality gate
+type Gate struct {
+	Name    string
+	Command string
+}
+
+// List returns the configured gates in the order they run
+func (g GatesConfig) List() []Gate {
+	var gates []Gate
+	for _, gate := range []Gate{{GateBuild, g.Build}, {GateLint, g.Lint}, {GateTest, g.Test}} {
+		if gate.Command != "" {
+			gates = append(gates, gate)
+		}
+	}
+	return gates
+}
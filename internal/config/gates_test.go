@@ -0,0 +1,68 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGatesConfig tests loading quality gates from the repository file and environment
+func TestGatesConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		configDirs(t, "", "")
+		cfg, err := New()
+		if err != nil {
+			t.Fatalf("New() returned unexpected error: %v", err)
+		}
+		if gates := cfg.Gates.List(); gates != nil {
+			t.Errorf("Gates.List() = %v, want no gates", gates)
+		}
+		if cfg.Gates.Timeout != 10*time.Minute {
+			t.Errorf("Gates.Timeout = %v, want 10m", cfg.Gates.Timeout)
+		}
+		if cfg.Gates.MaxFailures != 3 {
+			t.Errorf("Gates.MaxFailures = %d, want 3", cfg.Gates.MaxFailures)
+		}
+	})
+
+	t.Run("repository file and environment", func(t *testing.T) {
+		repoFile, _ := configDirs(t, `
+gates:
+  build: go build ./...
+  test: go test ./...
+  timeout: 5m
+`, "")
+		t.Setenv("ALPINE_GATE_TEST", "make test")
+		t.Setenv("ALPINE_MAX_GATE_FAILURES", "0")
+
+		cfg, origins, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned unexpected error: %v", err)
+		}
+		want := []Gate{{Name: GateBuild, Command: "go build ./..."}, {Name: GateTest, Command: "make test"}}
+		if gates := cfg.Gates.List(); !reflect.DeepEqual(gates, want) {
+			t.Errorf("Gates.List() = %v, want %v", gates, want)
+		}
+		if cfg.Gates.Timeout != 5*time.Minute {
+			t.Errorf("Gates.Timeout = %v, want 5m", cfg.Gates.Timeout)
+		}
+		if cfg.Gates.MaxFailures != 0 {
+			t.Errorf("Gates.MaxFailures = %d, want 0", cfg.Gates.MaxFailures)
+		}
+		if origin := origins["gates.build"]; origin.Source != OriginRepoFile || origin.Path != repoFile {
+			t.Errorf("origins[gates.build] = %+v, want the repository file", origin)
+		}
+		if origin := origins["gates.test"]; origin.Source != OriginEnv {
+			t.Errorf("origins[gates.test] = %+v, want the environment", origin)
+		}
+	})
+
+	t.Run("zero timeout", func(t *testing.T) {
+		configDirs(t, "", "")
+		t.Setenv("ALPINE_GATE_TIMEOUT", "0")
+		if _, err := New(); err == nil || !strings.Contains(err.Error(), "ALPINE_GATE_TIMEOUT must be positive") {
+			t.Errorf("New() error = %v", err)
+		}
+	})
+}
@@ -0,0 +1,26 @@
+package config
+
+import "os"
+
+// DefaultGitHubAPIURL is the GitHub REST API used when none is configured
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+// GitHubConfig holds the GitHub REST API alpine opens pull requests through
+type GitHubConfig struct {
+	// APIURL is the base URL of the REST API, e.g. https://ghe.example.com/api/v3
+	APIURL string
+
+	// Token authenticates API requests; see AuthToken for the fallbacks
+	Token string
+}
+
+// AuthToken returns the token for GitHub API requests: Token, the clone
+// token, or the GITHUB_TOKEN environment variable, in that order
+func (c *Config) AuthToken() string {
+	for _, token := range []string{c.GitHub.Token, c.Git.Clone.AuthToken} {
+		if token != "" {
+			return token
+		}
+	}
+	return os.Getenv("GITHUB_TOKEN")
+}
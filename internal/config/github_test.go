@@ -0,0 +1,72 @@
+package config
+
+import "testing"
+
+// TestPullRequestConfig tests loading the pull request option and the GitHub API settings
+func TestPullRequestConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		configDirs(t, "", "")
+		cfg, err := New()
+		if err != nil {
+			t.Fatalf("New() returned unexpected error: %v", err)
+		}
+		if cfg.Git.PullRequest {
+			t.Error("Git.PullRequest = true, want false")
+		}
+		if cfg.GitHub.APIURL != DefaultGitHubAPIURL {
+			t.Errorf("GitHub.APIURL = %q, want %q", cfg.GitHub.APIURL, DefaultGitHubAPIURL)
+		}
+	})
+
+	t.Run("config files and environment", func(t *testing.T) {
+		repoFile, _ := configDirs(t, `
+git:
+  pull_request: true
+`, `
+github:
+  api_url: https://ghe.example.com/api/v3/
+`)
+		t.Setenv("ALPINE_GITHUB_TOKEN", "secret")
+
+		cfg, origins, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned unexpected error: %v", err)
+		}
+		if !cfg.Git.PullRequest {
+			t.Error("Git.PullRequest = false, want true")
+		}
+		if cfg.GitHub.APIURL != "https://ghe.example.com/api/v3" {
+			t.Errorf("GitHub.APIURL = %q, want the trailing slash trimmed", cfg.GitHub.APIURL)
+		}
+		if origin := origins["git.pull_request"]; origin.Source != OriginRepoFile || origin.Path != repoFile {
+			t.Errorf("origins[git.pull_request] = %+v, want the repository file", origin)
+		}
+		if token := cfg.AuthToken(); token != "secret" {
+			t.Errorf("AuthToken() = %q, want secret", token)
+		}
+	})
+
+	t.Run("token fallbacks", func(t *testing.T) {
+		configDirs(t, "", "")
+		t.Setenv("GITHUB_TOKEN", "from-env")
+		cfg, err := New()
+		if err != nil {
+			t.Fatalf("New() returned unexpected error: %v", err)
+		}
+		if token := cfg.AuthToken(); token != "from-env" {
+			t.Errorf("AuthToken() = %q, want GITHUB_TOKEN", token)
+		}
+		cfg.Git.Clone.AuthToken = "clone"
+		if token := cfg.AuthToken(); token != "clone" {
+			t.Errorf("AuthToken() = %q, want the clone token", token)
+		}
+	})
+
+	t.Run("invalid boolean", func(t *testing.T) {
+		configDirs(t, "", "")
+		t.Setenv("ALPINE_GIT_PULL_REQUEST", "maybe")
+		if _, err := New(); err == nil {
+			t.Error("New() should reject an invalid ALPINE_GIT_PULL_REQUEST")
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Workflow phases MCP servers can be enabled for
+const (
+	// PhasePlan is plan generation (alpine plan and the workflow's planning iteration)
+	PhasePlan = "plan"
+	// PhaseExecute is every implementation iteration
+	PhaseExecute = "execute"
+)
+
+// MCP transports for servers reached over the network
+const (
+	MCPTransportHTTP = "http"
+	MCPTransportSSE  = "sse"
+)
+
+// MCPServer is an MCP server declared in a config file. A server is either a
+// stdio command or a URL.
+type MCPServer struct {
+	// Command starts a stdio server; Args and Env are passed to it
+	Command string            `yaml:"command"`
+	Args    []string          `yaml:"args"`
+	Env     map[string]string `yaml:"env"`
+
+	// URL is the endpoint of a network server, reached with Transport
+	// (http by default, or sse) and sent Headers
+	URL       string            `yaml:"url"`
+	Transport string            `yaml:"transport"`
+	Headers   map[string]string `yaml:"headers"`
+
+	// Phases are the workflow phases the server is enabled for; empty means all
+	Phases []string `yaml:"phases"`
+}
+
+// validate checks that the server has exactly one of a command and a URL,
+// and valid phases and transport
+func (s MCPServer) validate() error {
+	switch {
+	case s.Command == "" && s.URL == "":
+		return fmt.Errorf("needs a command or a url")
+	case s.Command != "" && s.URL != "":
+		return fmt.Errorf("cannot have both a command and a url")
+	case s.Command != "" && s.Transport != "":
+		return fmt.Errorf("transport only applies to url servers")
+	}
+	switch s.Transport {
+	case "", MCPTransportHTTP, MCPTransportSSE:
+	default:
+		return fmt.Errorf("transport must be one of: http, sse; got: %s", s.Transport)
+	}
+	for _, phase := range s.Phases {
+		if phase != PhasePlan && phase != PhaseExecute {
+			return fmt.Errorf("phases must be %s or %s, got: %s", PhasePlan, PhaseExecute, phase)
+		}
+	}
+	return nil
+}
+
+// EnabledFor reports whether the server is enabled for phase
+func (s MCPServer) EnabledFor(phase string) bool {
+	if len(s.Phases) == 0 {
+		return true
+	}
+	for _, p := range s.Phases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// describe summarizes the server for Describe
+func (s MCPServer) describe() string {
+	var target string
+	if s.Command != "" {
+		target = strings.Join(append([]string{s.Command}, s.Args...), " ")
+	} else {
+		transport := s.Transport
+		if transport == "" {
+			transport = MCPTransportHTTP
+		}
+		target = transport + " " + s.URL
+	}
+	if len(s.Phases) > 0 {
+		target += " (" + strings.Join(s.Phases, ",") + ")"
+	}
+	return target
+}
+
+// MCPServersFor returns the configured MCP servers enabled for phase, or nil
+// when there are none
+func (c *Config) MCPServersFor(phase string) map[string]MCPServer {
+	var servers map[string]MCPServer
+	for name, s := range c.MCPServers {
+		if !s.EnabledFor(phase) {
+			continue
+		}
+		if servers == nil {
+			servers = map[string]MCPServer{}
+		}
+		servers[name] = s
+	}
+	return servers
+}
+
+// ValidateMCPCommands checks that the command of every stdio MCP server can
+// be found, so a run fails at startup rather than when Claude connects
+func (c *Config) ValidateMCPCommands() error {
+	names := make([]string, 0, len(c.MCPServers))
+	for name := range c.MCPServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := c.MCPServers[name]
+		if s.Command == "" {
+			continue
+		}
+		if _, err := exec.LookPath(s.Command); err != nil {
+			return fmt.Errorf("MCP server %q: command %q not found: %w", name, s.Command, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestLoad_MCPServers tests MCP servers declared in config files
+func TestLoad_MCPServers(t *testing.T) {
+	repoFile, userFile := configDirs(t, `
+mcp_servers:
+  github:
+    url: https://api.githubcopilot.com/mcp/
+    headers:
+      Authorization: Bearer token
+    phases: [execute]
+`, `
+mcp_servers:
+  github:
+    command: github-mcp
+  context7:
+    command: npx
+    args: [-y, "@upstash/context7-mcp"]
+    env:
+      DEBUG: "1"
+    phases: [plan]
+`)
+
+	cfg, origins, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	github := cfg.MCPServers["github"]
+	if github.URL != "https://api.githubcopilot.com/mcp/" || github.Command != "" {
+		t.Errorf("github server = %+v, want the repo file's", github)
+	}
+	context7 := cfg.MCPServers["context7"]
+	if !reflect.DeepEqual(context7.Args, []string{"-y", "@upstash/context7-mcp"}) || context7.Env["DEBUG"] != "1" {
+		t.Errorf("context7 server = %+v", context7)
+	}
+	if origins["mcp_servers.github"].Path != repoFile || origins["mcp_servers.context7"].Path != userFile {
+		t.Errorf("MCP server origins = %v, %v", origins["mcp_servers.github"], origins["mcp_servers.context7"])
+	}
+
+	if plan := cfg.MCPServersFor(PhasePlan); len(plan) != 1 || plan["context7"].Command != "npx" {
+		t.Errorf("plan servers = %v, want context7", plan)
+	}
+	if exec := cfg.MCPServersFor(PhaseExecute); len(exec) != 1 || exec["github"].URL == "" {
+		t.Errorf("execute servers = %v, want github", exec)
+	}
+
+	byKey := map[string]Setting{}
+	for _, s := range Describe(cfg, origins) {
+		byKey[s.Key] = s
+	}
+	if got := byKey["mcp_servers.context7"].Value; got != "npx -y @upstash/context7-mcp (plan)" {
+		t.Errorf("context7 described as %q", got)
+	}
+	if got := byKey["mcp_servers.github"].Value; got != "http https://api.githubcopilot.com/mcp/ (execute)" {
+		t.Errorf("github described as %q", got)
+	}
+}
+
+// TestLoad_InvalidMCPServers tests that malformed MCP servers are rejected
+func TestLoad_InvalidMCPServers(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"no command or url", "mcp_servers:\n  x:\n    args: [a]\n", "mcp_servers.x: needs a command or a url"},
+		{"command and url", "mcp_servers:\n  x:\n    command: a\n    url: http://b\n", "cannot have both"},
+		{"bad transport", "mcp_servers:\n  x:\n    url: http://b\n    transport: ws\n", "transport must be one of"},
+		{"bad phase", "mcp_servers:\n  x:\n    command: a\n    phases: [review]\n", "phases must be plan or execute"},
+		{"unknown key", "mcp_servers:\n  x:\n    cmd: a\n", "mcp_servers:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configDirs(t, tt.yaml, "")
+			_, err := New()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestConfig_ValidateMCPCommands tests that missing stdio commands are reported
+func TestConfig_ValidateMCPCommands(t *testing.T) {
+	cfg := &Config{MCPServers: map[string]MCPServer{
+		"shell": {Command: "sh"},
+		"web":   {URL: "http://localhost:8080/mcp"},
+	}}
+	if err := cfg.ValidateMCPCommands(); err != nil {
+		t.Errorf("ValidateMCPCommands() returned unexpected error: %v", err)
+	}
+
+	cfg.MCPServers["missing"] = MCPServer{Command: "alpine-test-no-such-mcp-server"}
+	err := cfg.ValidateMCPCommands()
+	if err == nil || !strings.Contains(err.Error(), `MCP server "missing": command "alpine-test-no-such-mcp-server" not found`) {
+		t.Errorf("ValidateMCPCommands() error = %v", err)
+	}
+}
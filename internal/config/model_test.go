@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+// TestModelConfigDefaults tests that models default to DefaultModel
+func TestModelConfigDefaults(t *testing.T) {
+	_ = os.Unsetenv("ALPINE_MODEL")
+	_ = os.Unsetenv("ALPINE_PLAN_MODEL")
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+
+	if cfg.Model != DefaultModel {
+		t.Errorf("Model = %q, want %q (default)", cfg.Model, DefaultModel)
+	}
+	if cfg.PlanModel != "" {
+		t.Errorf("PlanModel = %q, want empty (default)", cfg.PlanModel)
+	}
+	if cfg.PlanningModel() != DefaultModel {
+		t.Errorf("PlanningModel() = %q, want %q", cfg.PlanningModel(), DefaultModel)
+	}
+}
+
+// TestModelConfigEnvironmentVariables tests loading per-phase models from environment
+func TestModelConfigEnvironmentVariables(t *testing.T) {
+	tests := []struct {
+		name              string
+		model             string
+		planModel         string
+		wantModel         string
+		wantPlanningModel string
+	}{
+		{
+			name:              "execution model only",
+			model:             "claude-opus-4-20250514",
+			wantModel:         "claude-opus-4-20250514",
+			wantPlanningModel: "claude-opus-4-20250514",
+		},
+		{
+			name:              "separate planning model",
+			model:             "claude-sonnet-4-20250514",
+			planModel:         "claude-opus-4-20250514",
+			wantModel:         "claude-sonnet-4-20250514",
+			wantPlanningModel: "claude-opus-4-20250514",
+		},
+		{
+			name:              "planning model only",
+			planModel:         "claude-opus-4-20250514",
+			wantModel:         DefaultModel,
+			wantPlanningModel: "claude-opus-4-20250514",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ALPINE_MODEL", tt.model)
+			t.Setenv("ALPINE_PLAN_MODEL", tt.planModel)
+
+			cfg, err := New()
+			if err != nil {
+				t.Fatalf("New() returned unexpected error: %v", err)
+			}
+			if cfg.Model != tt.wantModel {
+				t.Errorf("Model = %q, want %q", cfg.Model, tt.wantModel)
+			}
+			if cfg.PlanningModel() != tt.wantPlanningModel {
+				t.Errorf("PlanningModel() = %q, want %q", cfg.PlanningModel(), tt.wantPlanningModel)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestOutputFormatConfig tests loading the Claude output format from environment
+func TestOutputFormatConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "defaults to text", value: "", want: OutputFormatText},
+		{name: "text", value: "text", want: OutputFormatText},
+		{name: "stream-json", value: "stream-json", want: OutputFormatStreamJSON},
+		{name: "invalid", value: "json", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ALPINE_OUTPUT_FORMAT", tt.value)
+
+			cfg, err := New()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "ALPINE_OUTPUT_FORMAT") {
+					t.Fatalf("New() error = %v, want ALPINE_OUTPUT_FORMAT error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() returned unexpected error: %v", err)
+			}
+			if cfg.OutputFormat != tt.want {
+				t.Errorf("OutputFormat = %q, want %q", cfg.OutputFormat, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Built-in permission profile names
+const (
+	// ProfileReadOnly lets Claude read and search but not change anything
+	ProfileReadOnly = "readonly"
+	// ProfileDefault lets Claude run commands and edit files
+	ProfileDefault = "default"
+	// ProfileFull allows every built-in Claude tool
+	ProfileFull = "full"
+)
+
+// PermissionProfile is a named set of tool permissions, passed to Claude as
+// --allowedTools and --disallowedTools. Entries are tool names, optionally
+// with an argument pattern such as Bash(go test:*).
+type PermissionProfile struct {
+	// AllowedTools are the tools Claude may use without asking
+	AllowedTools []string `yaml:"allowed_tools"`
+
+	// DisallowedTools are the tools Claude must not use; they take precedence
+	// over AllowedTools
+	DisallowedTools []string `yaml:"disallowed_tools"`
+}
+
+// builtinProfiles are the permission profiles available without configuration
+var builtinProfiles = map[string]PermissionProfile{
+	ProfileReadOnly: {
+		AllowedTools:    []string{"Read", "Grep", "Glob", "LS", "WebSearch", "WebFetch", "mcp__context7__*"},
+		DisallowedTools: []string{"Bash", "Write", "Edit", "MultiEdit", "NotebookEdit"},
+	},
+	ProfileDefault: {
+		AllowedTools: []string{"Bash", "Read", "Write", "Edit", "Remove", "TodoWrite", "Task"},
+	},
+	ProfileFull: {
+		AllowedTools: []string{
+			"Bash", "Read", "Write", "Edit", "MultiEdit", "NotebookEdit", "Remove", "Grep", "Glob", "LS",
+			"WebSearch", "WebFetch", "TodoWrite", "Task",
+		},
+	},
+}
+
+// BuiltinProfile returns a copy of the named built-in permission profile
+func BuiltinProfile(name string) (PermissionProfile, bool) {
+	p, ok := builtinProfiles[name]
+	if !ok {
+		return PermissionProfile{}, false
+	}
+	return PermissionProfile{
+		AllowedTools:    append([]string(nil), p.AllowedTools...),
+		DisallowedTools: append([]string(nil), p.DisallowedTools...),
+	}, true
+}
+
+// ProfileNames returns the names of the built-in profiles and those defined
+// in c.Profiles
+func (c *Config) ProfileNames() []string {
+	seen := map[string]bool{}
+	var names []string
+	for name := range builtinProfiles {
+		seen[name] = true
+		names = append(names, name)
+	}
+	for name := range c.Profiles {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PermissionProfile returns the named profile. Profiles defined in config
+// files replace built-in profiles of the same name.
+func (c *Config) PermissionProfile(name string) (PermissionProfile, error) {
+	if p, ok := c.Profiles[name]; ok {
+		return p, nil
+	}
+	if p, ok := BuiltinProfile(name); ok {
+		return p, nil
+	}
+	return PermissionProfile{}, fmt.Errorf("unknown permission profile %q (available: %s)",
+		name, strings.Join(c.ProfileNames(), ", "))
+}
+
+// ExecutionPermissions returns the permission profile for implementation
+// iterations, the default profile when none is set. A configured AllowedTools
+// list replaces the profile's allowed tools.
+func (c *Config) ExecutionPermissions() (PermissionProfile, error) {
+	name := c.Profile
+	if name == "" {
+		name = ProfileDefault
+	}
+	p, err := c.PermissionProfile(name)
+	if err != nil {
+		return PermissionProfile{}, err
+	}
+	if len(c.AllowedTools) > 0 {
+		p.AllowedTools = c.AllowedTools
+	}
+	return p, nil
+}
+
+// PlanningPermissions returns the permission profile for the workflow's
+// planning iteration, falling back to the execution profile
+func (c *Config) PlanningPermissions() (PermissionProfile, error) {
+	if c.PlanProfile == "" {
+		return c.ExecutionPermissions()
+	}
+	return c.PermissionProfile(c.PlanProfile)
+}
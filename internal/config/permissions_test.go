@@ -0,0 +1,150 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestBuiltinProfiles tests the built-in permission profiles
+func TestBuiltinProfiles(t *testing.T) {
+	readonly, ok := BuiltinProfile(ProfileReadOnly)
+	if !ok {
+		t.Fatal("readonly profile missing")
+	}
+	for _, tool := range []string{"Bash", "Write", "Edit"} {
+		if !contains(readonly.DisallowedTools, tool) {
+			t.Errorf("readonly profile should disallow %s, got %v", tool, readonly.DisallowedTools)
+		}
+	}
+
+	full, _ := BuiltinProfile(ProfileFull)
+	if !contains(full.AllowedTools, "MultiEdit") || !contains(full.AllowedTools, "WebFetch") {
+		t.Errorf("full profile should allow every built-in tool, got %v", full.AllowedTools)
+	}
+	defaultProfile, _ := BuiltinProfile(ProfileDefault)
+	for _, tool := range defaultProfile.AllowedTools {
+		if !contains(full.AllowedTools, tool) {
+			t.Errorf("full profile should allow %s, which the default profile allows, got %v", tool, full.AllowedTools)
+		}
+	}
+
+	// Callers get copies they may modify
+	readonly.AllowedTools[0] = "changed"
+	if again, _ := BuiltinProfile(ProfileReadOnly); again.AllowedTools[0] == "changed" {
+		t.Error("BuiltinProfile returned a shared slice")
+	}
+
+	if _, ok := BuiltinProfile("nope"); ok {
+		t.Error("BuiltinProfile(nope) should not exist")
+	}
+}
+
+// TestConfig_PermissionProfiles tests profile resolution per phase
+func TestConfig_PermissionProfiles(t *testing.T) {
+	cfg := &Config{
+		Profile: "ci",
+		Profiles: map[string]PermissionProfile{
+			"ci":      {AllowedTools: []string{"Read", "Bash(go test:*)"}, DisallowedTools: []string{"WebFetch"}},
+			"default": {AllowedTools: []string{"Read"}},
+		},
+	}
+
+	exec, err := cfg.ExecutionPermissions()
+	if err != nil {
+		t.Fatalf("ExecutionPermissions() returned unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(exec.AllowedTools, []string{"Read", "Bash(go test:*)"}) {
+		t.Errorf("execution allowed tools = %v", exec.AllowedTools)
+	}
+
+	// Planning falls back to the execution profile
+	plan, err := cfg.PlanningPermissions()
+	if err != nil || !reflect.DeepEqual(plan, exec) {
+		t.Errorf("PlanningPermissions() = %v, %v; want the execution profile", plan, err)
+	}
+
+	cfg.PlanProfile = ProfileReadOnly
+	plan, _ = cfg.PlanningPermissions()
+	if builtin, _ := BuiltinProfile(ProfileReadOnly); !reflect.DeepEqual(plan, builtin) {
+		t.Errorf("PlanningPermissions() = %v, want readonly", plan)
+	}
+
+	// Profiles from config replace same-named built-ins
+	if p, _ := cfg.PermissionProfile(ProfileDefault); !reflect.DeepEqual(p.AllowedTools, []string{"Read"}) {
+		t.Errorf("configured default profile = %v", p)
+	}
+
+	// AllowedTools replaces the execution profile's allowed tools only
+	cfg.AllowedTools = []string{"Edit"}
+	exec, _ = cfg.ExecutionPermissions()
+	if !reflect.DeepEqual(exec.AllowedTools, []string{"Edit"}) || !reflect.DeepEqual(exec.DisallowedTools, []string{"WebFetch"}) {
+		t.Errorf("execution permissions with AllowedTools = %v", exec)
+	}
+
+	_, err = cfg.PermissionProfile("nope")
+	if err == nil || !strings.Contains(err.Error(), "available: ci, default, full, readonly") {
+		t.Errorf("PermissionProfile(nope) error = %v", err)
+	}
+}
+
+// TestLoad_Profiles tests profiles defined in config files and selected by env
+func TestLoad_Profiles(t *testing.T) {
+	repoFile, userFile := configDirs(t, `
+profile: ci
+profiles:
+  ci:
+    allowed_tools: [Read, "Bash(go test:*)"]
+`, `
+profiles:
+  ci:
+    allowed_tools: [Read]
+  audit:
+    allowed_tools: [Read, Grep]
+    disallowed_tools: [Bash]
+`)
+	t.Setenv("ALPINE_PLAN_PROFILE", "audit")
+
+	cfg, origins, err := Load()
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if cfg.Profile != "ci" || cfg.PlanProfile != "audit" {
+		t.Errorf("Profile = %q, PlanProfile = %q; want ci, audit", cfg.Profile, cfg.PlanProfile)
+	}
+	if got := cfg.Profiles["ci"].AllowedTools; !reflect.DeepEqual(got, []string{"Read", "Bash(go test:*)"}) {
+		t.Errorf("ci profile = %v, want the repo file's", got)
+	}
+	if origins["profiles.ci"].Path != repoFile || origins["profiles.audit"].Path != userFile {
+		t.Errorf("profile origins = %v, %v", origins["profiles.ci"], origins["profiles.audit"])
+	}
+
+	described := Describe(cfg, origins)
+	last := described[len(described)-1]
+	if last.Key != "profiles.ci" || last.Value != "allow=Read,Bash(go test:*)" {
+		t.Errorf("last described setting = %+v", last)
+	}
+}
+
+// TestLoad_InvalidProfiles tests that unknown profiles and profile keys are rejected
+func TestLoad_InvalidProfiles(t *testing.T) {
+	configDirs(t, "profiles:\n  ci:\n    allow: [Read]\n", "")
+	if _, err := New(); err == nil || !strings.Contains(err.Error(), "profiles:") {
+		t.Errorf("New() error = %v, want an invalid profiles error", err)
+	}
+
+	configDirs(t, "", "")
+	t.Setenv("ALPINE_PROFILE", "nope")
+	if _, err := New(); err == nil || !strings.Contains(err.Error(), `ALPINE_PROFILE: unknown permission profile "nope"`) {
+		t.Errorf("New() error = %v, want an unknown profile error", err)
+	}
+}
+
+func contains(list []string, item string) bool {
+	for _, v := range list {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
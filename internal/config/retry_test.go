@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var retryEnvVars = []string{
+	"ALPINE_CLAUDE_MAX_RETRIES", "ALPINE_CLAUDE_RETRY_BACKOFF", "ALPINE_CLAUDE_RETRY_MAX_BACKOFF",
+}
+
+// TestRetryConfigDefaults tests that transient Claude failures are retried by default
+func TestRetryConfigDefaults(t *testing.T) {
+	for _, env := range retryEnvVars {
+		_ = os.Unsetenv(env)
+	}
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+
+	want := RetryConfig{
+		MaxRetries:     3,
+		InitialBackoff: 5 * time.Second,
+		MaxBackoff:     2 * time.Minute,
+	}
+	if cfg.Retry != want {
+		t.Errorf("Retry = %+v, want %+v", cfg.Retry, want)
+	}
+}
+
+// TestRetryConfigEnvironmentVariables tests loading the retry policy from environment
+func TestRetryConfigEnvironmentVariables(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    RetryConfig
+		errMsg  string
+	}{
+		{
+			name: "all values set",
+			envVars: map[string]string{
+				"ALPINE_CLAUDE_MAX_RETRIES":       "5",
+				"ALPINE_CLAUDE_RETRY_BACKOFF":     "1",
+				"ALPINE_CLAUDE_RETRY_MAX_BACKOFF": "30",
+			},
+			want: RetryConfig{MaxRetries: 5, InitialBackoff: time.Second, MaxBackoff: 30 * time.Second},
+		},
+		{
+			name:    "zero disables retries",
+			envVars: map[string]string{"ALPINE_CLAUDE_MAX_RETRIES": "0"},
+			want:    RetryConfig{MaxRetries: 0, InitialBackoff: 5 * time.Second, MaxBackoff: 2 * time.Minute},
+		},
+		{
+			name:    "negative retries",
+			envVars: map[string]string{"ALPINE_CLAUDE_MAX_RETRIES": "-1"},
+			errMsg:  "ALPINE_CLAUDE_MAX_RETRIES",
+		},
+		{
+			name:    "invalid backoff",
+			envVars: map[string]string{"ALPINE_CLAUDE_RETRY_BACKOFF": "soon"},
+			errMsg:  "ALPINE_CLAUDE_RETRY_BACKOFF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range retryEnvVars {
+				_ = os.Unsetenv(env)
+			}
+			for key, value := range tt.envVars {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := New()
+			if tt.errMsg != "" {
+				if err == nil {
+					t.Fatalf("New() expected error containing %q, got nil", tt.errMsg)
+				}
+				if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("New() error = %q, want it to contain %q", err.Error(), tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() returned unexpected error: %v", err)
+			}
+			if cfg.Retry != tt.want {
+				t.Errorf("Retry = %+v, want %+v", cfg.Retry, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var rollbackEnvVars = []string{"ALPINE_ROLLBACK_CHECK", "ALPINE_ROLLBACK_TIMEOUT", "ALPINE_MAX_ROLLBACKS"}
+
+// TestRollbackConfig tests loading rollback settings from the environment
+func TestRollbackConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    RollbackConfig
+		errMsg  string
+	}{
+		{
+			name: "defaults disable rollback",
+			want: RollbackConfig{Timeout: 10 * time.Minute, MaxRollbacks: 3},
+		},
+		{
+			name: "all values set",
+			envVars: map[string]string{
+				"ALPINE_ROLLBACK_CHECK":   "go build ./...",
+				"ALPINE_ROLLBACK_TIMEOUT": "60",
+				"ALPINE_MAX_ROLLBACKS":    "0",
+			},
+			want: RollbackConfig{Check: "go build ./...", Timeout: time.Minute},
+		},
+		{
+			name:    "zero timeout",
+			envVars: map[string]string{"ALPINE_ROLLBACK_TIMEOUT": "0"},
+			errMsg:  "ALPINE_ROLLBACK_TIMEOUT must be positive",
+		},
+		{
+			name:    "negative max rollbacks",
+			envVars: map[string]string{"ALPINE_MAX_ROLLBACKS": "-1"},
+			errMsg:  "ALPINE_MAX_ROLLBACKS must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range rollbackEnvVars {
+				_ = os.Unsetenv(env)
+			}
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := New()
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("New() error = %v, want error containing %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() returned unexpected error: %v", err)
+			}
+			if cfg.Rollback != tt.want {
+				t.Errorf("Rollback = %+v, want %+v", cfg.Rollback, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// Sandbox backends
+const (
+	// SandboxNone runs Claude directly
+	SandboxNone = "none"
+	// SandboxBubblewrap runs Claude under bwrap
+	SandboxBubblewrap = "bwrap"
+	// SandboxUnshare runs Claude under util-linux unshare
+	SandboxUnshare = "unshare"
+)
+
+// DefaultSandboxAllowedHosts are the hosts Claude needs to reach its API
+var DefaultSandboxAllowedHosts = []string{"api.anthropic.com", "statsig.anthropic.com"}
+
+// DefaultSandboxWritable are written by Claude and common build tools
+var DefaultSandboxWritable = []string{"~/.cache"}
+
+// DefaultSandboxHide are credential files and directories hidden from Claude
+var DefaultSandboxHide = []string{
+	"~/.ssh", "~/.aws", "~/.azure", "~/.gnupg", "~/.docker", "~/.kube",
+	"~/.config/gh", "~/.config/gcloud", "~/.config/alpine",
+	"~/.netrc", "~/.git-credentials", "~/.npmrc", "~/.pypirc",
+}
+
+// SandboxConfig controls whether Claude runs in a sandbox confined to the
+// run's working directory
+type SandboxConfig struct {
+	// Backend is the sandbox backend: none, bwrap or unshare
+	Backend string
+
+	// AllowedHosts are the hosts Claude can connect to through the sandbox's
+	// proxy; a leading *. matches subdomains. Empty means no network.
+	AllowedHosts []string
+
+	// Writable are paths besides the working directory Claude can write
+	Writable []string
+
+	// Hide are paths of secrets hidden from Claude
+	Hide []string
+}
+
+// Enabled reports whether Claude runs in a sandbox
+func (s SandboxConfig) Enabled() bool {
+	return s.Backend != "" && s.Backend != SandboxNone
+}
+
+// ValidateSandboxBackend checks that backend is a known sandbox backend
+func ValidateSandboxBackend(backend string) error {
+	switch backend {
+	case SandboxNone, SandboxBubblewrap, SandboxUnshare:
+		return nil
+	default:
+		return fmt.Errorf("sandbox must be one of: none, bwrap, unshare; got: %s", backend)
+	}
+}
+
+// ValidateSandbox checks that the sandbox backend can run on this system, so
+// a run fails at startup rather than when Claude is first executed
+func (c *Config) ValidateSandbox() error {
+	if !c.Sandbox.Enabled() {
+		return nil
+	}
+	if err := ValidateSandboxBackend(c.Sandbox.Backend); err != nil {
+		return err
+	}
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("sandbox %s requires Linux", c.Sandbox.Backend)
+	}
+	if _, err := exec.LookPath(c.Sandbox.Backend); err != nil {
+		return fmt.Errorf("sandbox %s: command not found: %w", c.Sandbox.Backend, err)
+	}
+	return nil
+}
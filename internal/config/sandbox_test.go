@@ -0,0 +1,86 @@
+package config
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestLoad_Sandbox tests sandbox settings from the environment and config files
+func TestLoad_Sandbox(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		configDirs(t, "", "")
+		cfg, err := New()
+		if err != nil {
+			t.Fatalf("New() returned unexpected error: %v", err)
+		}
+		if cfg.Sandbox.Backend != SandboxNone || cfg.Sandbox.Enabled() {
+			t.Errorf("Sandbox.Backend = %q, want none and disabled", cfg.Sandbox.Backend)
+		}
+		if !reflect.DeepEqual(cfg.Sandbox.AllowedHosts, DefaultSandboxAllowedHosts) {
+			t.Errorf("Sandbox.AllowedHosts = %v, want %v", cfg.Sandbox.AllowedHosts, DefaultSandboxAllowedHosts)
+		}
+		if !reflect.DeepEqual(cfg.Sandbox.Hide, DefaultSandboxHide) || !reflect.DeepEqual(cfg.Sandbox.Writable, DefaultSandboxWritable) {
+			t.Errorf("Sandbox = %+v, want default paths", cfg.Sandbox)
+		}
+	})
+
+	t.Run("config file and environment", func(t *testing.T) {
+		_, userFile := configDirs(t, "", `
+sandbox:
+  backend: bwrap
+  allowed_hosts: []
+  hide: [~/.ssh, /etc/secrets]
+`)
+		t.Setenv("ALPINE_SANDBOX_WRITABLE", "~/.cache, ~/go")
+
+		cfg, origins, err := Load()
+		if err != nil {
+			t.Fatalf("Load() returned unexpected error: %v", err)
+		}
+		if cfg.Sandbox.Backend != SandboxBubblewrap || !cfg.Sandbox.Enabled() {
+			t.Errorf("Sandbox.Backend = %q, want bwrap", cfg.Sandbox.Backend)
+		}
+		if len(cfg.Sandbox.AllowedHosts) != 0 {
+			t.Errorf("Sandbox.AllowedHosts = %v, want none for an empty list", cfg.Sandbox.AllowedHosts)
+		}
+		if want := []string{"~/.ssh", "/etc/secrets"}; !reflect.DeepEqual(cfg.Sandbox.Hide, want) {
+			t.Errorf("Sandbox.Hide = %v, want %v", cfg.Sandbox.Hide, want)
+		}
+		if want := []string{"~/.cache", "~/go"}; !reflect.DeepEqual(cfg.Sandbox.Writable, want) {
+			t.Errorf("Sandbox.Writable = %v, want %v", cfg.Sandbox.Writable, want)
+		}
+		if origins["sandbox.backend"].Path != userFile || origins["sandbox.writable"].Source != OriginEnv {
+			t.Errorf("sandbox origins = %v, %v", origins["sandbox.backend"], origins["sandbox.writable"])
+		}
+	})
+
+	t.Run("invalid backend", func(t *testing.T) {
+		configDirs(t, "", "")
+		t.Setenv("ALPINE_SANDBOX", "docker")
+		_, err := New()
+		if err == nil || !strings.Contains(err.Error(), "ALPINE_SANDBOX: sandbox must be one of: none, bwrap, unshare; got: docker") {
+			t.Errorf("New() error = %v", err)
+		}
+	})
+}
+
+// TestConfig_ValidateSandbox tests that a sandbox backend must be installed
+func TestConfig_ValidateSandbox(t *testing.T) {
+	cfg := &Config{Sandbox: SandboxConfig{Backend: SandboxNone}}
+	if err := cfg.ValidateSandbox(); err != nil {
+		t.Errorf("ValidateSandbox() without a sandbox returned unexpected error: %v", err)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	cfg.Sandbox.Backend = SandboxBubblewrap
+	err := cfg.ValidateSandbox()
+	want := "sandbox bwrap: command not found"
+	if runtime.GOOS != "linux" {
+		want = "sandbox bwrap requires Linux"
+	}
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("ValidateSandbox() error = %v, want %q", err, want)
+	}
+}
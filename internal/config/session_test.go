@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+var sessionEnvVars = []string{
+	"ALPINE_CONTINUE_SESSION", "ALPINE_SESSION_MAX_TOKENS", "ALPINE_SESSION_MAX_ITERATIONS",
+}
+
+// TestSessionConfig tests loading session continuation settings from environment
+func TestSessionConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		want    SessionConfig
+		errMsg  string
+	}{
+		{
+			name: "defaults leave continuation off",
+			want: SessionConfig{Continue: false, MaxContextTokens: 150000},
+		},
+		{
+			name: "all values set",
+			envVars: map[string]string{
+				"ALPINE_CONTINUE_SESSION":       "true",
+				"ALPINE_SESSION_MAX_TOKENS":     "80000",
+				"ALPINE_SESSION_MAX_ITERATIONS": "10",
+			},
+			want: SessionConfig{Continue: true, MaxContextTokens: 80000, MaxIterations: 10},
+		},
+		{
+			name:    "zero disables the token limit",
+			envVars: map[string]string{"ALPINE_CONTINUE_SESSION": "true", "ALPINE_SESSION_MAX_TOKENS": "0"},
+			want:    SessionConfig{Continue: true},
+		},
+		{
+			name:    "invalid continue flag",
+			envVars: map[string]string{"ALPINE_CONTINUE_SESSION": "yes"},
+			errMsg:  "ALPINE_CONTINUE_SESSION",
+		},
+		{
+			name:    "negative iteration limit",
+			envVars: map[string]string{"ALPINE_SESSION_MAX_ITERATIONS": "-2"},
+			errMsg:  "ALPINE_SESSION_MAX_ITERATIONS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range sessionEnvVars {
+				_ = os.Unsetenv(env)
+			}
+			for key, value := range tt.envVars {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := New()
+			if tt.errMsg != "" {
+				if err == nil {
+					t.Fatalf("New() expected error containing %q, got nil", tt.errMsg)
+				}
+				if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("New() error = %q, want it to contain %q", err.Error(), tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() returned unexpected error: %v", err)
+			}
+			if cfg.Session != tt.want {
+				t.Errorf("Session = %+v, want %+v", cfg.Session, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+var stallEnvVars = []string{
+	"ALPINE_MAX_STALLS", "ALPINE_STALL_GRACE", "ALPINE_STALL_BACKOFF",
+	"ALPINE_STALL_ACTION", "ALPINE_STALL_NUDGE_PROMPT",
+}
+
+// TestStallConfigDefaults tests that stall detection is enabled with a nudge by default
+func TestStallConfigDefaults(t *testing.T) {
+	for _, env := range stallEnvVars {
+		_ = os.Unsetenv(env)
+	}
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("New() returned unexpected error: %v", err)
+	}
+
+	want := StallConfig{
+		MaxStalls:   3,
+		GracePeriod: 5 * time.Second,
+		Backoff:     2 * time.Second,
+		Action:      StallActionNudge,
+		NudgePrompt: DefaultStallNudgePrompt,
+	}
+	if cfg.Stall != want {
+		t.Errorf("Stall = %+v, want %+v", cfg.Stall, want)
+	}
+}
+
+// TestStallConfigEnvironmentVariables tests loading stall settings from environment
+func TestStallConfigEnvironmentVariables(t *testing.T) {
+	tests := []struct {
+		name    string
+		envVars map[string]string
+		check   func(t *testing.T, stall StallConfig)
+		errMsg  string
+	}{
+		{
+			name: "all values set",
+			envVars: map[string]string{
+				"ALPINE_MAX_STALLS":         "5",
+				"ALPINE_STALL_GRACE":        "10",
+				"ALPINE_STALL_BACKOFF":      "0",
+				"ALPINE_STALL_ACTION":       "retry",
+				"ALPINE_STALL_NUDGE_PROMPT": "Update the state file",
+			},
+			check: func(t *testing.T, stall StallConfig) {
+				want := StallConfig{
+					MaxStalls:   5,
+					GracePeriod: 10 * time.Second,
+					Backoff:     0,
+					Action:      StallActionRetry,
+					NudgePrompt: "Update the state file",
+				}
+				if stall != want {
+					t.Errorf("Stall = %+v, want %+v", stall, want)
+				}
+			},
+		},
+		{
+			name:    "zero disables stall detection",
+			envVars: map[string]string{"ALPINE_MAX_STALLS": "0"},
+			check: func(t *testing.T, stall StallConfig) {
+				if stall.MaxStalls != 0 {
+					t.Errorf("MaxStalls = %d, want 0", stall.MaxStalls)
+				}
+			},
+		},
+		{
+			name:    "invalid action",
+			envVars: map[string]string{"ALPINE_STALL_ACTION": "ignore"},
+			errMsg:  "ALPINE_STALL_ACTION must be one of: nudge, retry",
+		},
+		{
+			name:    "negative max stalls",
+			envVars: map[string]string{"ALPINE_MAX_STALLS": "-1"},
+			errMsg:  "ALPINE_MAX_STALLS must not be negative",
+		},
+		{
+			name:    "invalid grace period",
+			envVars: map[string]string{"ALPINE_STALL_GRACE": "soon"},
+			errMsg:  "invalid ALPINE_STALL_GRACE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range stallEnvVars {
+				_ = os.Unsetenv(env)
+			}
+			for k, v := range tt.envVars {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := New()
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("New() error = %v, want error containing %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() returned unexpected error: %v", err)
+			}
+			tt.check(t, cfg.Stall)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// journalSuffix names the state history file kept next to a state file
+const journalSuffix = ".journal.jsonl"
+
+// JournalEntry is one state observed by the workflow engine, together with the
+// Claude invocation that produced it
+type JournalEntry struct {
+	Timestamp  time.Time `json:"timestamp"`
+	RunID      string    `json:"run_id,omitempty"`
+	Iteration  int       `json:"iteration"`
+	Prompt     string    `json:"prompt,omitempty"`
+	ExitCode   int       `json:"exit_code"`
+	DurationMS int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	State      State     `json:"state"`
+
+	// Commit is the commit alpine made of the iteration's changes, if any
+	Commit string `json:"commit,omitempty"`
+}
+
+// Duration returns how long the Claude invocation for this entry took
+func (e JournalEntry) Duration() time.Duration {
+	return time.Duration(e.DurationMS) * time.Millisecond
+}
+
+// JournalPath returns the journal file for a state file, e.g.
+// agent_state/agent_state.journal.jsonl for agent_state/agent_state.json
+func JournalPath(stateFile string) string {
+	base := strings.TrimSuffix(filepath.Base(stateFile), filepath.Ext(stateFile))
+	return filepath.Join(filepath.Dir(stateFile), base+journalSuffix)
+}
+
+// AppendJournal appends an entry as one JSON line to the journal at path.
+// The journal shares the state file's lock, so readers never see a partial line.
+func AppendJournal(path string, entry JournalEntry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now().UTC()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	fileMutex.Lock()
+	defer fileMutex.Unlock()
+
+	unlock, err := lockStateDir(path, true)
+	if err != nil {
+		return fmt.Errorf("failed to lock journal: %w", err)
+	}
+	defer unlock()
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to append to journal: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to append to journal: %w", err)
+	}
+	return nil
+}
+
+// ReadJournal returns the entries in the journal at path, oldest first.
+// A missing journal has no entries. A truncated final line, left by a process
+// killed mid-append, is ignored.
+func ReadJournal(path string) ([]JournalEntry, error) {
+	data, err := readStateFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	var entries []JournalEntry
+	for i, raw := range lines {
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			if i == len(lines)-1 {
+				// The final line has no newline, so its append never finished
+				break
+			}
+			return nil, fmt.Errorf("failed to parse journal line %d: %w", i+1, err)
+		}
+		if err := entry.State.migrate(); err != nil {
+			return nil, fmt.Errorf("failed to parse journal line %d: %w", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// RunJournal returns the journal entries recorded for one run
+func RunJournal(path, runID string) ([]JournalEntry, error) {
+	entries, err := ReadJournal(path)
+	if err != nil {
+		return nil, err
+	}
+	var run []JournalEntry
+	for _, entry := range entries {
+		if entry.RunID == runID {
+			run = append(run, entry)
+		}
+	}
+	return run, nil
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalPath(t *testing.T) {
+	assert.Equal(t, filepath.Join("agent_state", "agent_state.journal.jsonl"),
+		JournalPath(filepath.Join("agent_state", "agent_state.json")))
+}
+
+func TestJournal_AppendAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.journal.jsonl")
+
+	entries, err := ReadJournal(path)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "missing journal has no entries")
+
+	require.NoError(t, AppendJournal(path, JournalEntry{
+		RunID: "run-1",
+		State: State{CurrentStepDescription: "Initializing", NextStepPrompt: "/start", Status: StatusRunning},
+	}))
+	require.NoError(t, AppendJournal(path, JournalEntry{
+		RunID:      "run-1",
+		Iteration:  1,
+		Prompt:     "/start",
+		ExitCode:   0,
+		DurationMS: 1500,
+		State:      State{CurrentStepDescription: "Done", Status: StatusCompleted},
+	}))
+	require.NoError(t, AppendJournal(path, JournalEntry{RunID: "run-2", Iteration: 1, ExitCode: 2, Error: "exit status 2"}))
+
+	entries, err = ReadJournal(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.False(t, entries[0].Timestamp.IsZero(), "append stamps the time")
+	assert.Equal(t, 1, entries[1].Iteration)
+	assert.Equal(t, "/start", entries[1].Prompt)
+	assert.Equal(t, 1500*time.Millisecond, entries[1].Duration())
+	assert.Equal(t, StatusCompleted, entries[1].State.Status)
+	assert.Equal(t, StateSchemaVersion, entries[1].State.SchemaVersion)
+	assert.Equal(t, 2, entries[2].ExitCode)
+
+	run, err := RunJournal(path, "run-1")
+	require.NoError(t, err)
+	assert.Len(t, run, 2)
+}
+
+func TestReadJournal_IgnoresTruncatedFinalLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.journal.jsonl")
+	require.NoError(t, AppendJournal(path, JournalEntry{Iteration: 1}))
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"iteration":2,"sta`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	entries, err := ReadJournal(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 1, entries[0].Iteration)
+}
+
+func TestReadJournal_RejectsCorruptLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.journal.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("{\"iteration\":1}\nnot json\n{\"iteration\":3}\n"), 0644))
+
+	_, err := ReadJournal(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
+
+func TestAppendJournal_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.journal.jsonl")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, AppendJournal(path, JournalEntry{Iteration: i}))
+		}(i)
+	}
+	wg.Wait()
+
+	entries, err := ReadJournal(path)
+	require.NoError(t, err)
+	assert.Len(t, entries, 50)
+}
@@ -4,16 +4,25 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Status constants
 const (
-	StatusRunning   = "running"
-	StatusCompleted = "completed"
+	StatusRunning       = "running"
+	StatusCompleted     = "completed"
+	StatusFailed        = "failed"
+	StatusBlocked       = "blocked"
+	StatusAwaitingInput = "awaiting_input"
 )
 
+// StateSchemaVersion is the version of the state file format written by Save.
+// Files without a schema_version are version 1, the original three-field format.
+const StateSchemaVersion = 2
+
 // Common prompt constants
 const (
 	PromptMakePlan = "/make_plan"
@@ -21,23 +30,33 @@ const (
 	PromptContinue = "/continue"
 )
 
-// fileMutex provides global synchronization for state file operations
+// fileMutex provides global synchronization for state file operations within
+// this process; lockStateDir extends it to other processes
 var fileMutex sync.Mutex
 
 // State represents the current workflow state
 type State struct {
+	SchemaVersion          int    `json:"schema_version,omitempty"`
 	CurrentStepDescription string `json:"current_step_description"`
 	NextStepPrompt         string `json:"next_step_prompt"`
 	Status                 string `json:"status"`
+
+	// Run metadata maintained by alpine. Claude only needs to write the
+	// fields above; alpine fills these in again on the next iteration.
+	RunID         string    `json:"run_id,omitempty"`
+	Iteration     int       `json:"iteration,omitempty"`
+	StartedAt     time.Time `json:"started_at,omitzero"`
+	UpdatedAt     time.Time `json:"updated_at,omitzero"`
+	LastPrompt    string    `json:"last_prompt,omitempty"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	PlanPath      string    `json:"plan_path,omitempty"`
+	SessionID     string    `json:"session_id,omitempty"`
 }
 
 // LoadState loads the state from a JSON file
 // If the file doesn't exist, it returns an empty State (not an error)
 func LoadState(path string) (*State, error) {
-	fileMutex.Lock()
-	defer fileMutex.Unlock()
-
-	data, err := os.ReadFile(path)
+	data, err := readStateFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Missing file is not an error - create new empty state
@@ -46,31 +65,176 @@ func LoadState(path string) (*State, error) {
 		return nil, fmt.Errorf("failed to read state file: %w", err)
 	}
 
-	var state State
-	if err := json.Unmarshal(data, &state); err != nil {
+	state, err := ParseState(data)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse state file: %w", err)
 	}
 
+	return state, nil
+}
+
+// ParseState decodes a state file, migrating older schema versions to the
+// current one
+func ParseState(data []byte) (*State, error) {
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	if err := state.migrate(); err != nil {
+		return nil, err
+	}
 	return &state, nil
 }
 
+// migrate upgrades a decoded state to StateSchemaVersion
+func (s *State) migrate() error {
+	if s.SchemaVersion > StateSchemaVersion {
+		return fmt.Errorf("state schema version %d is newer than supported version %d", s.SchemaVersion, StateSchemaVersion)
+	}
+	if s.SchemaVersion < 2 {
+		// Version 1 files were written by hand or by Claude and compared
+		// statuses case-insensitively
+		s.Status = strings.ToLower(strings.TrimSpace(s.Status))
+	}
+	s.SchemaVersion = StateSchemaVersion
+	return nil
+}
+
 // Save writes the state to a JSON file with pretty-printing (2-space indentation)
 func (s *State) Save(path string) error {
+	data, err := s.marshal()
+	if err != nil {
+		return err
+	}
+
+	fileMutex.Lock()
+	defer fileMutex.Unlock()
+
+	unlock, err := lockStateDir(path, true)
+	if err != nil {
+		return fmt.Errorf("failed to lock state file: %w", err)
+	}
+	defer unlock()
+
+	if err := writeStateFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	return nil
+}
+
+// WriteStateFile replaces the state file with data, which must be a JSON state
+// object. It is the entry point for writers outside the workflow engine, such
+// as `alpine state write`, and takes the same lock as Save so concurrent
+// readers never observe a partially written file.
+func WriteStateFile(path string, data []byte) error {
+	state, err := ParseState(data)
+	if err != nil {
+		return fmt.Errorf("invalid state: %w", err)
+	}
+	return state.Save(path)
+}
+
+// UpdateState loads the state file, applies update and saves the result while
+// holding the state lock, so no other writer can interleave between the read
+// and the write
+func UpdateState(path string, update func(*State) error) error {
 	fileMutex.Lock()
 	defer fileMutex.Unlock()
 
+	unlock, err := lockStateDir(path, true)
+	if err != nil {
+		return fmt.Errorf("failed to lock state file: %w", err)
+	}
+	defer unlock()
+
+	state := &State{}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err == nil {
+		if state, err = ParseState(data); err != nil {
+			return fmt.Errorf("failed to parse state file: %w", err)
+		}
+	}
+
+	if err := update(state); err != nil {
+		return err
+	}
+
+	data, err = state.marshal()
+	if err != nil {
+		return err
+	}
+	if err := writeStateFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// marshal stamps the schema version and update time, then encodes the state
+// as pretty-printed JSON with a trailing newline
+func (s *State) marshal() ([]byte, error) {
+	s.SchemaVersion = StateSchemaVersion
+	s.UpdatedAt = time.Now().UTC()
+
 	data, err := json.MarshalIndent(s, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to marshal state: %w", err)
+		return nil, fmt.Errorf("failed to marshal state: %w", err)
 	}
 
 	// Add newline at end for better formatting
-	data = append(data, '\n')
+	return append(data, '\n'), nil
+}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		return fmt.Errorf("failed to write state file: %w", err)
+// readStateFile reads the state file under a shared lock
+func readStateFile(path string) ([]byte, error) {
+	fileMutex.Lock()
+	defer fileMutex.Unlock()
+
+	unlock, err := lockStateDir(path, false)
+	if err != nil {
+		// A missing directory means there is no state file to read either
+		return nil, err
 	}
+	defer unlock()
+
+	return os.ReadFile(path)
+}
 
+// writeStateFileAtomic writes data to a temporary file next to path, syncs it
+// and renames it over path, so the state file is always either the old or the
+// new content. The caller must hold the state lock.
+func writeStateFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
 	return nil
 }
 
@@ -105,8 +269,10 @@ func (s *State) Validate() error {
 		return fmt.Errorf("status cannot be empty")
 	}
 
-	if s.Status != StatusRunning && s.Status != StatusCompleted {
-		return fmt.Errorf("status must be 'running' or 'completed'")
+	switch s.Status {
+	case StatusRunning, StatusCompleted, StatusFailed, StatusBlocked, StatusAwaitingInput:
+	default:
+		return fmt.Errorf("status must be one of 'running', 'completed', 'failed', 'blocked' or 'awaiting_input'")
 	}
 
 	// If status is running, next_step_prompt should not be empty
@@ -121,3 +287,14 @@ func (s *State) Validate() error {
 func (s *State) IsCompleted() bool {
 	return strings.ToLower(s.Status) == StatusCompleted
 }
+
+// IsStopped returns true if the workflow cannot continue without outside
+// action: it failed, is blocked, or is waiting for input
+func (s *State) IsStopped() bool {
+	switch strings.ToLower(s.Status) {
+	case StatusFailed, StatusBlocked, StatusAwaitingInput:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// lockStateDir only checks that the state directory exists; writes are still
+// atomic, but other processes are not excluded on this platform
+func lockStateDir(path string, exclusive bool) (unlock func(), err error) {
+	if _, err := os.Stat(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	return func() {}, nil
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stateWriterEnv makes the test binary act as an external state writer
+const stateWriterEnv = "ALPINE_TEST_STATE_WRITER"
+
+// TestHelperStateWriter is not a real test: it rewrites the state file in a
+// loop when run as a child process by TestState_NoTornReadsAcrossProcesses
+func TestHelperStateWriter(t *testing.T) {
+	path := os.Getenv(stateWriterEnv)
+	if path == "" {
+		t.Skip("helper process only")
+	}
+	count, _ := strconv.Atoi(os.Getenv(stateWriterEnv + "_COUNT"))
+	for i := 0; i < count; i++ {
+		state := &State{
+			// Large enough that a non-atomic write is observable mid-way
+			CurrentStepDescription: fmt.Sprintf("step %d %s", i, strings.Repeat("x", 64*1024)),
+			NextStepPrompt:         PromptContinue,
+			Status:                 StatusRunning,
+		}
+		if err := state.Save(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func TestState_NoTornReadsAcrossProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.json")
+	require.NoError(t, InitializeState("torn reads", "", false).Save(path))
+
+	writer := exec.Command(os.Args[0], "-test.run=^TestHelperStateWriter$")
+	writer.Env = append(os.Environ(), stateWriterEnv+"="+path, stateWriterEnv+"_COUNT=200")
+	writer.Stderr = os.Stderr
+	require.NoError(t, writer.Start())
+
+	done := make(chan error, 1)
+	go func() { done <- writer.Wait() }()
+
+	reads := 0
+	for {
+		select {
+		case err := <-done:
+			require.NoError(t, err, "writer process failed")
+			assert.Greater(t, reads, 0)
+			return
+		default:
+		}
+		state, err := LoadState(path)
+		require.NoError(t, err, "read %d observed a partially written state file", reads)
+		require.NotEmpty(t, state.Status)
+		reads++
+	}
+}
+
+func TestState_SaveLeavesNoTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "agent_state.json")
+	for i := 0; i < 3; i++ {
+		require.NoError(t, InitializeState("cleanup", "", false).Save(path))
+	}
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "agent_state.json", entries[0].Name())
+}
+
+func TestState_SaveFailsWithoutDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "agent_state.json")
+	err := InitializeState("no dir", "", false).Save(path)
+	assert.Error(t, err)
+}
+
+func TestWriteStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.json")
+
+	require.NoError(t, WriteStateFile(path, []byte(`{"current_step_description":"external","next_step_prompt":"","status":"completed"}`)))
+	state, err := LoadState(path)
+	require.NoError(t, err)
+	assert.Equal(t, "external", state.CurrentStepDescription)
+	assert.True(t, state.IsCompleted())
+
+	// Invalid input leaves the existing file untouched
+	err = WriteStateFile(path, []byte(`{"status":`))
+	require.Error(t, err)
+	state, err = LoadState(path)
+	require.NoError(t, err)
+	assert.Equal(t, "external", state.CurrentStepDescription)
+}
+
+func TestUpdateState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.json")
+	require.NoError(t, InitializeState("update", "", false).Save(path))
+
+	// Concurrent read-modify-write cycles must not lose updates
+	const updates = 20
+	errs := make(chan error, updates)
+	for i := 0; i < updates; i++ {
+		go func() {
+			errs <- UpdateState(path, func(s *State) error {
+				n, _ := strconv.Atoi(s.NextStepPrompt)
+				s.NextStepPrompt = strconv.Itoa(n + 1)
+				return nil
+			})
+		}()
+	}
+	for i := 0; i < updates; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	state, err := LoadState(path)
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(updates), state.NextStepPrompt)
+
+	// An error from the update function leaves the file unchanged
+	err = UpdateState(path, func(s *State) error {
+		s.Status = StatusFailed
+		return fmt.Errorf("abort")
+	})
+	require.EqualError(t, err, "abort")
+	state, err = LoadState(path)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, state.Status)
+}
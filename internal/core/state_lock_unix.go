@@ -0,0 +1,39 @@
+//go:build unix
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// lockStateDir takes an advisory flock on the directory containing the state
+// file, shared for readers and exclusive for writers. The directory is locked
+// rather than the file because writers replace the file by renaming over it.
+func lockStateDir(path string, exclusive bool) (unlock func(), err error) {
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	for {
+		err = syscall.Flock(int(dir.Fd()), how)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		_ = dir.Close()
+		return nil, err
+	}
+
+	return func() {
+		_ = syscall.Flock(int(dir.Fd()), syscall.LOCK_UN)
+		_ = dir.Close()
+	}, nil
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadState_MigratesVersion1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.json")
+	legacy := `{
+  "current_step_description": "Implementing feature",
+  "next_step_prompt": "/continue",
+  "status": "Completed "
+}`
+	require.NoError(t, os.WriteFile(path, []byte(legacy), 0644))
+
+	state, err := LoadState(path)
+	require.NoError(t, err)
+	assert.Equal(t, StateSchemaVersion, state.SchemaVersion)
+	assert.Equal(t, StatusCompleted, state.Status)
+	assert.Equal(t, "Implementing feature", state.CurrentStepDescription)
+	assert.Empty(t, state.RunID)
+	assert.True(t, state.StartedAt.IsZero())
+}
+
+func TestLoadState_RejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"schema_version": 99, "status": "running"}`), 0644))
+
+	_, err := LoadState(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "newer than supported")
+}
+
+func TestState_SaveRoundTripsMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.json")
+	started := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	state := &State{
+		CurrentStepDescription: "Stopped: budget",
+		Status:                 StatusFailed,
+		RunID:                  "run-123",
+		Iteration:              4,
+		StartedAt:              started,
+		LastPrompt:             "/continue",
+		FailureReason:          "reached max iterations (3)",
+		PlanPath:               "/work/plan.md",
+	}
+
+	before := time.Now().UTC()
+	require.NoError(t, state.Save(path))
+
+	loaded, err := LoadState(path)
+	require.NoError(t, err)
+	assert.Equal(t, StateSchemaVersion, loaded.SchemaVersion)
+	assert.Equal(t, "run-123", loaded.RunID)
+	assert.Equal(t, 4, loaded.Iteration)
+	assert.True(t, started.Equal(loaded.StartedAt))
+	assert.False(t, loaded.UpdatedAt.Before(before), "Save should stamp the update time")
+	assert.Equal(t, "/continue", loaded.LastPrompt)
+	assert.Equal(t, "reached max iterations (3)", loaded.FailureReason)
+	assert.Equal(t, "/work/plan.md", loaded.PlanPath)
+}
+
+func TestState_SaveOmitsUnsetMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.json")
+	require.NoError(t, InitializeState("minimal", "", false).Save(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	for _, field := range []string{"run_id", "iteration", "started_at", "last_prompt", "failure_reason", "plan_path"} {
+		assert.NotContains(t, string(data), `"`+field+`"`)
+	}
+	assert.Contains(t, string(data), `"schema_version": 2`)
+}
+
+func TestState_IsStopped(t *testing.T) {
+	for status, stopped := range map[string]bool{
+		StatusRunning:       false,
+		StatusCompleted:     false,
+		StatusFailed:        true,
+		StatusBlocked:       true,
+		StatusAwaitingInput: true,
+	} {
+		assert.Equal(t, stopped, (&State{Status: status}).IsStopped(), status)
+	}
+}
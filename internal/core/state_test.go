@@ -38,6 +38,32 @@ func TestState_Validate(t *testing.T) {
 			},
 			wantError: false,
 		},
+		{
+			name: "valid state with failed status",
+			state: State{
+				CurrentStepDescription: "Stopped: iteration budget exceeded",
+				NextStepPrompt:         "/continue",
+				Status:                 "failed",
+			},
+			wantError: false,
+		},
+		{
+			name: "valid state with blocked status and no next step",
+			state: State{
+				CurrentStepDescription: "Waiting on missing credentials",
+				Status:                 "blocked",
+			},
+			wantError: false,
+		},
+		{
+			name: "valid state with awaiting_input status",
+			state: State{
+				CurrentStepDescription: "Plan ready for review",
+				NextStepPrompt:         "/start",
+				Status:                 "awaiting_input",
+			},
+			wantError: false,
+		},
 		{
 			name: "empty current step description",
 			state: State{
@@ -66,7 +92,7 @@ func TestState_Validate(t *testing.T) {
 				Status:                 "invalid",
 			},
 			wantError: true,
-			errorMsg:  "status must be 'running' or 'completed'",
+			errorMsg:  "status must be one of 'running', 'completed', 'failed', 'blocked' or 'awaiting_input'",
 		},
 		{
 			name: "running status with empty next step",
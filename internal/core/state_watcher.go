@@ -0,0 +1,220 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Backland-Labs/alpine/internal/logger"
+)
+
+const (
+	// DefaultWatchPollInterval is how often the watcher polls when file notifications are unavailable
+	DefaultWatchPollInterval = 100 * time.Millisecond
+	// DefaultWatchDebounce is how long the watcher waits for writes to settle before reading
+	DefaultWatchDebounce = 20 * time.Millisecond
+)
+
+// fileNotifier reports that a watched file may have changed.
+// The events channel is closed when the notifier can no longer deliver
+// notifications, for example because the watched directory was removed.
+type fileNotifier interface {
+	Events() <-chan struct{}
+	Close() error
+}
+
+// StateWatcher watches a state file and delivers every distinct, fully parsed
+// State written to it, in order. It uses OS file notifications where available,
+// which catch in-place rewrites as well as atomic rename-into-place writes, and
+// falls back to polling when they are not. Partial writes are debounced and any
+// content that does not parse is skipped until the writer finishes.
+type StateWatcher struct {
+	path         string
+	pollInterval time.Duration
+	debounce     time.Duration
+
+	updates  chan *State
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+	started  bool
+	mu       sync.Mutex
+
+	// lastData holds the raw bytes of the last delivered state
+	lastData []byte
+	// lastState is the last delivered state without its update time, so
+	// rewrites that only refresh updated_at are not reported as changes
+	lastState State
+}
+
+// NewStateWatcher creates a watcher for the given state file.
+// The file and its directory do not need to exist yet.
+func NewStateWatcher(path string) *StateWatcher {
+	return &StateWatcher{
+		path:         path,
+		pollInterval: DefaultWatchPollInterval,
+		debounce:     DefaultWatchDebounce,
+		updates:      make(chan *State),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// SetPollInterval overrides the polling interval used by the fallback.
+// It must be called before Start.
+func (w *StateWatcher) SetPollInterval(interval time.Duration) {
+	if interval > 0 {
+		w.pollInterval = interval
+	}
+}
+
+// Start begins watching. The current file content, if any, is delivered first.
+// The updates channel is closed once the context is cancelled or Stop is called.
+func (w *StateWatcher) Start(ctx context.Context) error {
+	if w.path == "" {
+		return fmt.Errorf("state file path cannot be empty")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.started {
+		return fmt.Errorf("state watcher already started")
+	}
+	w.started = true
+
+	go w.run(ctx)
+	return nil
+}
+
+// Updates returns the channel of parsed states in the order they were written
+func (w *StateWatcher) Updates() <-chan *State {
+	return w.updates
+}
+
+// Stop stops the watcher and waits for it to exit. It is safe to call more than once.
+func (w *StateWatcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+
+	w.mu.Lock()
+	started := w.started
+	w.mu.Unlock()
+	if started {
+		<-w.done
+	}
+}
+
+// run is the main watch loop
+func (w *StateWatcher) run(ctx context.Context) {
+	defer close(w.done)
+	defer close(w.updates)
+
+	notifier := w.openNotifier()
+	defer func() {
+		if notifier != nil {
+			_ = notifier.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	var debounce <-chan time.Time
+
+	if !w.check(ctx) {
+		return
+	}
+
+	for {
+		var notifications <-chan struct{}
+		var poll <-chan time.Time
+		if notifier != nil {
+			notifications = notifier.Events()
+		} else {
+			poll = ticker.C
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stop:
+			return
+		case _, ok := <-notifications:
+			if !ok {
+				// Directory went away; fall back to polling until it reappears
+				logger.Debugf("State watcher lost notifications for %s, polling", w.path)
+				_ = notifier.Close()
+				notifier = nil
+				debounce = nil
+				if !w.check(ctx) {
+					return
+				}
+				continue
+			}
+			debounce = time.After(w.debounce)
+		case <-debounce:
+			debounce = nil
+			if !w.check(ctx) {
+				return
+			}
+		case <-poll:
+			notifier = w.openNotifier()
+			if !w.check(ctx) {
+				return
+			}
+		}
+	}
+}
+
+// openNotifier returns a file notifier, or nil when polling must be used
+func (w *StateWatcher) openNotifier() fileNotifier {
+	notifier, err := newFileNotifier(w.path)
+	if err != nil {
+		return nil
+	}
+	return notifier
+}
+
+// check reads the state file and delivers it if it changed.
+// It returns false when the watcher was stopped while delivering.
+func (w *StateWatcher) check(ctx context.Context) bool {
+	data, err := readStateFile(w.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Debugf("State watcher failed to read %s: %v", w.path, err)
+		}
+		return true
+	}
+
+	if bytes.Equal(data, w.lastData) {
+		return true
+	}
+
+	state, err := ParseState(data)
+	if err != nil {
+		// A writer bypassing the state lock left a partial file; its next
+		// event triggers a re-read
+		logger.Debugf("State watcher skipping unparseable content in %s: %v", w.path, err)
+		return true
+	}
+
+	content := *state
+	content.UpdatedAt = time.Time{}
+	if w.lastData != nil && content == w.lastState {
+		w.lastData = data
+		return true
+	}
+
+	select {
+	case w.updates <- state:
+		w.lastData = data
+		w.lastState = content
+		return true
+	case <-ctx.Done():
+		return false
+	case <-w.stop:
+		return false
+	}
+}
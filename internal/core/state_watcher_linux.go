@@ -0,0 +1,87 @@
+//go:build linux
+
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyNotifier watches the state file's directory with inotify so that
+// both in-place writes and files renamed into place are observed.
+type inotifyNotifier struct {
+	file   *os.File
+	name   string
+	events chan struct{}
+}
+
+// newFileNotifier creates an inotify watch on the directory containing path
+func newFileNotifier(path string) (fileNotifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+
+	mask := uint32(syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_CREATE |
+		syscall.IN_MOVED_TO | syscall.IN_DELETE | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF)
+	if _, err := syscall.InotifyAddWatch(fd, filepath.Dir(path), mask); err != nil {
+		_ = syscall.Close(fd)
+		return nil, fmt.Errorf("failed to watch %s: %w", filepath.Dir(path), err)
+	}
+
+	// A non-blocking descriptor is registered with the runtime poller, so
+	// closing the file unblocks the pending read.
+	n := &inotifyNotifier{
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		name:   filepath.Base(path),
+		events: make(chan struct{}, 1),
+	}
+	go n.readLoop()
+
+	return n, nil
+}
+
+// Events implements fileNotifier
+func (n *inotifyNotifier) Events() <-chan struct{} {
+	return n.events
+}
+
+// Close implements fileNotifier
+func (n *inotifyNotifier) Close() error {
+	return n.file.Close()
+}
+
+// readLoop decodes inotify events and signals those that concern the state file
+func (n *inotifyNotifier) readLoop() {
+	defer close(n.events)
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		count, err := n.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= count; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			nameEnd := nameStart + int(event.Len)
+			name := strings.TrimRight(string(buf[nameStart:nameEnd]), "\x00")
+			offset = nameEnd
+
+			if event.Mask&(syscall.IN_IGNORED|syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) != 0 {
+				return
+			}
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 || name == n.name {
+				select {
+				case n.events <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
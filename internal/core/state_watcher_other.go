@@ -0,0 +1,11 @@
+//go:build !linux
+
+package core
+
+import "errors"
+
+// newFileNotifier reports that file notifications are unavailable, so the
+// watcher polls instead
+func newFileNotifier(path string) (fileNotifier, error) {
+	return nil, errors.New("file notifications not supported on this platform")
+}
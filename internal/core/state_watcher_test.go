@@ -0,0 +1,137 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nextState reads the next update from the watcher or fails after a timeout
+func nextState(t *testing.T, w *StateWatcher) *State {
+	t.Helper()
+	select {
+	case state, ok := <-w.Updates():
+		require.True(t, ok, "updates channel closed unexpectedly")
+		return state
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for state update")
+		return nil
+	}
+}
+
+// assertNoUpdate verifies no update is delivered within a short window
+func assertNoUpdate(t *testing.T, w *StateWatcher) {
+	t.Helper()
+	select {
+	case state := <-w.Updates():
+		t.Fatalf("unexpected update: %+v", state)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
+
+func startWatcher(t *testing.T, path string) *StateWatcher {
+	t.Helper()
+	w := NewStateWatcher(path)
+	require.NoError(t, w.Start(context.Background()))
+	t.Cleanup(w.Stop)
+	return w
+}
+
+func TestStateWatcher_DeliversInitialState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.json")
+	require.NoError(t, (&State{CurrentStepDescription: "initial", NextStepPrompt: "/start", Status: StatusRunning}).Save(path))
+
+	w := startWatcher(t, path)
+
+	assert.Equal(t, "initial", nextState(t, w).CurrentStepDescription)
+	assertNoUpdate(t, w)
+}
+
+func TestStateWatcher_OrderedInPlaceRewrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.json")
+	w := startWatcher(t, path)
+
+	// Sub-second rewrites must each be observed, in order
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, (&State{CurrentStepDescription: fmt.Sprintf("step %d", i), NextStepPrompt: "/continue", Status: StatusRunning}).Save(path))
+		assert.Equal(t, fmt.Sprintf("step %d", i), nextState(t, w).CurrentStepDescription)
+	}
+}
+
+func TestStateWatcher_DetectsAtomicRename(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "agent_state.json")
+	require.NoError(t, (&State{CurrentStepDescription: "before", NextStepPrompt: "/start", Status: StatusRunning}).Save(path))
+
+	w := startWatcher(t, path)
+	assert.Equal(t, "before", nextState(t, w).CurrentStepDescription)
+
+	tmp := filepath.Join(dir, "agent_state.json.tmp")
+	require.NoError(t, (&State{CurrentStepDescription: "after", Status: StatusCompleted}).Save(tmp))
+	require.NoError(t, os.Rename(tmp, path))
+
+	state := nextState(t, w)
+	assert.Equal(t, "after", state.CurrentStepDescription)
+	assert.Equal(t, StatusCompleted, state.Status)
+}
+
+func TestStateWatcher_SkipsPartialWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.json")
+	w := startWatcher(t, path)
+
+	// A truncated document is never delivered
+	require.NoError(t, os.WriteFile(path, []byte(`{"current_step_description": "half`), 0644))
+	assertNoUpdate(t, w)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"current_step_description": "whole", "status": "running"}`), 0644))
+	assert.Equal(t, "whole", nextState(t, w).CurrentStepDescription)
+}
+
+func TestStateWatcher_IgnoresIdenticalRewrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.json")
+	state := &State{CurrentStepDescription: "same", NextStepPrompt: "/continue", Status: StatusRunning}
+	require.NoError(t, state.Save(path))
+
+	w := startWatcher(t, path)
+	nextState(t, w)
+
+	require.NoError(t, state.Save(path))
+	assertNoUpdate(t, w)
+}
+
+func TestStateWatcher_DirectoryCreatedLater(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "agent_state")
+	path := filepath.Join(dir, "agent_state.json")
+	w := startWatcher(t, path)
+
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, (&State{CurrentStepDescription: "created", NextStepPrompt: "/start", Status: StatusRunning}).Save(path))
+	assert.Equal(t, "created", nextState(t, w).CurrentStepDescription)
+
+	// Once the directory exists, later writes are still observed
+	require.NoError(t, (&State{CurrentStepDescription: "updated", Status: StatusCompleted}).Save(path))
+	assert.Equal(t, "updated", nextState(t, w).CurrentStepDescription)
+}
+
+func TestStateWatcher_StopClosesUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent_state.json")
+	w := NewStateWatcher(path)
+	require.NoError(t, w.Start(context.Background()))
+	assert.Error(t, w.Start(context.Background()), "second Start should fail")
+
+	w.Stop()
+	w.Stop()
+
+	_, ok := <-w.Updates()
+	assert.False(t, ok)
+}
+
+func TestStateWatcher_EmptyPath(t *testing.T) {
+	assert.Error(t, NewStateWatcher("").Start(context.Background()))
+}
@@ -157,6 +157,29 @@ func (c *Client) RunError(runID string, task string, err error) {
 	_ = c.PostEventAsync("RunError", eventData)
 }
 
+// RunStalled implements EventEmitter by posting a RunStalled event
+func (c *Client) RunStalled(runID string, task string, stalls int) {
+	_ = c.PostEventAsync("RunStalled", map[string]interface{}{
+		"task":   task,
+		"stalls": stalls,
+	})
+}
+
+// RunRetrying implements EventEmitter by posting a RunRetrying event
+func (c *Client) RunRetrying(runID string, task string, attempt int, reason string, delay time.Duration) {
+	_ = c.PostEventAsync("RunRetrying", map[string]interface{}{
+		"task":     task,
+		"attempt":  attempt,
+		"reason":   reason,
+		"delay_ms": delay.Milliseconds(),
+	})
+}
+
+// GateResult implements EventEmitter by posting a GateResult event
+func (c *Client) GateResult(runID string, task string, gate Gate) {
+	_ = c.PostEventAsync("GateResult", gate.data(task))
+}
+
 // StateSnapshot implements EventEmitter by posting a StateSnapshot event
 func (c *Client) StateSnapshot(runID string, snapshot interface{}) {
 	_ = c.PostEventAsync("StateSnapshot", map[string]interface{}{
@@ -19,6 +19,42 @@ type EventEmitter interface {
 
 	// StateSnapshot is called when the agent state changes
 	StateSnapshot(runID string, snapshot interface{})
+
+	// RunStalled is called when Claude exits without updating the agent state.
+	// stalls is the number of consecutive stalls so far.
+	RunStalled(runID string, task string, stalls int)
+
+	// RunRetrying is called before Claude is re-run after a transient failure.
+	// attempt is the retry about to be made and reason the classified failure.
+	RunRetrying(runID string, task string, attempt int, reason string, delay time.Duration)
+
+	// GateResult is called after alpine runs a quality gate
+	GateResult(runID string, task string, gate Gate)
+}
+
+// Gate is the result of a quality gate alpine ran after an iteration
+type Gate struct {
+	Name      string
+	Command   string
+	Iteration int
+	Passed    bool
+
+	// Output is the gate's truncated output when it failed
+	Output   string
+	Duration time.Duration
+}
+
+// data returns the event data describing the gate
+func (g Gate) data(task string) map[string]interface{} {
+	return map[string]interface{}{
+		"task":        task,
+		"gate":        g.Name,
+		"command":     g.Command,
+		"iteration":   g.Iteration,
+		"passed":      g.Passed,
+		"output":      g.Output,
+		"duration_ms": g.Duration.Milliseconds(),
+	}
 }
 
 // MockCall represents a single method call to the MockEmitter
@@ -28,6 +64,11 @@ type MockCall struct {
 	Task      string
 	Error     error
 	Snapshot  interface{}
+	Stalls    int
+	Attempt   int
+	Reason    string
+	Delay     time.Duration
+	Gate      Gate
 	Timestamp time.Time
 }
 
@@ -84,6 +125,41 @@ func (m *MockEmitter) StateSnapshot(runID string, snapshot interface{}) {
 	})
 }
 
+// RunStalled records a RunStalled call
+func (m *MockEmitter) RunStalled(runID string, task string, stalls int) {
+	m.Calls = append(m.Calls, MockCall{
+		Method:    "RunStalled",
+		RunID:     runID,
+		Task:      task,
+		Stalls:    stalls,
+		Timestamp: time.Now(),
+	})
+}
+
+// RunRetrying records a RunRetrying call
+func (m *MockEmitter) RunRetrying(runID string, task string, attempt int, reason string, delay time.Duration) {
+	m.Calls = append(m.Calls, MockCall{
+		Method:    "RunRetrying",
+		RunID:     runID,
+		Task:      task,
+		Attempt:   attempt,
+		Reason:    reason,
+		Delay:     delay,
+		Timestamp: time.Now(),
+	})
+}
+
+// GateResult records a GateResult call
+func (m *MockEmitter) GateResult(runID string, task string, gate Gate) {
+	m.Calls = append(m.Calls, MockCall{
+		Method:    "GateResult",
+		RunID:     runID,
+		Task:      task,
+		Gate:      gate,
+		Timestamp: time.Now(),
+	})
+}
+
 // GetLastCall returns the last recorded call or nil if no calls were made
 func (m *MockEmitter) GetLastCall() *MockCall {
 	if len(m.Calls) == 0 {
@@ -135,6 +211,18 @@ func (m *MockEmitter) GetRawEvents() []map[string]interface{} {
 			}
 		case "StateSnapshot":
 			event["data"].(map[string]interface{})["snapshot"] = call.Snapshot
+		case "RunStalled":
+			event["data"].(map[string]interface{})["task"] = call.Task
+			event["data"].(map[string]interface{})["stalls"] = call.Stalls
+		case "RunRetrying":
+			event["data"].(map[string]interface{})["task"] = call.Task
+			event["data"].(map[string]interface{})["attempt"] = call.Attempt
+			event["data"].(map[string]interface{})["reason"] = call.Reason
+			event["data"].(map[string]interface{})["delay_ms"] = call.Delay.Milliseconds()
+		case "GateResult":
+			data := call.Gate.data(call.Task)
+			data["runId"] = call.RunID
+			event["data"] = data
 		}
 
 		events = append(events, event)
@@ -170,6 +258,21 @@ func (n *NoOpEmitter) StateSnapshot(runID string, snapshot interface{}) {
 	// No operation
 }
 
+// RunStalled does nothing
+func (n *NoOpEmitter) RunStalled(runID string, task string, stalls int) {
+	// No operation
+}
+
+// RunRetrying does nothing
+func (n *NoOpEmitter) RunRetrying(runID string, task string, attempt int, reason string, delay time.Duration) {
+	// No operation
+}
+
+// GateResult does nothing
+func (n *NoOpEmitter) GateResult(runID string, task string, gate Gate) {
+	// No operation
+}
+
 // ServerEventEmitter broadcasts events via server's event system
 type ServerEventEmitter struct {
 	runID string
@@ -228,3 +331,32 @@ func (s *ServerEventEmitter) StateSnapshot(runID string, snapshot interface{}) {
 		})
 	}
 }
+
+// RunStalled broadcasts a RunStalled event
+func (s *ServerEventEmitter) RunStalled(runID string, task string, stalls int) {
+	if s.broadcastFunc != nil {
+		s.broadcastFunc("RunStalled", runID, map[string]interface{}{
+			"task":   task,
+			"stalls": stalls,
+		})
+	}
+}
+
+// RunRetrying broadcasts a RunRetrying event
+func (s *ServerEventEmitter) RunRetrying(runID string, task string, attempt int, reason string, delay time.Duration) {
+	if s.broadcastFunc != nil {
+		s.broadcastFunc("RunRetrying", runID, map[string]interface{}{
+			"task":     task,
+			"attempt":  attempt,
+			"reason":   reason,
+			"delay_ms": delay.Milliseconds(),
+		})
+	}
+}
+
+// GateResult broadcasts a GateResult event
+func (s *ServerEventEmitter) GateResult(runID string, task string, gate Gate) {
+	if s.broadcastFunc != nil {
+		s.broadcastFunc("GateResult", runID, gate.data(task))
+	}
+}
@@ -188,3 +188,34 @@ func TestMockEmitterFindCallsByMethod(t *testing.T) {
 		t.Errorf("Expected 1 RunError call, got %d", len(errorCalls))
 	}
 }
+
+// TestServerEventEmitterGateResult verifies that gate results are broadcast
+// with the gate's outcome and duration
+func TestServerEventEmitterGateResult(t *testing.T) {
+	var gotType, gotRunID string
+	var gotData map[string]interface{}
+	emitter := NewServerEventEmitter("run1", func(eventType, runID string, data map[string]interface{}) {
+		gotType, gotRunID, gotData = eventType, runID, data
+	})
+
+	emitter.GateResult("run1", "task1", Gate{
+		Name:      "test",
+		Command:   "go test ./...",
+		Iteration: 3,
+		Output:    "FAIL",
+		Duration:  1500 * time.Millisecond,
+	})
+
+	if gotType != "GateResult" || gotRunID != "run1" {
+		t.Fatalf("broadcast %s for %s, want GateResult for run1", gotType, gotRunID)
+	}
+	want := map[string]interface{}{
+		"task": "task1", "gate": "test", "command": "go test ./...", "iteration": 3,
+		"passed": false, "output": "FAIL", "duration_ms": int64(1500),
+	}
+	for key, value := range want {
+		if gotData[key] != value {
+			t.Errorf("data[%s] = %v, want %v", key, gotData[key], value)
+		}
+	}
+}
@@ -5,10 +5,7 @@ package events
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
@@ -17,12 +14,14 @@ import (
 )
 
 const (
-	// defaultPollInterval is the default interval for checking state file changes
-	defaultPollInterval = 100 * time.Millisecond
+	// defaultPollInterval is the fallback interval for checking state file changes
+	// when file notifications are unavailable
+	defaultPollInterval = core.DefaultWatchPollInterval
 )
 
 // StateMonitor watches the agent_state.json file for changes and emits StateSnapshot events.
-// It polls the file at regular intervals and emits events when changes are detected.
+// It uses a core.StateWatcher, so changes are picked up from file notifications where
+// available and by polling otherwise.
 // The monitor is safe for concurrent use.
 type StateMonitor struct {
 	stateFile    string
@@ -36,7 +35,7 @@ type StateMonitor struct {
 }
 
 // NewStateMonitor creates a new state monitor that watches the specified state file.
-// It uses the default polling interval of 100ms.
+// When file notifications are unavailable it polls every 100ms.
 func NewStateMonitor(stateFile string, emitter EventEmitter, runID string) *StateMonitor {
 	return &StateMonitor{
 		stateFile:    stateFile,
@@ -70,15 +69,17 @@ func (m *StateMonitor) Start(ctx context.Context) error {
 	return nil
 }
 
-// monitor is the main loop that checks for state file changes
+// monitor is the main loop that forwards state file changes as events
 func (m *StateMonitor) monitor(ctx context.Context) {
 	defer close(m.stoppedChan)
 
-	ticker := time.NewTicker(m.pollInterval)
-	defer ticker.Stop()
-
-	// Initial check for existing file
-	m.checkAndEmit()
+	watcher := core.NewStateWatcher(m.stateFile)
+	watcher.SetPollInterval(m.pollInterval)
+	if err := watcher.Start(ctx); err != nil {
+		logger.Debugf("Failed to start state watcher: %v", err)
+		return
+	}
+	defer watcher.Stop()
 
 	for {
 		select {
@@ -88,48 +89,27 @@ func (m *StateMonitor) monitor(ctx context.Context) {
 		case <-m.stopChan:
 			logger.Debug("State monitor stopping due to stop signal")
 			return
-		case <-ticker.C:
-			m.checkAndEmit()
+		case state, ok := <-watcher.Updates():
+			if !ok {
+				return
+			}
+			m.emitIfChanged(state)
 		}
 	}
 }
 
-// checkAndEmit checks if the state file has changed and emits an event if it has
-func (m *StateMonitor) checkAndEmit() {
-	// Check if file exists
-	stateDir := filepath.Dir(m.stateFile)
-	if _, err := os.Stat(stateDir); os.IsNotExist(err) {
-		// Directory doesn't exist yet, skip
-		return
-	}
-
-	// Try to read the state file
-	data, err := os.ReadFile(m.stateFile)
-	if err != nil {
-		if !os.IsNotExist(err) {
-			logger.Debugf("Error reading state file: %v", err)
-		}
-		return
-	}
-
-	// Parse the state
-	var state core.State
-	if err := json.Unmarshal(data, &state); err != nil {
-		logger.Debugf("Error parsing state file: %v", err)
-		return
-	}
-
-	// Check if state has changed
+// emitIfChanged emits a StateSnapshot event if the state differs from the last one emitted
+func (m *StateMonitor) emitIfChanged(state *core.State) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if m.hasStateChanged(&state) {
+	if m.hasStateChanged(state) {
 		// Update last state
-		m.lastState = &state
+		m.lastState = state
 
 		// Emit StateSnapshot event
 		logger.Debug("State change detected, emitting StateSnapshot event")
-		m.emitter.StateSnapshot(m.runID, state)
+		m.emitter.StateSnapshot(m.runID, *state)
 	}
 }
 
@@ -1,4 +1,4 @@
-// Package github provides utilities for interacting with GitHub issues
+// Package github provides utilities for interacting with GitHub issues and pull requests
 package github
 
 import (
@@ -11,8 +11,10 @@ import (
 
 // GitHubIssue represents the structure of a GitHub issue response
 type GitHubIssue struct {
-	Title string `json:"title"`
-	Body  string `json:"body"`
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	URL    string `json:"url"`
 }
 
 // IsGitHubIssueURL checks if a URL is a valid GitHub issue URL
@@ -25,43 +27,61 @@ func IsGitHubIssueURL(url string) bool {
 // FetchIssueDescription fetches issue data from GitHub using the gh CLI
 // Returns formatted task description with title and body
 func FetchIssueDescription(url string) (string, error) {
+	issue, err := fetchIssue(url, "title,body")
+	if err != nil {
+		return "", err
+	}
+
+	// Format task description (same format as used in plan.go)
+	taskDesc := fmt.Sprintf("Task: %s\n\n%s", issue.Title, issue.Body)
+
+	return taskDesc, nil
+}
+
+// FetchIssue fetches the number, title, body and URL of an issue using the gh CLI
+func FetchIssue(url string) (*GitHubIssue, error) {
+	return fetchIssue(url, "number,title,body,url")
+}
+
+// fetchIssue fetches the given JSON fields of an issue using the gh CLI
+func fetchIssue(url, fields string) (*GitHubIssue, error) {
 	if !IsGitHubIssueURL(url) {
-		return "", fmt.Errorf("invalid GitHub issue URL: %s", url)
+		return nil, fmt.Errorf("invalid GitHub issue URL: %s", url)
 	}
 
 	// Execute gh command
-	cmd := exec.Command("gh", "issue", "view", url, "--json", "title,body")
+	cmd := exec.Command("gh", "issue", "view", url, "--json", fields)
 
 	// Capture output
 	output, err := cmd.Output()
 	if err != nil {
 		// Check if gh is not found
 		if strings.Contains(err.Error(), "executable file not found") {
-			return "", fmt.Errorf("gh CLI not found. Please install from https://cli.github.com")
+			return nil, fmt.Errorf("gh CLI not found. Please install from https://cli.github.com")
 		}
 
 		// Check for exit error with stderr
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			stderr := string(exitErr.Stderr)
-			return "", fmt.Errorf("gh command failed: %s", stderr)
+			return nil, fmt.Errorf("gh command failed: %s", stderr)
 		}
 
-		return "", fmt.Errorf("gh command execution failed: %w", err)
+		return nil, fmt.Errorf("gh command execution failed: %w", err)
 	}
 
 	// Parse JSON response
 	var issue GitHubIssue
 	if err := json.Unmarshal(output, &issue); err != nil {
-		return "", fmt.Errorf("failed to parse gh response: %w", err)
+		return nil, fmt.Errorf("failed to parse gh response: %w", err)
 	}
 
 	// Validate required fields
 	if issue.Title == "" {
-		return "", fmt.Errorf("empty issue title received from GitHub")
+		return nil, fmt.Errorf("empty issue title received from GitHub")
+	}
+	if issue.URL == "" {
+		issue.URL = url
 	}
 
-	// Format task description (same format as used in plan.go)
-	taskDesc := fmt.Sprintf("Task: %s\n\n%s", issue.Title, issue.Body)
-
-	return taskDesc, nil
+	return &issue, nil
 }
@@ -0,0 +1,210 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the base URL of github.com's REST API
+const DefaultAPIURL = "https://api.github.com"
+
+// Repository identifies a GitHub repository
+type Repository struct {
+	Owner string
+	Name  string
+}
+
+// String returns the repository as owner/name
+func (r Repository) String() string {
+	return r.Owner + "/" + r.Name
+}
+
+// PullRequest is a pull request to open
+type PullRequest struct {
+	// Title and Body describe the change; Body is Markdown
+	Title string `json:"title"`
+	Body  string `json:"body"`
+
+	// Head is the branch with the changes and Base the branch they are
+	// proposed for
+	Head string `json:"head"`
+	Base string `json:"base"`
+}
+
+// PullRequestInfo is an opened pull request
+type PullRequestInfo struct {
+	Number int    `json:"number"`
+	URL    string `json:"html_url"`
+}
+
+// PullRequestCreator opens pull requests
+type PullRequestCreator interface {
+	// CreatePullRequest opens pr on repo. When an open pull request already
+	// proposes the same head for the same base, it is returned instead.
+	CreatePullRequest(ctx context.Context, repo Repository, pr PullRequest) (*PullRequestInfo, error)
+}
+
+// APIError is an unsuccessful response of the GitHub REST API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("GitHub API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Client calls the GitHub REST API. It implements PullRequestCreator.
+type Client struct {
+	// BaseURL is the API's base URL, DefaultAPIURL for github.com
+	BaseURL string
+
+	// Token authenticates requests; requests are anonymous without one
+	Token string
+
+	// HTTPClient sends the requests
+	HTTPClient *http.Client
+}
+
+// NewClient creates a client of the REST API at baseURL, or github.com's
+// when baseURL is empty
+func NewClient(baseURL, token string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultAPIURL
+	}
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// CreatePullRequest opens pr on repo, returning the already open pull
+// request of pr.Head when GitHub reports that one exists
+func (c *Client) CreatePullRequest(ctx context.Context, repo Repository, pr PullRequest) (*PullRequestInfo, error) {
+	path := fmt.Sprintf("/repos/%s/%s/pulls", url.PathEscape(repo.Owner), url.PathEscape(repo.Name))
+	var created PullRequestInfo
+	err := c.do(ctx, http.MethodPost, path, pr, &created)
+	if err == nil {
+		return &created, nil
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity || !strings.Contains(apiErr.Message, "already exists") {
+		return nil, err
+	}
+	query := url.Values{"state": {"open"}, "head": {repo.Owner + ":" + pr.Head}, "base": {pr.Base}}
+	var open []PullRequestInfo
+	if listErr := c.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, &open); listErr != nil || len(open) == 0 {
+		return nil, err
+	}
+	return &open[0], nil
+}
+
+// do sends a request with body encoded as JSON and decodes the response into result
+func (c *Client) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("GitHub API request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read GitHub API response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &APIError{StatusCode: resp.StatusCode, Message: errorMessage(data)}
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("failed to parse GitHub API response: %w", err)
+	}
+	return nil
+}
+
+// errorMessage returns the message of a GitHub API error response, with the
+// messages of its validation errors
+func errorMessage(data []byte) string {
+	var resp struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil || resp.Message == "" {
+		return strings.TrimSpace(string(data))
+	}
+	messages := []string{resp.Message}
+	for _, e := range resp.Errors {
+		if e.Message != "" {
+			messages = append(messages, e.Message)
+		}
+	}
+	return strings.Join(messages, ": ")
+}
+
+// remotePattern matches the owner and name at the end of a git remote URL:
+// https://[token@]host/owner/name[.git], ssh://git@host/owner/name[.git] or
+// git@host:owner/name[.git]
+var remotePattern = regexp.MustCompile(`^(?:[a-z+]+://[^/]+/|[^@/]+@[^:/]+:)([^/]+)/([^/]+?)(?:\.git)?/?$`)
+
+// ParseRepository returns the repository of a git remote URL
+func ParseRepository(remoteURL string) (Repository, error) {
+	matches := remotePattern.FindStringSubmatch(strings.TrimSpace(remoteURL))
+	if matches == nil {
+		return Repository{}, fmt.Errorf("not a GitHub repository URL: %s", remoteURL)
+	}
+	return Repository{Owner: matches[1], Name: matches[2]}, nil
+}
+
+// issuePattern captures the repository and number of a GitHub issue URL
+var issuePattern = regexp.MustCompile(`^https://github\.com/([^/]+)/([^/]+)/issues/(\d+)$`)
+
+// ParseIssueURL returns the repository and number of a GitHub issue URL
+func ParseIssueURL(issueURL string) (Repository, int, error) {
+	matches := issuePattern.FindStringSubmatch(issueURL)
+	if matches == nil {
+		return Repository{}, 0, fmt.Errorf("invalid GitHub issue URL: %s", issueURL)
+	}
+	number, err := strconv.Atoi(matches[3])
+	if err != nil {
+		return Repository{}, 0, fmt.Errorf("invalid issue number in %s: %w", issueURL, err)
+	}
+	return Repository{Owner: matches[1], Name: matches[2]}, number, nil
+}
@@ -0,0 +1,67 @@
+package gitx
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// Checkpoint is a commit alpine made after an iteration of a run
+type Checkpoint struct {
+	Commit    string
+	RunID     string
+	Iteration int
+}
+
+// Checkpoints returns the iteration commits on the branch checked out in dir,
+// newest first. Commits without alpine's run and iteration trailers, such as
+// squashed commits, are skipped.
+func Checkpoints(ctx context.Context, dir string) ([]Checkpoint, error) {
+	format := "--format=%H%x1f%(trailers:key=" + TrailerRunID + ",valueonly,separator=%x2C)" +
+		"%x1f%(trailers:key=" + TrailerIteration + ",valueonly,separator=%x2C)%x1e"
+	output, err := git(ctx, dir, "log", format, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	var checkpoints []Checkpoint
+	for _, record := range strings.Split(output, "\x1e") {
+		fields := strings.Split(strings.TrimSpace(record), "\x1f")
+		if len(fields) != 3 {
+			continue
+		}
+		runID := strings.TrimSpace(fields[1])
+		iteration, err := strconv.Atoi(strings.TrimSpace(fields[2]))
+		if runID == "" || err != nil {
+			continue
+		}
+		checkpoints = append(checkpoints, Checkpoint{Commit: fields[0], RunID: runID, Iteration: iteration})
+	}
+	return checkpoints, nil
+}
+
+// Reset discards every change in dir since commit: the branch is moved back
+// to it and untracked files are removed, except those matching exclude.
+func Reset(ctx context.Context, dir, commit string, exclude []string) error {
+	if _, err := git(ctx, dir, "reset", "-q", "--hard", commit); err != nil {
+		return err
+	}
+	clean := []string{"clean", "-q", "-fd"}
+	for _, pattern := range exclude {
+		clean = append(clean, "-e", pattern)
+	}
+	_, err := git(ctx, dir, clean...)
+	return err
+}
+
+// Dirty reports whether dir has uncommitted changes outside exclude
+func Dirty(ctx context.Context, dir string, exclude []string) (bool, error) {
+	args := []string{"status", "--porcelain", "--", "."}
+	for _, path := range exclude {
+		args = append(args, ":(exclude)"+path)
+	}
+	output, err := git(ctx, dir, args...)
+	if err != nil {
+		return false, err
+	}
+	return output != "", nil
+}
@@ -0,0 +1,83 @@
+package gitx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// commitIteration commits a file as an iteration of run
+func commitIteration(t *testing.T, dir, runID string, iteration int, file string) string {
+	t.Helper()
+	writeFile(t, filepath.Join(dir, file), file)
+	hash, err := CommitAll(context.Background(), dir, Commit{
+		Message:  "Add " + file,
+		Trailers: []Trailer{{Key: TrailerRunID, Value: runID}, {Key: TrailerIteration, Value: strconv.Itoa(iteration)}},
+	})
+	if err != nil {
+		t.Fatalf("CommitAll() returned unexpected error: %v", err)
+	}
+	return hash
+}
+
+// TestCheckpoints tests listing the iteration commits on the current branch
+func TestCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	dir := initRepo(t)
+	first := commitIteration(t, dir, "run-1", 1, "a.txt")
+	writeFile(t, filepath.Join(dir, "b.txt"), "b")
+	gitOutput(t, dir, "add", "b.txt")
+	gitOutput(t, dir, "commit", "-q", "-m", "Manual change")
+	third := commitIteration(t, dir, "run-2", 3, "c.txt")
+
+	checkpoints, err := Checkpoints(ctx, dir)
+	if err != nil {
+		t.Fatalf("Checkpoints() returned unexpected error: %v", err)
+	}
+	want := []Checkpoint{
+		{Commit: third, RunID: "run-2", Iteration: 3},
+		{Commit: first, RunID: "run-1", Iteration: 1},
+	}
+	if !reflect.DeepEqual(checkpoints, want) {
+		t.Errorf("Checkpoints() = %+v, want %+v", checkpoints, want)
+	}
+}
+
+// TestReset tests discarding changes back to a commit
+func TestReset(t *testing.T) {
+	ctx := context.Background()
+	dir := initRepo(t)
+	first := commitIteration(t, dir, "run-1", 1, "a.txt")
+	commitIteration(t, dir, "run-1", 2, "b.txt")
+	writeFile(t, filepath.Join(dir, "a.txt"), "changed")
+	writeFile(t, filepath.Join(dir, "new", "c.txt"), "c")
+	writeFile(t, filepath.Join(dir, "agent_state", "agent_state.json"), "{}")
+
+	if dirty, err := Dirty(ctx, dir, []string{"agent_state"}); err != nil || !dirty {
+		t.Errorf("Dirty() = %v, %v, want true", dirty, err)
+	}
+	if err := Reset(ctx, dir, first, []string{"agent_state"}); err != nil {
+		t.Fatalf("Reset() returned unexpected error: %v", err)
+	}
+
+	if head := gitOutput(t, dir, "rev-parse", "HEAD"); head != first {
+		t.Errorf("HEAD = %s, want %s", head, first)
+	}
+	for _, file := range []string{"b.txt", filepath.Join("new", "c.txt")} {
+		if _, err := os.Stat(filepath.Join(dir, file)); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", file)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(data) != "a.txt" {
+		t.Errorf("a.txt = %q, want the committed content", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "agent_state", "agent_state.json")); err != nil {
+		t.Errorf("excluded state file was removed: %v", err)
+	}
+	if dirty, err := Dirty(ctx, dir, []string{"agent_state"}); err != nil || dirty {
+		t.Errorf("Dirty() = %v, %v, want false", dirty, err)
+	}
+}
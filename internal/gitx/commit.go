@@ -0,0 +1,107 @@
+package gitx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Trailer keys of the commits alpine makes
+const (
+	TrailerRunID     = "Alpine-Run-ID"
+	TrailerIteration = "Alpine-Iteration"
+)
+
+// fallbackIdentity is used when the repository has no committer configured
+var fallbackIdentity = []string{"-c", "user.name=alpine", "-c", "user.email=alpine@localhost"}
+
+// Trailer is a "Key: Value" line at the end of a commit message
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+// Commit describes a commit made by CommitAll or Squash.
+type Commit struct {
+	// Message is the commit message; its first line is the subject
+	Message string
+
+	// Trailers are appended to the message
+	Trailers []Trailer
+
+	// Exclude are pathspecs left out of the commit, e.g. alpine's state directory
+	Exclude []string
+}
+
+// message returns the full commit message with trailers
+func (c Commit) message() string {
+	msg := strings.TrimSpace(c.Message)
+	if len(c.Trailers) == 0 {
+		return msg
+	}
+	lines := make([]string, len(c.Trailers))
+	for i, t := range c.Trailers {
+		lines[i] = t.Key + ": " + t.Value
+	}
+	return msg + "\n\n" + strings.Join(lines, "\n")
+}
+
+// Head returns the hash of the commit checked out in dir.
+func Head(ctx context.Context, dir string) (string, error) {
+	return git(ctx, dir, "rev-parse", "HEAD")
+}
+
+// CommitAll stages every change in dir except c.Exclude and commits it. It
+// returns the new commit's hash, or "" when there was nothing to commit.
+func CommitAll(ctx context.Context, dir string, c Commit) (string, error) {
+	add := []string{"add", "-A", "--", "."}
+	for _, path := range c.Exclude {
+		add = append(add, ":(exclude)"+path)
+	}
+	if _, err := git(ctx, dir, add...); err != nil {
+		return "", err
+	}
+	return commitStaged(ctx, dir, c)
+}
+
+// Squash replaces the commits after base with a single commit of the same
+// tree. It returns the new commit's hash, or "" when there are no commits
+// after base.
+func Squash(ctx context.Context, dir, base string, c Commit) (string, error) {
+	head, err := Head(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+	if head == base {
+		return "", nil
+	}
+	if _, err := git(ctx, dir, "reset", "--soft", base); err != nil {
+		return "", err
+	}
+	hash, err := commitStaged(ctx, dir, c)
+	if err != nil || hash == "" {
+		// Put the original commits back rather than leave them staged
+		if _, resetErr := git(ctx, dir, "reset", "--soft", head); resetErr != nil {
+			return "", fmt.Errorf("%v; restoring %s also failed: %w", err, head, resetErr)
+		}
+		if err == nil {
+			return "", nil
+		}
+	}
+	return hash, err
+}
+
+// commitStaged commits the index, doing nothing when it matches HEAD
+func commitStaged(ctx context.Context, dir string, c Commit) (string, error) {
+	if _, err := git(ctx, dir, "diff", "--cached", "--quiet"); err == nil {
+		return "", nil
+	}
+	args := []string{"commit", "--no-verify", "-q", "-m", c.message()}
+	if email, _ := git(ctx, dir, "config", "user.email"); email == "" {
+		args = append(append([]string(nil), fallbackIdentity...), args...)
+	}
+	if _, err := git(ctx, dir, args...); err != nil {
+		return "", err
+	}
+	return Head(ctx, dir)
+}
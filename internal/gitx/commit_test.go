@@ -0,0 +1,130 @@
+package gitx
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initRepo creates a repository with one commit and returns its directory
+func initRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		if output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	return dir
+}
+
+// gitOutput runs git in dir and returns its output
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCommitAll tests committing every change except excluded paths
+func TestCommitAll(t *testing.T) {
+	ctx := context.Background()
+	dir := initRepo(t)
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n")
+	writeFile(t, filepath.Join(dir, "agent_state", "agent_state.json"), "{}")
+
+	hash, err := CommitAll(ctx, dir, Commit{
+		Message:  "Add main package",
+		Trailers: []Trailer{{Key: TrailerRunID, Value: "run-1"}, {Key: TrailerIteration, Value: "2"}},
+		Exclude:  []string{"agent_state"},
+	})
+	if err != nil {
+		t.Fatalf("CommitAll failed: %v", err)
+	}
+	if head, _ := Head(ctx, dir); hash == "" || hash != head {
+		t.Errorf("CommitAll returned %q, HEAD is %q", hash, head)
+	}
+	if got := gitOutput(t, dir, "log", "-1", "--format=%B"); got != "Add main package\n\nAlpine-Run-ID: run-1\nAlpine-Iteration: 2" {
+		t.Errorf("commit message = %q", got)
+	}
+	if got := gitOutput(t, dir, "log", "-1", "--format=%(trailers:key=Alpine-Iteration,valueonly)"); got != "2" {
+		t.Errorf("iteration trailer = %q, want 2", got)
+	}
+	if got := gitOutput(t, dir, "show", "--name-only", "--format=", "HEAD"); got != "main.go" {
+		t.Errorf("committed files = %q, want only main.go", got)
+	}
+
+	// Nothing left to commit
+	hash, err = CommitAll(ctx, dir, Commit{Message: "Nothing", Exclude: []string{"agent_state"}})
+	if err != nil || hash != "" {
+		t.Errorf("CommitAll without changes = %q, %v; want no commit", hash, err)
+	}
+}
+
+// TestCommitAll_FallbackIdentity tests committing without a configured committer
+func TestCommitAll_FallbackIdentity(t *testing.T) {
+	dir := initRepo(t)
+	gitOutput(t, dir, "config", "--unset", "user.email")
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	writeFile(t, filepath.Join(dir, "a.txt"), "a")
+
+	if _, err := CommitAll(context.Background(), dir, Commit{Message: "Add a"}); err != nil {
+		t.Fatalf("CommitAll failed: %v", err)
+	}
+	if got := gitOutput(t, dir, "log", "-1", "--format=%ae"); got != "alpine@localhost" {
+		t.Errorf("author email = %q, want alpine@localhost", got)
+	}
+}
+
+// TestSquash tests replacing a branch's commits with one
+func TestSquash(t *testing.T) {
+	ctx := context.Background()
+	dir := initRepo(t)
+	base, _ := Head(ctx, dir)
+
+	if hash, err := Squash(ctx, dir, base, Commit{Message: "Nothing"}); err != nil || hash != "" {
+		t.Errorf("Squash without commits = %q, %v; want no commit", hash, err)
+	}
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		writeFile(t, filepath.Join(dir, name), name)
+		if _, err := CommitAll(ctx, dir, Commit{Message: "Add " + name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash, err := Squash(ctx, dir, base, Commit{Message: "Add files", Trailers: []Trailer{{Key: TrailerRunID, Value: "run-1"}}})
+	if err != nil {
+		t.Fatalf("Squash failed: %v", err)
+	}
+	if got := gitOutput(t, dir, "rev-list", "--count", base+"..HEAD"); got != "1" {
+		t.Errorf("commits after base = %s, want 1", got)
+	}
+	if got := gitOutput(t, dir, "rev-parse", "HEAD"); got != hash {
+		t.Errorf("Squash returned %q, HEAD is %q", hash, got)
+	}
+	if got := gitOutput(t, dir, "show", "--name-only", "--format=%s", "HEAD"); got != "Add files\n\na.txt\nb.txt" {
+		t.Errorf("squashed commit = %q", got)
+	}
+}
@@ -0,0 +1,15 @@
+package gitx
+
+import "context"
+
+// RemoteURL returns the fetch URL of remote in the repository at dir.
+func RemoteURL(ctx context.Context, dir, remote string) (string, error) {
+	return git(ctx, dir, "remote", "get-url", remote)
+}
+
+// Push pushes branch to remote and sets it as the branch's upstream. The
+// push is not forced, so it fails when the remote branch has diverged.
+func Push(ctx context.Context, dir, remote, branch string) error {
+	_, err := git(ctx, dir, "push", "-q", "-u", remote, branch+":"+branch)
+	return err
+}
@@ -0,0 +1,56 @@
+package gitx
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPush(t *testing.T) {
+	ctx := context.Background()
+	dir := initRepo(t)
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	gitOutput(t, dir, "init", "-q", "--bare", remote)
+	gitOutput(t, dir, "remote", "add", "origin", remote)
+	gitOutput(t, dir, "checkout", "-q", "-b", "alpine/test")
+	writeFile(t, filepath.Join(dir, "file.txt"), "content")
+	hash, err := CommitAll(ctx, dir, Commit{Message: "Add file"})
+	if err != nil {
+		t.Fatalf("CommitAll() error = %v", err)
+	}
+
+	url, err := RemoteURL(ctx, dir, "origin")
+	if err != nil {
+		t.Fatalf("RemoteURL() error = %v", err)
+	}
+	if url != remote {
+		t.Errorf("RemoteURL() = %q, want %q", url, remote)
+	}
+
+	if err := Push(ctx, dir, "origin", "alpine/test"); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if got := gitOutput(t, remote, "rev-parse", "alpine/test"); got != hash {
+		t.Errorf("remote alpine/test = %s, want %s", got, hash)
+	}
+	if upstream := gitOutput(t, dir, "rev-parse", "--abbrev-ref", "alpine/test@{upstream}"); upstream != "origin/alpine/test" {
+		t.Errorf("upstream = %q, want origin/alpine/test", upstream)
+	}
+
+	// A diverged remote branch is not overwritten
+	gitOutput(t, dir, "reset", "-q", "--hard", "HEAD^")
+	writeFile(t, filepath.Join(dir, "other.txt"), "content")
+	if _, err := CommitAll(ctx, dir, Commit{Message: "Other"}); err != nil {
+		t.Fatalf("CommitAll() error = %v", err)
+	}
+	if err := Push(ctx, dir, "origin", "alpine/test"); err == nil || !strings.Contains(err.Error(), "git push failed") {
+		t.Errorf("Push() error = %v, want a rejected push", err)
+	}
+}
+
+func TestRemoteURL_Missing(t *testing.T) {
+	if _, err := RemoteURL(context.Background(), initRepo(t), "origin"); err == nil {
+		t.Error("RemoteURL() should fail without the remote")
+	}
+}
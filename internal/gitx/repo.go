@@ -0,0 +1,56 @@
+package gitx
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// RepoInfo describes the git repository containing a directory.
+type RepoInfo struct {
+	// Root is the top-level directory of the repository (or worktree)
+	Root string
+
+	// Name is the base name of Root
+	Name string
+
+	// Branch is the checked-out branch, empty when HEAD is detached
+	Branch string
+}
+
+// InspectRepo returns the repository containing dir.
+func InspectRepo(ctx context.Context, dir string) (*RepoInfo, error) {
+	root, err := git(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	info := &RepoInfo{Root: root, Name: filepath.Base(root)}
+
+	// symbolic-ref fails on a detached HEAD, which leaves Branch empty
+	if branch, err := git(ctx, dir, "symbolic-ref", "--short", "-q", "HEAD"); err == nil {
+		info.Branch = branch
+	}
+	return info, nil
+}
+
+// CommonDir returns the absolute path of the git directory shared by the
+// repository containing dir and all its worktrees.
+func CommonDir(ctx context.Context, dir string) (string, error) {
+	return git(ctx, dir, "rev-parse", "--path-format=absolute", "--git-common-dir")
+}
+
+// git runs a git command in dir and returns its trimmed output.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
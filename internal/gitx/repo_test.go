@@ -0,0 +1,69 @@
+package gitx
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestInspectRepo tests reading the root, name and branch of a repository
+func TestInspectRepo(t *testing.T) {
+	repoDir := filepath.Join(t.TempDir(), "my-repo")
+	if output, err := exec.Command("git", "init", "-b", "feature/x", repoDir).CombinedOutput(); err != nil {
+		t.Fatalf("Failed to init git repo: %v\n%s", err, output)
+	}
+	subDir := filepath.Join(repoDir, "sub")
+	if err := os.Mkdir(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create subdirectory: %v", err)
+	}
+
+	info, err := InspectRepo(context.Background(), subDir)
+	if err != nil {
+		t.Fatalf("InspectRepo failed: %v", err)
+	}
+	resolved, _ := filepath.EvalSymlinks(repoDir)
+	if info.Root != resolved && info.Root != repoDir {
+		t.Errorf("expected root %s, got %s", repoDir, info.Root)
+	}
+	if info.Name != "my-repo" {
+		t.Errorf("expected name my-repo, got %s", info.Name)
+	}
+	if info.Branch != "feature/x" {
+		t.Errorf("expected branch feature/x, got %s", info.Branch)
+	}
+}
+
+// TestInspectRepo_NotARepository tests that directories outside git are reported
+func TestInspectRepo_NotARepository(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+	if _, err := InspectRepo(context.Background(), dir); err == nil {
+		t.Error("expected an error outside a git repository")
+	}
+}
+
+// TestCommonDir tests that a worktree shares the main repository's git directory
+func TestCommonDir(t *testing.T) {
+	repoDir := filepath.Join(t.TempDir(), "repo")
+	worktreeDir := filepath.Join(t.TempDir(), "worktree")
+	for _, args := range [][]string{
+		{"init", "-b", "main", repoDir},
+		{"-C", repoDir, "-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "--allow-empty", "-m", "initial"},
+		{"-C", repoDir, "worktree", "add", "-b", "feature", worktreeDir},
+	} {
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	gitDir, err := CommonDir(context.Background(), worktreeDir)
+	if err != nil {
+		t.Fatalf("CommonDir failed: %v", err)
+	}
+	resolved, _ := filepath.EvalSymlinks(filepath.Join(repoDir, ".git"))
+	if gitDir != resolved && gitDir != filepath.Join(repoDir, ".git") {
+		t.Errorf("expected git directory %s, got %s", filepath.Join(repoDir, ".git"), gitDir)
+	}
+}
@@ -0,0 +1,425 @@
+package hooks
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Backland-Labs/alpine/internal/policy"
+)
+
+// Environment variables that configure the hook handler
+const (
+	// EnvTodoFile names the file the current in-progress task is written to
+	EnvTodoFile = "ALPINE_TODO_FILE"
+	// EnvEventsEndpoint is the URL AG-UI tool call events are posted to
+	EnvEventsEndpoint = "ALPINE_EVENTS_ENDPOINT"
+	// EnvRunID identifies the run that AG-UI events belong to
+	EnvRunID = "ALPINE_RUN_ID"
+	// EnvPolicyFile names the policy file PreToolUse calls are checked against
+	EnvPolicyFile = "ALPINE_POLICY_FILE"
+)
+
+// Claude Code hook event names handled by the hook command
+const (
+	EventPreToolUse   = "PreToolUse"
+	EventPostToolUse  = "PostToolUse"
+	EventSubagentStop = "SubagentStop"
+)
+
+// eventTimeout bounds each AG-UI POST so a slow endpoint never stalls Claude
+const eventTimeout = 5 * time.Second
+
+// Input is the JSON payload Claude Code writes to a hook command's stdin
+type Input struct {
+	HookEventName  string          `json:"hook_event_name"`
+	SessionID      string          `json:"session_id"`
+	TranscriptPath string          `json:"transcript_path"`
+	StopHookActive bool            `json:"stop_hook_active"`
+	ToolName       string          `json:"tool_name"`
+	ToolInput      json.RawMessage `json:"tool_input"`
+	ToolResponse   json.RawMessage `json:"tool_response"`
+	ToolUseID      string          `json:"tool_use_id"`
+	Cwd            string          `json:"cwd"`
+
+	// Legacy field names accepted for compatibility with older payloads
+	Tool       string          `json:"tool"`
+	Args       json.RawMessage `json:"args"`
+	ToolOutput json.RawMessage `json:"tool_output"`
+	ToolCallID string          `json:"tool_call_id"`
+}
+
+// toolName returns the tool name, falling back to the legacy field
+func (in *Input) toolName() string {
+	if in.ToolName != "" {
+		return in.ToolName
+	}
+	return in.Tool
+}
+
+// toolInput returns the raw tool input, falling back to the legacy field
+func (in *Input) toolInput() json.RawMessage {
+	if len(in.ToolInput) > 0 && string(in.ToolInput) != "null" {
+		return in.ToolInput
+	}
+	return in.Args
+}
+
+// toolOutput returns the raw tool output, falling back to the legacy field
+func (in *Input) toolOutput() json.RawMessage {
+	for _, raw := range []json.RawMessage{in.ToolResponse, in.ToolOutput} {
+		if len(raw) > 0 && string(raw) != "null" {
+			return raw
+		}
+	}
+	return nil
+}
+
+// toolCallID returns the ID Claude assigned to the tool call. Older payloads
+// carry none, so an ID is derived from the session and input; PreToolUse and
+// PostToolUse for the same call run in separate processes and must agree on it.
+func (in *Input) toolCallID() string {
+	if in.ToolUseID != "" {
+		return in.ToolUseID
+	}
+	if in.ToolCallID != "" {
+		return in.ToolCallID
+	}
+	sum := sha256.New()
+	sum.Write([]byte(in.SessionID))
+	sum.Write([]byte{0})
+	sum.Write([]byte(in.toolName()))
+	sum.Write([]byte{0})
+	sum.Write(in.toolInput())
+	return "call-" + hex.EncodeToString(sum.Sum(nil))[:16]
+}
+
+// Handler implements Alpine's Claude Code hooks: it logs tool activity to
+// stderr, tracks the current TodoWrite task, blocks tool calls that break the
+// run's policy and forwards tool calls to the AG-UI events endpoint when one
+// is configured.
+type Handler struct {
+	stdout         io.Writer
+	stderr         io.Writer
+	todoFile       string
+	policyFile     string
+	eventsEndpoint string
+	runID          string
+	client         *http.Client
+	now            func() time.Time
+}
+
+// NewHandler creates a hook handler that writes hook decisions to stdout and
+// its log to stderr, and reads its configuration through getenv
+func NewHandler(stdout, stderr io.Writer, getenv func(string) string) *Handler {
+	runID := getenv(EnvRunID)
+	if runID == "" {
+		runID = "unknown"
+	}
+	return &Handler{
+		stdout:         stdout,
+		stderr:         stderr,
+		todoFile:       getenv(EnvTodoFile),
+		policyFile:     getenv(EnvPolicyFile),
+		eventsEndpoint: getenv(EnvEventsEndpoint),
+		runID:          runID,
+		client:         &http.Client{Timeout: eventTimeout},
+		now:            time.Now,
+	}
+}
+
+// Handle processes a single hook invocation read from r. Hooks must never
+// block Claude, so malformed input and delivery failures are not errors;
+// only a failure to read the input is returned.
+func (h *Handler) Handle(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read hook input: %w", err)
+	}
+
+	var input Input
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil
+	}
+
+	switch input.HookEventName {
+	case EventSubagentStop:
+		h.handleSubagentStop(&input)
+	case EventPreToolUse:
+		if h.enforcePolicy(&input) {
+			return nil
+		}
+		h.sendToolCallStart(&input)
+	case EventPostToolUse:
+		h.logToolUse(&input)
+		h.sendToolCallEnd(&input)
+	default:
+		// Payloads without an event name predate hook_event_name and describe
+		// a completed tool call
+		h.logToolUse(&input)
+		h.sendToolCallStart(&input)
+		h.sendToolCallEnd(&input)
+	}
+	return nil
+}
+
+// logf writes a timestamped line to the hook's stderr
+func (h *Handler) logf(format string, args ...interface{}) {
+	_, _ = fmt.Fprintf(h.stderr, "[%s] %s\n", h.now().Format("15:04:05"), fmt.Sprintf(format, args...))
+}
+
+// handleSubagentStop logs the completion of a Task subagent
+func (h *Handler) handleSubagentStop(input *Input) {
+	sessionID := input.SessionID
+	if sessionID == "" {
+		sessionID = "unknown"
+	}
+	h.logf("[AGENT] Subagent completed - Session: %s", sessionID)
+
+	// stop_hook_active means this stop was triggered by a hook; skip to avoid loops
+	if !input.StopHookActive && input.TranscriptPath != "" {
+		h.logf("[AGENT] Transcript saved to: %s", input.TranscriptPath)
+	}
+}
+
+// logToolUse writes a one-line summary of a tool call
+func (h *Handler) logToolUse(input *Input) {
+	name := input.toolName()
+	var args map[string]interface{}
+	_ = json.Unmarshal(input.toolInput(), &args)
+
+	str := func(key string) string {
+		value, _ := args[key].(string)
+		return value
+	}
+	pathOrDot := func() string {
+		if path := str("path"); path != "" {
+			return path
+		}
+		return "."
+	}
+
+	switch name {
+	case "TodoWrite":
+		h.handleTodoWrite(input.toolInput())
+	case "Read":
+		if path := str("file_path"); path != "" {
+			h.logf("[READ] Reading file: %s", path)
+		}
+	case "Write":
+		if path := str("file_path"); path != "" {
+			h.logf("[WRITE] Writing file: %s", path)
+		}
+	case "Edit", "MultiEdit":
+		if path := str("file_path"); path != "" {
+			h.logf("[EDIT] Editing file: %s", path)
+		}
+	case "Bash":
+		if command := str("command"); command != "" {
+			h.logf("[BASH] Executing: %s", command)
+		}
+	case "Grep":
+		if pattern := str("pattern"); pattern != "" {
+			h.logf("[GREP] Searching for '%s' in %s", pattern, pathOrDot())
+		}
+	case "Glob":
+		if pattern := str("pattern"); pattern != "" {
+			h.logf("[GLOB] Finding files matching '%s' in %s", pattern, pathOrDot())
+		}
+	case "LS":
+		if path := str("path"); path != "" {
+			h.logf("[LS] Listing directory: %s", path)
+		}
+	case "WebFetch":
+		if url := str("url"); url != "" {
+			h.logf("[WEB] Fetching: %s", url)
+		}
+	case "WebSearch":
+		if query := str("query"); query != "" {
+			h.logf("[SEARCH] Searching web for: %s", query)
+		}
+	case "Task":
+		if description := str("description"); description != "" {
+			h.logf("[TASK] Launching agent: %s", description)
+		}
+	case "":
+		// Nothing to report without a tool name
+	default:
+		h.logf("[TOOL] Using: %s", name)
+	}
+}
+
+// handleTodoWrite logs TodoWrite progress and records the in-progress task
+func (h *Handler) handleTodoWrite(raw json.RawMessage) {
+	var input struct {
+		Todos []struct {
+			Content string `json:"content"`
+			Status  string `json:"status"`
+		} `json:"todos"`
+	}
+	if err := json.Unmarshal(raw, &input); err != nil || input.Todos == nil {
+		return
+	}
+
+	var pending, inProgress, completed int
+	current := ""
+	for _, todo := range input.Todos {
+		switch todo.Status {
+		case "pending":
+			pending++
+		case "in_progress":
+			inProgress++
+			if current == "" {
+				current = todo.Content
+			}
+		case "completed":
+			completed++
+		}
+	}
+
+	h.logf("[TODO] Updated - Completed: %d, In Progress: %d, Pending: %d", completed, inProgress, pending)
+	if current == "" {
+		return
+	}
+	h.logf("[TODO] Current task: %s", current)
+
+	if h.todoFile != "" {
+		if err := os.WriteFile(h.todoFile, []byte(current), 0644); err != nil {
+			h.logf("[TODO] Failed to write todo file: %v", err)
+		}
+	}
+}
+
+// preToolUseOutput is the JSON a PreToolUse hook prints to deny a tool call
+type preToolUseOutput struct {
+	HookSpecificOutput struct {
+		HookEventName            string `json:"hookEventName"`
+		PermissionDecision       string `json:"permissionDecision"`
+		PermissionDecisionReason string `json:"permissionDecisionReason"`
+	} `json:"hookSpecificOutput"`
+}
+
+// enforcePolicy checks a PreToolUse call against the policy file, when one is
+// configured, and denies it with a reason Claude sees. A policy that cannot be
+// read denies every call rather than letting them through unchecked. It
+// reports whether the call was blocked.
+func (h *Handler) enforcePolicy(input *Input) bool {
+	if h.policyFile == "" || input.toolName() == "" {
+		return false
+	}
+
+	var violation *policy.Violation
+	p, err := policy.Load(h.policyFile)
+	if err != nil {
+		violation = &policy.Violation{Tool: input.toolName(), Reason: fmt.Sprintf("alpine policy is unavailable: %v", err)}
+	} else {
+		violation = p.Check(input.toolName(), input.toolInput(), input.Cwd)
+	}
+	if violation == nil {
+		return false
+	}
+	violation.Time = h.now()
+
+	var out preToolUseOutput
+	out.HookSpecificOutput.HookEventName = EventPreToolUse
+	out.HookSpecificOutput.PermissionDecision = "deny"
+	out.HookSpecificOutput.PermissionDecisionReason = violation.Reason
+	if data, err := json.Marshal(out); err == nil {
+		_, _ = fmt.Fprintln(h.stdout, string(data))
+	}
+
+	h.logf("[POLICY] Blocked %s: %s", violation.Tool, violation.Reason)
+	if p != nil && p.LogFile != "" {
+		if err := policy.AppendViolation(p.LogFile, *violation); err != nil {
+			h.logf("[POLICY] Failed to log blocked call: %v", err)
+		}
+	}
+	h.sendEvent(agUIEvent{
+		Type: "ToolCallBlocked",
+		Data: agUIEventData{
+			ToolCallID:   input.toolCallID(),
+			ToolCallName: input.toolName(),
+			RunID:        h.runID,
+			ToolInput:    input.toolInput(),
+			Reason:       violation.Reason,
+		},
+	})
+	return true
+}
+
+// agUIEvent is the payload posted to the AG-UI events endpoint
+type agUIEvent struct {
+	Type string        `json:"type"`
+	Data agUIEventData `json:"data"`
+}
+
+// agUIEventData describes the tool call an AG-UI event refers to
+type agUIEventData struct {
+	ToolCallID   string          `json:"toolCallId"`
+	ToolCallName string          `json:"toolCallName"`
+	RunID        string          `json:"runId"`
+	ToolInput    json.RawMessage `json:"toolInput,omitempty"`
+	ToolOutput   json.RawMessage `json:"toolOutput,omitempty"`
+	Reason       string          `json:"reason,omitempty"`
+}
+
+// sendToolCallStart posts a ToolCallStart event for the tool call
+func (h *Handler) sendToolCallStart(input *Input) {
+	h.sendEvent(agUIEvent{
+		Type: "ToolCallStart",
+		Data: agUIEventData{
+			ToolCallID:   input.toolCallID(),
+			ToolCallName: input.toolName(),
+			RunID:        h.runID,
+			ToolInput:    input.toolInput(),
+		},
+	})
+}
+
+// sendToolCallEnd posts a ToolCallEnd event when the tool produced output
+func (h *Handler) sendToolCallEnd(input *Input) {
+	output := input.toolOutput()
+	if output == nil {
+		return
+	}
+	h.sendEvent(agUIEvent{
+		Type: "ToolCallEnd",
+		Data: agUIEventData{
+			ToolCallID:   input.toolCallID(),
+			ToolCallName: input.toolName(),
+			RunID:        h.runID,
+			ToolOutput:   output,
+		},
+	})
+}
+
+// sendEvent posts an AG-UI event, reporting failures to stderr only
+func (h *Handler) sendEvent(event agUIEvent) {
+	if h.eventsEndpoint == "" || event.Data.ToolCallName == "" {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		h.logf("[AG-UI] Failed to encode %s event: %v", event.Type, err)
+		return
+	}
+
+	resp, err := h.client.Post(h.eventsEndpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		h.logf("[AG-UI] Failed to send %s event: %v", event.Type, err)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		h.logf("[AG-UI] Failed to send %s event: HTTP %d", event.Type, resp.StatusCode)
+	}
+}
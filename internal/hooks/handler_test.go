@@ -0,0 +1,459 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Backland-Labs/alpine/internal/policy"
+)
+
+// newTestHandler creates a handler with a fixed clock and the given environment
+func newTestHandler(stderr io.Writer, env map[string]string) *Handler {
+	h := NewHandler(io.Discard, stderr, func(key string) string { return env[key] })
+	h.now = func() time.Time { return time.Date(2025, 1, 2, 13, 4, 5, 0, time.Local) }
+	return h
+}
+
+// handleJSON marshals input and runs it through the handler
+func handleJSON(t *testing.T, h *Handler, input map[string]interface{}) {
+	t.Helper()
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("Failed to marshal input: %v", err)
+	}
+	if err := h.Handle(bytes.NewReader(data)); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+}
+
+// eventRecorder is an AG-UI endpoint that records the events it receives
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []map[string]interface{}
+}
+
+func (er *eventRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var event map[string]interface{}
+	body, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	er.mu.Lock()
+	er.events = append(er.events, event)
+	er.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (er *eventRecorder) Events() []map[string]interface{} {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	return append([]map[string]interface{}(nil), er.events...)
+}
+
+// TestHandlerToolSummaries tests the stderr log lines for tool calls
+func TestHandlerToolSummaries(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          map[string]interface{}
+		expectedOutput []string
+	}{
+		{
+			name: "TodoWrite with task counts",
+			input: map[string]interface{}{
+				"hook_event_name": "PostToolUse",
+				"tool_name":       "TodoWrite",
+				"tool_input": map[string]interface{}{
+					"todos": []map[string]interface{}{
+						{"content": "Task 1", "status": "completed"},
+						{"content": "Task 2", "status": "completed"},
+						{"content": "Current task", "status": "in_progress"},
+						{"content": "Task 4", "status": "pending"},
+						{"content": "Task 5", "status": "pending"},
+					},
+				},
+			},
+			expectedOutput: []string{
+				"[13:04:05] [TODO] Updated - Completed: 2, In Progress: 1, Pending: 2",
+				"[13:04:05] [TODO] Current task: Current task",
+			},
+		},
+		{
+			name: "Read tool",
+			input: map[string]interface{}{
+				"hook_event_name": "PostToolUse",
+				"tool_name":       "Read",
+				"tool_input":      map[string]interface{}{"file_path": "/test/file.go"},
+			},
+			expectedOutput: []string{"[READ] Reading file: /test/file.go"},
+		},
+		{
+			name: "MultiEdit tool",
+			input: map[string]interface{}{
+				"hook_event_name": "PostToolUse",
+				"tool_name":       "MultiEdit",
+				"tool_input":      map[string]interface{}{"file_path": "/test/file.go"},
+			},
+			expectedOutput: []string{"[EDIT] Editing file: /test/file.go"},
+		},
+		{
+			name: "Bash tool",
+			input: map[string]interface{}{
+				"hook_event_name": "PostToolUse",
+				"tool_name":       "Bash",
+				"tool_input":      map[string]interface{}{"command": "go test ./..."},
+			},
+			expectedOutput: []string{"[BASH] Executing: go test ./..."},
+		},
+		{
+			name: "Grep tool",
+			input: map[string]interface{}{
+				"hook_event_name": "PostToolUse",
+				"tool_name":       "Grep",
+				"tool_input":      map[string]interface{}{"pattern": "func Test", "path": "internal/"},
+			},
+			expectedOutput: []string{"[GREP] Searching for 'func Test' in internal/"},
+		},
+		{
+			name: "Glob tool defaults path",
+			input: map[string]interface{}{
+				"hook_event_name": "PostToolUse",
+				"tool_name":       "Glob",
+				"tool_input":      map[string]interface{}{"pattern": "**/*.go"},
+			},
+			expectedOutput: []string{"[GLOB] Finding files matching '**/*.go' in ."},
+		},
+		{
+			name: "Task tool",
+			input: map[string]interface{}{
+				"hook_event_name": "PostToolUse",
+				"tool_name":       "Task",
+				"tool_input": map[string]interface{}{
+					"description": "Find timeout functionality code",
+					"prompt":      "Search for code related to timeout waiting for state update",
+				},
+			},
+			expectedOutput: []string{"[TASK] Launching agent: Find timeout functionality code"},
+		},
+		{
+			name: "Unknown tool",
+			input: map[string]interface{}{
+				"hook_event_name": "PostToolUse",
+				"tool_name":       "mcp__github__create_issue",
+				"tool_input":      map[string]interface{}{},
+			},
+			expectedOutput: []string{"[TOOL] Using: mcp__github__create_issue"},
+		},
+		{
+			name: "Legacy format TodoWrite",
+			input: map[string]interface{}{
+				"tool": "TodoWrite",
+				"args": map[string]interface{}{
+					"todos": []map[string]interface{}{
+						{"content": "Legacy task", "status": "in_progress"},
+					},
+				},
+			},
+			expectedOutput: []string{
+				"[TODO] Updated - Completed: 0, In Progress: 1, Pending: 0",
+				"[TODO] Current task: Legacy task",
+			},
+		},
+		{
+			name: "SubagentStop",
+			input: map[string]interface{}{
+				"hook_event_name":  "SubagentStop",
+				"session_id":       "abc123",
+				"transcript_path":  "/tmp/transcript.jsonl",
+				"stop_hook_active": false,
+			},
+			expectedOutput: []string{
+				"[AGENT] Subagent completed - Session: abc123",
+				"[AGENT] Transcript saved to: /tmp/transcript.jsonl",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			handleJSON(t, newTestHandler(&stderr, nil), tt.input)
+
+			output := stderr.String()
+			for _, expected := range tt.expectedOutput {
+				if !strings.Contains(output, expected) {
+					t.Errorf("Expected output to contain %q, but got:\n%s", expected, output)
+				}
+			}
+		})
+	}
+}
+
+// TestHandlerPreToolUseIsQuiet tests that PreToolUse does not duplicate the PostToolUse log
+func TestHandlerPreToolUseIsQuiet(t *testing.T) {
+	var stderr bytes.Buffer
+	handleJSON(t, newTestHandler(&stderr, nil), map[string]interface{}{
+		"hook_event_name": "PreToolUse",
+		"tool_name":       "Read",
+		"tool_input":      map[string]interface{}{"file_path": "/test/file.go"},
+	})
+
+	if stderr.Len() != 0 {
+		t.Errorf("Expected no output for PreToolUse, got: %s", stderr.String())
+	}
+}
+
+// TestHandlerSubagentStopHookActive tests that an active stop hook skips transcript handling
+func TestHandlerSubagentStopHookActive(t *testing.T) {
+	var stderr bytes.Buffer
+	handleJSON(t, newTestHandler(&stderr, nil), map[string]interface{}{
+		"hook_event_name":  "SubagentStop",
+		"session_id":       "abc123",
+		"transcript_path":  "/tmp/transcript.jsonl",
+		"stop_hook_active": true,
+	})
+
+	if strings.Contains(stderr.String(), "Transcript saved") {
+		t.Errorf("Expected transcript to be skipped, got: %s", stderr.String())
+	}
+}
+
+// TestHandlerInvalidJSON tests that malformed input is ignored
+func TestHandlerInvalidJSON(t *testing.T) {
+	var stderr bytes.Buffer
+	if err := newTestHandler(&stderr, nil).Handle(strings.NewReader("not valid json")); err != nil {
+		t.Fatalf("Handler should ignore invalid JSON, but got error: %v", err)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("Expected no output, got: %s", stderr.String())
+	}
+}
+
+// TestHandlerTodoFileWrite tests that the in-progress task is written to the todo file
+func TestHandlerTodoFileWrite(t *testing.T) {
+	todoFilePath := filepath.Join(t.TempDir(), "current-todo.txt")
+	h := newTestHandler(io.Discard, map[string]string{EnvTodoFile: todoFilePath})
+
+	handleJSON(t, h, map[string]interface{}{
+		"hook_event_name": "PostToolUse",
+		"tool_name":       "TodoWrite",
+		"tool_input": map[string]interface{}{
+			"todos": []map[string]interface{}{
+				{"content": "Completed task", "status": "completed"},
+				{"content": "Current important task", "status": "in_progress"},
+				{"content": "Pending task", "status": "pending"},
+			},
+		},
+	})
+
+	content, err := os.ReadFile(todoFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read todo file: %v", err)
+	}
+	if string(content) != "Current important task" {
+		t.Errorf("Expected todo file to contain %q, but got %q", "Current important task", string(content))
+	}
+}
+
+// TestHandlerAgUIEvents tests that tool calls are posted to the events endpoint
+func TestHandlerAgUIEvents(t *testing.T) {
+	recorder := &eventRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	env := map[string]string{EnvEventsEndpoint: server.URL, EnvRunID: "test-run-456"}
+	toolInput := map[string]interface{}{"command": "echo 'test'"}
+
+	// PreToolUse and PostToolUse run as separate processes, so use separate handlers
+	handleJSON(t, newTestHandler(io.Discard, env), map[string]interface{}{
+		"hook_event_name": "PreToolUse",
+		"session_id":      "session-1",
+		"tool_name":       "Bash",
+		"tool_input":      toolInput,
+	})
+	handleJSON(t, newTestHandler(io.Discard, env), map[string]interface{}{
+		"hook_event_name": "PostToolUse",
+		"session_id":      "session-1",
+		"tool_name":       "Bash",
+		"tool_input":      toolInput,
+		"tool_response":   map[string]interface{}{"stdout": "test\n"},
+	})
+
+	events := recorder.Events()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events (Start and End), got %d", len(events))
+	}
+
+	start, end := events[0], events[1]
+	if start["type"] != "ToolCallStart" || end["type"] != "ToolCallEnd" {
+		t.Fatalf("Expected ToolCallStart then ToolCallEnd, got %v then %v", start["type"], end["type"])
+	}
+
+	startData, _ := start["data"].(map[string]interface{})
+	endData, _ := end["data"].(map[string]interface{})
+	if startData["toolCallName"] != "Bash" {
+		t.Errorf("Expected toolCallName 'Bash', got %v", startData["toolCallName"])
+	}
+	if startData["runId"] != "test-run-456" {
+		t.Errorf("Expected runId 'test-run-456', got %v", startData["runId"])
+	}
+	if startData["toolInput"] == nil {
+		t.Error("Expected toolInput on ToolCallStart")
+	}
+	if endData["toolOutput"] == nil {
+		t.Error("Expected toolOutput on ToolCallEnd")
+	}
+	if startData["toolCallId"] == "" || startData["toolCallId"] != endData["toolCallId"] {
+		t.Errorf("Expected matching non-empty toolCallIds, got %v and %v", startData["toolCallId"], endData["toolCallId"])
+	}
+}
+
+// TestHandlerAgUILegacyPayload tests payloads without hook_event_name
+func TestHandlerAgUILegacyPayload(t *testing.T) {
+	recorder := &eventRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	handleJSON(t, newTestHandler(io.Discard, map[string]string{EnvEventsEndpoint: server.URL}), map[string]interface{}{
+		"tool_name":    "Write",
+		"tool_input":   map[string]interface{}{"file_path": "/tmp/test.txt"},
+		"tool_output":  map[string]interface{}{"success": true},
+		"tool_call_id": "call-123",
+	})
+
+	events := recorder.Events()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events (Start and End), got %d", len(events))
+	}
+	data, _ := events[1]["data"].(map[string]interface{})
+	if data["toolCallId"] != "call-123" {
+		t.Errorf("Expected toolCallId 'call-123', got %v", data["toolCallId"])
+	}
+	if data["runId"] != "unknown" {
+		t.Errorf("Expected default runId 'unknown', got %v", data["runId"])
+	}
+}
+
+// TestHandlerAgUIEndpointUnavailable tests that delivery failures never fail the hook
+func TestHandlerAgUIEndpointUnavailable(t *testing.T) {
+	var stderr bytes.Buffer
+	h := newTestHandler(&stderr, map[string]string{EnvEventsEndpoint: "http://127.0.0.1:1/unreachable"})
+
+	handleJSON(t, h, map[string]interface{}{
+		"hook_event_name": "PostToolUse",
+		"tool_name":       "Read",
+		"tool_input":      map[string]interface{}{"file_path": "/tmp/test.txt"},
+		"tool_response":   map[string]interface{}{"content": "hi"},
+	})
+
+	output := stderr.String()
+	if !strings.Contains(output, "[READ] Reading file: /tmp/test.txt") {
+		t.Errorf("Expected tool log even with unavailable endpoint, got: %s", output)
+	}
+	if !strings.Contains(output, "Failed to send ToolCallEnd event") {
+		t.Errorf("Expected delivery failure to be logged, got: %s", output)
+	}
+}
+
+// TestHandlerPolicy tests that PreToolUse calls breaking the policy are denied,
+// logged and posted to the events endpoint
+func TestHandlerPolicy(t *testing.T) {
+	recorder := &eventRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	dir := t.TempDir()
+	p := &policy.Policy{Root: dir, WorktreeOnly: true, LogFile: filepath.Join(dir, "blocked.jsonl")}
+	policyFile := filepath.Join(dir, "policy.json")
+	if err := p.Save(policyFile); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{EnvPolicyFile: policyFile, EnvEventsEndpoint: server.URL, EnvRunID: "run-1"}
+
+	run := func(toolName string, toolInput map[string]interface{}) (stdout, stderr string) {
+		var out, errOut bytes.Buffer
+		h := newTestHandler(&errOut, env)
+		h.stdout = &out
+		handleJSON(t, h, map[string]interface{}{
+			"hook_event_name": "PreToolUse",
+			"tool_name":       toolName,
+			"tool_input":      toolInput,
+			"cwd":             dir,
+		})
+		return out.String(), errOut.String()
+	}
+
+	stdout, stderr := run("Bash", map[string]interface{}{"command": "git push --force"})
+	var decision struct {
+		HookSpecificOutput struct {
+			HookEventName            string `json:"hookEventName"`
+			PermissionDecision       string `json:"permissionDecision"`
+			PermissionDecisionReason string `json:"permissionDecisionReason"`
+		} `json:"hookSpecificOutput"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &decision); err != nil {
+		t.Fatalf("Expected a JSON decision on stdout, got %q: %v", stdout, err)
+	}
+	if decision.HookSpecificOutput.HookEventName != "PreToolUse" || decision.HookSpecificOutput.PermissionDecision != "deny" {
+		t.Errorf("Expected a PreToolUse deny decision, got %+v", decision.HookSpecificOutput)
+	}
+	if !strings.Contains(decision.HookSpecificOutput.PermissionDecisionReason, `"git push --force"`) {
+		t.Errorf("Expected the reason to name the command, got %q", decision.HookSpecificOutput.PermissionDecisionReason)
+	}
+	if !strings.Contains(stderr, "[POLICY] Blocked Bash:") {
+		t.Errorf("Expected the blocked call on stderr, got %q", stderr)
+	}
+
+	// Allowed calls produce no decision and proceed as usual
+	if stdout, _ := run("Write", map[string]interface{}{"file_path": "main.go"}); stdout != "" {
+		t.Errorf("Expected no decision for an allowed call, got %q", stdout)
+	}
+	if stdout, _ := run("Write", map[string]interface{}{"file_path": "/etc/hosts"}); !strings.Contains(stdout, "outside the worktree") {
+		t.Errorf("Expected a write outside the worktree to be denied, got %q", stdout)
+	}
+
+	violations, err := policy.ReadViolations(p.LogFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 2 || violations[0].Tool != "Bash" || violations[1].Subject != "/etc/hosts" {
+		t.Errorf("Expected both blocked calls in the policy log, got %+v", violations)
+	}
+
+	var types []interface{}
+	for _, event := range recorder.Events() {
+		types = append(types, event["type"])
+	}
+	want := []interface{}{"ToolCallBlocked", "ToolCallStart", "ToolCallBlocked"}
+	if len(types) != len(want) || types[0] != want[0] || types[1] != want[1] || types[2] != want[2] {
+		t.Errorf("Expected events %v, got %v", want, types)
+	}
+	data, _ := recorder.Events()[0]["data"].(map[string]interface{})
+	if reason, _ := data["reason"].(string); !strings.Contains(reason, "alpine policy") {
+		t.Errorf("Expected the blocked event to carry the reason, got %v", data["reason"])
+	}
+}
+
+// TestHandlerPolicyUnreadable tests that a missing policy file denies calls
+func TestHandlerPolicyUnreadable(t *testing.T) {
+	var out bytes.Buffer
+	h := newTestHandler(io.Discard, map[string]string{EnvPolicyFile: filepath.Join(t.TempDir(), "missing.json")})
+	h.stdout = &out
+	handleJSON(t, h, map[string]interface{}{
+		"hook_event_name": "PreToolUse",
+		"tool_name":       "Read",
+		"tool_input":      map[string]interface{}{"file_path": "README.md"},
+	})
+	if !strings.Contains(out.String(), `"permissionDecision":"deny"`) || !strings.Contains(out.String(), "policy is unavailable") {
+		t.Errorf("Expected the call to be denied, got %q", out.String())
+	}
+}
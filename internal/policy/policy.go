@@ -0,0 +1,385 @@
+// Package policy decides whether a Claude tool call is allowed by the Bash
+// command and file path rules of an alpine run. Alpine's PreToolUse hook
+// enforces it, so a violation is blocked before the tool runs.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RegexPrefix marks a rule as a regular expression; other rules are globs
+const RegexPrefix = "re:"
+
+// builtinBashDeny are denied in every policy: force pushes, including
+// --force-with-lease and combined short flags like -fu, and removing the
+// filesystem root or the home directory
+var builtinBashDeny = []string{
+	`re:\bgit\s+push\b.*\s(--force\S*|-[[:alnum:]]*f[[:alnum:]]*)(\s|$)`,
+	`re:\bgit\s+push\b.*\s\+\S`,
+	`re:\brm\s+(-\S+\s+)*(/|/\*|~|~/|\$HOME/?)(\s|$)`,
+}
+
+// builtinWriteDeny are paths the write tools may not change in any policy:
+// Claude's settings files, which hold alpine's hooks, and alpine's ledger
+// next to them
+var builtinWriteDeny = []string{
+	`re:(^|/)\.claude/settings[^/]*\.json[^/]*$`,
+}
+
+// pathKeys maps the file tools to the tool input field holding their path
+var pathKeys = map[string]string{
+	"Read":         "file_path",
+	"Write":        "file_path",
+	"Edit":         "file_path",
+	"MultiEdit":    "file_path",
+	"NotebookEdit": "notebook_path",
+	"Grep":         "path",
+	"Glob":         "path",
+	"LS":           "path",
+}
+
+// writeTools are the file tools that change files
+var writeTools = map[string]bool{
+	"Write":        true,
+	"Edit":         true,
+	"MultiEdit":    true,
+	"NotebookEdit": true,
+}
+
+// Rules are allow and deny patterns. A pattern is a glob, or a regular
+// expression when it starts with RegexPrefix. Deny rules take precedence;
+// when there are allow rules, anything they do not match is denied.
+type Rules struct {
+	Allow []string `yaml:"allow" json:"allow,omitempty"`
+	Deny  []string `yaml:"deny" json:"deny,omitempty"`
+}
+
+// Validate checks that every regular expression rule compiles
+func (r Rules) Validate() error {
+	for _, rules := range [][]string{r.Allow, r.Deny} {
+		for _, rule := range rules {
+			if expr, ok := strings.CutPrefix(rule, RegexPrefix); ok {
+				if _, err := regexp.Compile(expr); err != nil {
+					return fmt.Errorf("invalid rule %q: %w", rule, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Policy is the policy of one run, written to a file the hook reads
+type Policy struct {
+	// Root is the run's working directory, usually its worktree. Relative
+	// paths and path globs are resolved against it.
+	Root string `json:"root"`
+
+	// WorktreeOnly blocks file writes outside Root
+	WorktreeOnly bool `json:"worktree_only"`
+
+	// Bash rules match each command of a Bash tool call
+	Bash Rules `json:"bash"`
+
+	// Paths rules match the path of file tool calls
+	Paths Rules `json:"paths"`
+
+	// LogFile is where the hook appends each Violation as a JSON line (optional)
+	LogFile string `json:"log_file,omitempty"`
+}
+
+// Violation is a tool call the policy blocked
+type Violation struct {
+	Time time.Time `json:"time"`
+	Tool string    `json:"tool"`
+
+	// Subject is the command or path that was blocked
+	Subject string `json:"subject"`
+
+	// Reason is returned to Claude
+	Reason string `json:"reason"`
+}
+
+// Load reads a policy file
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Save writes the policy to path
+func (p *Policy) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode policy: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write policy file: %w", err)
+	}
+	return nil
+}
+
+// Check returns the violation when a call of tool with the given JSON input
+// breaks the policy, or nil when it is allowed. cwd resolves relative paths
+// and defaults to Root.
+func (p *Policy) Check(tool string, input json.RawMessage, cwd string) *Violation {
+	var args map[string]interface{}
+	_ = json.Unmarshal(input, &args)
+	str := func(key string) string {
+		value, _ := args[key].(string)
+		return value
+	}
+
+	if tool == "Bash" {
+		if command := str("command"); command != "" {
+			return p.checkCommand(command)
+		}
+		return nil
+	}
+	if key, ok := pathKeys[tool]; ok {
+		if path := str(key); path != "" {
+			return p.checkPath(tool, path, cwd)
+		}
+	}
+	return nil
+}
+
+// checkCommand checks every command of a Bash command line
+func (p *Policy) checkCommand(commandLine string) *Violation {
+	commands := SplitCommand(commandLine)
+	deny := append(append([]string(nil), builtinBashDeny...), p.Bash.Deny...)
+	for _, command := range commands {
+		if rule, ok := matchCommand(deny, command); ok {
+			return &Violation{Tool: "Bash", Subject: commandLine,
+				Reason: fmt.Sprintf("command %q is denied by alpine policy rule %q", command, rule)}
+		}
+	}
+	if len(p.Bash.Allow) == 0 {
+		return nil
+	}
+	for _, command := range commands {
+		if _, ok := matchCommand(p.Bash.Allow, command); !ok {
+			return &Violation{Tool: "Bash", Subject: commandLine,
+				Reason: fmt.Sprintf("command %q is not allowed by alpine policy", command)}
+		}
+	}
+	return nil
+}
+
+// checkPath checks the path of a file tool call
+func (p *Policy) checkPath(tool, path, cwd string) *Violation {
+	if cwd == "" {
+		cwd = p.Root
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cwd, path)
+	}
+	path = resolvePath(path)
+
+	if writeTools[tool] {
+		if rule, ok := p.matchPath(builtinWriteDeny, path); ok {
+			return &Violation{Tool: tool, Subject: path,
+				Reason: fmt.Sprintf("path %s is denied by alpine policy rule %q", path, rule)}
+		}
+	}
+	if rule, ok := p.matchPath(p.Paths.Deny, path); ok {
+		return &Violation{Tool: tool, Subject: path,
+			Reason: fmt.Sprintf("path %s is denied by alpine policy rule %q", path, rule)}
+	}
+	if len(p.Paths.Allow) > 0 {
+		if _, ok := p.matchPath(p.Paths.Allow, path); !ok {
+			return &Violation{Tool: tool, Subject: path,
+				Reason: fmt.Sprintf("path %s is not allowed by alpine policy", path)}
+		}
+	}
+	if p.WorktreeOnly && writeTools[tool] && p.Root != "" && !within(resolvePath(p.Root), path) {
+		return &Violation{Tool: tool, Subject: path,
+			Reason: fmt.Sprintf("alpine policy does not allow writing outside the worktree %s", p.Root)}
+	}
+	return nil
+}
+
+// SplitCommand splits a shell command line into its commands at ;, &&, ||,
+// pipes, newlines, subshells and command substitutions, normalizing
+// whitespace. Quoting is not interpreted, so a separator inside quotes also
+// splits, which errs on the side of checking more.
+func SplitCommand(commandLine string) []string {
+	var commands []string
+	var current strings.Builder
+	flush := func() {
+		command := strings.TrimSuffix(strings.TrimSpace(current.String()), "$")
+		if command = strings.Join(strings.Fields(command), " "); command != "" {
+			commands = append(commands, command)
+		}
+		current.Reset()
+	}
+	for i := 0; i < len(commandLine); i++ {
+		c := commandLine[i]
+		switch c {
+		case ';', '|', '\n', '(', ')', '`':
+			flush()
+		case '&':
+			// Redirections such as 2>&1 and &> are not separators
+			if i > 0 && (commandLine[i-1] == '>' || commandLine[i-1] == '<') || i+1 < len(commandLine) && commandLine[i+1] == '>' {
+				current.WriteByte(c)
+			} else {
+				flush()
+			}
+		default:
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return commands
+}
+
+// matchCommand returns the first rule matching command. Globs must match the
+// whole command, where * matches any text; regular expressions match
+// anywhere in it.
+func matchCommand(rules []string, command string) (string, bool) {
+	for _, rule := range rules {
+		var re *regexp.Regexp
+		if expr, ok := strings.CutPrefix(rule, RegexPrefix); ok {
+			re = compileOrNil(expr)
+		} else {
+			re = compileOrNil("^" + globToRegexp(strings.Join(strings.Fields(rule), " "), false) + "$")
+		}
+		if re != nil && re.MatchString(command) {
+			return rule, true
+		}
+	}
+	return "", false
+}
+
+// matchPath returns the first rule matching the absolute path. Relative globs
+// are resolved against Root; * does not cross directories but ** does, and a
+// glob matching a directory matches everything under it. Regular expressions
+// match anywhere in the path.
+func (p *Policy) matchPath(rules []string, path string) (string, bool) {
+	for _, rule := range rules {
+		var re *regexp.Regexp
+		if expr, ok := strings.CutPrefix(rule, RegexPrefix); ok {
+			re = compileOrNil(expr)
+		} else {
+			glob := rule
+			if !filepath.IsAbs(glob) && !strings.HasPrefix(glob, "**") {
+				glob = filepath.Join(resolvePath(p.Root), glob)
+			}
+			re = compileOrNil("^" + globToRegexp(glob, true) + "(/.*)?$")
+		}
+		if re != nil && re.MatchString(path) {
+			return rule, true
+		}
+	}
+	return "", false
+}
+
+// globToRegexp translates a glob to a regular expression. For paths, * and ?
+// do not match a slash and ** matches anything.
+func globToRegexp(glob string, path bool) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case c == '*' && path && i+1 < len(glob) && glob[i+1] == '*':
+			b.WriteString(".*")
+			i++
+		case c == '*' && path:
+			b.WriteString("[^/]*")
+		case c == '*':
+			b.WriteString(".*")
+		case c == '?' && path:
+			b.WriteString("[^/]")
+		case c == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// compileOrNil compiles expr, returning nil for invalid expressions, which
+// Rules.Validate rejects when the config is loaded
+func compileOrNil(expr string) *regexp.Regexp {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// resolvePath cleans path and resolves symlinks in its longest existing
+// prefix, so a path cannot escape a directory through a link
+func resolvePath(path string) string {
+	path = filepath.Clean(path)
+	rest := ""
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		if filepath.Dir(dir) == dir {
+			return path
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+// within reports whether path is root or inside it
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// AppendViolation appends v to the JSON lines log at path
+func AppendViolation(path string, v Violation) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode policy violation: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open policy log: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write policy log: %w", err)
+	}
+	return nil
+}
+
+// ReadViolations returns the violations logged at path, none when it does not exist
+func ReadViolations(path string) ([]Violation, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy log: %w", err)
+	}
+	var violations []Violation
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var v Violation
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			continue
+		}
+		violations = append(violations, v)
+	}
+	return violations, nil
+}
@@ -1,4 +1,6 @@
-// Package prompts contains embedded prompt templates used by Alpine
+// Package prompts contains the prompt templates used by Alpine. Templates use
+// text/template syntax and are looked up in the repository, then in the user's
+// configuration directory, then in the built-in defaults embedded here.
 package prompts
 
 import _ "embed"
@@ -7,3 +9,8 @@ import _ "embed"
 //
 //go:embed prompt-plan.md
 var PromptPlan string
+
+// PromptSystem contains the embedded content of prompt-system.md
+//
+//go:embed prompt-system.md
+var PromptSystem string
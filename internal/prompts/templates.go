@@ -0,0 +1,195 @@
+package prompts
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Template names
+const (
+	// NamePlan is the prompt that asks Claude to write plan.md
+	NamePlan = "plan"
+	// NameSystem is the system prompt appended to every workflow iteration
+	NameSystem = "system"
+)
+
+// Template sources, in lookup order
+const (
+	SourceRepo    = "repo"
+	SourceUser    = "user"
+	SourceBuiltin = "builtin"
+)
+
+// RepoDir is where a repository keeps its prompt templates, relative to its root
+var RepoDir = filepath.Join(".alpine", "prompts")
+
+// builtins maps template names to their embedded defaults
+var builtins = map[string]string{
+	NamePlan:   PromptPlan,
+	NameSystem: PromptSystem,
+}
+
+// Issue is the GitHub issue a run works on
+type Issue struct {
+	URL    string
+	Number int
+	Title  string
+	Body   string
+}
+
+// Data holds the variables available to templates
+type Data struct {
+	// Task is the task description, including the issue title and body for
+	// GitHub issues
+	Task string
+
+	// Issue is the GitHub issue being worked on, nil for plain tasks
+	Issue *Issue
+
+	// Repo is the repository name and Branch the checked-out branch
+	Repo   string
+	Branch string
+
+	// Iteration is the workflow iteration being prompted, 0 before the loop
+	Iteration int
+}
+
+// Template is a prompt template resolved from one of the sources
+type Template struct {
+	Name   string
+	Source string
+	// Path is the file the template was read from, empty for built-ins
+	Path string
+	Text string
+}
+
+// Loader resolves templates from a repository, a user directory and the
+// built-in defaults
+type Loader struct {
+	// RepoRoot is the repository whose RepoDir is searched first (optional)
+	RepoRoot string
+
+	// UserDir is the directory of user templates searched next (optional)
+	UserDir string
+}
+
+// NewLoader creates a loader for the repository at repoRoot and the user's
+// default template directory
+func NewLoader(repoRoot string) *Loader {
+	return &Loader{RepoRoot: repoRoot, UserDir: DefaultUserDir()}
+}
+
+// DefaultUserDir returns the user's template directory, e.g.
+// ~/.config/alpine/prompts, or "" when it cannot be determined
+func DefaultUserDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "alpine", "prompts")
+}
+
+// Names returns the names of all templates
+func Names() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Builtin returns the built-in default of the named template
+func Builtin(name string) (*Template, error) {
+	text, ok := builtins[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown prompt template %q (available: %v)", name, Names())
+	}
+	return &Template{Name: name, Source: SourceBuiltin, Text: text}, nil
+}
+
+// Lookup returns the effective named template: the repository's, else the
+// user's, else the built-in default
+func (l *Loader) Lookup(name string) (*Template, error) {
+	builtin, err := Builtin(name)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []struct{ source, dir string }{
+		{SourceRepo, ""},
+		{SourceUser, l.UserDir},
+	}
+	if l.RepoRoot != "" {
+		candidates[0].dir = filepath.Join(l.RepoRoot, RepoDir)
+	}
+	for _, c := range candidates {
+		if c.dir == "" {
+			continue
+		}
+		path := filepath.Join(c.dir, name+".md")
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt template: %w", err)
+		}
+		return &Template{Name: name, Source: c.source, Path: path, Text: string(data)}, nil
+	}
+	return builtin, nil
+}
+
+// Render renders the effective named template with data
+func (l *Loader) Render(name string, data Data) (string, error) {
+	t, err := l.Lookup(name)
+	if err != nil {
+		return "", err
+	}
+	return t.Render(data)
+}
+
+// Render executes the template with data. Referencing an unknown variable
+// is an error, so typos in user templates are caught.
+func (t *Template) Render(data Data) (string, error) {
+	tmpl, err := template.New(t.Name).Parse(t.Text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s prompt template %s: %w", t.Name, t.location(), err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s prompt template %s: %w", t.Name, t.location(), err)
+	}
+	return buf.String(), nil
+}
+
+// location describes where the template came from for messages
+func (t *Template) location() string {
+	if t.Path != "" {
+		return t.Path
+	}
+	return "(" + t.Source + ")"
+}
+
+// Diff returns a unified diff from the built-in default of t to t, empty
+// when t is the default
+func Diff(t *Template) (string, error) {
+	builtin, err := Builtin(t.Name)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(builtin.Text),
+		B:        difflib.SplitLines(t.Text),
+		FromFile: "builtin/" + t.Name + ".md",
+		ToFile:   t.location(),
+		Context:  3,
+	})
+}
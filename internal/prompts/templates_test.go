@@ -0,0 +1,94 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTemplate writes a template file named name.md into dir
+func writeTemplate(t *testing.T, dir, name, text string) string {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	path := filepath.Join(dir, name+".md")
+	require.NoError(t, os.WriteFile(path, []byte(text), 0644))
+	return path
+}
+
+func TestLoader_LookupOrder(t *testing.T) {
+	repo := t.TempDir()
+	userDir := t.TempDir()
+	loader := &Loader{RepoRoot: repo, UserDir: userDir}
+
+	tmpl, err := loader.Lookup(NamePlan)
+	require.NoError(t, err)
+	assert.Equal(t, SourceBuiltin, tmpl.Source)
+	assert.Equal(t, PromptPlan, tmpl.Text)
+
+	userPath := writeTemplate(t, userDir, NamePlan, "user plan")
+	tmpl, err = loader.Lookup(NamePlan)
+	require.NoError(t, err)
+	assert.Equal(t, SourceUser, tmpl.Source)
+	assert.Equal(t, userPath, tmpl.Path)
+
+	repoPath := writeTemplate(t, filepath.Join(repo, RepoDir), NamePlan, "repo plan")
+	tmpl, err = loader.Lookup(NamePlan)
+	require.NoError(t, err)
+	assert.Equal(t, SourceRepo, tmpl.Source)
+	assert.Equal(t, repoPath, tmpl.Path)
+	assert.Equal(t, "repo plan", tmpl.Text)
+
+	_, err = loader.Lookup("missing")
+	assert.ErrorContains(t, err, `unknown prompt template "missing"`)
+}
+
+func TestTemplate_Render(t *testing.T) {
+	tmpl := &Template{Name: NameSystem, Source: SourceRepo, Path: "system.md", Text: `{{.Repo}}@{{.Branch}} #{{.Iteration}}: {{.Task}}{{with .Issue}} ({{.URL}} #{{.Number}} {{.Title}}){{end}}`}
+
+	rendered, err := tmpl.Render(Data{Task: "Fix it", Repo: "alpine", Branch: "main", Iteration: 3})
+	require.NoError(t, err)
+	assert.Equal(t, "alpine@main #3: Fix it", rendered)
+
+	issue := &Issue{URL: "https://github.com/o/r/issues/7", Number: 7, Title: "Bug"}
+	rendered, err = tmpl.Render(Data{Task: "Fix it", Repo: "alpine", Branch: "main", Iteration: 3, Issue: issue})
+	require.NoError(t, err)
+	assert.Equal(t, "alpine@main #3: Fix it (https://github.com/o/r/issues/7 #7 Bug)", rendered)
+}
+
+func TestTemplate_RenderErrors(t *testing.T) {
+	_, err := (&Template{Name: NamePlan, Path: "plan.md", Text: "{{.Task"}).Render(Data{})
+	assert.ErrorContains(t, err, "invalid plan prompt template plan.md")
+
+	_, err = (&Template{Name: NamePlan, Path: "plan.md", Text: "{{.Tsak}}"}).Render(Data{})
+	assert.ErrorContains(t, err, "failed to render plan prompt template plan.md")
+}
+
+func TestBuiltins_Render(t *testing.T) {
+	loader := &Loader{}
+	plan, err := loader.Render(NamePlan, Data{Task: "Implement caching"})
+	require.NoError(t, err)
+	assert.Contains(t, plan, "<github_issue>\nImplement caching\n</github_issue>")
+
+	system, err := loader.Render(NameSystem, Data{})
+	require.NoError(t, err)
+	assert.Equal(t, PromptSystem, system)
+}
+
+func TestDiff(t *testing.T) {
+	builtin, err := Builtin(NameSystem)
+	require.NoError(t, err)
+	diff, err := Diff(builtin)
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+
+	custom := &Template{Name: NameSystem, Source: SourceRepo, Path: ".alpine/prompts/system.md", Text: "Be concise.\n"}
+	diff, err = Diff(custom)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(diff, "--- builtin/system.md\n+++ .alpine/prompts/system.md\n"), diff)
+	assert.Contains(t, diff, "\n-"+strings.TrimSpace(PromptSystem)+"\n")
+	assert.Contains(t, diff, "\n+Be concise.\n")
+}
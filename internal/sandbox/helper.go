@@ -0,0 +1,131 @@
+package sandbox
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// listFlag collects the values of a repeatable flag
+type listFlag []string
+
+func (l *listFlag) String() string     { return strings.Join(*l, ",") }
+func (l *listFlag) Set(v string) error { *l = append(*l, v); return nil }
+
+// RunHelper implements HelperCommand. It runs inside the sandbox's
+// namespaces: with --mount it makes the filesystem read-only except for the
+// --writable paths and hides the --hide paths, then it bridges a loopback
+// proxy address to the --proxy-socket and runs the command after -- with the
+// proxy in its environment. It returns the command's exit code.
+func RunHelper(args []string, stderr io.Writer) int {
+	var writable, hide listFlag
+	flags := flag.NewFlagSet(HelperCommand, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	mount := flags.Bool("mount", false, "set up the sandbox's mounts")
+	flags.Var(&writable, "writable", "path Claude can write (repeatable)")
+	flags.Var(&hide, "hide", "path to hide (repeatable)")
+	socket := flags.String("proxy-socket", "", "Unix socket of the network proxy")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	command := flags.Args()
+	if len(command) == 0 {
+		_, _ = fmt.Fprintln(stderr, "alpine sandbox: no command to run")
+		return 2
+	}
+
+	if *mount {
+		if err := setupMounts(writable, hide); err != nil {
+			_, _ = fmt.Fprintf(stderr, "alpine sandbox: %v\n", err)
+			return 1
+		}
+	}
+	env := os.Environ()
+	if *socket != "" {
+		proxyEnv, err := bridgeProxy(*socket)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "alpine sandbox: %v\n", err)
+			return 1
+		}
+		env = append(env, proxyEnv...)
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = env
+	cmd.SysProcAttr = childAttrs()
+	if err := cmd.Start(); err != nil {
+		_, _ = fmt.Fprintf(stderr, "alpine sandbox: %v\n", err)
+		return 127
+	}
+
+	// Pass signals on so Claude can shut down cleanly
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		for sig := range signals {
+			_ = cmd.Process.Signal(sig)
+		}
+	}()
+
+	err := cmd.Wait()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		if code := exitErr.ExitCode(); code >= 0 {
+			return code
+		}
+		return 1
+	default:
+		_, _ = fmt.Fprintf(stderr, "alpine sandbox: %v\n", err)
+		return 1
+	}
+}
+
+// bridgeProxy brings up the loopback interface and forwards connections to a
+// loopback port to the proxy's Unix socket, returning the environment that
+// points HTTP clients at it
+func bridgeProxy(socket string) ([]string, error) {
+	if err := loopbackUp(); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for proxy connections: %w", err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				upstream, err := net.Dial("unix", socket)
+				if err != nil {
+					_ = conn.Close()
+					return
+				}
+				pipe(conn, upstream)
+			}()
+		}
+	}()
+
+	proxyURL := "http://" + listener.Addr().String()
+	return []string{
+		"HTTPS_PROXY=" + proxyURL, "https_proxy=" + proxyURL,
+		"HTTP_PROXY=" + proxyURL, "http_proxy=" + proxyURL,
+		"NO_PROXY=localhost,127.0.0.1", "no_proxy=localhost,127.0.0.1",
+	}, nil
+}
@@ -0,0 +1,166 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// skipReadOnly are the kernel filesystems left as mounted by unshare
+var skipReadOnly = []string{"/proc", "/sys", "/dev"}
+
+// setupMounts makes every mount read-only, then binds the writable paths
+// read-write and covers the hidden ones. It needs a private mount namespace.
+func setupMounts(writable, hide []string) error {
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+
+	// Bind the writable paths first; remounting their parents read-only
+	// leaves these bind mounts writable
+	for _, path := range writable {
+		if err := syscall.Mount(path, path, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fmt.Errorf("failed to bind %s: %w", path, err)
+		}
+	}
+	mounts, err := mountPoints()
+	if err != nil {
+		return err
+	}
+	bound := map[string]bool{}
+	for _, path := range writable {
+		bound[path] = true
+	}
+	for _, mount := range mounts {
+		if bound[mount] || under(mount, skipReadOnly) || under(mount, writable) {
+			continue
+		}
+		if err := remount(mount, syscall.MS_RDONLY); err != nil {
+			return fmt.Errorf("failed to make %s read-only: %w", mount, err)
+		}
+	}
+
+	for _, path := range hide {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if info.IsDir() {
+			err = syscall.Mount("tmpfs", path, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=0700")
+		} else {
+			err = syscall.Mount("/dev/null", path, "", syscall.MS_BIND, "")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to hide %s: %w", path, err)
+		}
+	}
+
+	// The working directory still refers to the mount underneath the binds
+	if cwd, err := os.Getwd(); err == nil {
+		if err := os.Chdir(cwd); err != nil {
+			return fmt.Errorf("failed to enter %s: %w", cwd, err)
+		}
+	}
+	return nil
+}
+
+// statfsRelatime is ST_RELATIME, the one statfs flag whose mount flag differs
+const statfsRelatime = 0x1000
+
+// remount changes a bind mount's flags, keeping the flags a user namespace
+// may not clear
+func remount(path string, flags uintptr) error {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return err
+	}
+	const locked = syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC | syscall.MS_NOATIME | syscall.MS_NODIRATIME
+	flags |= uintptr(st.Flags) & locked
+	if st.Flags&statfsRelatime != 0 {
+		flags |= syscall.MS_RELATIME
+	}
+	return syscall.Mount("", path, "", syscall.MS_REMOUNT|syscall.MS_BIND|flags, "")
+}
+
+// mountPoints returns the mount points of the mount namespace
+func mountPoints() ([]string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mounts: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var mounts []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 4 {
+			mounts = append(mounts, unescapeMountPath(fields[4]))
+		}
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountPath decodes the octal escapes of mountinfo paths
+func unescapeMountPath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			var c byte
+			if _, err := fmt.Sscanf(path[i+1:i+4], "%03o", &c); err == nil {
+				b.WriteByte(c)
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
+
+// under reports whether path is one of roots or below one
+func under(path string, roots []string) bool {
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, strings.TrimSuffix(root, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// loopbackUp brings up the lo interface of a new network namespace
+func loopbackUp() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open socket: %w", err)
+	}
+	defer func() { _ = syscall.Close(fd) }()
+
+	var req struct {
+		name  [syscall.IFNAMSIZ]byte
+		flags uint16
+		_     [22]byte
+	}
+	copy(req.name[:], "lo")
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.SIOCGIFFLAGS, uintptr(unsafe.Pointer(&req))); errno != 0 {
+		return fmt.Errorf("failed to read loopback flags: %w", errno)
+	}
+	if req.flags&syscall.IFF_UP != 0 {
+		return nil
+	}
+	req.flags |= syscall.IFF_UP
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.SIOCSIFFLAGS, uintptr(unsafe.Pointer(&req))); errno != 0 {
+		return fmt.Errorf("failed to bring up loopback: %w", errno)
+	}
+	return nil
+}
+
+// childAttrs kill Claude when the helper dies
+func childAttrs() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}
+}
@@ -0,0 +1,122 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/config"
+)
+
+// helperEnv makes the test binary run RunHelper, standing in for alpine
+const helperEnv = "ALPINE_SANDBOX_TEST_HELPER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(helperEnv) == "1" {
+		os.Exit(RunHelper(os.Args[1:], os.Stderr))
+	}
+	os.Exit(m.Run())
+}
+
+// fakeClaude probes the sandbox and prints one name=ok|fail line per check
+const fakeClaude = `#!/bin/bash
+check() { if eval "$2" >/dev/null 2>&1; then echo "$1=ok"; else echo "$1=fail"; fi; }
+check write-inside 'echo x > inside.txt'
+check write-outside 'echo x > "$OUTSIDE/outside.txt"'
+check read-secret 'grep -q secret "$SECRET_DIR/key"'
+check read-outside 'grep -q visible "$OUTSIDE/visible.txt"'
+check direct 'exec 3<>/dev/tcp/127.0.0.1/$UPSTREAM_PORT'
+proxy=${HTTPS_PROXY#http://}
+connect() {
+	exec 3<>/dev/tcp/${proxy%:*}/${proxy##*:} || return 1
+	printf 'CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n' "$1" "$1" >&3
+	read -r status <&3
+	[[ $status == *" 200 "* ]]
+}
+check proxy-allowed 'connect 127.0.0.1:$UPSTREAM_PORT'
+check proxy-denied 'connect example.com:443'
+echo "args=$*"
+exit 3
+`
+
+func TestSandbox_UnshareEndToEnd(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping sandbox test in short mode")
+	}
+	if err := exec.Command("unshare", "--user", "--map-root-user", "--mount", "--net", "true").Run(); err != nil {
+		t.Skipf("unshare cannot create namespaces here: %v", err)
+	}
+
+	workDir := t.TempDir()
+	outside := t.TempDir()
+	secretDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "visible.txt"), []byte("visible"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(secretDir, "key"), []byte("secret"), 0600))
+	claudePath := filepath.Join(workDir, "claude")
+	require.NoError(t, os.WriteFile(claudePath, []byte(fakeClaude), 0755))
+
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = upstream.Close() }()
+	go func() {
+		for {
+			conn, err := upstream.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(upstream.Addr().String())
+
+	s, err := New(Options{
+		Backend:      config.SandboxUnshare,
+		Writable:     []string{workDir},
+		Hide:         []string{secretDir},
+		AllowedHosts: []string{"127.0.0.1"},
+		Helper:       []string{os.Args[0]},
+	})
+	require.NoError(t, err)
+	defer func() { _ = s.Close() }()
+
+	cmd := exec.Command(claudePath, "--print", "hello")
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(), helperEnv+"=1", "OUTSIDE="+outside, "SECRET_DIR="+secretDir, "UPSTREAM_PORT="+port)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	s.Wrap(cmd)
+	err = cmd.Run()
+
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr, "stderr: %s", stderr.String())
+	assert.Equal(t, 3, exitErr.ExitCode(), "Claude's exit code is passed through")
+
+	results := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		if name, value, ok := strings.Cut(line, "="); ok {
+			results[name] = value
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"write-inside":  "ok",
+		"write-outside": "fail",
+		"read-secret":   "fail",
+		"read-outside":  "ok",
+		"direct":        "fail",
+		"proxy-allowed": "ok",
+		"proxy-denied":  "fail",
+		"args":          "--print hello",
+	}, results, "stderr: %s", stderr.String())
+	assert.FileExists(t, filepath.Join(workDir, "inside.txt"))
+	assert.NoFileExists(t, filepath.Join(outside, "outside.txt"))
+}
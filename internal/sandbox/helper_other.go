@@ -0,0 +1,23 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// setupMounts is not supported outside Linux
+func setupMounts(writable, hide []string) error {
+	return fmt.Errorf("sandbox requires Linux")
+}
+
+// loopbackUp is not supported outside Linux
+func loopbackUp() error {
+	return fmt.Errorf("sandbox requires Linux")
+}
+
+// childAttrs has nothing to set outside Linux
+func childAttrs() *syscall.SysProcAttr {
+	return nil
+}
@@ -0,0 +1,200 @@
+package sandbox
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Backland-Labs/alpine/internal/logger"
+)
+
+// Proxy is an HTTP proxy that only connects to allowed hosts. It listens on
+// a Unix socket outside the sandbox, the only way out of its network
+// namespace, and serves both CONNECT tunnels and plain HTTP requests.
+type Proxy struct {
+	allowed   []string
+	transport *http.Transport
+	server    *http.Server
+
+	// tunnels are the connections of open CONNECT tunnels, which the
+	// server no longer tracks once hijacked
+	mu      sync.Mutex
+	tunnels map[net.Conn]struct{}
+	wg      sync.WaitGroup
+}
+
+// NewProxy creates a proxy allowing connections to the given hosts. A host
+// is a name or address matched exactly, or *.domain matching its subdomains.
+func NewProxy(allowed []string) *Proxy {
+	p := &Proxy{
+		allowed:   allowed,
+		transport: &http.Transport{Proxy: nil, ResponseHeaderTimeout: 5 * time.Minute},
+		tunnels:   map[net.Conn]struct{}{},
+	}
+	p.server = &http.Server{Handler: p, ReadHeaderTimeout: 30 * time.Second}
+	return p
+}
+
+// Serve starts serving on the Unix socket at path
+func (p *Proxy) Serve(path string) error {
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	go func() { _ = p.server.Serve(listener) }()
+	return nil
+}
+
+// Close stops the proxy and closes its connections, including open tunnels
+func (p *Proxy) Close() error {
+	err := p.server.Close()
+	p.transport.CloseIdleConnections()
+	p.mu.Lock()
+	for conn := range p.tunnels {
+		_ = conn.Close()
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+	return err
+}
+
+// Allowed reports whether the proxy connects to host
+func (p *Proxy) Allowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range p.allowed {
+		pattern = strings.ToLower(pattern)
+		if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP proxies one request
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.URL.Hostname()
+	if r.Method == http.MethodConnect {
+		host = hostOnly(r.Host)
+	}
+	if host == "" {
+		http.Error(w, "alpine sandbox: not a proxy request", http.StatusBadRequest)
+		return
+	}
+	if !p.Allowed(host) {
+		logger.WithFields(map[string]interface{}{
+			"host":   host,
+			"method": r.Method,
+		}).Warn("Sandbox blocked a network connection")
+		http.Error(w, fmt.Sprintf("alpine sandbox: connections to %s are not allowed", host), http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	p.forward(w, r)
+}
+
+// tunnel connects the client to the CONNECT request's host
+func (p *Proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	upstream, err := net.DialTimeout("tcp", r.Host, 30*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		_ = upstream.Close()
+		http.Error(w, "alpine sandbox: tunneling not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		_ = upstream.Close()
+		return
+	}
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		_ = client.Close()
+		_ = upstream.Close()
+		return
+	}
+	// Bytes the client sent after the CONNECT request are already buffered
+	if n := buffered.Reader.Buffered(); n > 0 {
+		data, _ := buffered.Reader.Peek(n)
+		if _, err := upstream.Write(data); err != nil {
+			_ = client.Close()
+			_ = upstream.Close()
+			return
+		}
+	}
+
+	p.mu.Lock()
+	p.tunnels[client] = struct{}{}
+	p.tunnels[upstream] = struct{}{}
+	p.mu.Unlock()
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		pipe(client, upstream)
+		p.mu.Lock()
+		delete(p.tunnels, client)
+		delete(p.tunnels, upstream)
+		p.mu.Unlock()
+	}()
+}
+
+// forward sends a plain HTTP request on to its host
+func (p *Proxy) forward(w http.ResponseWriter, r *http.Request) {
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	out.Header.Del("Proxy-Connection")
+	out.Header.Del("Proxy-Authorization")
+
+	resp, err := p.transport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// pipe copies between a and b until either side closes, then closes both
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+	_ = a.Close()
+	_ = b.Close()
+	<-done
+}
+
+// hostOnly strips the port from host:port
+func hostOnly(hostport string) string {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return hostport
+	}
+	return host
+}
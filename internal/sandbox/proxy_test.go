@@ -0,0 +1,116 @@
+package sandbox
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxy_Allowed(t *testing.T) {
+	p := NewProxy([]string{"api.anthropic.com", "*.github.com", "10.0.0.1"})
+	tests := map[string]bool{
+		"api.anthropic.com":  true,
+		"API.Anthropic.com.": true,
+		"anthropic.com":      false,
+		"uploads.github.com": true,
+		"github.com":         false,
+		"evilgithub.com":     false,
+		"10.0.0.1":           true,
+		"10.0.0.2":           false,
+	}
+	for host, want := range tests {
+		assert.Equal(t, want, p.Allowed(host), host)
+	}
+}
+
+// startProxy serves a proxy allowing hosts and returns its socket
+func startProxy(t *testing.T, hosts ...string) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "proxy.sock")
+	p := NewProxy(hosts)
+	require.NoError(t, p.Serve(socket))
+	t.Cleanup(func() { _ = p.Close() })
+	return socket
+}
+
+func TestProxy_Connect(t *testing.T) {
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = upstream.Close() }()
+	go func() {
+		conn, err := upstream.Accept()
+		if err != nil {
+			return
+		}
+		_, _ = io.Copy(conn, conn)
+		_ = conn.Close()
+	}()
+	socket := startProxy(t, "127.0.0.1")
+
+	connect := func(target string) (*bufio.Reader, net.Conn, string) {
+		conn, err := net.Dial("unix", socket)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		_, err = fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", target, target)
+		require.NoError(t, err)
+		reader := bufio.NewReader(conn)
+		status, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		return reader, conn, strings.TrimSpace(status)
+	}
+
+	t.Run("allowed host is tunneled", func(t *testing.T) {
+		reader, conn, status := connect(upstream.Addr().String())
+		assert.Equal(t, "HTTP/1.1 200 Connection Established", status)
+		_, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		_, err = conn.Write([]byte("ping\n"))
+		require.NoError(t, err)
+		echo, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "ping\n", echo)
+	})
+
+	t.Run("other hosts are forbidden", func(t *testing.T) {
+		_, _, status := connect("example.com:443")
+		assert.Equal(t, "HTTP/1.1 403 Forbidden", status)
+	})
+}
+
+func TestProxy_Forward(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Path", r.URL.Path)
+		_, _ = io.WriteString(w, "hello")
+	}))
+	defer server.Close()
+	socket := startProxy(t, "127.0.0.1")
+
+	client := &http.Client{Transport: &http.Transport{
+		Proxy: http.ProxyURL(&url.URL{Scheme: "http", Host: "sandbox-proxy"}),
+		Dial:  func(string, string) (net.Conn, error) { return net.Dial("unix", socket) },
+	}}
+
+	resp, err := client.Get(server.URL + "/models")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/models", resp.Header.Get("X-Path"))
+	assert.Equal(t, "hello", string(body))
+
+	resp, err = client.Get("http://example.com/")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
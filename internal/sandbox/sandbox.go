@@ -0,0 +1,188 @@
+// Package sandbox runs Claude in Linux namespaces confined to a run's working
+// directory. The filesystem is read-only apart from the working directory and
+// a few writable paths, secrets are hidden, and the network is only reachable
+// through a proxy that allows a list of hosts.
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Backland-Labs/alpine/internal/config"
+)
+
+// HelperCommand is the hidden alpine subcommand that prepares the sandbox from
+// inside its namespaces and then runs Claude
+const HelperCommand = "sandbox-exec"
+
+// Options configure a Sandbox
+type Options struct {
+	// Backend is config.SandboxBubblewrap or config.SandboxUnshare
+	Backend string
+
+	// Writable are paths Claude can write, starting with its working directory.
+	// Paths that do not exist are skipped.
+	Writable []string
+
+	// Hide are paths of secrets replaced by empty ones. Paths that do not
+	// exist are skipped.
+	Hide []string
+
+	// AllowedHosts are the hosts Claude can connect to; empty means no network
+	AllowedHosts []string
+
+	// Helper is the command running HelperCommand, by default the running
+	// alpine binary
+	Helper []string
+}
+
+// Sandbox wraps commands to run in a sandbox. Paths may start with ~/ for the
+// home directory.
+type Sandbox struct {
+	opts   Options
+	dir    string
+	socket string
+	proxy  *Proxy
+}
+
+// New creates a sandbox and starts its network proxy. Close releases it.
+func New(opts Options) (*Sandbox, error) {
+	switch opts.Backend {
+	case config.SandboxBubblewrap, config.SandboxUnshare:
+	default:
+		return nil, fmt.Errorf("unknown sandbox backend: %s", opts.Backend)
+	}
+	if len(opts.Helper) == 0 {
+		executable, err := os.Executable()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve alpine executable: %w", err)
+		}
+		opts.Helper = []string{executable, HelperCommand}
+	}
+	opts.Writable = expandPaths(opts.Writable)
+	opts.Hide = expandPaths(opts.Hide)
+
+	dir, err := os.MkdirTemp("", "alpine-sandbox-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+	s := &Sandbox{opts: opts, dir: dir}
+	if len(opts.AllowedHosts) > 0 {
+		s.socket = filepath.Join(dir, "proxy.sock")
+		s.proxy = NewProxy(opts.AllowedHosts)
+		if err := s.proxy.Serve(s.socket); err != nil {
+			_ = os.RemoveAll(dir)
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Close stops the proxy and removes the sandbox's files
+func (s *Sandbox) Close() error {
+	var err error
+	if s.proxy != nil {
+		err = s.proxy.Close()
+	}
+	if rmErr := os.RemoveAll(s.dir); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// Wrap rewrites cmd to run its program in the sandbox. The command keeps its
+// directory, environment and I/O.
+func (s *Sandbox) Wrap(cmd *exec.Cmd) {
+	inner := append(append([]string(nil), s.opts.Helper...), s.helperArgs()...)
+	inner = append(inner, "--", cmd.Path)
+	inner = append(inner, cmd.Args[1:]...)
+
+	var args []string
+	switch s.opts.Backend {
+	case config.SandboxBubblewrap:
+		args = append([]string{"bwrap"}, s.bwrapArgs(cmd.Dir)...)
+	case config.SandboxUnshare:
+		args = []string{"unshare", "--user", "--map-root-user", "--mount", "--net", "--ipc", "--uts",
+			"--pid", "--fork", "--kill-child", "--mount-proc"}
+	}
+	args = append(append(args, "--"), inner...)
+
+	cmd.Path = args[0]
+	cmd.Err = nil
+	if path, err := exec.LookPath(args[0]); err == nil {
+		cmd.Path = path
+	} else {
+		cmd.Err = err
+	}
+	cmd.Args = args
+}
+
+// helperArgs are the options of HelperCommand. Under bwrap the mounts are
+// already set up, so the helper only bridges the network.
+func (s *Sandbox) helperArgs() []string {
+	var args []string
+	if s.opts.Backend == config.SandboxUnshare {
+		args = append(args, "--mount")
+		for _, path := range existing(s.opts.Writable) {
+			args = append(args, "--writable", path)
+		}
+		for _, path := range existing(s.opts.Hide) {
+			args = append(args, "--hide", path)
+		}
+	}
+	if s.socket != "" {
+		args = append(args, "--proxy-socket", s.socket)
+	}
+	return args
+}
+
+// bwrapArgs mount the filesystem read-only with writable paths bound
+// read-write and hidden paths covered, in new namespaces without network
+func (s *Sandbox) bwrapArgs(dir string) []string {
+	args := []string{"--die-with-parent", "--unshare-all",
+		"--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc"}
+	for _, path := range existing(s.opts.Writable) {
+		args = append(args, "--bind", path, path)
+	}
+	for _, path := range existing(s.opts.Hide) {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			args = append(args, "--tmpfs", path)
+		} else {
+			args = append(args, "--ro-bind", "/dev/null", path)
+		}
+	}
+	if dir != "" {
+		args = append(args, "--chdir", dir)
+	}
+	return args
+}
+
+// expandPaths replaces a leading ~ with the home directory and cleans paths
+func expandPaths(paths []string) []string {
+	home, _ := os.UserHomeDir()
+	expanded := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if path == "~" || strings.HasPrefix(path, "~/") {
+			if home == "" {
+				continue
+			}
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+		expanded = append(expanded, filepath.Clean(path))
+	}
+	return expanded
+}
+
+// existing returns the paths that exist
+func existing(paths []string) []string {
+	var found []string
+	for _, path := range paths {
+		if _, err := os.Lstat(path); err == nil {
+			found = append(found, path)
+		}
+	}
+	return found
+}
@@ -0,0 +1,95 @@
+package sandbox
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/config"
+)
+
+func TestNew_UnknownBackend(t *testing.T) {
+	_, err := New(Options{Backend: "docker"})
+	assert.EqualError(t, err, "unknown sandbox backend: docker")
+}
+
+func TestSandbox_Wrap(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	workDir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(home, ".ssh"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".netrc"), nil, 0600))
+
+	opts := Options{
+		Writable:     []string{workDir, "/does/not/exist"},
+		Hide:         []string{"~/.ssh", "~/.netrc", "~/.aws"},
+		AllowedHosts: []string{"api.anthropic.com"},
+		Helper:       []string{"/usr/bin/alpine", HelperCommand},
+	}
+	newSandbox := func(backend string) *Sandbox {
+		opts.Backend = backend
+		s, err := New(opts)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = s.Close() })
+		return s
+	}
+	newCmd := func() *exec.Cmd {
+		cmd := exec.Command("claude", "--print", "hi")
+		cmd.Path = "/usr/local/bin/claude"
+		cmd.Dir = workDir
+		return cmd
+	}
+
+	t.Run("bwrap", func(t *testing.T) {
+		s := newSandbox(config.SandboxBubblewrap)
+		cmd := newCmd()
+		s.Wrap(cmd)
+
+		assert.Equal(t, []string{"bwrap", "--die-with-parent", "--unshare-all",
+			"--ro-bind", "/", "/", "--dev", "/dev", "--proc", "/proc",
+			"--bind", workDir, workDir,
+			"--tmpfs", filepath.Join(home, ".ssh"),
+			"--ro-bind", "/dev/null", filepath.Join(home, ".netrc"),
+			"--chdir", workDir,
+			"--", "/usr/bin/alpine", HelperCommand, "--proxy-socket", s.socket,
+			"--", "/usr/local/bin/claude", "--print", "hi"}, cmd.Args)
+		assert.Equal(t, workDir, cmd.Dir)
+	})
+
+	t.Run("unshare", func(t *testing.T) {
+		s := newSandbox(config.SandboxUnshare)
+		cmd := newCmd()
+		s.Wrap(cmd)
+
+		assert.Equal(t, []string{"unshare", "--user", "--map-root-user", "--mount", "--net", "--ipc", "--uts",
+			"--pid", "--fork", "--kill-child", "--mount-proc",
+			"--", "/usr/bin/alpine", HelperCommand, "--mount",
+			"--writable", workDir,
+			"--hide", filepath.Join(home, ".ssh"), "--hide", filepath.Join(home, ".netrc"),
+			"--proxy-socket", s.socket,
+			"--", "/usr/local/bin/claude", "--print", "hi"}, cmd.Args)
+	})
+
+	t.Run("no allowed hosts means no proxy", func(t *testing.T) {
+		opts.AllowedHosts = nil
+		s := newSandbox(config.SandboxBubblewrap)
+		cmd := newCmd()
+		s.Wrap(cmd)
+
+		assert.NotContains(t, cmd.Args, "--proxy-socket")
+		assert.Nil(t, s.proxy)
+	})
+}
+
+func TestSandbox_Close(t *testing.T) {
+	s, err := New(Options{Backend: config.SandboxUnshare, AllowedHosts: []string{"api.anthropic.com"}, Helper: []string{"alpine"}})
+	require.NoError(t, err)
+	require.FileExists(t, s.socket)
+
+	require.NoError(t, s.Close())
+	assert.NoDirExists(t, s.dir)
+}
@@ -393,6 +393,48 @@ func TestGetWorkflowState(t *testing.T) {
 	}
 }
 
+// TestGetWorkflowTimeline tests that a run's journaled steps are returned
+func TestGetWorkflowTimeline(t *testing.T) {
+	tempDir := t.TempDir()
+	engine := NewAlpineWorkflowEngine(&MockClaudeExecutor{}, &MockWorktreeManager{}, &config.Config{})
+
+	stateFile := filepath.Join(tempDir, stateFileRelativePath)
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	engine.workflows["run-123"] = &workflowInstance{
+		worktreeDir: tempDir,
+		events:      make(chan WorkflowEvent, 1),
+		stateFile:   stateFile,
+	}
+
+	journal := core.JournalPath(stateFile)
+	for _, entry := range []core.JournalEntry{
+		{RunID: "run-123", State: core.State{CurrentStepDescription: "Initializing", Status: core.StatusRunning}},
+		{RunID: "other-run", Iteration: 1},
+		{RunID: "run-123", Iteration: 1, Prompt: "/start", DurationMS: 20, State: core.State{CurrentStepDescription: "Step 1", Status: core.StatusRunning}},
+	} {
+		if err := core.AppendJournal(journal, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	steps, err := engine.GetWorkflowTimeline(context.Background(), "run-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(steps) != 2 {
+		t.Fatalf("expected 2 steps for the run, got %d", len(steps))
+	}
+	if steps[1].State.CurrentStepDescription != "Step 1" || steps[1].Prompt != "/start" {
+		t.Errorf("unexpected step: %+v", steps[1])
+	}
+
+	if _, err := engine.GetWorkflowTimeline(context.Background(), "missing"); err == nil {
+		t.Error("expected error for unknown workflow")
+	}
+}
+
 // TestApprovePlan tests the ApprovePlan method
 func TestApprovePlan(t *testing.T) {
 	tests := []struct {
@@ -664,13 +706,16 @@ func TestCreateWorkflowDirectory(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		dir, err := engine.createWorkflowDirectory(ctx, "run-123", cancel)
+		dir, branch, err := engine.createWorkflowDirectory(ctx, "run-123", cancel)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
 		if !strings.HasPrefix(dir, "/tmp/worktree-") {
 			t.Errorf("expected worktree directory, got %s", dir)
 		}
+		if branch != "alpine/"+worktreeNamePrefix+"run-123" {
+			t.Errorf("expected the worktree's branch, got %q", branch)
+		}
 	})
 
 	t.Run("without git repository", func(t *testing.T) {
@@ -687,13 +732,16 @@ func TestCreateWorkflowDirectory(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		dir, err := engine.createWorkflowDirectory(ctx, "run-456", cancel)
+		dir, branch, err := engine.createWorkflowDirectory(ctx, "run-456", cancel)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 		}
 		if !strings.Contains(dir, tempDirPrefix) {
 			t.Errorf("expected temp directory with prefix %s, got %s", tempDirPrefix, dir)
 		}
+		if branch != "" {
+			t.Errorf("expected no branch for a temp directory, got %q", branch)
+		}
 	})
 }
 
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Backland-Labs/alpine/internal/core"
 	"github.com/Backland-Labs/alpine/internal/logger"
 )
 
@@ -91,9 +92,15 @@ func (s *Server) agentsRunHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var payload struct {
-		IssueURL string `json:"issue_url"`
-		Plan     *bool  `json:"plan,omitempty"`
-		AgentID  string `json:"agent_id"`
+		IssueURL    string `json:"issue_url"`
+		Plan        *bool  `json:"plan,omitempty"`
+		AgentID     string `json:"agent_id"`
+		Model       string `json:"model,omitempty"`
+		Profile     string `json:"profile,omitempty"`
+		PlanProfile string `json:"plan_profile,omitempty"`
+		Sandbox     string `json:"sandbox,omitempty"`
+		PullRequest *bool  `json:"pull_request,omitempty"`
+		DryRun      bool   `json:"dry_run,omitempty"`
 	}
 
 	logger.Debug("Decoding agent run payload")
@@ -108,8 +115,13 @@ func (s *Server) agentsRunHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	logger.WithFields(map[string]interface{}{
-		"issue_url": payload.IssueURL,
-		"agent_id":  payload.AgentID,
+		"issue_url":    payload.IssueURL,
+		"agent_id":     payload.AgentID,
+		"model":        payload.Model,
+		"profile":      payload.Profile,
+		"plan_profile": payload.PlanProfile,
+		"sandbox":      payload.Sandbox,
+		"dry_run":      payload.DryRun,
 	}).Debug("Agent run payload decoded")
 
 	// Validate payload
@@ -124,6 +136,25 @@ func (s *Server) agentsRunHandler(w http.ResponseWriter, r *http.Request) {
 		s.respondWithError(w, http.StatusBadRequest, "agent_id is required")
 		return
 	}
+	if validator, ok := s.workflowEngine.(PermissionProfileValidator); ok {
+		for _, profile := range []string{payload.Profile, payload.PlanProfile} {
+			if profile == "" {
+				continue
+			}
+			if err := validator.ValidatePermissionProfile(profile); err != nil {
+				logger.WithField("profile", profile).Debug("Unknown permission profile in payload")
+				s.respondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+	}
+	if validator, ok := s.workflowEngine.(SandboxValidator); ok && payload.Sandbox != "" {
+		if err := validator.ValidateSandbox(payload.Sandbox); err != nil {
+			logger.WithField("sandbox", payload.Sandbox).Debug("Unavailable sandbox in payload")
+			s.respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
 	// Create new run
 	runID := GenerateID("run")
@@ -133,6 +164,14 @@ func (s *Server) agentsRunHandler(w http.ResponseWriter, r *http.Request) {
 		"issue_url": payload.IssueURL,
 	}).Info("Creating new workflow run")
 
+	// Record the requested model, or the engine's default when none was requested
+	model := payload.Model
+	if model == "" {
+		if provider, ok := s.workflowEngine.(DefaultModelProvider); ok {
+			model = provider.DefaultModel()
+		}
+	}
+
 	run := &Run{
 		ID:      runID,
 		AgentID: payload.AgentID,
@@ -140,6 +179,8 @@ func (s *Server) agentsRunHandler(w http.ResponseWriter, r *http.Request) {
 		Issue:   payload.IssueURL,
 		Created: time.Now(),
 		Updated: time.Now(),
+		Model:   model,
+		DryRun:  payload.DryRun,
 	}
 
 	// Store run
@@ -167,7 +208,15 @@ func (s *Server) agentsRunHandler(w http.ResponseWriter, r *http.Request) {
 			"issue_url": payload.IssueURL,
 		}).Debug("Starting workflow execution")
 
-		worktreeDir, err := s.workflowEngine.StartWorkflow(r.Context(), payload.IssueURL, run.ID, plan)
+		ctx := WithRunOptions(r.Context(), RunOptions{
+			Model:       payload.Model,
+			Profile:     payload.Profile,
+			PlanProfile: payload.PlanProfile,
+			Sandbox:     payload.Sandbox,
+			PullRequest: payload.PullRequest,
+			DryRun:      payload.DryRun,
+		})
+		worktreeDir, err := s.workflowEngine.StartWorkflow(ctx, payload.IssueURL, run.ID, plan)
 		if err != nil {
 			logger.WithFields(map[string]interface{}{
 				"run_id":    run.ID,
@@ -202,6 +251,8 @@ func (s *Server) agentsRunHandler(w http.ResponseWriter, r *http.Request) {
 				"created":      run.Created,
 				"updated":      run.Updated,
 				"worktree_dir": run.WorktreeDir,
+				"model":        run.Model,
+				"dry_run":      run.DryRun,
 				"error":        errorResponse.Message,
 				"warning":      MsgFallbackWarning,
 			}
@@ -334,6 +385,7 @@ func (s *Server) runDetailsHandler(w http.ResponseWriter, r *http.Request) {
 		"created":      run.Created,
 		"updated":      run.Updated,
 		"worktree_dir": run.WorktreeDir,
+		"model":        run.Model,
 	}
 
 	// Add workflow state if available
@@ -341,6 +393,7 @@ func (s *Server) runDetailsHandler(w http.ResponseWriter, r *http.Request) {
 		logger.WithField("run_id", runID).Debug("Fetching workflow state")
 		if state, err := s.workflowEngine.GetWorkflowState(r.Context(), runID); err == nil {
 			response["current_step"] = state.CurrentStepDescription
+			response["state"] = state
 			logger.WithFields(map[string]interface{}{
 				"run_id":          runID,
 				"workflow_status": state.Status,
@@ -357,6 +410,15 @@ func (s *Server) runDetailsHandler(w http.ResponseWriter, r *http.Request) {
 				run.Status = "completed"
 				run.Updated = time.Now()
 				s.mu.Unlock()
+			} else if state.Status == core.StatusFailed && run.Status == StatusRunning {
+				logger.WithFields(map[string]interface{}{
+					"run_id":         runID,
+					"failure_reason": state.FailureReason,
+				}).Info("Updating run status to failed")
+				s.mu.Lock()
+				run.Status = StatusFailed
+				run.Updated = time.Now()
+				s.mu.Unlock()
 			}
 		} else {
 			logger.WithFields(map[string]interface{}{
@@ -492,6 +554,55 @@ func (s *Server) runCancelHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// runTimelineHandler returns the journaled state transitions of a run
+func (s *Server) runTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	runID := r.PathValue("id")
+
+	s.mu.Lock()
+	_, exists := s.runs[runID]
+	s.mu.Unlock()
+
+	if !exists {
+		s.respondWithError(w, http.StatusNotFound, "Run not found")
+		return
+	}
+
+	provider, ok := s.workflowEngine.(TimelineProvider)
+	if !ok {
+		s.respondWithError(w, http.StatusNotImplemented, "Run timelines are not supported by this workflow engine")
+		return
+	}
+
+	steps, err := provider.GetWorkflowTimeline(r.Context(), runID)
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"run_id": runID,
+			"error":  err.Error(),
+		}).Error("Failed to get run timeline")
+		s.respondWithError(w, http.StatusInternalServerError, "Failed to get run timeline")
+		return
+	}
+	if steps == nil {
+		steps = []core.JournalEntry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"run_id": runID,
+		"steps":  steps,
+	}); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"run_id": runID,
+			"error":  err.Error(),
+		}).Error("Failed to encode run timeline")
+	}
+}
+
 // planGetHandler retrieves plan content for a run
 func (s *Server) planGetHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -501,7 +501,7 @@ func TestWorkflowIntegrationCoverage(t *testing.T) {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
-		_, err := engine.createWorkflowDirectory(ctx, "run-123", cancel)
+		_, _, err := engine.createWorkflowDirectory(ctx, "run-123", cancel)
 		if err == nil {
 			t.Error("expected error when worktree creation fails")
 		}
@@ -30,6 +30,69 @@ type WorkflowEngine interface {
 	SubscribeToEvents(ctx context.Context, runID string) (<-chan WorkflowEvent, error)
 }
 
+// RunOptions holds per-run settings requested through the REST API.
+// They are passed to WorkflowEngine.StartWorkflow through the context.
+type RunOptions struct {
+	// Model overrides the configured Claude model for the run (optional)
+	Model string
+
+	// Profile and PlanProfile override the configured permission profiles
+	// for implementation iterations and plan generation (optional)
+	Profile     string
+	PlanProfile string
+
+	// Sandbox overrides the configured sandbox backend: none, bwrap or
+	// unshare (optional)
+	Sandbox string
+
+	// PullRequest overrides whether the run's branch is pushed and a pull
+	// request opened against the base branch when the run completes (optional)
+	PullRequest *bool
+
+	// DryRun logs each claude command line instead of running it, with a
+	// simulated state progression standing in for Claude (optional)
+	DryRun bool
+}
+
+// runOptionsKey is the context key for RunOptions
+type runOptionsKey struct{}
+
+// WithRunOptions returns a context carrying the given run options
+func WithRunOptions(ctx context.Context, opts RunOptions) context.Context {
+	return context.WithValue(ctx, runOptionsKey{}, opts)
+}
+
+// RunOptionsFromContext returns the run options stored in ctx, or zero options if none
+func RunOptionsFromContext(ctx context.Context) RunOptions {
+	opts, _ := ctx.Value(runOptionsKey{}).(RunOptions)
+	return opts
+}
+
+// PermissionProfileValidator is implemented by workflow engines that can
+// check permission profile names before a run starts
+type PermissionProfileValidator interface {
+	ValidatePermissionProfile(name string) error
+}
+
+// SandboxValidator is implemented by workflow engines that can check a
+// sandbox backend is known and available before a run starts
+type SandboxValidator interface {
+	ValidateSandbox(backend string) error
+}
+
+// DefaultModelProvider is implemented by workflow engines that can report the
+// Claude model used for runs that do not request one
+type DefaultModelProvider interface {
+	DefaultModel() string
+}
+
+// TimelineProvider is implemented by workflow engines that journal the states
+// a run passes through
+type TimelineProvider interface {
+	// GetWorkflowTimeline returns the journaled steps of a run, oldest first
+	GetWorkflowTimeline(ctx context.Context, runID string) ([]core.JournalEntry, error)
+}
+
 // WorkflowEvent represents an event emitted during workflow execution
 type WorkflowEvent struct {
 	Type      string    `json:"type"`
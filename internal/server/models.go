@@ -64,6 +64,8 @@ type Run struct {
 	Created     time.Time `json:"created"`
 	Updated     time.Time `json:"updated"`
 	WorktreeDir string    `json:"worktree_dir,omitempty"`
+	Model       string    `json:"model,omitempty"`   // Claude model used for the run
+	DryRun      bool      `json:"dry_run,omitempty"` // Claude commands are logged instead of run
 }
 
 // Validate checks if the Run has all required fields properly set.
@@ -0,0 +1,387 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/config"
+	"github.com/Backland-Labs/alpine/internal/events"
+)
+
+// modelReportingEngine is a MockWorkflowEngine that also reports a default model
+type modelReportingEngine struct {
+	MockWorkflowEngine
+	defaultModel string
+}
+
+func (m *modelReportingEngine) DefaultModel() string {
+	return m.defaultModel
+}
+
+func postAgentRun(t *testing.T, server *Server, payload map[string]interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/agents/run", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.agentsRunHandler(w, req)
+	return w
+}
+
+func TestAgentsRunHandler_ModelField(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       map[string]interface{}
+		expectedOpts  string
+		expectedModel string
+	}{
+		{
+			name: "requested model is passed to the engine and recorded",
+			payload: map[string]interface{}{
+				"issue_url": "https://github.com/owner/repo/issues/1",
+				"agent_id":  "alpine-agent",
+				"model":     "claude-opus-4-20250514",
+			},
+			expectedOpts:  "claude-opus-4-20250514",
+			expectedModel: "claude-opus-4-20250514",
+		},
+		{
+			name: "omitted model records the engine default",
+			payload: map[string]interface{}{
+				"issue_url": "https://github.com/owner/repo/issues/2",
+				"agent_id":  "alpine-agent",
+			},
+			expectedOpts:  "",
+			expectedModel: "engine-default-model",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var captured RunOptions
+			engine := &modelReportingEngine{defaultModel: "engine-default-model"}
+			engine.StartWorkflowFunc = func(ctx context.Context, issueURL, runID string, plan bool) (string, error) {
+				captured = RunOptionsFromContext(ctx)
+				return "/tmp/worktree", nil
+			}
+
+			server := NewServer(0)
+			server.SetWorkflowEngine(engine)
+
+			w := postAgentRun(t, server, tt.payload)
+			require.Equal(t, http.StatusCreated, w.Code)
+			assert.Equal(t, tt.expectedOpts, captured.Model)
+
+			var run Run
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&run))
+			assert.Equal(t, tt.expectedModel, run.Model)
+
+			// The run record keeps the model for later lookups
+			server.mu.Lock()
+			stored := server.runs[run.ID]
+			server.mu.Unlock()
+			require.NotNil(t, stored)
+			assert.Equal(t, tt.expectedModel, stored.Model)
+		})
+	}
+}
+
+func TestRunOptionsFromContext_Empty(t *testing.T) {
+	assert.Equal(t, RunOptions{}, RunOptionsFromContext(context.Background()))
+}
+
+func TestAlpineWorkflowEngine_StartWorkflowUsesRequestedModel(t *testing.T) {
+	models := make(chan string, 1)
+	executor := &MockClaudeExecutor{
+		ExecuteFunc: func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+			select {
+			case models <- cfg.Model:
+			default:
+			}
+			<-ctx.Done()
+			return "", ctx.Err()
+		},
+	}
+
+	cfg := &config.Config{
+		WorkDir: t.TempDir(),
+		Model:   "configured-model",
+	}
+	engine := NewAlpineWorkflowEngine(executor, nil, cfg)
+	assert.Equal(t, "configured-model", engine.DefaultModel())
+
+	ctx := WithRunOptions(context.Background(), RunOptions{Model: "requested-model"})
+	_, err := engine.StartWorkflow(ctx, "https://github.com/owner/repo/issues/3", "run-model", false)
+	require.NoError(t, err)
+	defer engine.Cleanup("run-model")
+	defer func() { _ = engine.CancelWorkflow(context.Background(), "run-model") }()
+
+	select {
+	case model := <-models:
+		assert.Equal(t, "requested-model", model)
+	case <-time.After(5 * time.Second):
+		t.Fatal("workflow never executed Claude")
+	}
+
+	// The shared engine configuration is left untouched
+	assert.Equal(t, "configured-model", cfg.Model)
+}
+
+// profileValidatingEngine is a MockWorkflowEngine that knows one permission profile
+type profileValidatingEngine struct {
+	MockWorkflowEngine
+}
+
+func (m *profileValidatingEngine) ValidatePermissionProfile(name string) error {
+	if name != "ci" {
+		return fmt.Errorf("unknown permission profile %q", name)
+	}
+	return nil
+}
+
+func TestAgentsRunHandler_ProfileFields(t *testing.T) {
+	var captured RunOptions
+	started := false
+	engine := &profileValidatingEngine{}
+	engine.StartWorkflowFunc = func(ctx context.Context, issueURL, runID string, plan bool) (string, error) {
+		started = true
+		captured = RunOptionsFromContext(ctx)
+		return "/tmp/worktree", nil
+	}
+	server := NewServer(0)
+	server.SetWorkflowEngine(engine)
+
+	w := postAgentRun(t, server, map[string]interface{}{
+		"issue_url":    "https://github.com/owner/repo/issues/1",
+		"agent_id":     "alpine-agent",
+		"profile":      "ci",
+		"plan_profile": "ci",
+	})
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "ci", captured.Profile)
+	assert.Equal(t, "ci", captured.PlanProfile)
+
+	started = false
+	w = postAgentRun(t, server, map[string]interface{}{
+		"issue_url":    "https://github.com/owner/repo/issues/2",
+		"agent_id":     "alpine-agent",
+		"plan_profile": "nope",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `unknown permission profile \"nope\"`)
+	assert.False(t, started, "workflow should not start with an unknown profile")
+}
+
+func TestAlpineWorkflowEngine_StartWorkflowUsesRequestedProfile(t *testing.T) {
+	tools := make(chan []string, 1)
+	executor := &MockClaudeExecutor{
+		ExecuteFunc: func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+			select {
+			case tools <- cfg.AllowedTools:
+			default:
+			}
+			<-ctx.Done()
+			return "", ctx.Err()
+		},
+	}
+
+	cfg := &config.Config{WorkDir: t.TempDir(), Profile: config.ProfileDefault}
+	engine := NewAlpineWorkflowEngine(executor, nil, cfg)
+	assert.Error(t, engine.ValidatePermissionProfile("nope"))
+
+	_, err := engine.StartWorkflow(WithRunOptions(context.Background(), RunOptions{Profile: "nope"}),
+		"https://github.com/owner/repo/issues/3", "run-bad-profile", false)
+	require.Error(t, err)
+
+	ctx := WithRunOptions(context.Background(), RunOptions{Profile: config.ProfileReadOnly})
+	_, err = engine.StartWorkflow(ctx, "https://github.com/owner/repo/issues/3", "run-profile", false)
+	require.NoError(t, err)
+	defer engine.Cleanup("run-profile")
+	defer func() { _ = engine.CancelWorkflow(context.Background(), "run-profile") }()
+
+	readonly, _ := config.BuiltinProfile(config.ProfileReadOnly)
+	select {
+	case got := <-tools:
+		assert.Equal(t, readonly.AllowedTools, got)
+	case <-time.After(5 * time.Second):
+		t.Fatal("workflow never executed Claude")
+	}
+	assert.Equal(t, config.ProfileDefault, cfg.Profile)
+}
+
+func TestAgentsRunHandler_DryRunField(t *testing.T) {
+	var captured RunOptions
+	engine := &MockWorkflowEngine{
+		StartWorkflowFunc: func(ctx context.Context, issueURL, runID string, plan bool) (string, error) {
+			captured = RunOptionsFromContext(ctx)
+			return "/tmp/worktree", nil
+		},
+	}
+	server := NewServer(0)
+	server.SetWorkflowEngine(engine)
+
+	w := postAgentRun(t, server, map[string]interface{}{
+		"issue_url": "https://github.com/owner/repo/issues/4",
+		"agent_id":  "alpine-agent",
+		"dry_run":   true,
+	})
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.True(t, captured.DryRun)
+
+	var run Run
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&run))
+	assert.True(t, run.DryRun)
+}
+
+func TestAgentsRunHandler_PullRequestField(t *testing.T) {
+	var captured []RunOptions
+	engine := &MockWorkflowEngine{
+		StartWorkflowFunc: func(ctx context.Context, issueURL, runID string, plan bool) (string, error) {
+			captured = append(captured, RunOptionsFromContext(ctx))
+			return "/tmp/worktree", nil
+		},
+	}
+	server := NewServer(0)
+	server.SetWorkflowEngine(engine)
+
+	for _, payload := range []map[string]interface{}{
+		{"issue_url": "https://github.com/owner/repo/issues/8", "agent_id": "alpine-agent", "pull_request": true},
+		{"issue_url": "https://github.com/owner/repo/issues/8", "agent_id": "alpine-agent", "pull_request": false},
+		{"issue_url": "https://github.com/owner/repo/issues/8", "agent_id": "alpine-agent"},
+	} {
+		w := postAgentRun(t, server, payload)
+		require.Equal(t, http.StatusCreated, w.Code)
+	}
+	require.Len(t, captured, 3)
+	require.NotNil(t, captured[0].PullRequest)
+	assert.True(t, *captured[0].PullRequest)
+	require.NotNil(t, captured[1].PullRequest)
+	assert.False(t, *captured[1].PullRequest)
+	assert.Nil(t, captured[2].PullRequest, "the configured option applies when the field is omitted")
+}
+
+func TestAlpineWorkflowEngine_StartWorkflowDryRun(t *testing.T) {
+	// A dry run never reaches the shared executor or a claude binary
+	t.Setenv("PATH", t.TempDir())
+	executor := &MockClaudeExecutor{
+		ExecuteFunc: func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+			t.Error("dry run executed Claude")
+			return "", nil
+		},
+	}
+	t.Setenv("TMPDIR", t.TempDir())
+	// Gates are printed rather than run
+	engine := NewAlpineWorkflowEngine(executor, nil, &config.Config{Gates: config.GatesConfig{Build: "touch gate-ran"}})
+
+	ctx := WithRunOptions(context.Background(), RunOptions{DryRun: true})
+	workDir, err := engine.StartWorkflow(ctx, "Add a greeting endpoint", "run-dry", false)
+	require.NoError(t, err)
+	defer engine.Cleanup("run-dry")
+
+	subCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	eventsCh, err := engine.SubscribeToEvents(subCtx, "run-dry")
+	require.NoError(t, err)
+	var last WorkflowEvent
+	var printed []string
+	for event := range eventsCh {
+		last = event
+		if event.Type == "dry_run" {
+			printed = append(printed, event.Content)
+		}
+	}
+	require.NoError(t, subCtx.Err(), "dry run did not finish")
+	assert.Equal(t, events.AGUIEventRunFinished, last.Type)
+	assert.NoFileExists(t, filepath.Join(workDir, "gate-ran"))
+
+	// What the run skipped is visible to API clients
+	require.NotEmpty(t, printed)
+	assert.Contains(t, printed[0], "[dry-run] cd "+workDir+" && claude")
+	assert.Contains(t, printed, "[dry-run] build gate: touch gate-ran")
+}
+
+// sandboxValidatingEngine is a MockWorkflowEngine where only bwrap is available
+type sandboxValidatingEngine struct {
+	MockWorkflowEngine
+}
+
+func (m *sandboxValidatingEngine) ValidateSandbox(backend string) error {
+	if backend != config.SandboxBubblewrap {
+		return fmt.Errorf("sandbox %s: command not found", backend)
+	}
+	return nil
+}
+
+func TestAgentsRunHandler_SandboxField(t *testing.T) {
+	var captured RunOptions
+	engine := &sandboxValidatingEngine{}
+	engine.StartWorkflowFunc = func(ctx context.Context, issueURL, runID string, plan bool) (string, error) {
+		captured = RunOptionsFromContext(ctx)
+		return "/tmp/worktree", nil
+	}
+	server := NewServer(0)
+	server.SetWorkflowEngine(engine)
+
+	w := postAgentRun(t, server, map[string]interface{}{
+		"issue_url": "https://github.com/owner/repo/issues/5",
+		"agent_id":  "alpine-agent",
+		"sandbox":   "bwrap",
+	})
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, config.SandboxBubblewrap, captured.Sandbox)
+
+	w = postAgentRun(t, server, map[string]interface{}{
+		"issue_url": "https://github.com/owner/repo/issues/6",
+		"agent_id":  "alpine-agent",
+		"sandbox":   "unshare",
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "sandbox unshare: command not found")
+}
+
+func TestAlpineWorkflowEngine_StartWorkflowUsesRequestedSandbox(t *testing.T) {
+	engine := NewAlpineWorkflowEngine(&MockClaudeExecutor{}, nil, &config.Config{WorkDir: t.TempDir()})
+	assert.ErrorContains(t, engine.ValidateSandbox("docker"), "sandbox must be one of: none, bwrap, unshare")
+	_, err := engine.StartWorkflow(WithRunOptions(context.Background(), RunOptions{Sandbox: "docker"}),
+		"https://github.com/owner/repo/issues/7", "run-bad-sandbox", false)
+	require.Error(t, err)
+
+	if err := engine.ValidateSandbox(config.SandboxUnshare); err != nil {
+		t.Skipf("unshare sandbox unavailable: %v", err)
+	}
+	sandboxed := make(chan bool, 1)
+	engine.claudeExecutor = &MockClaudeExecutor{
+		ExecuteFunc: func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+			select {
+			case sandboxed <- cfg.Sandbox != nil:
+			default:
+			}
+			<-ctx.Done()
+			return "", ctx.Err()
+		},
+	}
+	ctx := WithRunOptions(context.Background(), RunOptions{Sandbox: config.SandboxUnshare})
+	_, err = engine.StartWorkflow(ctx, "https://github.com/owner/repo/issues/7", "run-sandbox", false)
+	require.NoError(t, err)
+	defer engine.Cleanup("run-sandbox")
+	defer func() { _ = engine.CancelWorkflow(context.Background(), "run-sandbox") }()
+
+	select {
+	case got := <-sandboxed:
+		assert.True(t, got, "Claude should run in the requested sandbox")
+	case <-time.After(5 * time.Second):
+		t.Fatal("workflow never executed Claude")
+	}
+}
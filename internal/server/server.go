@@ -181,6 +181,7 @@ func (s *Server) Start(ctx context.Context) error {
 		s.enhancedRunEventsHandler(w, r, s.runEventHub)
 	})))
 	mux.Handle("/runs/{id}/cancel", middleware(http.HandlerFunc(s.runCancelHandler)))
+	mux.Handle("/runs/{id}/timeline", middleware(http.HandlerFunc(s.runTimelineHandler)))
 	mux.Handle("/plans/{runId}", middleware(http.HandlerFunc(s.planGetHandler)))
 	mux.Handle("/plans/{runId}/approve", middleware(http.HandlerFunc(s.planApproveHandler)))
 	mux.Handle("/plans/{runId}/feedback", middleware(http.HandlerFunc(s.planFeedbackHandler)))
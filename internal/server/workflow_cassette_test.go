@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/claude/cassette"
+	"github.com/Backland-Labs/alpine/internal/config"
+	"github.com/Backland-Labs/alpine/internal/core"
+	"github.com/Backland-Labs/alpine/internal/events"
+)
+
+// TestAlpineWorkflowEngine_ReplaysCassette runs a whole server workflow
+// against the fake claude binary
+func TestAlpineWorkflowEngine_ReplaysCassette(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the fake claude binary")
+	}
+	t.Setenv("TMPDIR", t.TempDir())
+	player := cassette.InstallInteractions(t,
+		cassette.StateUpdate("/start Add a greeting endpoint", "", core.State{
+			CurrentStepDescription: "Added failing test for GET /greeting",
+			NextStepPrompt:         "/continue",
+			Status:                 core.StatusRunning,
+		}),
+		cassette.StateUpdate("/continue", "", core.State{
+			CurrentStepDescription: "Implemented GET /greeting handler",
+			Status:                 core.StatusCompleted,
+		}),
+	)
+
+	engine := NewAlpineWorkflowEngine(claude.NewExecutor(), nil, &config.Config{})
+	runID := "run-cassette"
+	if _, err := engine.StartWorkflow(context.Background(), "Add a greeting endpoint", runID, false); err != nil {
+		t.Fatalf("failed to start workflow: %v", err)
+	}
+	defer engine.Cleanup(runID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	eventsCh, err := engine.SubscribeToEvents(ctx, runID)
+	if err != nil {
+		t.Fatalf("failed to subscribe to events: %v", err)
+	}
+	var last WorkflowEvent
+	for event := range eventsCh {
+		last = event
+	}
+	if ctx.Err() != nil {
+		t.Fatal("workflow did not finish")
+	}
+	if last.Type != events.AGUIEventRunFinished {
+		t.Fatalf("expected workflow to finish, last event was %s: %v", last.Type, last.Data)
+	}
+
+	if player.Remaining() != 0 {
+		t.Errorf("expected every interaction to be replayed, %d left", player.Remaining())
+	}
+}
@@ -3,8 +3,10 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -12,6 +14,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/Backland-Labs/alpine/internal/claude"
 	"github.com/Backland-Labs/alpine/internal/config"
 	"github.com/Backland-Labs/alpine/internal/core"
 	"github.com/Backland-Labs/alpine/internal/events"
@@ -79,6 +82,29 @@ func (e *AlpineWorkflowEngine) SetServer(server *Server) {
 	e.server = server
 }
 
+// ValidatePermissionProfile returns an error unless name is a built-in or
+// configured permission profile
+func (e *AlpineWorkflowEngine) ValidatePermissionProfile(name string) error {
+	_, err := e.cfg.PermissionProfile(name)
+	return err
+}
+
+// ValidateSandbox returns an error unless backend is a sandbox backend that
+// can run on this system
+func (e *AlpineWorkflowEngine) ValidateSandbox(backend string) error {
+	if err := config.ValidateSandboxBackend(backend); err != nil {
+		return err
+	}
+	cfg := *e.cfg
+	cfg.Sandbox.Backend = backend
+	return cfg.ValidateSandbox()
+}
+
+// DefaultModel returns the configured Claude model used when a run does not request one
+func (e *AlpineWorkflowEngine) DefaultModel() string {
+	return e.cfg.Model
+}
+
 // StartWorkflow initiates a new workflow run with the given GitHub issue URL.
 // It creates an isolated environment (worktree or temporary directory) for the workflow
 // and starts execution in the background. Returns the workflow directory path.
@@ -103,6 +129,36 @@ func (e *AlpineWorkflowEngine) StartWorkflow(ctx context.Context, issueURL strin
 	// Create custom config for this workflow
 	workflowCfg := *e.cfg // Copy config
 
+	// Apply per-run options requested through the API
+	opts := RunOptionsFromContext(ctx)
+	if opts.Model != "" {
+		workflowCfg.Model = opts.Model
+	}
+	if opts.Profile != "" {
+		if err := e.ValidatePermissionProfile(opts.Profile); err != nil {
+			cancel()
+			return "", err
+		}
+		workflowCfg.Profile = opts.Profile
+	}
+	if opts.PlanProfile != "" {
+		if err := e.ValidatePermissionProfile(opts.PlanProfile); err != nil {
+			cancel()
+			return "", err
+		}
+		workflowCfg.PlanProfile = opts.PlanProfile
+	}
+	if opts.Sandbox != "" {
+		if err := e.ValidateSandbox(opts.Sandbox); err != nil {
+			cancel()
+			return "", err
+		}
+		workflowCfg.Sandbox.Backend = opts.Sandbox
+	}
+	if opts.PullRequest != nil {
+		workflowCfg.Git.PullRequest = *opts.PullRequest
+	}
+
 	// Create workflow instance early so it exists for directory tracking
 	instance := &workflowInstance{
 		engine:      nil, // Will be set after engine creation
@@ -121,7 +177,7 @@ func (e *AlpineWorkflowEngine) StartWorkflow(ctx context.Context, issueURL strin
 	e.mu.Unlock()
 
 	// Create isolated directory for the workflow
-	worktreeDir, err := e.createWorkflowDirectory(workflowCtx, runID, cancel)
+	worktreeDir, runBranch, err := e.createWorkflowDirectory(workflowCtx, runID, cancel)
 	if err != nil {
 		// Clean up the instance if directory creation fails
 		e.mu.Lock()
@@ -146,6 +202,7 @@ func (e *AlpineWorkflowEngine) StartWorkflow(ctx context.Context, issueURL strin
 		"worktree_dir": worktreeDir,
 		"state_file":   workflowCfg.StateFile,
 		"work_dir":     workflowCfg.WorkDir,
+		"model":        workflowCfg.Model,
 		"operation":    "workflow_config_setup",
 	}).Info("Configured workflow with WorkDir for server execution")
 
@@ -156,8 +213,32 @@ func (e *AlpineWorkflowEngine) StartWorkflow(ctx context.Context, issueURL strin
 		logger.Debugf("Created server streamer for workflow %s", runID)
 	}
 
-	engine := workflow.NewEngine(e.claudeExecutor, nil, &workflowCfg, streamer)
+	// Dry runs get their own executor so other runs keep executing Claude,
+	// and send their commands, commits, pull request and gates to the run's
+	// events instead of making them
+	executor := e.claudeExecutor
+	var dryRunOut io.Writer
+	if opts.DryRun {
+		workflowCfg.DryRun = true
+		dryRunOut = &dryRunEvents{engine: e, instance: instance, runID: runID}
+		dryRunExecutor := claude.NewExecutor()
+		dryRunExecutor.SetDryRun(claude.NewDryRun(dryRunOut, nil))
+		executor = dryRunExecutor
+		logger.WithField("run_id", runID).Info("Dry run: Claude commands will be sent as dry_run events instead of executed")
+	}
+
+	engine := workflow.NewEngine(executor, nil, &workflowCfg, streamer)
 	engine.SetStateFile(workflowCfg.StateFile)
+	engine.SetRunID(runID)
+	if dryRunOut != nil {
+		engine.SetDryRunOutput(dryRunOut)
+	}
+
+	// Worktrees and cloned repositories are on a branch created for this run,
+	// which the engine commits each iteration to
+	if runBranch != "" {
+		engine.SetRunBranch(runBranch)
+	}
 
 	// Set up ServerEventEmitter for workflow lifecycle events
 	if e.server != nil {
@@ -270,6 +351,26 @@ func (e *AlpineWorkflowEngine) GetWorkflowState(ctx context.Context, runID strin
 	return state, nil
 }
 
+// GetWorkflowTimeline returns the steps journaled for a workflow run
+func (e *AlpineWorkflowEngine) GetWorkflowTimeline(ctx context.Context, runID string) ([]core.JournalEntry, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	instance, exists := e.workflows[runID]
+	if !exists {
+		logger.Debugf("Workflow timeline requested for non-existent workflow: %s", runID)
+		return nil, fmt.Errorf("workflow %s not found", runID)
+	}
+
+	entries, err := core.RunJournal(core.JournalPath(instance.stateFile), runID)
+	if err != nil {
+		logger.Errorf("Failed to read journal for workflow %s: %v", runID, err)
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	return entries, nil
+}
+
 // ApprovePlan approves a workflow plan and continues execution.
 // It updates the workflow state to trigger the implementation phase.
 func (e *AlpineWorkflowEngine) ApprovePlan(ctx context.Context, runID string) error {
@@ -284,25 +385,22 @@ func (e *AlpineWorkflowEngine) ApprovePlan(ctx context.Context, runID string) er
 		return fmt.Errorf("workflow %s not found", runID)
 	}
 
-	// Update state to continue with implementation
-	state, err := core.LoadState(instance.stateFile)
-	if err != nil {
-		return fmt.Errorf("failed to load state: %w", err)
-	}
-
 	// Update state to trigger implementation
-	state.CurrentStepDescription = "Plan approved, continuing implementation"
+	err := core.UpdateState(instance.stateFile, func(state *core.State) error {
+		state.CurrentStepDescription = "Plan approved, continuing implementation"
 
-	// Get the original issue URL from workflow instance context
-	issueURL, ok := instance.ctx.Value("issue_url").(string)
-	if ok && issueURL != "" {
-		state.NextStepPrompt = "/start " + issueURL
-	} else {
-		state.NextStepPrompt = "/start"
-	}
-	state.Status = core.StatusRunning
-	if err := state.Save(instance.stateFile); err != nil {
-		return fmt.Errorf("failed to save state: %w", err)
+		// Get the original issue URL from workflow instance context
+		issueURL, ok := instance.ctx.Value("issue_url").(string)
+		if ok && issueURL != "" {
+			state.NextStepPrompt = "/start " + issueURL
+		} else {
+			state.NextStepPrompt = "/start"
+		}
+		state.Status = core.StatusRunning
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update state: %w", err)
 	}
 
 	// Send plan approved event (non-blocking)
@@ -499,10 +597,11 @@ func (e *AlpineWorkflowEngine) cleanupClonedRepositories(runID string, clonedDir
 // createWorkflowDirectory creates an isolated directory for workflow execution.
 // It uses a worktree if available and enabled, otherwise creates a temporary directory.
 // For GitHub issue URLs, it attempts to clone the repository first and create a worktree within it.
-func (e *AlpineWorkflowEngine) createWorkflowDirectory(ctx context.Context, runID string, cancel context.CancelFunc) (string, error) {
+// It also returns the branch created for the run, which is empty for temporary directories.
+func (e *AlpineWorkflowEngine) createWorkflowDirectory(ctx context.Context, runID string, cancel context.CancelFunc) (string, string, error) {
 	// Try to create worktree in cloned repository for GitHub issues
-	if worktreeDir, created := e.tryCreateClonedWorktree(ctx, runID); created {
-		return worktreeDir, nil
+	if worktreeDir, branch, created := e.tryCreateClonedWorktree(ctx, runID); created {
+		return worktreeDir, branch, nil
 	}
 
 	// Fallback to regular worktree creation
@@ -510,12 +609,13 @@ func (e *AlpineWorkflowEngine) createWorkflowDirectory(ctx context.Context, runI
 }
 
 // tryCreateClonedWorktree attempts to clone a GitHub repository and create a worktree within it.
-// Returns the worktree directory path and a boolean indicating if the operation succeeded.
-func (e *AlpineWorkflowEngine) tryCreateClonedWorktree(ctx context.Context, runID string) (string, bool) {
+// Returns the worktree directory path, the branch created for the run and a boolean
+// indicating if the operation succeeded.
+func (e *AlpineWorkflowEngine) tryCreateClonedWorktree(ctx context.Context, runID string) (string, string, bool) {
 	// Check if context contains a GitHub issue URL for cloning
 	issueURL, ok := ctx.Value("issue_url").(string)
 	if !ok || issueURL == "" || !isGitHubIssueURL(issueURL) || !e.cfg.Git.Clone.Enabled {
-		return "", false
+		return "", "", false
 	}
 
 	logger.Infof("Detected GitHub issue URL for workflow %s: %s", runID, issueURL)
@@ -524,7 +624,7 @@ func (e *AlpineWorkflowEngine) tryCreateClonedWorktree(ctx context.Context, runI
 	owner, repo, _, err := parseGitHubIssueURL(issueURL)
 	if err != nil {
 		logger.Warnf("Failed to parse GitHub issue URL %s: %v, falling back to regular worktree", issueURL, err)
-		return "", false
+		return "", "", false
 	}
 
 	// Clone the repository
@@ -532,7 +632,7 @@ func (e *AlpineWorkflowEngine) tryCreateClonedWorktree(ctx context.Context, runI
 	clonedDir, err := e.cloneRepositoryWithLogging(ctx, repoURL, runID)
 	if err != nil {
 		logger.Warnf("Failed to clone repository %s: %v, falling back to regular worktree", repoURL, err)
-		return "", false
+		return "", "", false
 	}
 
 	// Skip worktree creation in Docker environments - use branches instead
@@ -560,7 +660,7 @@ func (e *AlpineWorkflowEngine) tryCreateClonedWorktree(ctx context.Context, runI
 			"operation":   "branch_creation_failed",
 		}).Error("Failed to create/publish branch for workflow - aborting")
 		// Cannot continue without a published branch - no way to track changes
-		return "", false
+		return "", "", false
 	}
 
 	logger.WithFields(map[string]interface{}{
@@ -570,7 +670,7 @@ func (e *AlpineWorkflowEngine) tryCreateClonedWorktree(ctx context.Context, runI
 		"operation":   "branch_creation_success",
 	}).Info("Successfully created and published branch, using cloned repository for workflow")
 
-	return clonedDir, true
+	return clonedDir, branchName, true
 }
 
 // cloneRepositoryWithLogging clones a repository with comprehensive logging and directory tracking.
@@ -797,7 +897,8 @@ func (e *AlpineWorkflowEngine) createWorktreeInClonedRepo(ctx context.Context, r
 }
 
 // createFallbackWorktree creates a regular worktree or temporary directory as fallback.
-func (e *AlpineWorkflowEngine) createFallbackWorktree(ctx context.Context, runID string, cancel context.CancelFunc) (string, error) {
+// It returns the worktree's branch, or an empty branch for a temporary directory.
+func (e *AlpineWorkflowEngine) createFallbackWorktree(ctx context.Context, runID string, cancel context.CancelFunc) (string, string, error) {
 	// Try to create regular worktree
 	if e.wtMgr != nil && e.cfg.Git.WorktreeEnabled {
 		worktreeName := fmt.Sprintf("%s%s", worktreeNamePrefix, runID)
@@ -805,10 +906,10 @@ func (e *AlpineWorkflowEngine) createFallbackWorktree(ctx context.Context, runID
 		if err != nil {
 			cancel()
 			logger.Errorf("Failed to create worktree for workflow %s: %v", runID, err)
-			return "", fmt.Errorf("failed to create worktree: %w", err)
+			return "", "", fmt.Errorf("failed to create worktree: %w", err)
 		}
 		logger.Infof("Created worktree for workflow %s at: %s", runID, wt.Path)
-		return wt.Path, nil
+		return wt.Path, wt.Branch, nil
 	}
 
 	// Use temporary directory as final fallback
@@ -817,10 +918,10 @@ func (e *AlpineWorkflowEngine) createFallbackWorktree(ctx context.Context, runID
 	if err != nil {
 		cancel()
 		logger.Errorf("Failed to create temp directory for workflow %s: %v", runID, err)
-		return "", fmt.Errorf("failed to create temp directory: %w", err)
+		return "", "", fmt.Errorf("failed to create temp directory: %w", err)
 	}
 	logger.Infof("Created temporary directory for workflow %s at: %s", runID, tempDir)
-	return tempDir, nil
+	return tempDir, "", nil
 }
 
 // runWorkflowAsync executes the workflow in a goroutine and manages event broadcasting.
@@ -900,6 +1001,41 @@ func (e *AlpineWorkflowEngine) runWorkflowAsync(instance *workflowInstance, issu
 	}
 }
 
+// dryRunEvents sends each line a dry run prints as a dry_run event of the run,
+// so API clients see the commands and actions the run skipped
+type dryRunEvents struct {
+	engine   *AlpineWorkflowEngine
+	instance *workflowInstance
+	runID    string
+
+	mu      sync.Mutex
+	partial []byte
+}
+
+// Write implements io.Writer, sending an event for each complete line
+func (w *dryRunEvents) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(w.partial[:i])
+		w.partial = w.partial[i+1:]
+		logger.WithField("run_id", w.runID).Info(line)
+		w.engine.sendEventNonBlocking(w.instance, WorkflowEvent{
+			Type:      "dry_run",
+			RunID:     w.runID,
+			Timestamp: time.Now(),
+			Content:   line,
+			Source:    "alpine",
+		})
+	}
+}
+
 // sendEventNonBlocking attempts to send an event to the workflow's event channel.
 // If the channel is full or closed, the event is dropped and a warning is logged.
 func (e *AlpineWorkflowEngine) sendEventNonBlocking(instance *workflowInstance, event WorkflowEvent) {
@@ -605,6 +605,123 @@ func TestWorkflowStateSync(t *testing.T) {
 			t.Errorf("expected current_step in response, got %v", response["current_step"])
 		}
 	})
+
+	t.Run("run details expose state metadata and failures", func(t *testing.T) {
+		mockEngine := &MockWorkflowEngine{
+			GetWorkflowStateFunc: func(ctx context.Context, runID string) (*core.State, error) {
+				return &core.State{
+					SchemaVersion:          core.StateSchemaVersion,
+					CurrentStepDescription: "Stopped: budget",
+					Status:                 core.StatusFailed,
+					RunID:                  runID,
+					Iteration:              3,
+					FailureReason:          "reached max iterations (3)",
+				}, nil
+			},
+		}
+
+		baseServer := NewServer(0)
+		baseServer.SetWorkflowEngine(mockEngine)
+		baseServer.runs["run_456"] = &Run{ID: "run_456", Status: "running"}
+
+		req := httptest.NewRequest(http.MethodGet, "/runs/run_456", nil)
+		req.SetPathValue("id", "run_456")
+		w := httptest.NewRecorder()
+		baseServer.runDetailsHandler(w, req)
+
+		var response struct {
+			State core.State `json:"state"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.State.RunID != "run_456" || response.State.Iteration != 3 {
+			t.Errorf("expected state metadata in response, got %+v", response.State)
+		}
+		if response.State.FailureReason != "reached max iterations (3)" {
+			t.Errorf("expected failure_reason in response, got %q", response.State.FailureReason)
+		}
+		if baseServer.runs["run_456"].Status != StatusFailed {
+			t.Errorf("expected run status to become failed, got %s", baseServer.runs["run_456"].Status)
+		}
+	})
+}
+
+// timelineWorkflowEngine adds TimelineProvider support to MockWorkflowEngine
+type timelineWorkflowEngine struct {
+	*MockWorkflowEngine
+	steps []core.JournalEntry
+}
+
+func (m *timelineWorkflowEngine) GetWorkflowTimeline(ctx context.Context, runID string) ([]core.JournalEntry, error) {
+	return m.steps, nil
+}
+
+// TestRunTimelineHandler tests the run timeline endpoint
+func TestRunTimelineHandler(t *testing.T) {
+	t.Run("returns journaled steps", func(t *testing.T) {
+		engine := &timelineWorkflowEngine{
+			MockWorkflowEngine: &MockWorkflowEngine{},
+			steps: []core.JournalEntry{
+				{RunID: "run_123", Iteration: 1, Prompt: "/start", ExitCode: 0, DurationMS: 1200,
+					State: core.State{CurrentStepDescription: "Step 1", Status: core.StatusRunning}},
+			},
+		}
+		server := NewServer(0)
+		server.SetWorkflowEngine(engine)
+		server.runs["run_123"] = &Run{ID: "run_123", Status: "running"}
+
+		req := httptest.NewRequest(http.MethodGet, "/runs/run_123/timeline", nil)
+		req.SetPathValue("id", "run_123")
+		w := httptest.NewRecorder()
+		server.runTimelineHandler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		var response struct {
+			RunID string              `json:"run_id"`
+			Steps []core.JournalEntry `json:"steps"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if response.RunID != "run_123" || len(response.Steps) != 1 {
+			t.Fatalf("unexpected response: %+v", response)
+		}
+		if response.Steps[0].DurationMS != 1200 || response.Steps[0].State.CurrentStepDescription != "Step 1" {
+			t.Errorf("unexpected step: %+v", response.Steps[0])
+		}
+	})
+
+	t.Run("unknown run", func(t *testing.T) {
+		server := NewServer(0)
+		server.SetWorkflowEngine(&timelineWorkflowEngine{MockWorkflowEngine: &MockWorkflowEngine{}})
+
+		req := httptest.NewRequest(http.MethodGet, "/runs/missing/timeline", nil)
+		req.SetPathValue("id", "missing")
+		w := httptest.NewRecorder()
+		server.runTimelineHandler(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("engine without timelines", func(t *testing.T) {
+		server := NewServer(0)
+		server.SetWorkflowEngine(&MockWorkflowEngine{})
+		server.runs["run_123"] = &Run{ID: "run_123", Status: "running"}
+
+		req := httptest.NewRequest(http.MethodGet, "/runs/run_123/timeline", nil)
+		req.SetPathValue("id", "run_123")
+		w := httptest.NewRecorder()
+		server.runTimelineHandler(w, req)
+
+		if w.Code != http.StatusNotImplemented {
+			t.Errorf("expected status 501, got %d", w.Code)
+		}
+	})
 }
 
 // TestConcurrentWorkflowOperations tests thread-safety of workflow operations
@@ -1194,7 +1311,7 @@ func TestCreateWorkflowDirectoryWithGitHubClone(t *testing.T) {
 		cancel := func() {}
 
 		// Call createWorkflowDirectory - this should detect GitHub URL and clone repository
-		worktreeDir, err := engine.createWorkflowDirectory(ctx, "test-run-123", cancel)
+		worktreeDir, _, err := engine.createWorkflowDirectory(ctx, "test-run-123", cancel)
 
 		// Verify that the method detects GitHub URL and attempts clone
 		if err != nil {
@@ -1244,7 +1361,7 @@ func TestCreateWorkflowDirectoryWithGitHubClone(t *testing.T) {
 		cancel := func() {}
 
 		// Call createWorkflowDirectory
-		worktreeDir, err := engine.createWorkflowDirectory(ctx, "test-run-123", cancel)
+		worktreeDir, _, err := engine.createWorkflowDirectory(ctx, "test-run-123", cancel)
 
 		// Should use regular worktree creation (existing behavior)
 		if err != nil {
@@ -1293,7 +1410,7 @@ func TestCreateWorkflowDirectoryWithGitHubClone(t *testing.T) {
 		cancel := func() {}
 
 		// Call createWorkflowDirectory
-		worktreeDir, err := engine.createWorkflowDirectory(ctx, "test-run-123", cancel)
+		worktreeDir, _, err := engine.createWorkflowDirectory(ctx, "test-run-123", cancel)
 
 		// Should not return error (graceful fallback)
 		if err != nil {
@@ -1339,7 +1456,7 @@ func TestCreateWorkflowDirectoryWithGitHubClone(t *testing.T) {
 		cancel := func() {}
 
 		// Call createWorkflowDirectory
-		worktreeDir, err := engine.createWorkflowDirectory(ctx, "test-run-123", cancel)
+		worktreeDir, _, err := engine.createWorkflowDirectory(ctx, "test-run-123", cancel)
 
 		// Should use regular worktree creation
 		if err != nil {
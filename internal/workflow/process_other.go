@@ -0,0 +1,9 @@
+//go:build !unix
+
+package workflow
+
+import "os/exec"
+
+// killProcessGroup leaves cancelling cmd to kill only its process where
+// process groups are unavailable
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -1,12 +1,18 @@
 package workflow
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 
@@ -16,11 +22,51 @@ import (
 	"github.com/Backland-Labs/alpine/internal/events"
 	"github.com/Backland-Labs/alpine/internal/github"
 	"github.com/Backland-Labs/alpine/internal/gitx"
+	"github.com/Backland-Labs/alpine/internal/hooks"
 	"github.com/Backland-Labs/alpine/internal/logger"
 	"github.com/Backland-Labs/alpine/internal/output"
+	"github.com/Backland-Labs/alpine/internal/policy"
 	"github.com/Backland-Labs/alpine/internal/prompts"
+	"github.com/Backland-Labs/alpine/internal/sandbox"
 )
 
+// ErrBudgetExceeded is returned when a run hits one of its configured
+// iteration or wall-clock limits
+var ErrBudgetExceeded = errors.New("workflow budget exceeded")
+
+// ErrStalled is returned when Claude repeatedly exits without updating the
+// state file
+var ErrStalled = errors.New("workflow stalled")
+
+// ErrCheckFailed is returned when iterations keep failing the rollback check
+var ErrCheckFailed = errors.New("rollback check failed")
+
+// ErrGateFailed is returned when iterations keep failing a quality gate
+var ErrGateFailed = errors.New("quality gate failed")
+
+// maxCommitSubject is the longest commit subject alpine writes
+const maxCommitSubject = 72
+
+// maxCheckOutput is the most output of a failed check passed to Claude
+const maxCheckOutput = 4000
+
+// maxPullRequestPlan bounds the plan quoted in a pull request's body, well
+// under GitHub's limit on the body's length
+const maxPullRequestPlan = 50000
+
+// Summary describes a completed run
+type Summary struct {
+	RunID      string
+	Iterations int
+	Duration   time.Duration
+
+	// Gates are the latest result of each quality gate
+	Gates []events.Gate
+
+	// PullRequest is the URL of the pull request opened for the run, if any
+	PullRequest string
+}
+
 // ClaudeExecutor interface for executing Claude commands
 type ClaudeExecutor interface {
 	Execute(ctx context.Context, config claude.ExecuteConfig) (string, error)
@@ -37,14 +83,39 @@ type Engine struct {
 	claudeExecutor ClaudeExecutor
 	wtMgr          gitx.WorktreeManager
 	cfg            *config.Config
-	stateFile      string
+	stateFile      string // State file of the current run, resolved under workDir
+	baseStateFile  string // State file as configured; relative paths are resolved per run
 	printer        *output.Printer
 	wt             *gitx.Worktree      // Current worktree if created
-	originalDir    string              // Original directory to restore if needed
+	workDir        string              // Root directory of this run; never the process cwd by side effect
 	eventEmitter   events.EventEmitter // Optional event emitter for lifecycle events
 	runID          string              // Unique identifier for this run
+	nextRunID      string              // Run ID to use for the next run instead of a generated one
+	startedAt      time.Time           // When the current run started
+	planPath       string              // Plan file the current run generates, if any
+	iteration      int                 // Iteration currently executing
 	taskDesc       string              // Task description for event tracking
 	streamer       events.Streamer     // Optional streamer for real-time output
+	planPrompt     string              // Initial planning prompt, used to select the plan model
+	session        claude.SessionInfo  // Claude session continued by the next iteration, if any
+	sessionRuns    int                 // Iterations run in the current session
+	prompts        *prompts.Loader     // Resolves prompt templates for the run's repository
+	promptData     prompts.Data        // Variables prompt templates are rendered with
+	mcpConfigs     map[string]string   // MCP config file for each phase with MCP servers
+	policyFile     string              // Policy file the PreToolUse hook enforces, if any
+	policyLog      string              // Log of tool calls the policy blocked
+	policyReported int                 // Blocked tool calls already reported
+	sandbox        *sandbox.Sandbox    // Sandbox Claude runs in, if any
+	runBranch      string              // Branch created for the run, which alpine commits to
+	nextRunBranch  string              // Run branch the next run's working directory is checked out on
+	commitBase     string              // Commit the run's branch started from, for squashing
+	gates          []events.Gate       // Latest result of each quality gate in the current run
+	dryRunOut      io.Writer           // Receives the actions a dry run skips instead of the printer, if set
+	summary        Summary             // Summary of the last completed run
+
+	// pullRequests opens pull requests for completed runs; a REST client of
+	// cfg.GitHub is used when nil
+	pullRequests github.PullRequestCreator
 }
 
 // NewEngine creates a new workflow engine
@@ -54,6 +125,7 @@ func NewEngine(executor ClaudeExecutor, wtMgr gitx.WorktreeManager, cfg *config.
 		wtMgr:          wtMgr,
 		cfg:            cfg,
 		stateFile:      cfg.StateFile,
+		baseStateFile:  cfg.StateFile,
 		printer:        output.NewPrinter(),
 		streamer:       streamer,
 	}
@@ -67,9 +139,23 @@ func (e *Engine) Run(ctx context.Context, taskDescription string, generatePlan b
 		"state_file":       e.stateFile,
 	}).Debug("Starting workflow run")
 
-	// Generate a unique run ID for event tracking
-	e.runID = uuid.New().String()
+	// Generate a unique run ID for event tracking unless the caller chose one
+	e.runID = e.nextRunID
+	e.nextRunID = ""
+	if e.runID == "" {
+		e.runID = uuid.New().String()
+	}
 	e.taskDesc = taskDescription
+	e.startedAt = time.Now().UTC()
+	e.planPath = ""
+	e.session = claude.SessionInfo{}
+	e.sessionRuns = 0
+	e.gates = nil
+	e.summary = Summary{}
+	e.stateFile = e.baseStateFile
+	e.wt = nil
+	e.runBranch = e.nextRunBranch
+	e.nextRunBranch = ""
 
 	logger.WithFields(map[string]interface{}{
 		"run_id":           e.runID,
@@ -89,20 +175,16 @@ func (e *Engine) Run(ctx context.Context, taskDescription string, generatePlan b
 		}
 	}()
 
-	// Ensure agent_state directory exists
-	stateDir := filepath.Dir(e.stateFile)
-	logger.WithFields(map[string]interface{}{
-		"directory":  stateDir,
-		"state_file": e.stateFile,
-	}).Debug("Ensuring agent_state directory exists")
-	if err := os.MkdirAll(stateDir, 0755); err != nil {
-		logger.WithFields(map[string]interface{}{
-			"error":     err.Error(),
-			"directory": stateDir,
-		}).Error("Failed to create agent_state directory")
-		return fmt.Errorf("failed to create agent_state directory: %w", err)
+	// Bound the run by its wall-clock budget. Worktree cleanup keeps using the
+	// caller's ctx so it still runs after the budget has expired.
+	runCtx, cancelRun := e.withRunBudget(ctx)
+	defer cancelRun()
+
+	// Resolve the run's root directory. Runs never change the process working
+	// directory, so concurrent runs in one process stay isolated.
+	if err := e.resolveWorkDir(); err != nil {
+		return err
 	}
-	logger.WithField("directory", stateDir).Debug("Agent_state directory verified")
 
 	// Check if this is bare mode
 	isBareMode := taskDescription == "" && !generatePlan && !e.cfg.Git.WorktreeEnabled
@@ -133,41 +215,82 @@ func (e *Engine) Run(ctx context.Context, taskDescription string, generatePlan b
 		logger.Debug("Skipping worktree creation in bare mode")
 	}
 
-	// Setup cleanup
+	// Clean up the worktree however the run ends from here on
+	wt := e.wt
 	defer func() {
-		if e.wt != nil && e.cfg.Git.AutoCleanupWT {
+		if wt != nil && e.cfg.Git.AutoCleanupWT {
 			logger.WithFields(map[string]interface{}{
-				"worktree_path": e.wt.Path,
-				"branch":        e.wt.Branch,
+				"worktree_path": wt.Path,
+				"branch":        wt.Branch,
 			}).Debug("Cleaning up worktree")
-			if err := e.wtMgr.Cleanup(ctx, e.wt); err != nil {
+			if err := e.wtMgr.Cleanup(ctx, wt); err != nil {
 				logger.WithFields(map[string]interface{}{
 					"error":         err.Error(),
-					"worktree_path": e.wt.Path,
+					"worktree_path": wt.Path,
 				}).Error("Failed to cleanup worktree")
 				e.printer.Warning("Failed to cleanup worktree: %v", err)
 			} else {
-				logger.WithField("worktree_path", e.wt.Path).Info("Successfully cleaned up worktree")
-				e.printer.Info("Cleaned up worktree: %s", e.wt.Path)
-			}
-		}
-		// Restore original directory if we changed it
-		if e.originalDir != "" && e.wt != nil {
-			logger.WithFields(map[string]interface{}{
-				"original_dir": e.originalDir,
-				"current_dir":  e.wt.Path,
-			}).Debug("Restoring original directory")
-			if err := os.Chdir(e.originalDir); err != nil {
-				logger.WithFields(map[string]interface{}{
-					"error":        err.Error(),
-					"original_dir": e.originalDir,
-				}).Error("Failed to restore original directory")
-			} else {
-				logger.WithField("original_dir", e.originalDir).Debug("Successfully restored original directory")
+				logger.WithField("worktree_path", wt.Path).Info("Successfully cleaned up worktree")
+				e.printer.Info("Cleaned up worktree: %s", wt.Path)
 			}
 		}
 	}()
 
+	// Remember where the run's branch started so its commits can be squashed
+	e.commitBase = ""
+	if e.runBranch != "" && e.cfg.Git.Squash {
+		head, err := gitx.Head(ctx, e.workDir)
+		if err != nil {
+			logger.WithField("error", err.Error()).Warn("Failed to read HEAD; commits will not be squashed")
+		}
+		e.commitBase = head
+	}
+
+	// Relative state file paths live under the run's root, which is the
+	// worktree when one was created
+	if !filepath.IsAbs(e.stateFile) {
+		e.stateFile = filepath.Join(e.workDir, e.stateFile)
+	}
+
+	// Ensure agent_state directory exists
+	stateDir := filepath.Dir(e.stateFile)
+	logger.WithFields(map[string]interface{}{
+		"directory":  stateDir,
+		"state_file": e.stateFile,
+	}).Debug("Ensuring agent_state directory exists")
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"error":     err.Error(),
+			"directory": stateDir,
+		}).Error("Failed to create agent_state directory")
+		return fmt.Errorf("failed to create agent_state directory: %w", err)
+	}
+	logger.WithField("directory", stateDir).Debug("Agent_state directory verified")
+
+	// Resolve prompt templates from the run's repository
+	e.preparePrompts(ctx, taskDescription)
+
+	// Write the MCP configs passed to Claude for each phase
+	cleanupMCP, err := e.prepareMCPConfigs()
+	if err != nil {
+		return err
+	}
+	defer cleanupMCP()
+
+	// Install the hook enforcing the command and path policy; it is removed
+	// before the worktree is cleaned up
+	cleanupPolicy, err := e.preparePolicy()
+	if err != nil {
+		return err
+	}
+	defer cleanupPolicy()
+
+	cleanupSandbox, err := e.prepareSandbox(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanupSandbox()
+
 	// Handle bare mode initialization
 	if isBareMode {
 		logger.WithField("state_file", e.stateFile).Debug("Checking for existing state file in bare mode")
@@ -175,12 +298,15 @@ func (e *Engine) Run(ctx context.Context, taskDescription string, generatePlan b
 			// Continue from existing state
 			logger.WithField("state_file", e.stateFile).Info("Continuing from existing state file")
 			e.printer.Info("Continuing from existing state file")
-			return e.runWorkflowLoop(ctx)
+			if err := e.resumeState(); err != nil {
+				return err
+			}
+			return e.runWorkflowLoop(runCtx)
 		} else if os.IsNotExist(err) {
 			// Initialize with /start
 			logger.WithField("state_file", e.stateFile).Info("Starting bare execution with /start")
 			e.printer.Info("Starting bare execution with /start")
-			if err := e.initializeWorkflow(ctx, "/start", false); err != nil {
+			if err := e.initializeWorkflow(runCtx, "/start", false); err != nil {
 				logger.WithFields(map[string]interface{}{
 					"error":   err.Error(),
 					"command": "/start",
@@ -201,7 +327,7 @@ func (e *Engine) Run(ctx context.Context, taskDescription string, generatePlan b
 			"task_description": taskDescription,
 			"state_file":       e.stateFile,
 		}).Debug("Initializing workflow with task")
-		if err := e.initializeWorkflow(ctx, taskDescription, generatePlan); err != nil {
+		if err := e.initializeWorkflow(runCtx, taskDescription, generatePlan); err != nil {
 			logger.WithFields(map[string]interface{}{
 				"error":            err.Error(),
 				"task_description": taskDescription,
@@ -212,15 +338,20 @@ func (e *Engine) Run(ctx context.Context, taskDescription string, generatePlan b
 	}
 
 	logger.WithField("run_id", e.runID).Info("Starting workflow execution loop")
-	return e.runWorkflowLoop(ctx)
+	return e.runWorkflowLoop(runCtx)
 }
 
 // runWorkflowLoop runs the main execution loop
 func (e *Engine) runWorkflowLoop(ctx context.Context) error {
 	// Main execution loop
 	iteration := 0
+	stalls := 0       // Consecutive iterations that left the state unchanged
+	rollbacks := 0    // Consecutive iterations rolled back after failing the check
+	gateFailures := 0 // Consecutive iterations failing a quality gate
+	retryPrompt := "" // Prompt to run instead of the state's after a stall or rollback
 	for {
 		iteration++
+		e.iteration = iteration
 		logger.WithFields(map[string]interface{}{
 			"iteration": iteration,
 			"run_id":    e.runID,
@@ -229,6 +360,9 @@ func (e *Engine) runWorkflowLoop(ctx context.Context) error {
 		// Check context cancellation
 		select {
 		case <-ctx.Done():
+			if cause := budgetCause(ctx); cause != nil {
+				return e.stopOnBudget(cause)
+			}
 			logger.WithFields(map[string]interface{}{
 				"run_id":    e.runID,
 				"iteration": iteration,
@@ -265,6 +399,15 @@ func (e *Engine) runWorkflowLoop(ctx context.Context) error {
 				"final_step": state.CurrentStepDescription,
 			}).Info("Workflow completed successfully")
 			e.printer.Success("Workflow completed successfully")
+			e.summary = Summary{
+				RunID:      e.runID,
+				Iterations: iteration - 1,
+				Duration:   time.Since(e.startedAt),
+				Gates:      e.gates,
+			}
+			e.printSummary()
+			e.squashCommits(ctx)
+			e.openPullRequest(ctx)
 
 			// Emit RunFinished event
 			if e.eventEmitter != nil {
@@ -287,10 +430,31 @@ func (e *Engine) runWorkflowLoop(ctx context.Context) error {
 			return nil
 		}
 
+		// Failed, blocked and awaiting_input states need outside action
+		if state.IsStopped() {
+			return e.stopOnState(state)
+		}
+
+		// Enforce the iteration budget once the state shows more work to do
+		if maxIterations := e.cfg.Budget.MaxIterations; maxIterations > 0 && iteration > maxIterations {
+			return e.stopOnBudget(fmt.Errorf("%w: reached max iterations (%d)", ErrBudgetExceeded, maxIterations))
+		}
+
+		prompt := state.NextStepPrompt
+		if retryPrompt != "" {
+			prompt = retryPrompt
+			retryPrompt = ""
+		}
+
+		// Choose the Claude session and record run metadata before Claude
+		// takes over the state file
+		sessionID, resumeSession := e.sessionFor(prompt)
+		e.recordIteration(iteration, prompt)
+
 		// Execute Claude with the next prompt
-		e.printer.Step("Executing Claude with prompt: %s", state.NextStepPrompt)
+		e.printer.Step("Executing Claude with prompt: %s", prompt)
 		logger.WithFields(map[string]interface{}{
-			"prompt":       state.NextStepPrompt,
+			"prompt":       prompt,
 			"iteration":    iteration,
 			"run_id":       e.runID,
 			"current_step": state.CurrentStepDescription,
@@ -309,32 +473,88 @@ func (e *Engine) runWorkflowLoop(ctx context.Context) error {
 			}
 		}
 
+		systemPrompt, err := e.renderPrompt(prompts.NameSystem, iteration)
+		if err != nil {
+			progress.Stop()
+			return err
+		}
+		permissions, err := e.permissionsForPrompt(state.NextStepPrompt)
+		if err != nil {
+			progress.Stop()
+			return err
+		}
+		// Allow the tools of the phase's MCP servers
+		phase := e.phaseForPrompt(state.NextStepPrompt)
+		mcpConfig := e.mcpConfigs[phase]
+		if mcpConfig != "" {
+			permissions.AllowedTools = append(append([]string(nil), permissions.AllowedTools...),
+				claude.MCPToolPatterns(e.cfg.MCPServersFor(phase))...)
+		}
+
 		config := claude.ExecuteConfig{
-			Prompt:    state.NextStepPrompt,
-			StateFile: e.stateFile,
-			WorkDir:   e.cfg.WorkDir,
+			Prompt:          prompt,
+			SystemPrompt:    strings.TrimSpace(systemPrompt),
+			StateFile:       e.stateFile,
+			WorkDir:         e.workDir,
+			Model:           e.modelForPrompt(state.NextStepPrompt),
+			OutputFormat:    e.cfg.OutputFormat,
+			AllowedTools:    permissions.AllowedTools,
+			DisallowedTools: permissions.DisallowedTools,
+			MCPConfig:       mcpConfig,
+			Retry:           e.cfg.Retry,
+			OnRetry:         e.reportRetry,
+			SessionID:       sessionID,
+			ResumeSession:   resumeSession,
+			OnSession:       e.recordSession,
+			Sandbox:         e.sandbox,
+		}
+
+		if e.policyFile != "" {
+			config.EnvironmentVariables = map[string]string{hooks.EnvPolicyFile: e.policyFile}
 		}
 
 		logger.WithFields(map[string]interface{}{
 			"prompt":     config.Prompt,
 			"state_file": config.StateFile,
 			"work_dir":   config.WorkDir,
+			"model":      config.Model,
 			"run_id":     e.runID,
 			"iteration":  iteration,
 			"operation":  "workflow_claude_config",
 		}).Info("Passing WorkDir to Claude executor")
 
+		// Bound this iteration by its own timeout
+		iterCtx, cancelIter := e.withIterationBudget(ctx)
+
 		startTime := time.Now()
 		claudeErr := func() error {
-			if _, err := e.claudeExecutor.Execute(ctx, config); err != nil {
+			if _, err := e.claudeExecutor.Execute(iterCtx, config); err != nil {
 				return err
 			}
 			return nil
 		}()
 
 		progress.Stop()
+		duration := time.Since(startTime)
+		e.reportPolicyViolations(iteration)
 
 		if claudeErr != nil {
+			cancelIter()
+			e.journal(core.JournalEntry{
+				Iteration:  iteration,
+				Prompt:     config.Prompt,
+				ExitCode:   claudeExitCode(claudeErr),
+				DurationMS: duration.Milliseconds(),
+				Error:      claudeErr.Error(),
+				State:      *state,
+			})
+			// Don't leave a half-finished iteration for a resumed run to build on
+			if e.cfg.Rollback.Check != "" {
+				e.resetToCheckpoint(ctx)
+			}
+			if cause := budgetCause(iterCtx); cause != nil {
+				return e.stopOnBudget(cause)
+			}
 			logger.WithFields(map[string]interface{}{
 				"error":       claudeErr.Error(),
 				"duration":    time.Since(startTime).String(),
@@ -356,7 +576,29 @@ func (e *Engine) runWorkflowLoop(ctx context.Context) error {
 			"state_file": e.stateFile,
 			"iteration":  iteration,
 		}).Debug("Waiting for state file update")
-		if err := e.waitForStateUpdate(ctx, state); err != nil {
+		waitCtx, cancelWait := e.withStallGrace(iterCtx)
+		newState, err := e.waitForStateUpdate(waitCtx, state)
+		stalled := err != nil && waitCtx.Err() != nil && iterCtx.Err() == nil
+		cancelWait()
+		cancelIter()
+		if stalled {
+			stalls++
+			e.journal(core.JournalEntry{
+				Iteration:  iteration,
+				Prompt:     config.Prompt,
+				DurationMS: duration.Milliseconds(),
+				Error:      "stalled: Claude exited without updating the state file",
+				State:      *state,
+			})
+			if retryPrompt, err = e.handleStall(ctx, stalls, config.Prompt); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			if cause := budgetCause(iterCtx); cause != nil {
+				return e.stopOnBudget(cause)
+			}
 			logger.WithFields(map[string]interface{}{
 				"error":      err.Error(),
 				"state_file": e.stateFile,
@@ -364,10 +606,1013 @@ func (e *Engine) runWorkflowLoop(ctx context.Context) error {
 			}).Error("Error waiting for state update")
 			return fmt.Errorf("error waiting for state update: %w", err)
 		}
+		stalls = 0
+		if output, err := e.runCheck(ctx); err != nil {
+			if ctx.Err() != nil {
+				// The loop handles cancellation and the run's budget
+				continue
+			}
+			rollbacks++
+			e.journal(core.JournalEntry{
+				Iteration:  iteration,
+				Prompt:     config.Prompt,
+				DurationMS: duration.Milliseconds(),
+				Error:      fmt.Sprintf("check failed: %v", err),
+				State:      *newState,
+			})
+			if retryPrompt, err = e.rollBack(ctx, rollbacks, state, config.Prompt, output); err != nil {
+				return err
+			}
+			continue
+		}
+		rollbacks = 0
+		// Completion is only accepted once every quality gate passes
+		failed := e.runGates(ctx, iteration, newState)
+		if len(failed) > 0 && ctx.Err() == nil {
+			gateFailures++
+			if newState, err = e.reopenForGates(newState, failed); err != nil {
+				return err
+			}
+		} else if len(failed) == 0 {
+			gateFailures = 0
+		}
+		e.journal(core.JournalEntry{
+			Iteration:  iteration,
+			Prompt:     config.Prompt,
+			DurationMS: duration.Milliseconds(),
+			State:      *newState,
+			Commit:     e.commitIteration(ctx, iteration, newState),
+		})
+		if gateFailures > e.cfg.Gates.MaxFailures {
+			reason := fmt.Errorf("%w: %d iterations in a row failed a gate (last: %s)", ErrGateFailed, gateFailures, failed[0].Name)
+			e.printer.Error("Stopping workflow: %v", reason)
+			return e.recordFailure(reason)
+		}
 		logger.WithField("iteration", iteration).Debug("State file updated, continuing to next iteration")
 	}
 }
 
+// phaseForPrompt returns the workflow phase the prompt starts: planning for
+// the initial plan prompt and /make_plan, execution for everything else
+func (e *Engine) phaseForPrompt(prompt string) string {
+	if (e.planPrompt != "" && prompt == e.planPrompt) || strings.HasPrefix(prompt, core.PromptMakePlan) {
+		return config.PhasePlan
+	}
+	return config.PhaseExecute
+}
+
+// modelForPrompt returns the configured model for the workflow phase the prompt starts.
+// Planning prompts use the plan model; everything else uses the execution model.
+func (e *Engine) modelForPrompt(prompt string) string {
+	if e.phaseForPrompt(prompt) == config.PhasePlan {
+		return e.cfg.PlanningModel()
+	}
+	return e.cfg.Model
+}
+
+// permissionsForPrompt returns the configured permission profile for the
+// workflow phase the prompt starts, like modelForPrompt
+func (e *Engine) permissionsForPrompt(prompt string) (config.PermissionProfile, error) {
+	if e.phaseForPrompt(prompt) == config.PhasePlan {
+		return e.cfg.PlanningPermissions()
+	}
+	return e.cfg.ExecutionPermissions()
+}
+
+// prepareMCPConfigs writes an MCP config file for each phase that has MCP
+// servers enabled into a temporary directory, and returns a function that
+// removes it. The files live outside the worktree so they are never committed.
+func (e *Engine) prepareMCPConfigs() (func(), error) {
+	e.mcpConfigs = nil
+	if len(e.cfg.MCPServers) == 0 {
+		return func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "alpine-mcp-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MCP config directory: %w", err)
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			logger.WithField("error", err.Error()).Warn("Failed to remove MCP config directory")
+		}
+	}
+
+	e.mcpConfigs = map[string]string{}
+	for _, phase := range []string{config.PhasePlan, config.PhaseExecute} {
+		servers := e.cfg.MCPServersFor(phase)
+		if len(servers) == 0 {
+			continue
+		}
+		path := filepath.Join(dir, phase+".json")
+		if err := claude.WriteMCPConfig(path, servers); err != nil {
+			cleanup()
+			return nil, err
+		}
+		e.mcpConfigs[phase] = path
+		logger.WithFields(map[string]interface{}{
+			"run_id":  e.runID,
+			"phase":   phase,
+			"servers": len(servers),
+			"path":    path,
+		}).Debug("Wrote MCP config")
+	}
+	return cleanup, nil
+}
+
+// preparePolicy writes the run's command and path policy to a temporary
+// directory and installs the PreToolUse hook enforcing it in the run's
+// working directory. It returns a function that removes both.
+func (e *Engine) preparePolicy() (func(), error) {
+	e.policyFile, e.policyLog, e.policyReported = "", "", 0
+	if !e.cfg.Policy.Enabled {
+		return func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "alpine-policy-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create policy directory: %w", err)
+	}
+	removeDir := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			logger.WithField("error", err.Error()).Warn("Failed to remove policy directory")
+		}
+	}
+
+	p := &policy.Policy{
+		Root:         e.workDir,
+		WorktreeOnly: e.cfg.Policy.WorktreeOnly,
+		Bash:         e.cfg.Policy.Bash,
+		Paths:        e.cfg.Policy.Paths,
+		LogFile:      filepath.Join(dir, "blocked.jsonl"),
+	}
+	path := filepath.Join(dir, "policy.json")
+	if err := p.Save(path); err != nil {
+		removeDir()
+		return nil, err
+	}
+	releaseHook, err := claude.SetupPolicyHook(e.workDir)
+	if err != nil {
+		removeDir()
+		return nil, fmt.Errorf("failed to install policy hook: %w", err)
+	}
+
+	e.policyFile, e.policyLog = path, p.LogFile
+	logger.WithFields(map[string]interface{}{
+		"run_id":      e.runID,
+		"policy_file": path,
+		"root":        p.Root,
+	}).Debug("Installed policy hook")
+	return func() {
+		releaseHook()
+		removeDir()
+	}, nil
+}
+
+// prepareSandbox creates the sandbox Claude runs in when one is configured.
+// Besides the working directory, Claude can write the repository's git
+// directory, the temporary directory, its own settings and the configured
+// writable paths. It returns a function that releases the sandbox.
+func (e *Engine) prepareSandbox(ctx context.Context) (func(), error) {
+	e.sandbox = nil
+	if !e.cfg.Sandbox.Enabled() {
+		return func() {}, nil
+	}
+
+	writable := []string{e.workDir, os.TempDir(), "~/.claude", "~/.claude.json"}
+	if stateDir := filepath.Dir(e.stateFile); filepath.IsAbs(stateDir) {
+		writable = append(writable, stateDir)
+	}
+	// Commits in a worktree write to the main repository's git directory
+	if gitDir, err := gitx.CommonDir(ctx, e.workDir); err == nil {
+		writable = append(writable, gitDir)
+	}
+	writable = append(writable, e.cfg.Sandbox.Writable...)
+
+	sb, err := sandbox.New(sandbox.Options{
+		Backend:      e.cfg.Sandbox.Backend,
+		Writable:     writable,
+		Hide:         e.cfg.Sandbox.Hide,
+		AllowedHosts: e.cfg.Sandbox.AllowedHosts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox: %w", err)
+	}
+	e.sandbox = sb
+	logger.WithFields(map[string]interface{}{
+		"run_id":        e.runID,
+		"backend":       e.cfg.Sandbox.Backend,
+		"allowed_hosts": e.cfg.Sandbox.AllowedHosts,
+	}).Info("Running Claude in sandbox")
+	return func() {
+		if err := sb.Close(); err != nil {
+			logger.WithField("error", err.Error()).Warn("Failed to release sandbox")
+		}
+		e.sandbox = nil
+	}, nil
+}
+
+// printDryRun prints an action a dry run skips, like the claude command
+// lines of the dry-run executor
+func (e *Engine) printDryRun(format string, args ...interface{}) {
+	if e.dryRunOut != nil {
+		_, _ = fmt.Fprintf(e.dryRunOut, "[dry-run] "+format+"\n", args...)
+		return
+	}
+	e.printer.Print("[dry-run] "+format+"\n", args...)
+}
+
+// commitsIterations reports whether the run commits each iteration: on a run
+// branch with git.auto_commit on, or when it opens a pull request
+func (e *Engine) commitsIterations() bool {
+	return e.runBranch != "" && (e.cfg.Git.AutoCommit || e.cfg.Git.PullRequest)
+}
+
+// commitIteration commits the changes of a completed iteration in the run's
+// worktree, with the step description as message and the run ID and
+// iteration as trailers. It returns the commit's hash, or "" when there was
+// nothing to commit.
+func (e *Engine) commitIteration(ctx context.Context, iteration int, state *core.State) string {
+	if !e.commitsIterations() {
+		return ""
+	}
+	message := commitMessage(state.CurrentStepDescription, fmt.Sprintf("alpine: iteration %d", iteration))
+	if e.cfg.DryRun {
+		subject, _, _ := strings.Cut(message, "\n")
+		e.printDryRun("commit iteration %d on %s: %s", iteration, e.runBranch, subject)
+		return ""
+	}
+	hash, err := gitx.CommitAll(ctx, e.workDir, gitx.Commit{
+		Message: message,
+		Trailers: []gitx.Trailer{
+			{Key: gitx.TrailerRunID, Value: e.runID},
+			{Key: gitx.TrailerIteration, Value: strconv.Itoa(iteration)},
+		},
+		Exclude: e.commitExcludes(),
+	})
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"error":     err.Error(),
+			"iteration": iteration,
+		}).Warn("Failed to commit iteration")
+		e.printer.Warning("Failed to commit iteration %d: %v", iteration, err)
+		return ""
+	}
+	if hash != "" {
+		logger.WithFields(map[string]interface{}{
+			"run_id":    e.runID,
+			"iteration": iteration,
+			"commit":    hash,
+		}).Info("Committed iteration")
+	}
+	return hash
+}
+
+// squashCommits replaces the commits of a completed run with one commit
+// named after the task when squashing is configured
+func (e *Engine) squashCommits(ctx context.Context) {
+	if e.runBranch == "" || !e.cfg.Git.Squash || e.commitBase == "" {
+		return
+	}
+	if e.cfg.DryRun {
+		e.printDryRun("squash the commits on %s since %s", e.runBranch, shortHash(e.commitBase))
+		return
+	}
+	hash, err := gitx.Squash(ctx, e.workDir, e.commitBase, gitx.Commit{
+		Message:  commitMessage(e.taskDesc, "alpine: "+e.runBranch),
+		Trailers: []gitx.Trailer{{Key: gitx.TrailerRunID, Value: e.runID}},
+	})
+	if err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to squash run commits")
+		e.printer.Warning("Failed to squash commits: %v", err)
+		return
+	}
+	if hash != "" {
+		logger.WithFields(map[string]interface{}{
+			"run_id": e.runID,
+			"commit": hash,
+		}).Info("Squashed run commits")
+		e.printer.Info("Squashed commits on %s into %s", e.runBranch, shortHash(hash))
+	}
+}
+
+// openPullRequest pushes a completed run's branch to origin and opens a pull
+// request against the base branch when configured. A failure is reported
+// without failing the run, whose work is committed on the branch.
+func (e *Engine) openPullRequest(ctx context.Context) {
+	if e.runBranch == "" || !e.cfg.Git.PullRequest {
+		return
+	}
+	if e.cfg.DryRun {
+		e.printDryRun("git push origin %s", e.runBranch)
+		e.printDryRun("open a pull request from %s into %s", e.runBranch, e.cfg.Git.BaseBranch)
+		return
+	}
+	pr, err := e.createPullRequest(ctx)
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"run_id": e.runID,
+			"branch": e.runBranch,
+			"error":  err.Error(),
+		}).Warn("Failed to open pull request")
+		e.printer.Warning("Failed to open a pull request for %s: %v", e.runBranch, err)
+		return
+	}
+	e.summary.PullRequest = pr.URL
+	logger.WithFields(map[string]interface{}{
+		"run_id":       e.runID,
+		"branch":       e.runBranch,
+		"pull_request": pr.URL,
+	}).Info("Opened pull request")
+	e.printer.Success("Opened pull request #%d: %s", pr.Number, pr.URL)
+}
+
+// createPullRequest pushes the run's branch and opens its pull request on
+// the repository of the origin remote
+func (e *Engine) createPullRequest(ctx context.Context) (*github.PullRequestInfo, error) {
+	remoteURL, err := gitx.RemoteURL(ctx, e.workDir, "origin")
+	if err != nil {
+		return nil, err
+	}
+	repo, err := github.ParseRepository(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := gitx.Push(ctx, e.workDir, "origin", e.runBranch); err != nil {
+		return nil, err
+	}
+
+	title, body := e.pullRequestContent(repo)
+	creator := e.pullRequests
+	if creator == nil {
+		creator = github.NewClient(e.cfg.GitHub.APIURL, e.cfg.AuthToken())
+	}
+	return creator.CreatePullRequest(ctx, repo, github.PullRequest{
+		Title: title,
+		Body:  body,
+		Head:  e.runBranch,
+		Base:  e.cfg.Git.BaseBranch,
+	})
+}
+
+// pullRequestContent returns the title and body of the run's pull request on
+// repo: the issue's title, or the task's first line, and a body linking the
+// issue, with the plan and the run summary
+func (e *Engine) pullRequestContent(repo github.Repository) (string, string) {
+	title, description, _ := strings.Cut(commitMessage(e.taskDesc, "alpine: "+e.runBranch), "\n\n")
+
+	var b strings.Builder
+	if issueRepo, number, err := github.ParseIssueURL(strings.TrimSpace(e.taskDesc)); err == nil {
+		title = fmt.Sprintf("Resolve #%d", number)
+		if issue := e.promptData.Issue; issue != nil && issue.Title != "" {
+			title = issue.Title
+		} else if fetched, err := github.FetchIssue(e.taskDesc); err == nil {
+			title = fetched.Title
+		} else {
+			logger.WithField("error", err.Error()).Debug("Failed to fetch the issue's title for the pull request")
+		}
+		ref := fmt.Sprintf("#%d", number)
+		if issueRepo != repo {
+			ref = issueRepo.String() + ref
+		}
+		fmt.Fprintf(&b, "Closes %s\n\n", ref)
+	} else if description != "" {
+		fmt.Fprintf(&b, "%s\n\n", description)
+	}
+
+	if plan, err := os.ReadFile(filepath.Join(e.workDir, "plan.md")); err == nil && len(bytes.TrimSpace(plan)) > 0 {
+		text := strings.TrimSpace(string(plan))
+		if len(text) > maxPullRequestPlan {
+			cut := maxPullRequestPlan
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+			text = text[:cut] + "\n\n[... truncated]"
+		}
+		fmt.Fprintf(&b, "## Plan\n\n<details>\n<summary>plan.md</summary>\n\n%s\n\n</details>\n\n", text)
+	}
+
+	s := e.summary
+	fmt.Fprintf(&b, "## Run summary\n\n- Run: `%s`\n- Iterations: %d\n- Duration: %s\n", s.RunID, s.Iterations, s.Duration.Round(time.Second))
+	for _, gate := range s.Gates {
+		status := "passed"
+		if !gate.Passed {
+			status = "failed"
+		}
+		fmt.Fprintf(&b, "- %s gate %s: `%s`\n", gate.Name, status, gate.Command)
+	}
+	return title, b.String()
+}
+
+// commitExcludes are the paths alpine writes in the worktree for itself,
+// which are never committed or cleaned
+func (e *Engine) commitExcludes() []string {
+	return WorktreeExcludes(e.workDir, e.stateFile)
+}
+
+// WorktreeExcludes returns the paths alpine writes in workDir for itself:
+// Claude's local settings with the files kept next to them, and the directory
+// of stateFile when it is inside workDir
+func WorktreeExcludes(workDir, stateFile string) []string {
+	excludes := []string{claude.LocalSettingsFiles}
+	if rel, err := filepath.Rel(workDir, filepath.Dir(stateFile)); err == nil && !strings.HasPrefix(rel, "..") {
+		excludes = append(excludes, rel)
+	}
+	return excludes
+}
+
+// runCheck runs the rollback check in the run's working directory and
+// returns its output. It succeeds when no check is configured, and prints
+// the check instead in a dry run.
+func (e *Engine) runCheck(ctx context.Context) (string, error) {
+	check := e.cfg.Rollback.Check
+	if check == "" {
+		return "", nil
+	}
+	if e.cfg.DryRun {
+		e.printDryRun("rollback check: %s", check)
+		return "", nil
+	}
+	e.printer.Step("Running check: %s", check)
+	output, err := e.runCommand(ctx, check, e.cfg.Rollback.Timeout)
+	logger.WithFields(map[string]interface{}{
+		"run_id":    e.runID,
+		"iteration": e.iteration,
+		"check":     check,
+		"passed":    err == nil,
+	}).Info("Ran rollback check")
+	return output, err
+}
+
+// commandWaitDelay bounds how long runCommand waits for the output of a
+// command once it has exited or been killed, since processes that left its
+// process group may still hold it
+var commandWaitDelay = 5 * time.Second
+
+// runCommand runs a shell command in the run's working directory, bounded by
+// timeout when it is positive, and returns its combined output. A command
+// cut short by ctx rather than its own timeout fails with ctx's cause; the
+// commands it started are killed with it.
+func (e *Engine) runCommand(ctx context.Context, command string, timeout time.Duration) (string, error) {
+	cmdCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		cmdCtx, cancel = context.WithTimeoutCause(ctx, timeout, fmt.Errorf("timed out after %s", timeout))
+		defer cancel()
+	}
+	cmd := exec.CommandContext(cmdCtx, "sh", "-c", command)
+	cmd.Dir = e.workDir
+	cmd.WaitDelay = commandWaitDelay
+	killProcessGroup(cmd)
+	output, err := cmd.CombinedOutput()
+	switch {
+	case err != nil && cmdCtx.Err() != nil:
+		err = context.Cause(cmdCtx)
+	case errors.Is(err, exec.ErrWaitDelay):
+		// The command succeeded; a process it left behind kept the output open
+		err = nil
+	}
+	return string(output), err
+}
+
+// runGates runs the configured quality gates after an iteration that left
+// the run running or completed, stopping at the first failure, and returns
+// the gates that failed. Each result is reported as a GateResult event; a
+// dry run prints the gates instead.
+func (e *Engine) runGates(ctx context.Context, iteration int, state *core.State) []events.Gate {
+	gates := e.cfg.Gates.List()
+	if len(gates) == 0 || (state.Status != core.StatusRunning && !state.IsCompleted()) {
+		return nil
+	}
+	for _, gate := range gates {
+		if e.cfg.DryRun {
+			e.printDryRun("%s gate: %s", gate.Name, gate.Command)
+			continue
+		}
+		e.printer.Step("Running %s gate: %s", gate.Name, gate.Command)
+		start := time.Now()
+		output, err := e.runCommand(ctx, gate.Command, e.cfg.Gates.Timeout)
+		if ctx.Err() != nil {
+			return nil
+		}
+		result := events.Gate{
+			Name:      gate.Name,
+			Command:   gate.Command,
+			Iteration: iteration,
+			Passed:    err == nil,
+			Duration:  time.Since(start),
+		}
+		if err != nil {
+			result.Output = truncateOutput(output, maxCheckOutput)
+			if result.Output == "" {
+				result.Output = err.Error()
+			}
+		}
+		logger.WithFields(map[string]interface{}{
+			"run_id":      e.runID,
+			"iteration":   iteration,
+			"gate":        gate.Name,
+			"command":     gate.Command,
+			"passed":      result.Passed,
+			"duration_ms": result.Duration.Milliseconds(),
+		}).Info("Ran quality gate")
+		e.recordGate(result)
+		if e.eventEmitter != nil {
+			e.eventEmitter.GateResult(e.runID, e.taskDesc, result)
+		}
+		if !result.Passed {
+			e.printer.Warning("%s gate failed after iteration %d: %v", gate.Name, iteration, err)
+			return []events.Gate{result}
+		}
+		e.printer.Success("%s gate passed (%s)", gate.Name, result.Duration.Round(time.Millisecond))
+	}
+	return nil
+}
+
+// recordGate keeps the latest result of each gate for the run's summary
+func (e *Engine) recordGate(result events.Gate) {
+	for i, gate := range e.gates {
+		if gate.Name == result.Name {
+			e.gates[i] = result
+			return
+		}
+	}
+	e.gates = append(e.gates, result)
+}
+
+// reopenForGates returns the run to running after an iteration failed a
+// quality gate, with a prompt carrying the failure. A completed state is
+// continued with /continue; a running state keeps its next prompt.
+func (e *Engine) reopenForGates(state *core.State, failed []events.Gate) (*core.State, error) {
+	prompt := state.NextStepPrompt
+	if state.IsCompleted() || prompt == "" {
+		prompt = core.PromptContinue
+	}
+	prompt = gateFailedPrompt(prompt, failed, state.IsCompleted())
+	err := core.UpdateState(e.stateFile, func(s *core.State) error {
+		s.Status = core.StatusRunning
+		s.NextStepPrompt = prompt
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reopen state after a failed gate: %w", err)
+	}
+	reopened, err := core.LoadState(e.stateFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	return reopened, nil
+}
+
+// gateFailedPrompt appends the output of failed quality gates to prompt
+func gateFailedPrompt(prompt string, failed []events.Gate, completed bool) string {
+	var b strings.Builder
+	b.WriteString(prompt)
+	for _, gate := range failed {
+		if completed {
+			fmt.Fprintf(&b, "\n\nThe task is not complete: the %s gate failed.", gate.Name)
+		} else {
+			fmt.Fprintf(&b, "\n\nThe %s gate failed after your last iteration.", gate.Name)
+		}
+		fmt.Fprintf(&b, " Fix it before moving on:\n\n$ %s\n```\n%s\n```", gate.Command, gate.Output)
+	}
+	return b.String()
+}
+
+// printSummary prints the completed run's iterations, duration and the
+// latest result of each quality gate
+func (e *Engine) printSummary() {
+	s := e.summary
+	e.printer.Info("Run summary: %d iterations in %s", s.Iterations, s.Duration.Round(time.Second))
+	for _, gate := range s.Gates {
+		status := "passed"
+		if !gate.Passed {
+			status = "failed"
+		}
+		e.printer.Detail("%s gate %s: %s (%s)", gate.Name, status, gate.Command, gate.Duration.Round(time.Millisecond))
+	}
+}
+
+// rollBack handles an iteration that failed the rollback check: the working
+// directory is reset to the last iteration commit and the state the
+// iteration started from is restored. It returns the prompt retrying the
+// iteration with the check's output, or fails the run once more than
+// MaxRollbacks iterations in a row have failed the check.
+func (e *Engine) rollBack(ctx context.Context, rollbacks int, state *core.State, prompt, output string) (string, error) {
+	check := e.cfg.Rollback.Check
+	logger.WithFields(map[string]interface{}{
+		"run_id":        e.runID,
+		"iteration":     e.iteration,
+		"check":         check,
+		"rollbacks":     rollbacks,
+		"max_rollbacks": e.cfg.Rollback.MaxRollbacks,
+	}).Warn("Iteration failed the rollback check")
+	e.printer.Warning("Check failed after iteration %d: %s", e.iteration, check)
+
+	rolledBack := e.resetToCheckpoint(ctx)
+	e.stampState(state)
+	if err := state.Save(e.stateFile); err != nil {
+		return "", fmt.Errorf("failed to restore state after a failed check: %w", err)
+	}
+
+	if rollbacks > e.cfg.Rollback.MaxRollbacks {
+		reason := fmt.Errorf("%w: %q failed %d times in a row", ErrCheckFailed, check, rollbacks)
+		e.printer.Error("Stopping workflow: %v", reason)
+		return "", e.recordFailure(reason)
+	}
+	return checkFailedPrompt(prompt, check, output, rolledBack), nil
+}
+
+// resetToCheckpoint discards the changes made in the working directory since
+// the last iteration commit. It reports whether they were discarded, which
+// needs per-iteration commits on a run branch.
+func (e *Engine) resetToCheckpoint(ctx context.Context) bool {
+	if !e.commitsIterations() {
+		e.printer.Warning("Keeping the iteration's changes: rolling back needs per-iteration commits on a run branch")
+		return false
+	}
+	if err := gitx.Reset(ctx, e.workDir, "HEAD", e.commitExcludes()); err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to roll back iteration")
+		e.printer.Warning("Failed to roll back iteration %d: %v", e.iteration, err)
+		return false
+	}
+	head, _ := gitx.Head(ctx, e.workDir)
+	logger.WithFields(map[string]interface{}{
+		"run_id":    e.runID,
+		"iteration": e.iteration,
+		"commit":    head,
+	}).Info("Rolled back iteration")
+	e.printer.Info("Rolled back iteration %d to %s", e.iteration, shortHash(head))
+	return true
+}
+
+// checkFailedPrompt retries prompt with the output of the check the previous
+// attempt failed
+func checkFailedPrompt(prompt, check, output string, rolledBack bool) string {
+	what := fmt.Sprintf("`%s` failed after your previous attempt at this step.", check)
+	if rolledBack {
+		what = fmt.Sprintf("Your previous attempt at this step was rolled back because `%s` failed.", check)
+	}
+	return fmt.Sprintf("%s\n\n%s Fix the cause of this failure:\n\n```\n%s\n```",
+		prompt, what, truncateOutput(output, maxCheckOutput))
+}
+
+// truncateOutput keeps the last max bytes of command output, where failures
+// are usually reported
+func truncateOutput(output string, max int) string {
+	output = strings.TrimSpace(output)
+	if len(output) <= max {
+		return output
+	}
+	start := len(output) - max
+	for start < len(output) && !utf8.RuneStart(output[start]) {
+		start++
+	}
+	return "[... truncated]\n" + output[start:]
+}
+
+// commitMessage makes a commit message from a description, using fallback
+// when it is empty. A long first line is shortened for the subject and the
+// full description kept in the body.
+func commitMessage(description, fallback string) string {
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return fallback
+	}
+	subject, body, _ := strings.Cut(description, "\n")
+	if len(subject) > maxCommitSubject {
+		subject = strings.TrimSpace(subject[:maxCommitSubject-3]) + "..."
+		body = description
+	}
+	if body = strings.TrimSpace(body); body == "" {
+		return subject
+	}
+	return subject + "\n\n" + body
+}
+
+// shortHash abbreviates a commit hash for display
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// reportPolicyViolations logs and prints the tool calls the policy blocked
+// since the last report
+func (e *Engine) reportPolicyViolations(iteration int) {
+	if e.policyLog == "" {
+		return
+	}
+	violations, err := policy.ReadViolations(e.policyLog)
+	if err != nil {
+		logger.WithField("error", err.Error()).Warn("Failed to read policy log")
+		return
+	}
+	for _, v := range violations[min(e.policyReported, len(violations)):] {
+		logger.WithFields(map[string]interface{}{
+			"run_id":    e.runID,
+			"iteration": iteration,
+			"tool":      v.Tool,
+			"subject":   v.Subject,
+			"reason":    v.Reason,
+		}).Warn("Policy blocked a tool call")
+		e.printer.Warning("Policy blocked %s: %s", v.Tool, v.Reason)
+	}
+	e.policyReported = len(violations)
+}
+
+// withRunBudget returns a context bounded by the configured maximum run duration
+func (e *Engine) withRunBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	maxDuration := e.cfg.Budget.MaxDuration
+	if maxDuration <= 0 {
+		return context.WithCancel(ctx)
+	}
+	logger.WithFields(map[string]interface{}{
+		"run_id":       e.runID,
+		"max_duration": maxDuration.String(),
+	}).Debug("Applying run duration budget")
+	return context.WithTimeoutCause(ctx, maxDuration,
+		fmt.Errorf("%w: run exceeded max duration of %s", ErrBudgetExceeded, maxDuration))
+}
+
+// withIterationBudget returns a context bounded by the configured per-iteration timeout
+func (e *Engine) withIterationBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := e.cfg.Budget.IterationTimeout
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, timeout,
+		fmt.Errorf("%w: iteration exceeded timeout of %s", ErrBudgetExceeded, timeout))
+}
+
+// budgetCause returns the budget error that cancelled ctx, or nil if ctx is
+// still live or was cancelled for another reason
+func budgetCause(ctx context.Context) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	if cause := context.Cause(ctx); errors.Is(cause, ErrBudgetExceeded) {
+		return cause
+	}
+	return nil
+}
+
+// stopOnBudget records a terminal failed state with the budget reason and
+// returns the reason so the caller can end the run
+func (e *Engine) stopOnBudget(reason error) error {
+	logger.WithFields(map[string]interface{}{
+		"run_id": e.runID,
+		"reason": reason.Error(),
+	}).Warn("Workflow budget exceeded, stopping run")
+	e.printer.Warning("Stopping workflow: %v", reason)
+
+	return e.recordFailure(reason)
+}
+
+// recordFailure saves a terminal failed state with the given reason, keeping
+// the pending prompt so the run can be resumed, and returns the reason
+func (e *Engine) recordFailure(reason error) error {
+	state, err := core.LoadState(e.stateFile)
+	if err != nil {
+		logger.WithField("error", err.Error()).Debug("Could not load state before recording failure")
+		state = &core.State{}
+	}
+	e.stampState(state)
+	state.Status = core.StatusFailed
+	state.CurrentStepDescription = fmt.Sprintf("Stopped: %v", reason)
+	state.FailureReason = reason.Error()
+	if err := state.Save(e.stateFile); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"error":      err.Error(),
+			"state_file": e.stateFile,
+		}).Error("Failed to save terminal state")
+	}
+	e.journal(core.JournalEntry{Iteration: e.iteration, Error: reason.Error(), State: *state})
+
+	return reason
+}
+
+// withStallGrace bounds the wait for a state update after Claude has exited
+// by the stall grace period. Stall detection is off when MaxStalls is zero.
+func (e *Engine) withStallGrace(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.cfg.Stall.MaxStalls <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, e.cfg.Stall.GracePeriod)
+}
+
+// handleStall reports a stall and returns the prompt for the next iteration
+// after the stall backoff, or fails the run once MaxStalls consecutive stalls
+// have occurred
+func (e *Engine) handleStall(ctx context.Context, stalls int, prompt string) (string, error) {
+	stallCfg := e.cfg.Stall
+	logger.WithFields(map[string]interface{}{
+		"run_id":     e.runID,
+		"stalls":     stalls,
+		"max_stalls": stallCfg.MaxStalls,
+		"prompt":     prompt,
+		"action":     stallCfg.Action,
+		"state_file": e.stateFile,
+	}).Warn("Claude exited without updating the state file")
+	if e.eventEmitter != nil {
+		e.eventEmitter.RunStalled(e.runID, e.taskDesc, stalls)
+	}
+
+	if stalls >= stallCfg.MaxStalls {
+		reason := fmt.Errorf("%w: Claude exited %d times in a row without updating %s", ErrStalled, stalls, e.stateFile)
+		e.printer.Error("Stopping workflow: %v", reason)
+		return "", e.recordFailure(reason)
+	}
+	e.printer.Warning("Claude exited without updating the state file (stall %d of %d)", stalls, stallCfg.MaxStalls)
+
+	// Back off before re-running Claude; cancellation is handled by the loop
+	if backoff := stallCfg.Backoff << (stalls - 1); backoff > 0 {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+
+	if stallCfg.Action == config.StallActionRetry {
+		return prompt, nil
+	}
+	if stallCfg.NudgePrompt == "" {
+		return config.DefaultStallNudgePrompt, nil
+	}
+	return stallCfg.NudgePrompt, nil
+}
+
+// reportRetry tells the user and event listeners that Claude is being re-run
+// after a transient failure
+func (e *Engine) reportRetry(event claude.RetryEvent) {
+	e.printer.Warning("Claude failed (%s), retrying in %s (retry %d of %d)",
+		event.Err.Kind, event.Delay.Round(time.Second), event.Attempt, event.MaxRetries)
+	if e.eventEmitter != nil {
+		e.eventEmitter.RunRetrying(e.runID, e.taskDesc, event.Attempt, string(event.Err.Kind), event.Delay)
+	}
+}
+
+// sessionFor returns the Claude session the next iteration runs in and
+// whether it resumes it. With continuation enabled, iterations resume the
+// previous session; a new one starts for each workflow phase (/make_plan and
+// /start, which includes the prompt set when a plan is approved) and once the
+// session reaches its iteration or context size limit.
+func (e *Engine) sessionFor(prompt string) (sessionID string, resume bool) {
+	sessionCfg := e.cfg.Session
+	if !sessionCfg.Continue {
+		return "", false
+	}
+
+	reason := ""
+	switch {
+	case e.session.ID == "":
+		reason = "no previous session"
+	case strings.HasPrefix(prompt, core.PromptMakePlan) || strings.HasPrefix(prompt, core.PromptStart):
+		reason = "new workflow phase"
+	case sessionCfg.MaxIterations > 0 && e.sessionRuns >= sessionCfg.MaxIterations:
+		reason = "session iteration limit reached"
+	case sessionCfg.MaxContextTokens > 0 && e.session.ContextTokens >= sessionCfg.MaxContextTokens:
+		reason = "session context limit reached"
+	}
+
+	if reason == "" {
+		e.sessionRuns++
+		logger.WithFields(map[string]interface{}{
+			"run_id":         e.runID,
+			"session_id":     e.session.ID,
+			"session_runs":   e.sessionRuns,
+			"context_tokens": e.session.ContextTokens,
+		}).Info("Continuing Claude session")
+		return e.session.ID, true
+	}
+
+	logger.WithFields(map[string]interface{}{
+		"run_id":           e.runID,
+		"reason":           reason,
+		"previous_session": e.session.ID,
+		"context_tokens":   e.session.ContextTokens,
+	}).Info("Starting new Claude session")
+	e.session = claude.SessionInfo{ID: claude.NewSessionID()}
+	e.sessionRuns = 1
+	return e.session.ID, false
+}
+
+// recordSession remembers the session an iteration ran in so the next
+// iteration can continue it
+func (e *Engine) recordSession(session claude.SessionInfo) {
+	e.session = session
+}
+
+// stopOnState ends the run at a failed, blocked or awaiting_input state. The
+// state file is kept so the run can be resumed once the cause is addressed.
+// Failed runs return an error; blocked and awaiting_input runs end cleanly.
+func (e *Engine) stopOnState(state *core.State) error {
+	reason := state.FailureReason
+	if reason == "" {
+		reason = state.CurrentStepDescription
+	}
+	logger.WithFields(map[string]interface{}{
+		"run_id":     e.runID,
+		"status":     state.Status,
+		"reason":     reason,
+		"state_file": e.stateFile,
+	}).Warn("Workflow stopped by state")
+
+	if state.Status == core.StatusFailed {
+		e.printer.Error("Workflow failed: %s", reason)
+		return fmt.Errorf("workflow failed: %s", reason)
+	}
+	e.printer.Warning("Workflow %s: %s", strings.ReplaceAll(state.Status, "_", " "), reason)
+	e.printer.Info("Resolve the issue and run alpine again without a task to resume from %s", e.stateFile)
+	return nil
+}
+
+// resumeState returns a stopped state left by an earlier run to running so
+// that continuing from it picks up at its next prompt
+func (e *Engine) resumeState() error {
+	err := core.UpdateState(e.stateFile, func(state *core.State) error {
+		if state.IsStopped() && state.NextStepPrompt != "" {
+			logger.WithFields(map[string]interface{}{
+				"previous_status": state.Status,
+				"next_prompt":     state.NextStepPrompt,
+			}).Info("Resuming stopped workflow")
+			state.Status = core.StatusRunning
+			state.FailureReason = ""
+		}
+		// Continue the session the stopped run was in
+		if e.cfg.Session.Continue && state.SessionID != "" {
+			e.session = claude.SessionInfo{ID: state.SessionID}
+		}
+		e.stampState(state)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to resume from state file: %w", err)
+	}
+	return nil
+}
+
+// recordIteration writes the run metadata for an iteration into the state
+// file. Claude may rewrite the file without these fields, so they are
+// restored before every iteration. Failures only lose metadata and are logged.
+func (e *Engine) recordIteration(iteration int, prompt string) {
+	err := core.UpdateState(e.stateFile, func(state *core.State) error {
+		e.stampState(state)
+		state.Iteration = iteration
+		state.LastPrompt = prompt
+		return nil
+	})
+	if err != nil {
+		logger.WithFields(map[string]interface{}{
+			"error":      err.Error(),
+			"state_file": e.stateFile,
+			"iteration":  iteration,
+		}).Warn("Failed to record iteration metadata in state file")
+	}
+}
+
+// journal appends an observed state to the run's journal next to the state
+// file. Failures only lose history and are logged.
+func (e *Engine) journal(entry core.JournalEntry) {
+	entry.RunID = e.runID
+	path := core.JournalPath(e.stateFile)
+	if err := core.AppendJournal(path, entry); err != nil {
+		logger.WithFields(map[string]interface{}{
+			"error":     err.Error(),
+			"journal":   path,
+			"iteration": entry.Iteration,
+		}).Warn("Failed to append state to journal")
+	}
+}
+
+// claudeExitCode returns the exit code of a failed Claude invocation, 0 for
+// success and -1 when Claude did not exit on its own (e.g. it failed to start)
+func claudeExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// stampState fills in the run metadata alpine maintains in the state file
+func (e *Engine) stampState(state *core.State) {
+	state.RunID = e.runID
+	state.StartedAt = e.startedAt
+	if e.planPath != "" {
+		state.PlanPath = e.planPath
+	}
+	if e.session.ID != "" {
+		state.SessionID = e.session.ID
+	}
+}
+
 // initializeWorkflow creates the initial state file
 func (e *Engine) initializeWorkflow(ctx context.Context, taskDescription string, generatePlan bool) error {
 	var prompt string
@@ -378,7 +1623,7 @@ func (e *Engine) initializeWorkflow(ctx context.Context, taskDescription string,
 		if github.IsGitHubIssueURL(taskDescription) {
 			logger.WithField("github_url", taskDescription).Info("Detected GitHub issue URL, fetching description")
 
-			description, err := github.FetchIssueDescription(taskDescription)
+			issue, err := github.FetchIssue(taskDescription)
 			if err != nil {
 				logger.WithFields(map[string]interface{}{
 					"github_url": taskDescription,
@@ -387,14 +1632,21 @@ func (e *Engine) initializeWorkflow(ctx context.Context, taskDescription string,
 				taskText = taskDescription
 			} else {
 				logger.WithField("github_url", taskDescription).Info("Successfully fetched GitHub issue description")
-				taskText = description
+				taskText = fmt.Sprintf("Task: %s\n\n%s", issue.Title, issue.Body)
+				e.promptData.Issue = &prompts.Issue{URL: issue.URL, Number: issue.Number, Title: issue.Title, Body: issue.Body}
 			}
 		} else {
 			taskText = taskDescription
 		}
 
-		// Use the embedded prompt template and replace {{TASK}} with the task description
-		prompt = strings.ReplaceAll(prompts.PromptPlan, "{{TASK}}", taskText)
+		// Render the plan prompt template with the task description
+		e.promptData.Task = taskText
+		var err error
+		if prompt, err = e.renderPrompt(prompts.NamePlan, 0); err != nil {
+			return err
+		}
+		e.planPrompt = prompt
+		e.planPath = filepath.Join(e.workDir, "plan.md")
 	} else {
 		prompt = "/start " + taskDescription
 	}
@@ -435,6 +1687,7 @@ func (e *Engine) initializeWorkflow(ctx context.Context, taskDescription string,
 		NextStepPrompt:         prompt,
 		Status:                 "running",
 	}
+	e.stampState(state)
 
 	logger.WithFields(map[string]interface{}{
 		"state_file": e.stateFile,
@@ -450,102 +1703,138 @@ func (e *Engine) initializeWorkflow(ctx context.Context, taskDescription string,
 		return err
 	}
 
+	e.journal(core.JournalEntry{State: *state})
+
 	logger.WithField("state_file", e.stateFile).Info("Initial workflow state created successfully")
 	return nil
 }
 
-// waitForStateUpdate waits for the state file to be updated
-func (e *Engine) waitForStateUpdate(ctx context.Context, previousState *core.State) error {
-	// Check immediately if state has already been updated (for synchronous updates in tests)
-	logger.WithField("state_file", e.stateFile).Debug("Checking for immediate state update")
-	newState, err := core.LoadState(e.stateFile)
-	if err == nil && (newState.CurrentStepDescription != previousState.CurrentStepDescription ||
-		newState.NextStepPrompt != previousState.NextStepPrompt ||
-		newState.Status != previousState.Status) {
+// preparePrompts resolves prompt templates from the repository containing
+// the run's root and collects the variables they are rendered with
+func (e *Engine) preparePrompts(ctx context.Context, taskDescription string) {
+	e.prompts = prompts.NewLoader(e.workDir)
+	e.promptData = prompts.Data{Task: taskDescription, Repo: filepath.Base(e.workDir)}
+
+	if info, err := gitx.InspectRepo(ctx, e.workDir); err == nil {
+		e.prompts.RepoRoot = info.Root
+		e.promptData.Repo = info.Name
+		e.promptData.Branch = info.Branch
+	} else {
 		logger.WithFields(map[string]interface{}{
-			"old_status": previousState.Status,
-			"new_status": newState.Status,
-			"old_step":   previousState.CurrentStepDescription,
-			"new_step":   newState.CurrentStepDescription,
-		}).Debug("State already updated")
-		return nil // State already updated
+			"work_dir": e.workDir,
+			"error":    err.Error(),
+		}).Debug("Run is not in a git repository; prompt templates get no branch")
 	}
+	if e.wt != nil && e.wt.Branch != "" {
+		e.promptData.Branch = e.wt.Branch
+	}
+}
 
-	// Get initial modification time
-	initialStat, err := os.Stat(e.stateFile)
-	if err != nil {
-		logger.WithFields(map[string]interface{}{
-			"error":      err.Error(),
-			"state_file": e.stateFile,
-		}).Error("Failed to stat state file")
-		return fmt.Errorf("failed to stat state file: %w", err)
+// renderPrompt renders the effective named prompt template for iteration
+func (e *Engine) renderPrompt(name string, iteration int) (string, error) {
+	loader := e.prompts
+	if loader == nil {
+		loader = &prompts.Loader{}
 	}
-	initialModTime := initialStat.ModTime()
-	logger.WithFields(map[string]interface{}{
-		"state_file": e.stateFile,
-		"mod_time":   initialModTime.Format(time.RFC3339),
-	}).Debug("Got initial state file modification time")
+	data := e.promptData
+	data.Iteration = iteration
+	return loader.Render(name, data)
+}
+
+// waitForStateUpdate waits for the state file to be updated and returns the new state
+func (e *Engine) waitForStateUpdate(ctx context.Context, previousState *core.State) (*core.State, error) {
+	logger.WithField("state_file", e.stateFile).Debug("Watching state file for update")
+
+	watcher := core.NewStateWatcher(e.stateFile)
+	if err := watcher.Start(ctx); err != nil {
+		return nil, fmt.Errorf("failed to watch state file: %w", err)
+	}
+	defer watcher.Stop()
 
 	// Show progress indicator while waiting
 	progress := e.printer.StartProgress("Waiting for state file update")
 	defer progress.Stop()
 
-	ticker := time.NewTicker(500 * time.Millisecond)
-	defer ticker.Stop()
-
+	// The watcher delivers the current content first, which covers updates
+	// written before we started watching (e.g. synchronous updates in tests)
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
-			// Check if file has been modified
-			stat, err := os.Stat(e.stateFile)
-			if err != nil {
-				if os.IsNotExist(err) {
-					// File was deleted, wait for it to be recreated
-					logger.WithField("state_file", e.stateFile).Debug("State file deleted, waiting for recreation")
-					continue
-				}
-				logger.WithFields(map[string]interface{}{
-					"error":      err.Error(),
-					"state_file": e.stateFile,
-				}).Error("Failed to stat state file during wait")
-				return fmt.Errorf("failed to stat state file: %w", err)
+			return nil, ctx.Err()
+		case newState, ok := <-watcher.Updates():
+			if !ok {
+				return nil, ctx.Err()
 			}
 
-			if stat.ModTime().After(initialModTime) {
+			if newState.CurrentStepDescription != previousState.CurrentStepDescription ||
+				newState.NextStepPrompt != previousState.NextStepPrompt ||
+				newState.Status != previousState.Status {
 				logger.WithFields(map[string]interface{}{
-					"old_mod_time": initialModTime.Format(time.RFC3339),
-					"new_mod_time": stat.ModTime().Format(time.RFC3339),
-				}).Debug("State file modification detected")
-
-				// File has been modified, load and check if content changed
-				newState, err := core.LoadState(e.stateFile)
-				if err != nil {
-					// File might be in the middle of being written, try again
-					logger.WithField("error", err.Error()).Debug("State file read error, likely mid-write")
-					continue
-				}
-
-				// Check if state actually changed
-				if newState.CurrentStepDescription != previousState.CurrentStepDescription ||
-					newState.NextStepPrompt != previousState.NextStepPrompt ||
-					newState.Status != previousState.Status {
-					logger.WithFields(map[string]interface{}{
-						"old_status":    previousState.Status,
-						"new_status":    newState.Status,
-						"state_changed": true,
-					}).Info("State file content changed")
-					return nil // State has been updated
-				}
+					"old_status": previousState.Status,
+					"new_status": newState.Status,
+					"old_step":   previousState.CurrentStepDescription,
+					"new_step":   newState.CurrentStepDescription,
+				}).Info("State file content changed")
+				return newState, nil // State has been updated
 			}
 		}
 	}
 }
 
+// resolveWorkDir sets the run's root directory from the configuration,
+// falling back to the current directory when none is configured
+func (e *Engine) resolveWorkDir() error {
+	e.workDir = e.cfg.WorkDir
+	if e.workDir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			logger.WithField("error", err.Error()).Error("Failed to get current directory")
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		e.workDir = cwd
+	}
+	logger.WithField("work_dir", e.workDir).Debug("Resolved run working directory")
+	return nil
+}
+
+// WorkDir returns the root directory of the current or last run
+func (e *Engine) WorkDir() string {
+	return e.workDir
+}
+
+// SetRunID sets the ID used for the next run instead of a generated one, so
+// callers such as the HTTP server can correlate the state file with their run
+func (e *Engine) SetRunID(runID string) {
+	e.nextRunID = runID
+}
+
+// RunID returns the ID of the current or last run
+func (e *Engine) RunID() string {
+	return e.runID
+}
+
 // SetStateFile allows overriding the state file path (mainly for testing)
 func (e *Engine) SetStateFile(path string) {
 	e.stateFile = path
+	e.baseStateFile = path
+}
+
+// SetRunBranch tells the engine that the next run's working directory is
+// checked out on a branch created for the run, which it commits to as it
+// does in the worktrees it creates itself
+func (e *Engine) SetRunBranch(branch string) {
+	e.nextRunBranch = branch
+}
+
+// SetPullRequestCreator replaces the GitHub API client that opens pull
+// requests for completed runs
+func (e *Engine) SetPullRequestCreator(creator github.PullRequestCreator) {
+	e.pullRequests = creator
+}
+
+// Summary returns the summary of the engine's last completed run
+func (e *Engine) Summary() Summary {
+	return e.summary
 }
 
 // SetPrinter allows overriding the printer (mainly for testing)
@@ -553,6 +1842,11 @@ func (e *Engine) SetPrinter(printer *output.Printer) {
 	e.printer = printer
 }
 
+// SetDryRunOutput sends the actions a dry run skips to w instead of the printer
+func (e *Engine) SetDryRunOutput(w io.Writer) {
+	e.dryRunOut = w
+}
+
 // SetEventEmitter allows setting an event emitter (mainly for HTTP server mode)
 func (e *Engine) SetEventEmitter(emitter events.EventEmitter) {
 	e.eventEmitter = emitter
@@ -568,15 +1862,7 @@ func (e *Engine) createWorktree(ctx context.Context, taskDescription string) err
 		return nil
 	}
 
-	// Save original directory to restore later if needed
 	var err error
-	e.originalDir, err = os.Getwd()
-	if err != nil {
-		logger.WithField("error", err.Error()).Error("Failed to get current directory")
-		return fmt.Errorf("failed to get current directory: %w", err)
-	}
-	logger.WithField("original_dir", e.originalDir).Debug("Saved original directory")
-
 	// Create worktree
 	logger.WithFields(map[string]interface{}{
 		"task":   taskDescription,
@@ -596,28 +1882,14 @@ func (e *Engine) createWorktree(ctx context.Context, taskDescription string) err
 		"run_id": e.runID,
 	}).Info("Worktree created successfully")
 
-	// Change to worktree directory
+	// The worktree becomes the run's root; the process cwd is left alone
+	e.workDir = e.wt.Path
 	logger.WithFields(map[string]interface{}{
-		"from": e.originalDir,
-		"to":   e.wt.Path,
-	}).Debug("Changing to worktree directory")
-	if err := os.Chdir(e.wt.Path); err != nil {
-		logger.WithFields(map[string]interface{}{
-			"error":         err.Error(),
-			"worktree_path": e.wt.Path,
-		}).Error("Failed to change to worktree directory")
-		return fmt.Errorf("failed to change to worktree directory: %w", err)
-	}
-
-	// State file path remains constant
-	// The working directory has changed to the worktree, but the state file
-	// path remains relative to that directory
-	logger.WithFields(map[string]interface{}{
-		"state_file":    e.stateFile,
-		"worktree_path": e.wt.Path,
-		"working_dir":   e.wt.Path,
-	}).Debug("State file path configured in worktree")
+		"work_dir":   e.workDir,
+		"state_file": e.stateFile,
+	}).Debug("Run root set to worktree")
 
 	e.printer.Info("Created worktree: %s (branch: %s)", e.wt.Path, e.wt.Branch)
+	e.runBranch = e.wt.Branch
 	return nil
 }
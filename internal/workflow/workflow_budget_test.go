@@ -0,0 +1,168 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/core"
+	"github.com/Backland-Labs/alpine/internal/events"
+	"github.com/Backland-Labs/alpine/internal/output"
+)
+
+// funcExecutor adapts a function to the ClaudeExecutor interface
+type funcExecutor func(ctx context.Context, config claude.ExecuteConfig) (string, error)
+
+func (f funcExecutor) Execute(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+	return f(ctx, config)
+}
+
+// neverEndingExecutor advances the state on every call without ever completing
+func neverEndingExecutor(t *testing.T, calls *int) funcExecutor {
+	return func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+		*calls++
+		state := &core.State{
+			CurrentStepDescription: fmt.Sprintf("Step %d", *calls),
+			NextStepPrompt:         "/continue",
+			Status:                 core.StatusRunning,
+		}
+		require.NoError(t, state.Save(config.StateFile))
+		return "ok", nil
+	}
+}
+
+// newBudgetTestEngine creates an engine with a temp state file and silent printer
+func newBudgetTestEngine(t *testing.T, executor ClaudeExecutor, mutate func(*Engine)) (*Engine, string) {
+	t.Helper()
+	stateFile := filepath.Join(t.TempDir(), "agent_state", "agent_state.json")
+	cfg := testConfig(false)
+	engine := NewEngine(executor, nil, cfg, nil)
+	engine.SetStateFile(stateFile)
+	engine.SetPrinter(output.NewPrinterWithWriters(io.Discard, io.Discard, false))
+	if mutate != nil {
+		mutate(engine)
+	}
+	return engine, stateFile
+}
+
+func TestEngine_Budget_MaxIterations(t *testing.T) {
+	calls := 0
+	emitter := events.NewMockEmitter()
+	engine, stateFile := newBudgetTestEngine(t, neverEndingExecutor(t, &calls), func(e *Engine) {
+		e.cfg.Budget.MaxIterations = 3
+		e.SetEventEmitter(emitter)
+	})
+
+	err := engine.Run(context.Background(), "loop forever", false)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrBudgetExceeded))
+	assert.Contains(t, err.Error(), "max iterations (3)")
+	assert.Equal(t, 3, calls, "Claude should run exactly MaxIterations times")
+
+	// The run leaves a terminal state recording why it stopped
+	state, loadErr := core.LoadState(stateFile)
+	require.NoError(t, loadErr)
+	assert.Equal(t, core.StatusFailed, state.Status)
+	assert.Contains(t, state.CurrentStepDescription, "max iterations")
+
+	errorCalls := emitter.FindCallsByMethod("RunError")
+	require.Len(t, errorCalls, 1)
+	assert.True(t, errors.Is(errorCalls[0].Error, ErrBudgetExceeded))
+	assert.Empty(t, emitter.FindCallsByMethod("RunFinished"))
+}
+
+func TestEngine_Budget_CompletesOnLastIteration(t *testing.T) {
+	calls := 0
+	completing := funcExecutor(func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+		calls++
+		state := &core.State{CurrentStepDescription: fmt.Sprintf("Step %d", calls), NextStepPrompt: "/continue", Status: core.StatusRunning}
+		if calls == 3 {
+			state = &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+		}
+		require.NoError(t, state.Save(config.StateFile))
+		return "ok", nil
+	})
+	emitter := events.NewMockEmitter()
+	engine, stateFile := newBudgetTestEngine(t, completing, func(e *Engine) {
+		e.cfg.Budget.MaxIterations = 3
+		e.SetEventEmitter(emitter)
+	})
+
+	require.NoError(t, engine.Run(context.Background(), "finish in time", false))
+	assert.Equal(t, 3, calls)
+	assert.NoFileExists(t, stateFile, "the completed state is cleaned up, not marked failed")
+	assert.Len(t, emitter.FindCallsByMethod("RunFinished"), 1)
+	assert.Empty(t, emitter.FindCallsByMethod("RunError"))
+}
+
+func TestEngine_Budget_IterationTimeout(t *testing.T) {
+	// Executor blocks until its context is cancelled, like a hung Claude process
+	blocking := funcExecutor(func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	engine, stateFile := newBudgetTestEngine(t, blocking, func(e *Engine) {
+		e.cfg.Budget.IterationTimeout = 50 * time.Millisecond
+	})
+
+	start := time.Now()
+	err := engine.Run(context.Background(), "hang", false)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrBudgetExceeded))
+	assert.Contains(t, err.Error(), "iteration exceeded timeout")
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	state, loadErr := core.LoadState(stateFile)
+	require.NoError(t, loadErr)
+	assert.Equal(t, core.StatusFailed, state.Status)
+}
+
+func TestEngine_Budget_MaxDuration(t *testing.T) {
+	// Executor returns without touching state, so the engine waits for an update
+	idle := funcExecutor(func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+		return "ok", nil
+	})
+	engine, stateFile := newBudgetTestEngine(t, idle, func(e *Engine) {
+		e.cfg.Budget.MaxDuration = 100 * time.Millisecond
+	})
+
+	err := engine.Run(context.Background(), "wait", false)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrBudgetExceeded))
+	assert.Contains(t, err.Error(), "run exceeded max duration")
+
+	state, loadErr := core.LoadState(stateFile)
+	require.NoError(t, loadErr)
+	assert.Equal(t, core.StatusFailed, state.Status)
+	assert.Equal(t, "/start wait", state.NextStepPrompt, "pending prompt is kept so the run can be resumed")
+}
+
+func TestEngine_Budget_UserCancellationIsNotBudget(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelling := funcExecutor(func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+		cancel()
+		return "", ctx.Err()
+	})
+	engine, stateFile := newBudgetTestEngine(t, cancelling, func(e *Engine) {
+		e.cfg.Budget.MaxDuration = time.Hour
+	})
+
+	err := engine.Run(ctx, "cancel me", false)
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, ErrBudgetExceeded))
+
+	state, loadErr := core.LoadState(stateFile)
+	require.NoError(t, loadErr)
+	assert.Equal(t, core.StatusRunning, state.Status, "cancellation must not write a budget stop")
+	_, statErr := os.Stat(stateFile)
+	assert.NoError(t, statErr)
+}
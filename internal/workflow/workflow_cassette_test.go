@@ -0,0 +1,62 @@
+package workflow
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/claude/cassette"
+	"github.com/Backland-Labs/alpine/internal/core"
+	"github.com/Backland-Labs/alpine/internal/output"
+)
+
+func TestEngine_ReplaysRecordedWorkflow(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the fake claude binary")
+	}
+	player := cassette.Install(t, filepath.Join("testdata", "cassettes", "greeting_endpoint.json"))
+
+	cfg := testConfig(false)
+	cfg.WorkDir = t.TempDir()
+	engine := NewEngine(claude.NewExecutor(), nil, cfg, nil)
+	engine.SetPrinter(output.NewPrinterWithWriters(io.Discard, io.Discard, false))
+
+	require.NoError(t, engine.Run(context.Background(), "Add a greeting endpoint", false))
+	assert.Zero(t, player.Remaining(), "every recorded invocation should be replayed")
+
+	// The journal shows each state the recorded Claude run produced
+	entries, err := core.ReadJournal(core.JournalPath(filepath.Join(cfg.WorkDir, cfg.StateFile)))
+	require.NoError(t, err)
+	var steps []string
+	for _, entry := range entries {
+		steps = append(steps, entry.State.CurrentStepDescription)
+	}
+	assert.Equal(t, []string{
+		"Initializing workflow for task",
+		"Added failing test for GET /greeting",
+		"Implemented GET /greeting handler",
+		"Documented GET /greeting; all tasks complete",
+	}, steps)
+}
+
+func TestEngine_ReplayMismatchFailsRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the fake claude binary")
+	}
+	player := cassette.InstallInteractions(t,
+		cassette.StateUpdate("/start a different task", "", core.State{Status: core.StatusCompleted}))
+
+	cfg := testConfig(false)
+	cfg.WorkDir = t.TempDir()
+	engine := NewEngine(claude.NewExecutor(), nil, cfg, nil)
+	engine.SetPrinter(output.NewPrinterWithWriters(io.Discard, io.Discard, false))
+
+	err := engine.Run(context.Background(), "Add a greeting endpoint", false)
+	require.Error(t, err)
+	assert.Equal(t, 1, player.Remaining())
+}
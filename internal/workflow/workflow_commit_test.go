@@ -0,0 +1,132 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/core"
+)
+
+// initRunRepo creates a repository checked out on a run branch and returns
+// its directory. The first commit is tagged initial.
+func initRunRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "alpine/test"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+		{"tag", "initial"},
+	} {
+		runGit(t, dir, args...)
+	}
+	return dir
+}
+
+// runGit runs git in dir and returns its trimmed output
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, output)
+	return strings.TrimSpace(string(output))
+}
+
+// fileWritingExecutor writes step<n>.txt on each call and completes after steps calls
+func fileWritingExecutor(t *testing.T, dir string, steps int) funcExecutor {
+	calls := 0
+	return func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		calls++
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("step%d.txt", calls)), []byte("x"), 0644))
+		state := &core.State{CurrentStepDescription: fmt.Sprintf("Implement step %d", calls), NextStepPrompt: "/continue", Status: core.StatusRunning}
+		if calls == steps {
+			state = &core.State{CurrentStepDescription: "Finish the feature", Status: core.StatusCompleted}
+		}
+		require.NoError(t, state.Save(cfg.StateFile))
+		return "ok", nil
+	}
+}
+
+func TestEngine_CommitsEachIteration(t *testing.T) {
+	repo := initRunRepo(t)
+	engine, _ := newBudgetTestEngine(t, fileWritingExecutor(t, repo, 2), func(e *Engine) {
+		e.cfg.WorkDir = repo
+		e.cfg.Git.AutoCommit = true
+		e.SetStateFile(filepath.Join(repo, "agent_state", "agent_state.json"))
+		e.SetRunBranch("alpine/test")
+		e.SetRunID("run-commits")
+	})
+	require.NoError(t, engine.Run(context.Background(), "Add steps", false))
+
+	// The initial state plus one commit per iteration
+	subjects := runGit(t, repo, "log", "--format=%s", "initial..HEAD")
+	assert.Equal(t, "Finish the feature\nImplement step 1", subjects)
+	assert.Equal(t, "run-commits", runGit(t, repo, "log", "-1", "--format=%(trailers:key=Alpine-Run-ID,valueonly)"))
+	assert.Equal(t, "2", runGit(t, repo, "log", "-1", "--format=%(trailers:key=Alpine-Iteration,valueonly)"))
+	assert.Equal(t, "step2.txt", runGit(t, repo, "show", "--name-only", "--format=", "HEAD"))
+	assert.Empty(t, runGit(t, repo, "ls-files", "agent_state"), "alpine's state is never committed")
+
+	// The journal records each iteration's commit
+	entries, err := core.ReadJournal(core.JournalPath(engine.stateFile))
+	require.NoError(t, err)
+	var commits []string
+	for _, entry := range entries {
+		if entry.Commit != "" {
+			commits = append(commits, entry.Commit)
+		}
+	}
+	assert.Equal(t, []string{runGit(t, repo, "rev-parse", "HEAD~1"), runGit(t, repo, "rev-parse", "HEAD")}, commits)
+}
+
+func TestEngine_SquashesCommits(t *testing.T) {
+	repo := initRunRepo(t)
+	engine, _ := newBudgetTestEngine(t, fileWritingExecutor(t, repo, 3), func(e *Engine) {
+		e.cfg.WorkDir = repo
+		e.cfg.Git.AutoCommit = true
+		e.cfg.Git.Squash = true
+		e.SetStateFile(filepath.Join(repo, "agent_state", "agent_state.json"))
+		e.SetRunBranch("alpine/test")
+		e.SetRunID("run-squash")
+	})
+	require.NoError(t, engine.Run(context.Background(), "Add three steps\n\nDetails of the task", false))
+
+	assert.Equal(t, "1", runGit(t, repo, "rev-list", "--count", "initial..HEAD"))
+	assert.Equal(t, "Add three steps\n\nDetails of the task\n\nAlpine-Run-ID: run-squash",
+		runGit(t, repo, "log", "-1", "--format=%B"))
+	assert.Equal(t, "step1.txt\nstep2.txt\nstep3.txt", runGit(t, repo, "show", "--name-only", "--format=", "HEAD"))
+}
+
+func TestEngine_NoCommitsWithoutRunBranch(t *testing.T) {
+	repo := initRunRepo(t)
+	engine, _ := newBudgetTestEngine(t, fileWritingExecutor(t, repo, 1), func(e *Engine) {
+		e.cfg.WorkDir = repo
+		e.cfg.Git.AutoCommit = true
+	})
+	require.NoError(t, engine.Run(context.Background(), "Add a step", false))
+	assert.Equal(t, "0", runGit(t, repo, "rev-list", "--count", "initial..HEAD"))
+}
+
+func TestCommitMessage(t *testing.T) {
+	long := strings.Repeat("word ", 20)
+	tests := []struct {
+		description string
+		want        string
+	}{
+		{"", "fallback"},
+		{"  Add parser  ", "Add parser"},
+		{"Add parser\n\nwith tests", "Add parser\n\nwith tests"},
+		{long, strings.TrimSpace(long[:maxCommitSubject-3]) + "...\n\n" + strings.TrimSpace(long)},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, commitMessage(tt.description, "fallback"), tt.description)
+	}
+}
@@ -0,0 +1,174 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/core"
+	"github.com/Backland-Labs/alpine/internal/events"
+	"github.com/Backland-Labs/alpine/internal/output"
+)
+
+// doneGate fails until done.txt exists in the working directory
+const doneGate = `test -e done.txt || { echo "done.txt is missing"; exit 1; }`
+
+func TestEngine_GatesRejectCompletion(t *testing.T) {
+	dir := t.TempDir()
+	var prompts []string
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		prompts = append(prompts, cfg.Prompt)
+		if len(prompts) > 1 {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "done.txt"), []byte("done"), 0644))
+		}
+		state := &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+		require.NoError(t, state.Save(cfg.StateFile))
+		return "ok", nil
+	})
+	emitter := events.NewMockEmitter()
+	var out bytes.Buffer
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.WorkDir = dir
+		e.cfg.Gates.Build = "true"
+		e.cfg.Gates.Test = doneGate
+		e.cfg.Gates.MaxFailures = 3
+		e.SetEventEmitter(emitter)
+		e.SetPrinter(output.NewPrinterWithWriters(&out, &out, false))
+	})
+
+	require.NoError(t, engine.Run(context.Background(), "Create done.txt", false))
+
+	// The claimed completion was sent back with the gate's output
+	require.Len(t, prompts, 2)
+	assert.True(t, strings.HasPrefix(prompts[1], core.PromptContinue+"\n\n"))
+	assert.Contains(t, prompts[1], "The task is not complete: the test gate failed")
+	assert.Contains(t, prompts[1], "$ "+doneGate)
+	assert.Contains(t, prompts[1], "done.txt is missing")
+
+	// Every gate run is streamed
+	var results []string
+	for _, call := range emitter.FindCallsByMethod("GateResult") {
+		status := "passed"
+		if !call.Gate.Passed {
+			status = "failed"
+		}
+		results = append(results, call.Gate.Name+" "+status)
+		assert.Equal(t, engine.RunID(), call.RunID)
+	}
+	assert.Equal(t, []string{"build passed", "test failed", "build passed", "test passed"}, results)
+
+	// The summary has the final result of each gate
+	summary := engine.Summary()
+	assert.Equal(t, 2, summary.Iterations)
+	require.Len(t, summary.Gates, 2)
+	assert.Equal(t, "build", summary.Gates[0].Name)
+	assert.True(t, summary.Gates[1].Passed)
+	assert.Equal(t, 2, summary.Gates[1].Iteration)
+	assert.Contains(t, out.String(), "Run summary: 2 iterations")
+	assert.Contains(t, out.String(), "test gate passed: "+doneGate)
+}
+
+func TestEngine_GateFailureKeepsNextPrompt(t *testing.T) {
+	dir := t.TempDir()
+	var prompts []string
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		prompts = append(prompts, cfg.Prompt)
+		state := &core.State{CurrentStepDescription: "Step", NextStepPrompt: "/next_step", Status: core.StatusRunning}
+		if len(prompts) > 1 {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "done.txt"), []byte("done"), 0644))
+			state = &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+		}
+		require.NoError(t, state.Save(cfg.StateFile))
+		return "ok", nil
+	})
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.WorkDir = dir
+		e.cfg.Gates.Lint = doneGate
+		e.cfg.Gates.Test = "echo never run; exit 1"
+		e.cfg.Gates.MaxFailures = 3
+		e.cfg.Budget.MaxIterations = 3
+	})
+
+	err := engine.Run(context.Background(), "Create done.txt", false)
+	require.ErrorIs(t, err, ErrBudgetExceeded, "the test gate never passes")
+	require.Len(t, prompts, 3)
+	assert.True(t, strings.HasPrefix(prompts[1], "/next_step\n\nThe lint gate failed after your last iteration"))
+	assert.NotContains(t, prompts[1], "never run", "gates stop at the first failure")
+}
+
+func TestEngine_GateFailureLimit(t *testing.T) {
+	calls := 0
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		calls++
+		state := &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+		require.NoError(t, state.Save(cfg.StateFile))
+		return "ok", nil
+	})
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.WorkDir = t.TempDir()
+		e.cfg.Gates.Test = doneGate
+		e.cfg.Gates.MaxFailures = 2
+	})
+
+	err := engine.Run(context.Background(), "Create done.txt", false)
+	require.ErrorIs(t, err, ErrGateFailed)
+	assert.Equal(t, 3, calls, "the run fails after MaxFailures reopened completions")
+
+	state, err := core.LoadState(engine.stateFile)
+	require.NoError(t, err)
+	assert.Equal(t, core.StatusFailed, state.Status)
+	assert.Contains(t, state.FailureReason, "3 iterations in a row failed a gate (last: test)")
+}
+
+func TestEngine_GatesSkipStoppedStates(t *testing.T) {
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		state := &core.State{CurrentStepDescription: "Need a decision", Status: core.StatusBlocked}
+		require.NoError(t, state.Save(cfg.StateFile))
+		return "ok", nil
+	})
+	emitter := events.NewMockEmitter()
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.WorkDir = t.TempDir()
+		e.cfg.Gates.Build = "exit 1"
+		e.SetEventEmitter(emitter)
+	})
+
+	require.NoError(t, engine.Run(context.Background(), "Ask", false))
+	assert.Empty(t, emitter.FindCallsByMethod("GateResult"))
+}
+
+func TestEngine_RunCommandTimeouts(t *testing.T) {
+	engine, _ := newBudgetTestEngine(t, nil, func(e *Engine) {
+		e.workDir = t.TempDir()
+	})
+
+	// The command's own timeout kills the commands the shell started too
+	start := time.Now()
+	_, err := engine.runCommand(context.Background(), "sleep 5; echo done", 100*time.Millisecond)
+	require.EqualError(t, err, "timed out after 100ms")
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	// A run budget expiring before the command's timeout
+	budget := fmt.Errorf("%w: run exceeded max duration of 100ms", ErrBudgetExceeded)
+	ctx, cancel := context.WithTimeoutCause(context.Background(), 100*time.Millisecond, budget)
+	defer cancel()
+	start = time.Now()
+	_, err = engine.runCommand(ctx, "sleep 5 | cat", time.Minute)
+	require.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	// Cancellation without a timeout of its own
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = engine.runCommand(ctx, "sleep 5", 0)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,137 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/config"
+	"github.com/Backland-Labs/alpine/internal/core"
+)
+
+func TestEngine_ModelPerPhase(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     string
+		planModel string
+		wantPlan  string
+		wantExec  string
+	}{
+		{
+			name:      "separate planning model",
+			model:     "exec-model",
+			planModel: "plan-model",
+			wantPlan:  "plan-model",
+			wantExec:  "exec-model",
+		},
+		{
+			name:     "planning falls back to execution model",
+			model:    "exec-model",
+			wantPlan: "exec-model",
+			wantExec: "exec-model",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var models []string
+			executor := funcExecutor(func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+				models = append(models, config.Model)
+
+				// Planning hands over to implementation, which then completes
+				next := &core.State{CurrentStepDescription: "Plan written", NextStepPrompt: core.PromptStart, Status: core.StatusRunning}
+				if len(models) > 1 {
+					next = &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+				}
+				require.NoError(t, next.Save(config.StateFile))
+				return "ok", nil
+			})
+
+			engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+				e.cfg.Model = tt.model
+				e.cfg.PlanModel = tt.planModel
+			})
+
+			require.NoError(t, engine.Run(context.Background(), "add a feature", true))
+			assert.Equal(t, []string{tt.wantPlan, tt.wantExec}, models)
+		})
+	}
+}
+
+func TestEngine_PermissionProfilePerPhase(t *testing.T) {
+	var calls []claude.ExecuteConfig
+	executor := funcExecutor(func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+		calls = append(calls, config)
+
+		next := &core.State{CurrentStepDescription: "Plan written", NextStepPrompt: core.PromptStart, Status: core.StatusRunning}
+		if len(calls) > 1 {
+			next = &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+		}
+		require.NoError(t, next.Save(config.StateFile))
+		return "ok", nil
+	})
+
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.Profile = "ci"
+		e.cfg.PlanProfile = config.ProfileFull
+		e.cfg.Profiles = map[string]config.PermissionProfile{
+			"ci": {AllowedTools: []string{"Read", "Bash(go test:*)"}, DisallowedTools: []string{"WebFetch"}},
+		}
+	})
+
+	require.NoError(t, engine.Run(context.Background(), "add a feature", true))
+	require.Len(t, calls, 2)
+
+	full, _ := config.BuiltinProfile(config.ProfileFull)
+	assert.Equal(t, full.AllowedTools, calls[0].AllowedTools)
+	assert.Empty(t, calls[0].DisallowedTools)
+	assert.Equal(t, []string{"Read", "Bash(go test:*)"}, calls[1].AllowedTools)
+	assert.Equal(t, []string{"WebFetch"}, calls[1].DisallowedTools)
+}
+
+func TestEngine_MCPServersPerPhase(t *testing.T) {
+	var calls []claude.ExecuteConfig
+	configs := map[string]string{}
+	executor := funcExecutor(func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+		calls = append(calls, config)
+		data, err := os.ReadFile(config.MCPConfig)
+		require.NoError(t, err)
+		configs[config.MCPConfig] = string(data)
+
+		next := &core.State{CurrentStepDescription: "Plan written", NextStepPrompt: core.PromptStart, Status: core.StatusRunning}
+		if len(calls) > 1 {
+			next = &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+		}
+		require.NoError(t, next.Save(config.StateFile))
+		return "ok", nil
+	})
+
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.MCPServers = map[string]config.MCPServer{
+			"docs":   {Command: "sh", Phases: []string{config.PhasePlan}},
+			"github": {URL: "https://example.com/mcp"},
+		}
+	})
+
+	require.NoError(t, engine.Run(context.Background(), "add a feature", true))
+	require.Len(t, calls, 2)
+
+	plan, execute := calls[0], calls[1]
+	assert.NotEqual(t, plan.MCPConfig, execute.MCPConfig)
+	assert.Contains(t, configs[plan.MCPConfig], `"docs"`)
+	assert.Contains(t, configs[plan.MCPConfig], `"github"`)
+	assert.NotContains(t, configs[execute.MCPConfig], `"docs"`)
+	assert.Contains(t, configs[execute.MCPConfig], `"github"`)
+
+	assert.Subset(t, plan.AllowedTools, []string{"mcp__docs", "mcp__github"})
+	assert.Contains(t, execute.AllowedTools, "mcp__github")
+	assert.NotContains(t, execute.AllowedTools, "mcp__docs")
+
+	// The configs are removed when the run ends
+	_, err := os.Stat(plan.MCPConfig)
+	assert.True(t, os.IsNotExist(err))
+}
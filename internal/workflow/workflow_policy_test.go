@@ -0,0 +1,119 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/config"
+	"github.com/Backland-Labs/alpine/internal/core"
+	"github.com/Backland-Labs/alpine/internal/hooks"
+	"github.com/Backland-Labs/alpine/internal/output"
+	"github.com/Backland-Labs/alpine/internal/policy"
+)
+
+func TestEngine_PolicyHook(t *testing.T) {
+	workDir := t.TempDir()
+	settingsPath := filepath.Join(workDir, ".claude", "settings.local.json")
+
+	var policyFile string
+	calls := 0
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		calls++
+		policyFile = cfg.EnvironmentVariables[hooks.EnvPolicyFile]
+		require.NotEmpty(t, policyFile)
+
+		// The hook is installed in the run's working directory
+		settings, err := os.ReadFile(settingsPath)
+		require.NoError(t, err)
+		var doc struct {
+			Hooks map[string]json.RawMessage `json:"hooks"`
+		}
+		require.NoError(t, json.Unmarshal(settings, &doc))
+		assert.Contains(t, doc.Hooks, hooks.EventPreToolUse)
+
+		// Simulate the hook blocking a call
+		p, err := policy.Load(policyFile)
+		require.NoError(t, err)
+		assert.Equal(t, workDir, p.Root)
+		assert.True(t, p.WorktreeOnly)
+		assert.Equal(t, []string{"make deploy*"}, p.Bash.Deny)
+		v := p.Check("Bash", json.RawMessage(`{"command":"make deploy"}`), "")
+		require.NotNil(t, v)
+		require.NoError(t, policy.AppendViolation(p.LogFile, *v))
+
+		next := &core.State{CurrentStepDescription: "Step", NextStepPrompt: "/continue", Status: core.StatusRunning}
+		if calls > 1 {
+			next = &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+		}
+		require.NoError(t, next.Save(cfg.StateFile))
+		return "ok", nil
+	})
+
+	var out bytes.Buffer
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.WorkDir = workDir
+		e.cfg.Policy = config.PolicyConfig{
+			Enabled:      true,
+			WorktreeOnly: true,
+			Bash:         policy.Rules{Deny: []string{"make deploy*"}},
+		}
+		e.SetPrinter(output.NewPrinterWithWriters(&out, &out, false))
+	})
+
+	require.NoError(t, engine.Run(context.Background(), "deploy", false))
+	assert.Equal(t, 2, calls)
+
+	// Each blocked call is reported once
+	assert.Equal(t, 2, bytes.Count(out.Bytes(), []byte("Policy blocked Bash")))
+
+	// The settings and policy file are removed when the run ends
+	_, err := os.Stat(settingsPath)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(policyFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestEngine_PolicyDisabled(t *testing.T) {
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		assert.NotContains(t, cfg.EnvironmentVariables, hooks.EnvPolicyFile)
+		require.NoError(t, (&core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}).Save(cfg.StateFile))
+		return "ok", nil
+	})
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.WorkDir = t.TempDir()
+	})
+	require.NoError(t, engine.Run(context.Background(), "task", false))
+	_, err := os.Stat(filepath.Join(engine.cfg.WorkDir, ".claude"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestEngine_PolicyHookNotCommitted(t *testing.T) {
+	repo := initRunRepo(t)
+	commit := fileWritingExecutor(t, repo, 2)
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		// The hook's settings and their ledger stay in place for the whole run
+		assert.FileExists(t, filepath.Join(repo, ".claude", "settings.local.json"))
+		assert.FileExists(t, filepath.Join(repo, ".claude", "settings.local.json.alpine"))
+		return commit(ctx, cfg)
+	})
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.WorkDir = repo
+		e.cfg.Git.AutoCommit = true
+		e.cfg.Policy = config.PolicyConfig{Enabled: true, WorktreeOnly: true}
+		e.SetStateFile(filepath.Join(repo, "agent_state", "agent_state.json"))
+		e.SetRunBranch("alpine/test")
+	})
+	require.NoError(t, engine.Run(context.Background(), "Add steps", false))
+
+	assert.Equal(t, "2", runGit(t, repo, "rev-list", "--count", "initial..HEAD"))
+	assert.Equal(t, "step1.txt\nstep2.txt", runGit(t, repo, "ls-tree", "-r", "--name-only", "HEAD"),
+		"the settings and their ledger are never committed")
+}
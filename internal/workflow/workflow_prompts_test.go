@@ -0,0 +1,70 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/core"
+	"github.com/Backland-Labs/alpine/internal/prompts"
+)
+
+func TestEngine_RendersRepositoryPromptTemplates(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repo := t.TempDir()
+	templates := filepath.Join(repo, prompts.RepoDir)
+	require.NoError(t, os.MkdirAll(templates, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templates, "plan.md"),
+		[]byte("Plan {{.Task}} in {{.Repo}}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(templates, "system.md"),
+		[]byte("Iteration {{.Iteration}} of {{.Task}}\n"), 0644))
+
+	var configs []claude.ExecuteConfig
+	executor := funcExecutor(func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+		configs = append(configs, config)
+		state := &core.State{
+			CurrentStepDescription: fmt.Sprintf("Step %d", len(configs)),
+			NextStepPrompt:         "/continue",
+			Status:                 core.StatusRunning,
+		}
+		if len(configs) == 2 {
+			state.Status = core.StatusCompleted
+		}
+		return "", state.Save(config.StateFile)
+	})
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.WorkDir = repo
+	})
+
+	require.NoError(t, engine.Run(context.Background(), "Add caching", true))
+	require.Len(t, configs, 2)
+	assert.Equal(t, "Plan Add caching in "+filepath.Base(repo), configs[0].Prompt)
+	assert.Equal(t, "Iteration 1 of Add caching", configs[0].SystemPrompt)
+	assert.Equal(t, "Iteration 2 of Add caching", configs[1].SystemPrompt)
+}
+
+func TestEngine_InvalidPromptTemplateFailsRun(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	repo := t.TempDir()
+	templates := filepath.Join(repo, prompts.RepoDir)
+	require.NoError(t, os.MkdirAll(templates, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templates, "system.md"), []byte("{{.Unknown}}"), 0644))
+
+	executor := funcExecutor(func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+		t.Error("Claude should not run with a broken system prompt")
+		return "", nil
+	})
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.WorkDir = repo
+	})
+
+	err := engine.Run(context.Background(), "Add caching", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "system prompt template")
+}
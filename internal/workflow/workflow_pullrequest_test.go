@@ -0,0 +1,174 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/events"
+	"github.com/Backland-Labs/alpine/internal/github"
+	"github.com/Backland-Labs/alpine/internal/output"
+	"github.com/Backland-Labs/alpine/internal/prompts"
+)
+
+// fakeGitHub serves the pulls endpoint of acme/widgets, recording the pull
+// requests opened, and returns its URL
+func fakeGitHub(t *testing.T, opened *[]github.PullRequest) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/acme/widgets/pulls" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+			return
+		}
+		var pr github.PullRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&pr))
+		*opened = append(*opened, pr)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"number":12,"html_url":"https://github.com/acme/widgets/pull/12"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// pullRequestRepo creates a run repository whose origin is acme/widgets on
+// GitHub but pushes to a local bare repository, which it returns too
+func pullRequestRepo(t *testing.T) (string, string) {
+	t.Helper()
+	repo := initRunRepo(t)
+	remote := filepath.Join(t.TempDir(), "widgets.git")
+	runGit(t, repo, "init", "-q", "--bare", remote)
+	runGit(t, repo, "remote", "add", "origin", "https://github.com/acme/widgets.git")
+	runGit(t, repo, "config", "remote.origin.pushurl", remote)
+	return repo, remote
+}
+
+func TestEngine_OpensPullRequest(t *testing.T) {
+	repo, remote := pullRequestRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "plan.md"), []byte("# Plan\n\n- [x] Cache lookups\n"), 0644))
+	var opened []github.PullRequest
+	var out bytes.Buffer
+	engine, _ := newBudgetTestEngine(t, fileWritingExecutor(t, repo, 2), func(e *Engine) {
+		e.cfg.WorkDir = repo
+		e.cfg.Git.PullRequest = true
+		e.cfg.Git.BaseBranch = "main"
+		e.cfg.GitHub.APIURL = fakeGitHub(t, &opened)
+		e.cfg.GitHub.Token = "secret"
+		e.SetStateFile(filepath.Join(repo, "agent_state", "agent_state.json"))
+		e.SetRunBranch("alpine/test")
+		e.SetRunID("run-pr")
+		e.SetPrinter(output.NewPrinterWithWriters(&out, &out, false))
+	})
+
+	require.NoError(t, engine.Run(context.Background(), "Add caching\n\nCache the widget lookups", false))
+
+	// The branch with every iteration's commit is pushed, iterations being
+	// committed for the pull request without git.auto_commit
+	assert.Equal(t, runGit(t, repo, "rev-parse", "HEAD"), runGit(t, remote, "rev-parse", "alpine/test"))
+
+	require.Len(t, opened, 1)
+	pr := opened[0]
+	assert.Equal(t, "Add caching", pr.Title)
+	assert.Equal(t, "alpine/test", pr.Head)
+	assert.Equal(t, "main", pr.Base)
+	assert.Contains(t, pr.Body, "Cache the widget lookups\n\n## Plan")
+	assert.Contains(t, pr.Body, "- [x] Cache lookups")
+	assert.Contains(t, pr.Body, "- Run: `run-pr`\n- Iterations: 2\n")
+	assert.NotContains(t, pr.Body, "Closes")
+
+	assert.Equal(t, "https://github.com/acme/widgets/pull/12", engine.Summary().PullRequest)
+	assert.Contains(t, out.String(), "Opened pull request #12")
+}
+
+func TestEngine_DryRunOpensNoPullRequest(t *testing.T) {
+	repo, remote := pullRequestRepo(t)
+	head := runGit(t, repo, "rev-parse", "HEAD")
+	var opened []github.PullRequest
+	var out bytes.Buffer
+	engine, _ := newBudgetTestEngine(t, fileWritingExecutor(t, repo, 2), func(e *Engine) {
+		e.cfg.WorkDir = repo
+		e.cfg.DryRun = true
+		e.cfg.Git.PullRequest = true
+		e.cfg.Git.Squash = true
+		e.cfg.Gates.Test = "touch gate-ran; exit 1"
+		e.cfg.Rollback.Check = "touch check-ran; exit 1"
+		e.cfg.GitHub.APIURL = fakeGitHub(t, &opened)
+		e.cfg.GitHub.Token = "secret"
+		e.SetStateFile(filepath.Join(repo, "agent_state", "agent_state.json"))
+		e.SetRunBranch("alpine/test")
+		e.SetPrinter(output.NewPrinterWithWriters(&out, &out, false))
+	})
+
+	require.NoError(t, engine.Run(context.Background(), "Add caching", false))
+
+	// Nothing is committed, pushed or opened, and no command runs
+	assert.Empty(t, opened)
+	assert.Equal(t, head, runGit(t, repo, "rev-parse", "HEAD"))
+	assert.Empty(t, runGit(t, remote, "branch", "--list"))
+	assert.Empty(t, engine.Summary().PullRequest)
+	assert.NoFileExists(t, filepath.Join(repo, "gate-ran"))
+	assert.NoFileExists(t, filepath.Join(repo, "check-ran"))
+
+	for _, action := range []string{
+		"[dry-run] rollback check: touch check-ran; exit 1\n",
+		"[dry-run] test gate: touch gate-ran; exit 1\n",
+		"[dry-run] commit iteration 1 on alpine/test: ",
+		"[dry-run] git push origin alpine/test\n",
+		"[dry-run] open a pull request from alpine/test into main\n",
+	} {
+		assert.Contains(t, out.String(), action)
+	}
+}
+
+func TestEngine_PullRequestFailureKeepsRun(t *testing.T) {
+	repo, _ := pullRequestRepo(t)
+	var opened []github.PullRequest
+	var out bytes.Buffer
+	engine, _ := newBudgetTestEngine(t, fileWritingExecutor(t, repo, 1), func(e *Engine) {
+		e.cfg.WorkDir = repo
+		e.cfg.Git.AutoCommit = true
+		e.cfg.Git.PullRequest = true
+		e.cfg.GitHub.APIURL = fakeGitHub(t, &opened)
+		e.cfg.GitHub.Token = "wrong"
+		e.SetStateFile(filepath.Join(repo, "agent_state", "agent_state.json"))
+		e.SetRunBranch("alpine/test")
+		e.SetPrinter(output.NewPrinterWithWriters(&out, &out, false))
+	})
+
+	require.NoError(t, engine.Run(context.Background(), "Add caching", false), "the run's work is on its branch")
+	assert.Empty(t, opened)
+	assert.Empty(t, engine.Summary().PullRequest)
+	assert.Contains(t, out.String(), "Failed to open a pull request for alpine/test: GitHub API returned 404")
+}
+
+func TestEngine_PullRequestContent(t *testing.T) {
+	engine, _ := newBudgetTestEngine(t, nil, func(e *Engine) {
+		e.workDir = t.TempDir()
+		e.runBranch = "alpine/fix-login"
+		e.taskDesc = "https://github.com/acme/widgets/issues/7"
+		e.promptData.Issue = &prompts.Issue{Number: 7, Title: "Login fails with SSO"}
+		e.summary = Summary{
+			RunID:      "run-7",
+			Iterations: 3,
+			Duration:   90 * time.Second,
+			Gates:      []events.Gate{{Name: "test", Command: "go test ./...", Passed: true}},
+		}
+	})
+
+	title, body := engine.pullRequestContent(github.Repository{Owner: "acme", Name: "widgets"})
+	assert.Equal(t, "Login fails with SSO", title)
+	assert.Equal(t, "Closes #7\n\n## Run summary\n\n- Run: `run-7`\n- Iterations: 3\n- Duration: 1m30s\n- test gate passed: `go test ./...`\n", body)
+
+	// Issues of another repository are referenced with its name
+	_, body = engine.pullRequestContent(github.Repository{Owner: "fork", Name: "widgets"})
+	assert.Contains(t, body, "Closes acme/widgets#7\n")
+}
@@ -0,0 +1,51 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/config"
+	"github.com/Backland-Labs/alpine/internal/core"
+	"github.com/Backland-Labs/alpine/internal/events"
+)
+
+func TestEngine_ReportsClaudeRetries(t *testing.T) {
+	policy := config.RetryConfig{MaxRetries: 2, InitialBackoff: time.Second, MaxBackoff: time.Minute}
+	var received claude.RetryPolicy
+
+	// The executor reports one transient failure before completing the run
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		received = cfg.Retry
+		require.NotNil(t, cfg.OnRetry)
+		cfg.OnRetry(claude.RetryEvent{
+			Attempt:    1,
+			MaxRetries: cfg.Retry.MaxRetries,
+			Delay:      1500 * time.Millisecond,
+			Err:        &claude.ExecutionError{Kind: claude.FailureOverloaded, ExitCode: 1, Err: assert.AnError},
+		})
+		state := &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+		require.NoError(t, state.Save(cfg.StateFile))
+		return "ok", nil
+	})
+
+	emitter := events.NewMockEmitter()
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.Retry = policy
+		e.SetEventEmitter(emitter)
+	})
+
+	require.NoError(t, engine.Run(context.Background(), "flaky api", false))
+	assert.Equal(t, policy, received, "the configured retry policy should reach the executor")
+
+	retries := emitter.FindCallsByMethod("RunRetrying")
+	require.Len(t, retries, 1)
+	assert.Equal(t, 1, retries[0].Attempt)
+	assert.Equal(t, "overloaded", retries[0].Reason)
+	assert.Equal(t, 1500*time.Millisecond, retries[0].Delay)
+	assert.Equal(t, "flaky api", retries[0].Task)
+}
@@ -0,0 +1,183 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/core"
+)
+
+// brokenCheck fails while broken.txt exists in the working directory
+const brokenCheck = `if [ -e broken.txt ]; then echo "broken.txt must not exist"; exit 1; fi`
+
+// newRollbackTestEngine creates an engine committing iterations to a run
+// branch in repo, with brokenCheck as its rollback check
+func newRollbackTestEngine(t *testing.T, repo string, executor ClaudeExecutor) *Engine {
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.WorkDir = repo
+		e.cfg.Git.AutoCommit = true
+		e.cfg.Rollback.Check = brokenCheck
+		e.cfg.Rollback.MaxRollbacks = 2
+		e.SetStateFile(filepath.Join(repo, "agent_state", "agent_state.json"))
+		e.SetRunBranch("alpine/test")
+	})
+	return engine
+}
+
+func TestEngine_RollsBackFailedCheck(t *testing.T) {
+	repo := initRunRepo(t)
+	var prompts []string
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		prompts = append(prompts, cfg.Prompt)
+		state := &core.State{CurrentStepDescription: "Add good.txt", NextStepPrompt: "/continue", Status: core.StatusRunning}
+		switch len(prompts) {
+		case 1:
+			require.NoError(t, os.WriteFile(filepath.Join(repo, "good.txt"), []byte("good"), 0644))
+		case 2:
+			// Breaks the tree and claims to be done
+			require.NoError(t, os.WriteFile(filepath.Join(repo, "broken.txt"), []byte("broken"), 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(repo, "good.txt"), []byte("damaged"), 0644))
+			state = &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+		default:
+			require.NoError(t, os.WriteFile(filepath.Join(repo, "fixed.txt"), []byte("fixed"), 0644))
+			state = &core.State{CurrentStepDescription: "Add fixed.txt", Status: core.StatusCompleted}
+		}
+		require.NoError(t, state.Save(cfg.StateFile))
+		return "ok", nil
+	})
+	engine := newRollbackTestEngine(t, repo, executor)
+
+	require.NoError(t, engine.Run(context.Background(), "Add files", false))
+
+	// The broken iteration was discarded and retried with the check's output
+	require.Len(t, prompts, 3)
+	assert.True(t, strings.HasPrefix(prompts[2], prompts[1]+"\n\n"), "the retry repeats the rolled back prompt")
+	assert.Contains(t, prompts[2], "rolled back because `"+brokenCheck+"` failed")
+	assert.Contains(t, prompts[2], "broken.txt must not exist")
+
+	assert.Equal(t, "Add fixed.txt\nAdd good.txt", runGit(t, repo, "log", "--format=%s", "initial..HEAD"))
+	assert.NoFileExists(t, filepath.Join(repo, "broken.txt"))
+	data, err := os.ReadFile(filepath.Join(repo, "good.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "good", string(data))
+
+	entries, err := core.ReadJournal(core.JournalPath(engine.stateFile))
+	require.NoError(t, err)
+	var failed []string
+	for _, entry := range entries {
+		if entry.Error != "" {
+			failed = append(failed, entry.Error)
+		}
+	}
+	assert.Equal(t, []string{"check failed: exit status 1"}, failed)
+}
+
+func TestEngine_RollbackKeepsClaudeSettings(t *testing.T) {
+	repo := initRunRepo(t)
+	settings := filepath.Join(repo, ".claude", "settings.local.json")
+	original := []byte("{\n  \"model\": \"opus\"\n}\n")
+	require.NoError(t, os.MkdirAll(filepath.Dir(settings), 0755))
+	require.NoError(t, os.WriteFile(settings, original, 0644))
+
+	calls := 0
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		calls++
+		state := &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+		if calls == 1 {
+			require.NoError(t, os.WriteFile(filepath.Join(repo, "broken.txt"), []byte("broken"), 0644))
+		} else {
+			// The rollback left the policy hook's ledger in place
+			assert.FileExists(t, settings+".alpine")
+		}
+		require.NoError(t, state.Save(cfg.StateFile))
+		return "ok", nil
+	})
+	engine := newRollbackTestEngine(t, repo, executor)
+	engine.cfg.Policy.Enabled = true
+
+	require.NoError(t, engine.Run(context.Background(), "Add files", false))
+	assert.Equal(t, 2, calls)
+	assert.NoFileExists(t, filepath.Join(repo, "broken.txt"))
+
+	// The user's settings are restored exactly once the run ends
+	data, err := os.ReadFile(settings)
+	require.NoError(t, err)
+	assert.Equal(t, string(original), string(data))
+	assert.NoFileExists(t, settings+".alpine")
+}
+
+func TestEngine_RollbackLimit(t *testing.T) {
+	repo := initRunRepo(t)
+	calls := 0
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		calls++
+		require.NoError(t, os.WriteFile(filepath.Join(repo, "broken.txt"), []byte("broken"), 0644))
+		state := &core.State{CurrentStepDescription: "Break things", NextStepPrompt: "/continue", Status: core.StatusRunning}
+		require.NoError(t, state.Save(cfg.StateFile))
+		return "ok", nil
+	})
+	engine := newRollbackTestEngine(t, repo, executor)
+
+	err := engine.Run(context.Background(), "Break things", false)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrCheckFailed))
+	assert.Contains(t, err.Error(), "failed 3 times in a row")
+	assert.Equal(t, 3, calls, "the run fails after MaxRollbacks retries")
+	assert.NoFileExists(t, filepath.Join(repo, "broken.txt"))
+
+	state, err := core.LoadState(engine.stateFile)
+	require.NoError(t, err)
+	assert.Equal(t, core.StatusFailed, state.Status)
+	assert.NotEmpty(t, state.NextStepPrompt, "the run can be resumed")
+}
+
+func TestEngine_RollbackOnClaudeError(t *testing.T) {
+	repo := initRunRepo(t)
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		require.NoError(t, os.WriteFile(filepath.Join(repo, "half.txt"), []byte("half"), 0644))
+		return "", errors.New("claude crashed")
+	})
+	engine := newRollbackTestEngine(t, repo, executor)
+
+	err := engine.Run(context.Background(), "Crash", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "claude crashed")
+	assert.NoFileExists(t, filepath.Join(repo, "half.txt"))
+	assert.FileExists(t, engine.stateFile, "the state is kept for resuming")
+}
+
+func TestEngine_CheckFailureWithoutCommits(t *testing.T) {
+	repo := initRunRepo(t)
+	var prompts []string
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		prompts = append(prompts, cfg.Prompt)
+		if len(prompts) == 1 {
+			require.NoError(t, os.WriteFile(filepath.Join(repo, "broken.txt"), []byte("broken"), 0644))
+		} else {
+			require.NoError(t, os.Remove(filepath.Join(repo, "broken.txt")))
+		}
+		state := &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+		require.NoError(t, state.Save(cfg.StateFile))
+		return "ok", nil
+	})
+	engine := newRollbackTestEngine(t, repo, executor)
+	engine.cfg.Git.AutoCommit = false
+
+	require.NoError(t, engine.Run(context.Background(), "Fix it", false))
+	require.Len(t, prompts, 2)
+	assert.Contains(t, prompts[1], "`"+brokenCheck+"` failed after your previous attempt")
+}
+
+func TestTruncateOutput(t *testing.T) {
+	assert.Equal(t, "short", truncateOutput("  short\n", 10))
+	assert.Equal(t, "[... truncated]\n6789", truncateOutput("0123456789", 4))
+	assert.Equal(t, "[... truncated]\né", truncateOutput("aé", 2), "cuts at a rune boundary")
+}
@@ -0,0 +1,76 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/config"
+	"github.com/Backland-Labs/alpine/internal/core"
+)
+
+// argAfter returns the argument following flag in args
+func argAfter(args []string, flag string) string {
+	for i, arg := range args[:len(args)-1] {
+		if arg == flag {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+func TestEngine_Sandbox(t *testing.T) {
+	workDir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	var wrapped []string
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		require.NotNil(t, cfg.Sandbox)
+		cmd := exec.Command("claude", "--print")
+		cfg.Sandbox.Wrap(cmd)
+		wrapped = cmd.Args
+		require.NoError(t, (&core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}).Save(cfg.StateFile))
+		return "ok", nil
+	})
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.WorkDir = workDir
+		e.cfg.Sandbox = config.SandboxConfig{
+			Backend:      config.SandboxUnshare,
+			AllowedHosts: []string{"api.anthropic.com"},
+			Writable:     []string{cacheDir},
+		}
+	})
+	require.NoError(t, engine.Run(context.Background(), "task", false))
+
+	require.NotEmpty(t, wrapped)
+	assert.Equal(t, "unshare", wrapped[0])
+	assert.Equal(t, workDir, argAfter(wrapped, "--writable"), "the working directory is writable first")
+	assert.Contains(t, wrapped, cacheDir)
+	assert.Contains(t, wrapped, os.TempDir())
+
+	// The proxy is stopped and its socket removed when the run ends
+	socket := argAfter(wrapped, "--proxy-socket")
+	require.NotEmpty(t, socket)
+	_, err := os.Stat(filepath.Dir(socket))
+	assert.True(t, os.IsNotExist(err))
+	assert.Nil(t, engine.sandbox)
+}
+
+func TestEngine_NoSandbox(t *testing.T) {
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		assert.Nil(t, cfg.Sandbox)
+		require.NoError(t, (&core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}).Save(cfg.StateFile))
+		return "ok", nil
+	})
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.WorkDir = t.TempDir()
+		e.cfg.Sandbox = config.SandboxConfig{Backend: config.SandboxNone}
+	})
+	require.NoError(t, engine.Run(context.Background(), "task", false))
+}
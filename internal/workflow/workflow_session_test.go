@@ -0,0 +1,117 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/config"
+	"github.com/Backland-Labs/alpine/internal/core"
+)
+
+// sessionCall records the session settings of one Claude execution
+type sessionCall struct {
+	prompt    string
+	sessionID string
+	resume    bool
+	state     core.State
+}
+
+// sessionExecutor walks the state through the given prompts, reporting
+// contextTokens for every session, and records what it was asked to run
+func sessionExecutor(t *testing.T, calls *[]sessionCall, prompts []string, contextTokens int) funcExecutor {
+	return func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		state, err := core.LoadState(cfg.StateFile)
+		require.NoError(t, err)
+		*calls = append(*calls, sessionCall{prompt: cfg.Prompt, sessionID: cfg.SessionID, resume: cfg.ResumeSession, state: *state})
+
+		next := &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+		if n := len(*calls); n <= len(prompts) {
+			next = &core.State{CurrentStepDescription: fmt.Sprintf("Step %d", n), NextStepPrompt: prompts[n-1], Status: core.StatusRunning}
+		}
+		require.NoError(t, next.Save(cfg.StateFile))
+
+		if cfg.OnSession != nil && cfg.SessionID != "" {
+			cfg.OnSession(claude.SessionInfo{ID: cfg.SessionID, ContextTokens: contextTokens})
+		}
+		return "ok", nil
+	}
+}
+
+func TestEngine_Session_ContinuesAcrossIterations(t *testing.T) {
+	var calls []sessionCall
+	executor := sessionExecutor(t, &calls, []string{"/continue", "/continue", "/start approved", "/continue"}, 1000)
+	engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+		e.cfg.Session = config.SessionConfig{Continue: true, MaxContextTokens: 50000}
+	})
+
+	require.NoError(t, engine.Run(context.Background(), "session", false))
+	require.Len(t, calls, 5)
+
+	// /start begins a session and /continue resumes it
+	assert.False(t, calls[0].resume)
+	require.NotEmpty(t, calls[0].sessionID)
+	for _, call := range calls[1:3] {
+		assert.True(t, call.resume)
+		assert.Equal(t, calls[0].sessionID, call.sessionID)
+	}
+
+	// A new phase, as after plan approval, starts a fresh session
+	assert.False(t, calls[3].resume)
+	assert.NotEqual(t, calls[0].sessionID, calls[3].sessionID)
+	assert.True(t, calls[4].resume)
+	assert.Equal(t, calls[3].sessionID, calls[4].sessionID)
+
+	// The state file records the session each iteration runs in
+	assert.Equal(t, calls[1].sessionID, calls[1].state.SessionID)
+	assert.Equal(t, calls[4].sessionID, calls[4].state.SessionID)
+}
+
+func TestEngine_Session_StartsNewSessionAtLimits(t *testing.T) {
+	t.Run("context limit", func(t *testing.T) {
+		var calls []sessionCall
+		executor := sessionExecutor(t, &calls, []string{"/continue", "/continue"}, 60000)
+		engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+			e.cfg.Session = config.SessionConfig{Continue: true, MaxContextTokens: 50000}
+		})
+
+		require.NoError(t, engine.Run(context.Background(), "big context", false))
+		require.Len(t, calls, 3)
+		for _, call := range calls {
+			assert.False(t, call.resume, "every session exceeds the context limit")
+		}
+		assert.NotEqual(t, calls[1].sessionID, calls[2].sessionID)
+	})
+
+	t.Run("iteration limit", func(t *testing.T) {
+		var calls []sessionCall
+		executor := sessionExecutor(t, &calls, []string{"/continue", "/continue", "/continue"}, 0)
+		engine, _ := newBudgetTestEngine(t, executor, func(e *Engine) {
+			e.cfg.Session = config.SessionConfig{Continue: true, MaxIterations: 2}
+		})
+
+		require.NoError(t, engine.Run(context.Background(), "many steps", false))
+		require.Len(t, calls, 4)
+		assert.Equal(t, []bool{false, true, false, true},
+			[]bool{calls[0].resume, calls[1].resume, calls[2].resume, calls[3].resume})
+		assert.Equal(t, calls[2].sessionID, calls[3].sessionID)
+		assert.NotEqual(t, calls[1].sessionID, calls[2].sessionID)
+	})
+}
+
+func TestEngine_Session_DisabledByDefault(t *testing.T) {
+	var calls []sessionCall
+	engine, _ := newBudgetTestEngine(t, sessionExecutor(t, &calls, []string{"/continue"}, 0), nil)
+
+	require.NoError(t, engine.Run(context.Background(), "fresh sessions", false))
+	require.Len(t, calls, 2)
+	for _, call := range calls {
+		assert.Empty(t, call.sessionID)
+		assert.False(t, call.resume)
+		assert.Empty(t, call.state.SessionID)
+	}
+}
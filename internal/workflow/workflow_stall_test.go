@@ -0,0 +1,125 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/config"
+	"github.com/Backland-Labs/alpine/internal/core"
+	"github.com/Backland-Labs/alpine/internal/events"
+)
+
+// withFastStalls enables stall detection with timings suitable for tests
+func withFastStalls(maxStalls int, action string) func(*Engine) {
+	return func(e *Engine) {
+		e.cfg.Stall = config.StallConfig{
+			MaxStalls:   maxStalls,
+			GracePeriod: 50 * time.Millisecond,
+			Backoff:     time.Millisecond,
+			Action:      action,
+			NudgePrompt: "update the state file",
+		}
+	}
+}
+
+// stallingExecutor records prompts and completes the run only on the call
+// numbered completeOn; every other call leaves the state file untouched
+func stallingExecutor(t *testing.T, prompts *[]string, completeOn int) funcExecutor {
+	return func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+		*prompts = append(*prompts, config.Prompt)
+		if len(*prompts) == completeOn {
+			state := &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+			require.NoError(t, state.Save(config.StateFile))
+		}
+		return "ok", nil
+	}
+}
+
+func TestEngine_Stall_NudgesClaude(t *testing.T) {
+	var prompts []string
+	emitter := events.NewMockEmitter()
+	engine, _ := newBudgetTestEngine(t, stallingExecutor(t, &prompts, 2), func(e *Engine) {
+		withFastStalls(3, config.StallActionNudge)(e)
+		e.SetEventEmitter(emitter)
+	})
+
+	require.NoError(t, engine.Run(context.Background(), "stall once", false))
+	assert.Equal(t, []string{"/start stall once", "update the state file"}, prompts)
+
+	stalled := emitter.FindCallsByMethod("RunStalled")
+	require.Len(t, stalled, 1)
+	assert.Equal(t, 1, stalled[0].Stalls)
+	assert.Len(t, emitter.FindCallsByMethod("RunFinished"), 1)
+}
+
+func TestEngine_Stall_RetriesSamePrompt(t *testing.T) {
+	var prompts []string
+	engine, _ := newBudgetTestEngine(t, stallingExecutor(t, &prompts, 3), withFastStalls(3, config.StallActionRetry))
+
+	require.NoError(t, engine.Run(context.Background(), "retry", false))
+	assert.Equal(t, []string{"/start retry", "/start retry", "/start retry"}, prompts)
+}
+
+func TestEngine_Stall_FailsAfterMaxStalls(t *testing.T) {
+	var prompts []string
+	emitter := events.NewMockEmitter()
+	engine, stateFile := newBudgetTestEngine(t, stallingExecutor(t, &prompts, 0), func(e *Engine) {
+		withFastStalls(2, config.StallActionNudge)(e)
+		e.SetEventEmitter(emitter)
+	})
+
+	start := time.Now()
+	err := engine.Run(context.Background(), "never updates", false)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrStalled))
+	assert.Contains(t, err.Error(), "2 times in a row")
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Len(t, prompts, 2)
+
+	state, loadErr := core.LoadState(stateFile)
+	require.NoError(t, loadErr)
+	assert.Equal(t, core.StatusFailed, state.Status)
+	assert.Contains(t, state.FailureReason, "workflow stalled")
+	assert.Equal(t, "/start never updates", state.NextStepPrompt, "pending prompt is kept so the run can be resumed")
+
+	stalled := emitter.FindCallsByMethod("RunStalled")
+	require.Len(t, stalled, 2)
+	assert.Equal(t, 2, stalled[1].Stalls)
+
+	entries, journalErr := core.ReadJournal(core.JournalPath(stateFile))
+	require.NoError(t, journalErr)
+	stallEntries := 0
+	for _, entry := range entries {
+		if entry.Error != "" && entry.State.Status == core.StatusRunning {
+			stallEntries++
+		}
+	}
+	assert.Equal(t, 2, stallEntries, "each stall is journaled")
+}
+
+func TestEngine_Stall_CountResetsOnProgress(t *testing.T) {
+	// Stall, advance, stall, complete: never two stalls in a row
+	calls := 0
+	executor := funcExecutor(func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+		calls++
+		switch calls {
+		case 2:
+			state := &core.State{CurrentStepDescription: "Step 2", NextStepPrompt: "/continue", Status: core.StatusRunning}
+			require.NoError(t, state.Save(config.StateFile))
+		case 4:
+			state := &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+			require.NoError(t, state.Save(config.StateFile))
+		}
+		return "ok", nil
+	})
+	engine, _ := newBudgetTestEngine(t, executor, withFastStalls(2, config.StallActionNudge))
+
+	require.NoError(t, engine.Run(context.Background(), "reset", false))
+	assert.Equal(t, 4, calls)
+}
@@ -0,0 +1,139 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/core"
+)
+
+func TestEngine_RecordsRunMetadata(t *testing.T) {
+	var seen []*core.State
+	calls := 0
+	executor := funcExecutor(func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+		calls++
+		state, err := core.LoadState(config.StateFile)
+		require.NoError(t, err)
+		seen = append(seen, state)
+
+		// Claude rewrites the file with only the fields it knows about
+		next := &core.State{CurrentStepDescription: "Step 1", NextStepPrompt: "/continue", Status: core.StatusRunning}
+		if calls == 2 {
+			next = &core.State{CurrentStepDescription: "Blocked on credentials", Status: core.StatusBlocked}
+		}
+		require.NoError(t, next.Save(config.StateFile))
+		return "ok", nil
+	})
+	engine, stateFile := newBudgetTestEngine(t, executor, nil)
+	engine.SetRunID("run-42")
+
+	require.NoError(t, engine.Run(context.Background(), "metadata", false))
+	assert.Equal(t, "run-42", engine.RunID())
+	require.Len(t, seen, 2)
+
+	for i, state := range seen {
+		assert.Equal(t, core.StateSchemaVersion, state.SchemaVersion)
+		assert.Equal(t, "run-42", state.RunID)
+		assert.Equal(t, i+1, state.Iteration)
+		assert.False(t, state.StartedAt.IsZero())
+		assert.False(t, state.UpdatedAt.IsZero())
+	}
+	assert.Equal(t, "/start metadata", seen[0].LastPrompt)
+	assert.Equal(t, "/continue", seen[1].LastPrompt)
+	assert.True(t, seen[0].StartedAt.Equal(seen[1].StartedAt), "metadata dropped by Claude is restored")
+
+	// A blocked run ends without error and keeps its state for resuming
+	state, err := core.LoadState(stateFile)
+	require.NoError(t, err)
+	assert.Equal(t, core.StatusBlocked, state.Status)
+}
+
+func TestEngine_FailedStateStopsRun(t *testing.T) {
+	calls := 0
+	executor := funcExecutor(func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+		calls++
+		state := &core.State{
+			CurrentStepDescription: "Tests cannot run",
+			NextStepPrompt:         "/continue",
+			Status:                 core.StatusFailed,
+			FailureReason:          "missing test database",
+		}
+		require.NoError(t, state.Save(config.StateFile))
+		return "ok", nil
+	})
+	engine, stateFile := newBudgetTestEngine(t, executor, nil)
+
+	err := engine.Run(context.Background(), "fail", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing test database")
+	assert.Equal(t, 1, calls, "Claude should not run again after a failed state")
+
+	_, statErr := os.Stat(stateFile)
+	assert.NoError(t, statErr, "failed state file is kept")
+}
+
+func TestEngine_BareModeResumesStoppedState(t *testing.T) {
+	var prompts []string
+	executor := funcExecutor(func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+		prompts = append(prompts, config.Prompt)
+		state := &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+		require.NoError(t, state.Save(config.StateFile))
+		return "ok", nil
+	})
+	engine, stateFile := newBudgetTestEngine(t, executor, nil)
+	require.NoError(t, os.MkdirAll(filepath.Dir(stateFile), 0755))
+	stopped := &core.State{
+		CurrentStepDescription: "Waiting for API key",
+		NextStepPrompt:         "/continue",
+		Status:                 core.StatusAwaitingInput,
+	}
+	require.NoError(t, stopped.Save(stateFile))
+
+	require.NoError(t, engine.Run(context.Background(), "", false))
+	assert.Equal(t, []string{"/continue"}, prompts)
+}
+
+func TestEngine_JournalsObservedStates(t *testing.T) {
+	calls := 0
+	executor := funcExecutor(func(ctx context.Context, config claude.ExecuteConfig) (string, error) {
+		calls++
+		if calls == 1 {
+			state := &core.State{CurrentStepDescription: "Step 1", NextStepPrompt: "/continue", Status: core.StatusRunning}
+			require.NoError(t, state.Save(config.StateFile))
+			return "ok", nil
+		}
+		// Claude exits non-zero on the second iteration
+		err := exec.Command("sh", "-c", "exit 3").Run()
+		return "", fmt.Errorf("claude execution failed: %w", err)
+	})
+	engine, stateFile := newBudgetTestEngine(t, executor, nil)
+	engine.SetRunID("run-journal")
+
+	require.Error(t, engine.Run(context.Background(), "journal", false))
+
+	entries, err := core.RunJournal(core.JournalPath(stateFile), "run-journal")
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	// The initial state, then one entry per iteration
+	assert.Equal(t, 0, entries[0].Iteration)
+	assert.Equal(t, "/start journal", entries[0].State.NextStepPrompt)
+
+	assert.Equal(t, 1, entries[1].Iteration)
+	assert.Equal(t, "/start journal", entries[1].Prompt)
+	assert.Equal(t, 0, entries[1].ExitCode)
+	assert.Equal(t, "Step 1", entries[1].State.CurrentStepDescription)
+
+	assert.Equal(t, 2, entries[2].Iteration)
+	assert.Equal(t, "/continue", entries[2].Prompt)
+	assert.Equal(t, 3, entries[2].ExitCode)
+	assert.NotEmpty(t, entries[2].Error)
+}
@@ -129,7 +129,7 @@ func TestWorkflowWithoutStreamer(t *testing.T) {
 	mockExecutor := &MockExecutorWithStreamer{}
 	mockWtMgr := &mock.WorktreeManager{}
 	cfg := &config.Config{
-		StateFile: "test_state.json",
+		StateFile: filepath.Join(t.TempDir(), "test_state.json"),
 		Git:       config.GitConfig{WorktreeEnabled: false},
 	}
 
@@ -155,7 +155,7 @@ func TestWorkflowRunIDCorrelation(t *testing.T) {
 	mockStreamer := &MockStreamer{}
 	mockWtMgr := &mock.WorktreeManager{}
 	cfg := &config.Config{
-		StateFile: "test_state.json",
+		StateFile: filepath.Join(t.TempDir(), "test_state.json"),
 		Git:       config.GitConfig{WorktreeEnabled: false},
 	}
 
@@ -218,7 +218,7 @@ func TestStreamerPropagationDuringExecution(t *testing.T) {
 	mockStreamer := &MockStreamer{}
 	mockWtMgr := &mock.WorktreeManager{}
 	cfg := &config.Config{
-		StateFile: "test_state.json",
+		StateFile: filepath.Join(t.TempDir(), "test_state.json"),
 		Git:       config.GitConfig{WorktreeEnabled: false},
 	}
 
@@ -249,7 +249,7 @@ func TestErrorHandlingWithStreamer(t *testing.T) {
 	}
 	mockWtMgr := &mock.WorktreeManager{}
 	cfg := &config.Config{
-		StateFile: "test_state.json",
+		StateFile: filepath.Join(t.TempDir(), "test_state.json"),
 		Git:       config.GitConfig{WorktreeEnabled: false},
 	}
 
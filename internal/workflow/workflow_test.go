@@ -352,12 +352,9 @@ func TestEngineCreatesWorktree(t *testing.T) {
 	tempDir := t.TempDir()
 	worktreeDir := filepath.Join(tempDir, "test-worktree")
 
-	// Save current directory to restore later
+	// The run must not change the process working directory
 	originalDir, err := os.Getwd()
 	require.NoError(t, err)
-	defer func() {
-		_ = os.Chdir(originalDir)
-	}()
 
 	// Mock worktree manager
 	mockWT := &gitx.Worktree{
@@ -366,22 +363,17 @@ func TestEngineCreatesWorktree(t *testing.T) {
 		ParentRepo: tempDir,
 	}
 
-	// Create a simple test executor that saves state relative to current directory
+	// Create a simple test executor that saves state to the path it is given
 	executor := &testExecutor{
 		t: t,
 		executions: []testExecution{
 			{
-				expectedPrompt: "/run_implementation_loop test task",
+				expectedPrompt: "/start test task",
 				beforeExecution: func() {
-					// At this point we should be in the worktree directory
+					// The worktree is the run's root, but the process stays where it was
 					cwd, err := os.Getwd()
 					require.NoError(t, err)
-					// Resolve symlinks for comparison (macOS has /var -> /private/var)
-					resolvedCwd, err := filepath.EvalSymlinks(cwd)
-					require.NoError(t, err)
-					resolvedWorktreeDir, err := filepath.EvalSymlinks(worktreeDir)
-					require.NoError(t, err)
-					assert.Equal(t, resolvedWorktreeDir, resolvedCwd)
+					assert.Equal(t, originalDir, cwd)
 				},
 				stateUpdate: &core.State{
 					CurrentStepDescription: "Task completed",
@@ -411,8 +403,10 @@ func TestEngineCreatesWorktree(t *testing.T) {
 	err = engine.Run(ctx, "test task", false)
 	require.NoError(t, err)
 
-	// Verify worktree was created
+	// Verify worktree was created and used as the run's root
 	assert.Len(t, wtMgr.CreateCalls, 1)
+	assert.Equal(t, worktreeDir, engine.WorkDir())
+	assert.Equal(t, filepath.Join(worktreeDir, "agent_state", "agent_state.json"), engine.stateFile)
 	assert.Equal(t, "test task", wtMgr.CreateCalls[0].TaskName)
 
 	// Verify cleanup was called (auto cleanup is enabled)
@@ -487,7 +481,7 @@ func TestEngineStateFileInWorktree(t *testing.T) {
 		t: t,
 		executions: []testExecution{
 			{
-				expectedPrompt: "/run_implementation_loop test task",
+				expectedPrompt: "/start test task",
 				stateUpdate: &core.State{
 					CurrentStepDescription: "Task completed",
 					NextStepPrompt:         "",
@@ -0,0 +1,154 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Backland-Labs/alpine/internal/claude"
+	"github.com/Backland-Labs/alpine/internal/core"
+	"github.com/Backland-Labs/alpine/internal/gitx"
+	gitxmock "github.com/Backland-Labs/alpine/internal/gitx/mock"
+	"github.com/Backland-Labs/alpine/internal/output"
+)
+
+// fakeClaudeScript records its working directory and completes the workflow by
+// writing the state file relative to that directory, as the real Claude does
+const fakeClaudeScript = `#!/bin/sh
+pwd > claude_cwd.txt
+mkdir -p agent_state
+printf '{"current_step_description":"done","next_step_prompt":"","status":"completed"}' > agent_state/agent_state.json
+`
+
+func TestEngine_ParallelRunsStayIsolated(t *testing.T) {
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "claude"), []byte(fakeClaudeScript), 0755))
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	originalDir, err := os.Getwd()
+	require.NoError(t, err)
+
+	// One executor is shared by every run, as in the HTTP server
+	executor := claude.NewExecutor()
+
+	const runs = 8
+	root := t.TempDir()
+	worktrees := make([]string, runs)
+	var wg sync.WaitGroup
+	errs := make([]error, runs)
+
+	for i := 0; i < runs; i++ {
+		worktrees[i] = filepath.Join(root, fmt.Sprintf("worktree-%d", i))
+		wtMgr := &gitxmock.WorktreeManager{
+			CreateFunc: func(ctx context.Context, taskName string) (*gitx.Worktree, error) {
+				path := worktrees[i]
+				if err := os.MkdirAll(path, 0755); err != nil {
+					return nil, err
+				}
+				return &gitx.Worktree{Path: path, Branch: "alpine/" + taskName, ParentRepo: root}, nil
+			},
+			CleanupFunc: func(ctx context.Context, wt *gitx.Worktree) error { return nil },
+		}
+
+		cfg := testConfig(true)
+		engine := NewEngine(executor, wtMgr, cfg, nil)
+		engine.SetPrinter(output.NewPrinterWithWriters(io.Discard, io.Discard, false))
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = engine.Run(context.Background(), fmt.Sprintf("task %d", i), false)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < runs; i++ {
+		require.NoError(t, errs[i], "run %d", i)
+
+		// Claude ran in this run's own worktree
+		cwd, err := os.ReadFile(filepath.Join(worktrees[i], "claude_cwd.txt"))
+		require.NoError(t, err, "run %d", i)
+		resolved, err := filepath.EvalSymlinks(worktrees[i])
+		require.NoError(t, err)
+		assert.Equal(t, resolved, strings.TrimSpace(string(cwd)), "run %d", i)
+
+		// The run's state file lived in, and was cleaned up from, its worktree
+		_, err = os.Stat(filepath.Join(worktrees[i], "agent_state", "agent_state.json"))
+		assert.True(t, os.IsNotExist(err), "run %d state file should be removed on completion", i)
+	}
+
+	// No run changed the process working directory or wrote into it
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, originalDir, cwd)
+	_, err = os.Stat(filepath.Join(originalDir, "claude_cwd.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestEngine_ReusedEngineResolvesEachRun(t *testing.T) {
+	first, second := initRunRepo(t), initRunRepo(t)
+	var stateFiles []string
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		stateFiles = append(stateFiles, cfg.StateFile)
+		require.NoError(t, os.WriteFile(filepath.Join(cfg.WorkDir, "change.txt"), []byte("x"), 0644))
+		state := &core.State{CurrentStepDescription: "Done", Status: core.StatusCompleted}
+		require.NoError(t, state.Save(cfg.StateFile))
+		return "ok", nil
+	})
+	cfg := testConfig(false)
+	cfg.Git.AutoCommit = true
+	engine := NewEngine(executor, nil, cfg, nil)
+	engine.SetPrinter(output.NewPrinterWithWriters(io.Discard, io.Discard, false))
+
+	cfg.WorkDir = first
+	engine.SetRunBranch("alpine/test")
+	require.NoError(t, engine.Run(context.Background(), "First task", false))
+
+	// The second run neither inherits the first run's state file nor its branch
+	cfg.WorkDir = second
+	require.NoError(t, engine.Run(context.Background(), "Second task", false))
+
+	assert.Equal(t, []string{
+		filepath.Join(first, "agent_state", "agent_state.json"),
+		filepath.Join(second, "agent_state", "agent_state.json"),
+	}, stateFiles)
+	assert.Equal(t, "1", runGit(t, first, "rev-list", "--count", "initial..HEAD"))
+	assert.Equal(t, "0", runGit(t, second, "rev-list", "--count", "initial..HEAD"))
+}
+
+func TestEngine_CleansUpWorktreeWhenSetupFails(t *testing.T) {
+	root := t.TempDir()
+	var cleaned []string
+	wtMgr := &gitxmock.WorktreeManager{
+		CreateFunc: func(ctx context.Context, taskName string) (*gitx.Worktree, error) {
+			return &gitx.Worktree{Path: root, Branch: "alpine/" + taskName, ParentRepo: root}, nil
+		},
+		CleanupFunc: func(ctx context.Context, wt *gitx.Worktree) error {
+			cleaned = append(cleaned, wt.Path)
+			return nil
+		},
+	}
+	executor := funcExecutor(func(ctx context.Context, cfg claude.ExecuteConfig) (string, error) {
+		t.Error("Claude should not run")
+		return "", nil
+	})
+	engine := NewEngine(executor, wtMgr, testConfig(true), nil)
+	engine.SetPrinter(output.NewPrinterWithWriters(io.Discard, io.Discard, false))
+
+	// The state directory cannot be created below a regular file
+	blocker := filepath.Join(root, "blocker")
+	require.NoError(t, os.WriteFile(blocker, nil, 0644))
+	engine.SetStateFile(filepath.Join(blocker, "agent_state", "agent_state.json"))
+
+	err := engine.Run(context.Background(), "task", false)
+	require.ErrorContains(t, err, "failed to create agent_state directory")
+	assert.Equal(t, []string{root}, cleaned)
+}
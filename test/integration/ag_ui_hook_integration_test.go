@@ -5,13 +5,12 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
-	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/Backland-Labs/alpine/internal/hooks"
 )
 
 // EventCollector collects events sent by the hook
@@ -44,17 +43,15 @@ func (ec *EventCollector) GetEvents() []map[string]interface{} {
 	return result
 }
 
-// TestAgUIHookIntegration tests the hook script in a realistic scenario
-func TestAgUIHookIntegration(t *testing.T) {
-	// Find the hook script
-	projectRoot := findProjectRoot(t)
-	hookScript := filepath.Join(projectRoot, "hooks", "alpine-ag-ui-emitter.rs")
-
-	// Verify hook script exists
-	if _, err := os.Stat(hookScript); os.IsNotExist(err) {
-		t.Skip("Hook script not found at", hookScript)
-	}
+// runHook runs the alpine hook handler with the given environment, as Claude
+// Code would when invoking the hook command
+func runHook(input string, env map[string]string) error {
+	handler := hooks.NewHandler(io.Discard, io.Discard, func(key string) string { return env[key] })
+	return handler.Handle(strings.NewReader(input))
+}
 
+// TestAgUIHookIntegration tests the hook handler in a realistic scenario
+func TestAgUIHookIntegration(t *testing.T) {
 	// Create event collector server
 	collector := &EventCollector{}
 	server := httptest.NewServer(collector)
@@ -122,15 +119,12 @@ func TestAgUIHookIntegration(t *testing.T) {
 
 			// Execute hook
 			toolJSON, _ := json.Marshal(tc.toolData)
-			cmd := exec.Command(hookScript)
-			cmd.Env = append(os.Environ(),
-				"ALPINE_EVENTS_ENDPOINT="+server.URL,
-				"ALPINE_RUN_ID="+runID,
-			)
-			cmd.Stdin = strings.NewReader(string(toolJSON))
-
-			if err := cmd.Run(); err != nil {
-				t.Fatalf("Hook script failed: %v", err)
+			err := runHook(string(toolJSON), map[string]string{
+				hooks.EnvEventsEndpoint: server.URL,
+				hooks.EnvRunID:          runID,
+			})
+			if err != nil {
+				t.Fatalf("Hook failed: %v", err)
 			}
 
 			// Give server time to receive events
@@ -179,14 +173,6 @@ func TestAgUIHookIntegration(t *testing.T) {
 
 // TestAgUIHookPerformance tests hook performance under load
 func TestAgUIHookPerformance(t *testing.T) {
-	// Find the hook script
-	projectRoot := findProjectRoot(t)
-	hookScript := filepath.Join(projectRoot, "hooks", "alpine-ag-ui-emitter.rs")
-
-	if _, err := os.Stat(hookScript); os.IsNotExist(err) {
-		t.Skip("Hook script not found")
-	}
-
 	// Create a server that counts requests
 	var requestCount int
 	var mu sync.Mutex
@@ -222,14 +208,11 @@ func TestAgUIHookPerformance(t *testing.T) {
 				}
 
 				toolJSON, _ := json.Marshal(toolData)
-				cmd := exec.Command(hookScript)
-				cmd.Env = append(os.Environ(),
-					"ALPINE_EVENTS_ENDPOINT="+server.URL,
-					"ALPINE_RUN_ID=perf-test",
-				)
-				cmd.Stdin = strings.NewReader(string(toolJSON))
-
-				if err := cmd.Run(); err != nil {
+				err := runHook(string(toolJSON), map[string]string{
+					hooks.EnvEventsEndpoint: server.URL,
+					hooks.EnvRunID:          "perf-test",
+				})
+				if err != nil {
 					t.Errorf("Worker %d iteration %d failed: %v", workerID, j, err)
 				}
 			}
@@ -254,28 +237,3 @@ func TestAgUIHookPerformance(t *testing.T) {
 		t.Errorf("Hook is too slow: %v per invocation", avgTimePerHook)
 	}
 }
-
-// findProjectRoot finds the alpine project root directory
-func findProjectRoot(t *testing.T) string {
-	t.Helper()
-
-	// Start from test directory and go up
-	dir, err := os.Getwd()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	for {
-		// Check if go.mod exists
-		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
-			return dir
-		}
-
-		// Go up one directory
-		parent := filepath.Dir(dir)
-		if parent == dir {
-			t.Fatal("Could not find project root")
-		}
-		dir = parent
-	}
-}
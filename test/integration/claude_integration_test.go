@@ -54,9 +54,9 @@ func TestClaudeCommandExecution(t *testing.T) {
 		{
 			name: "Execution with custom MCP servers",
 			config: claude.ExecuteConfig{
-				Prompt:     "/mcp-test Test with MCP servers",
-				StateFile:  stateFile,
-				MCPServers: []string{"context7", "web-search"},
+				Prompt:    "/mcp-test Test with MCP servers",
+				StateFile: stateFile,
+				MCPConfig: filepath.Join(tempDir, "mcp.json"),
 			},
 			initialState: &core.State{
 				CurrentStepDescription: "Testing MCP servers",